@@ -9,6 +9,15 @@ if [ ${TESTMODE} == "lint" ]; then
   gometalinter --deadline=300s --tests ./...
 fi
 
+if [ ${TESTMODE} == "crosscompile" ]; then
+  # make sure that quic-go builds on platforms without (or with restricted) UDP sockets
+  # The integration tests depend on Ginkgo, which doesn't build for js/wasm, the benchmark only contains tests.
+  pkgs=$(go list ./... | grep -v -e integrationtests -e benchmark)
+  GOOS=js GOARCH=wasm go build $pkgs
+  GOOS=android GOARCH=arm64 go build $pkgs
+  GOOS=darwin GOARCH=arm64 go build $pkgs
+fi
+
 if [ ${TESTMODE} == "unit" ]; then
   ginkgo -r -v -cover -randomizeAllSpecs -randomizeSuites -trace -skipPackage integrationtests,benchmark
 fi
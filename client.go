@@ -3,8 +3,10 @@ package quic
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"strings"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
@@ -12,21 +14,28 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 type client struct {
 	mutex sync.Mutex
 
-	conn connection
+	conn *conn
 	// If the client is created with DialAddr, we create a packet conn.
 	// If it is started with Dial, we take a packet conn as a parameter.
 	createdPacketConn bool
+	// The dialer and the address used to create the packet conn.
+	// They are used to obtain a new packet conn when rebinding.
+	dialer   PacketConnDialer
+	dialAddr string
 
-	packetHandlers packetHandlerManager
+	packetHandlersMutex sync.Mutex
+	packetHandlers      packetHandlerManager
 
 	versionNegotiated                utils.AtomicBool // has the server accepted our version
 	receivedVersionNegotiationPacket bool
 	negotiatedVersions               []protocol.VersionNumber // the list of versions from the version negotiation packet
+	unsupportedVersions              []protocol.VersionNumber // the list of versions from a version negotiation packet, if none of them is supported
 
 	tlsConf *tls.Config
 	config  *Config
@@ -56,6 +65,9 @@ var (
 
 // DialAddr establishes a new QUIC connection to a server.
 // It uses a new UDP connection and closes this connection when the QUIC session is closed.
+// The local address of the UDP connection and additional socket options can be configured using
+// the LocalAddr and Control fields of the Config.
+// If the Config sets a PacketConnDialer, the connection is obtained from the dialer instead.
 // The hostname for SNI is taken from the given address.
 func DialAddr(
 	addr string,
@@ -73,21 +85,48 @@ func DialAddrContext(
 	tlsConf *tls.Config,
 	config *Config,
 ) (Session, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	var dialer PacketConnDialer = &udpDialer{config: config}
+	if config != nil && config.PacketConnDialer != nil {
+		dialer = config.PacketConnDialer
+	}
+	pconn, remoteAddr, err := dialer.DialPacketConn(ctx, addr)
 	if err != nil {
 		return nil, err
 	}
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	return dialContext(ctx, pconn, remoteAddr, addr, tlsConf, config, dialer)
+}
+
+// udpDialer is the PacketConnDialer used if no PacketConnDialer is configured.
+// It uses a new UDP socket for every connection.
+type udpDialer struct {
+	config *Config
+}
+
+var _ PacketConnDialer = &udpDialer{}
+
+func (d *udpDialer) DialPacketConn(ctx context.Context, addr string) (net.PacketConn, net.Addr, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, true)
+	conf := populateClientConfig(d.config, true)
+	localAddr := &net.UDPAddr{IP: net.IPv4zero, Port: 0}
+	if conf.LocalAddr != nil {
+		localAddr = conf.LocalAddr
+	}
+	udpConn, err := listenUDP(ctx, localAddr.String(), conf.Control)
+	if err != nil {
+		return nil, nil, err
+	}
+	setSocketBuffers(udpConn, conf.ReceiveBufferSize, conf.SendBufferSize, utils.DefaultLogger.WithPrefix("client"))
+	return udpConn, udpAddr, nil
 }
 
 // Dial establishes a new QUIC connection to a server using a net.PacketConn.
 // The same PacketConn can be used for multiple calls to Dial and Listen,
 // QUIC connection IDs are used for demultiplexing the different connections.
 // The host parameter is used for SNI.
+// If the net.PacketConn is a Substrate, the host doesn't need to contain a port.
 func Dial(
 	pconn net.PacketConn,
 	remoteAddr net.Addr,
@@ -108,9 +147,12 @@ func DialContext(
 	tlsConf *tls.Config,
 	config *Config,
 ) (Session, error) {
-	return dialContext(ctx, pconn, remoteAddr, host, tlsConf, config, false)
+	return dialContext(ctx, pconn, remoteAddr, host, tlsConf, config, nil)
 }
 
+// dialContext dials a new connection.
+// The dialer is the PacketConnDialer that was used to create the pconn.
+// It is nil if the pconn was passed in by the application.
 func dialContext(
 	ctx context.Context,
 	pconn net.PacketConn,
@@ -118,10 +160,11 @@ func dialContext(
 	host string,
 	tlsConf *tls.Config,
 	config *Config,
-	createdPacketConn bool,
+	dialer PacketConnDialer,
 ) (Session, error) {
+	createdPacketConn := dialer != nil
 	config = populateClientConfig(config, createdPacketConn)
-	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength)
+	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.Clock)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +172,8 @@ func dialContext(
 	if err != nil {
 		return nil, err
 	}
+	c.dialer = dialer
+	c.dialAddr = host
 	c.packetHandlers = packetHandlers
 	if err := c.dial(ctx); err != nil {
 		return nil, err
@@ -148,12 +193,20 @@ func newClient(
 		tlsConf = &tls.Config{}
 	}
 	if tlsConf.ServerName == "" {
-		var err error
-		tlsConf.ServerName, _, err = net.SplitHostPort(host)
-		if err != nil {
-			return nil, err
+		if _, ok := pconn.(Substrate); ok && !strings.Contains(host, ":") {
+			// addresses of a Substrate don't necessarily have a port
+			tlsConf.ServerName = host
+		} else {
+			var err error
+			tlsConf.ServerName, _, err = net.SplitHostPort(host)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
+	if s, ok := pconn.(Substrate); ok && s.MaxDatagramSize(remoteAddr) < protocol.MinInitialPacketSize {
+		return nil, fmt.Errorf("quic: the substrate's maximum datagram size (%d bytes) is smaller than the minimum QUIC packet size (%d bytes)", s.MaxDatagramSize(remoteAddr), protocol.MinInitialPacketSize)
+	}
 
 	// check that all versions are actually supported
 	if config != nil {
@@ -226,10 +279,30 @@ func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges <= 0 {
+		maxAckRanges = protocol.DefaultMaxTrackedReceivedAckRanges
+	}
 	connIDLen := config.ConnectionIDLength
 	if connIDLen == 0 && !createdPacketConn {
 		connIDLen = protocol.DefaultConnectionIDLength
 	}
+	receiveBufferSize := config.ReceiveBufferSize
+	if receiveBufferSize == 0 {
+		receiveBufferSize = protocol.DesiredReceiveBufferSize
+	}
+	sendBufferSize := config.SendBufferSize
+	if sendBufferSize == 0 {
+		sendBufferSize = protocol.DesiredSendBufferSize
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = utils.DefaultClock
+	}
+	fecGroupSize := config.FECGroupSize
+	if fecGroupSize > protocol.MaxFECGroupSize {
+		fecGroupSize = protocol.MaxFECGroupSize
+	}
 
 	return &Config{
 		Versions:                              versions,
@@ -238,9 +311,29 @@ func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 		ConnectionIDLength:                    connIDLen,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
+		MaxReadAhead:                          config.MaxReadAhead,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
+		MaxConcurrentStreamHandlers:           config.MaxConcurrentStreamHandlers,
+		MaxAckRanges:                          maxAckRanges,
 		KeepAlive:                             config.KeepAlive,
+		PathHealthChanged:                     config.PathHealthChanged,
+		HyStartPlusPlus:                       config.HyStartPlusPlus,
+		QuicTracer:                            config.QuicTracer,
+		PacketConnDialer:                      config.PacketConnDialer,
+		LocalAddr:                             config.LocalAddr,
+		Control:                               config.Control,
+		AutoRebind:                            config.AutoRebind && createdPacketConn,
+		ReceiveBufferSize:                     receiveBufferSize,
+		SendBufferSize:                        sendBufferSize,
+		Clock:                                 clock,
+		DecryptionWorkers:                     config.DecryptionWorkers,
+		FECGroupSize:                          fecGroupSize,
+		KernelPacing:                          config.KernelPacing,
+		StreamCompression:                     config.StreamCompression,
+		StreamCompressionDictionary:           config.StreamCompressionDictionary,
+		PacketPadding:                         config.PacketPadding,
+		CoverTrafficInterval:                  config.CoverTrafficInterval,
 	}
 }
 
@@ -268,7 +361,7 @@ func (c *client) establishSecureConnection(ctx context.Context) error {
 	go func() {
 		err := c.session.run() // returns as soon as the session is closed
 		if err != errCloseForRecreating && c.createdPacketConn {
-			c.packetHandlers.Close()
+			c.getPacketHandlers().Close()
 		}
 		errorChan <- err
 	}()
@@ -279,6 +372,11 @@ func (c *client) establishSecureConnection(ctx context.Context) error {
 		c.session.Close()
 		return ctx.Err()
 	case err := <-errorChan:
+		if hErr, ok := err.(*HandshakeError); ok {
+			c.mutex.Lock()
+			hErr.OfferedVersions = c.unsupportedVersions
+			c.mutex.Unlock()
+		}
 		return err
 	case <-c.handshakeChan:
 		// handshake successfully completed
@@ -320,8 +418,16 @@ func (c *client) handleVersionNegotiationPacket(hdr *wire.Header) {
 	}
 
 	c.logger.Infof("Received a Version Negotiation packet. Supported Versions: %s", hdr.SupportedVersions)
+	if c.config.QuicTracer != nil {
+		c.config.QuicTracer.Trace(c.destConnID, quictrace.Event{
+			Time:              c.config.Clock.Now(),
+			EventType:         quictrace.VersionNegotiationReceived,
+			SupportedVersions: hdr.SupportedVersions,
+		})
+	}
 	newVersion, ok := protocol.ChooseSupportedVersion(c.config.Versions, hdr.SupportedVersions)
 	if !ok {
+		c.unsupportedVersions = hdr.SupportedVersions
 		c.session.destroy(qerr.InvalidVersion)
 		c.logger.Debugf("No compatible version found.")
 		return
@@ -348,14 +454,18 @@ func (c *client) createNewTLSSession(version protocol.VersionNumber) error {
 		MaxUniStreams:                  uint64(c.config.MaxIncomingUniStreams),
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableMigration:               true,
+		EnableFEC:                      c.config.FECGroupSize > 0,
+		EnableStreamCompression:        c.config.StreamCompression,
+		StreamCompressionDictionaryID:  streamCompressionDictionaryID(c.config.StreamCompressionDictionary),
 	}
 
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	runner := &runner{
 		onHandshakeCompleteImpl: func(_ Session) { close(c.handshakeChan) },
-		retireConnectionIDImpl:  c.packetHandlers.Retire,
-		removeConnectionIDImpl:  c.packetHandlers.Remove,
+		retireConnectionIDImpl:  func(id protocol.ConnectionID) { c.getPacketHandlers().Retire(id) },
+		removeConnectionIDImpl:  func(id protocol.ConnectionID) { c.getPacketHandlers().Remove(id) },
+		rebindImpl:              c.rebind,
 	}
 	sess, err := newClientSession(
 		c.conn,
@@ -378,6 +488,41 @@ func (c *client) createNewTLSSession(version protocol.VersionNumber) error {
 	return nil
 }
 
+func (c *client) getPacketHandlers() packetHandlerManager {
+	c.packetHandlersMutex.Lock()
+	defer c.packetHandlersMutex.Unlock()
+	return c.packetHandlers
+}
+
+// rebind replaces the packet conn by a new one, obtained from the dialer.
+// The session continues to use the same connection IDs.
+func (c *client) rebind() error {
+	if c.dialer == nil {
+		return errors.New("cannot rebind a packet conn that was passed to Dial")
+	}
+	pconn, _, err := c.dialer.DialPacketConn(context.Background(), c.dialAddr)
+	if err != nil {
+		return err
+	}
+	packetHandlers, err := getMultiplexer().AddConn(pconn, c.config.ConnectionIDLength, c.config.Clock)
+	if err != nil {
+		pconn.Close()
+		return err
+	}
+	packetHandlers.Add(c.srcConnID, c)
+	c.packetHandlersMutex.Lock()
+	oldPacketHandlers := c.packetHandlers
+	c.packetHandlers = packetHandlers
+	c.packetHandlersMutex.Unlock()
+	c.conn.setPacketConn(pconn)
+	// Remove the session before closing the packet handler map, since closing it destroys all sessions.
+	oldPacketHandlers.Remove(c.srcConnID)
+	if err := oldPacketHandlers.Close(); err != nil {
+		c.logger.Debugf("Error closing the old packet conn: %s", err)
+	}
+	return nil
+}
+
 func (c *client) Close() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
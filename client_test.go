@@ -7,6 +7,9 @@ import (
 	"errors"
 	"net"
 	"os"
+	"reflect"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -15,6 +18,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -130,7 +134,7 @@ var _ = Describe("Client", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			manager.EXPECT().Close()
-			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			remoteAddrChan := make(chan string, 1)
 			newClientSession = func(
@@ -156,11 +160,98 @@ var _ = Describe("Client", func() {
 			Eventually(remoteAddrChan).Should(Receive(Equal("127.0.0.1:17890")))
 		})
 
+		It("uses the PacketConnDialer", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(gomock.Any(), gomock.Any())
+			managerClosed := make(chan struct{})
+			manager.EXPECT().Close().Do(func() { close(managerClosed) })
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+			remoteAddrChan := make(chan net.Addr, 1)
+			newClientSession = func(
+				conn connection,
+				_ sessionRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ *handshake.TransportParameters,
+				_ protocol.VersionNumber,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) (quicSession, error) {
+				remoteAddrChan <- conn.RemoteAddr()
+				closed := make(chan struct{})
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().run().Do(func() { <-closed })
+				sess.EXPECT().Close().Do(func() { close(closed) })
+				return sess, nil
+			}
+			dialer := &packetConnDialer{conn: packetConn, addr: addr}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := DialAddrContext(ctx, "proxied.example.com:443", nil, &Config{PacketConnDialer: dialer})
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(dialer.dialed).To(Equal("proxied.example.com:443"))
+			Eventually(remoteAddrChan).Should(Receive(Equal(addr)))
+			Eventually(managerClosed).Should(BeClosed())
+		})
+
+		It("returns errors from the PacketConnDialer", func() {
+			testErr := errors.New("proxy unavailable")
+			_, err := DialAddr("proxied.example.com:443", nil, &Config{PacketConnDialer: &packetConnDialer{err: testErr}})
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("binds the UDP socket to the configured local address", func() {
+			laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			ln, err := net.ListenUDP("udp", laddr)
+			Expect(err).ToNot(HaveOccurred())
+			// find a free port, then release it so the dialer can bind to it
+			localAddr := ln.LocalAddr().(*net.UDPAddr)
+			Expect(ln.Close()).To(Succeed())
+			d := &udpDialer{config: &Config{LocalAddr: localAddr}}
+			conn, raddr, err := d.DialPacketConn(context.Background(), "localhost:17890")
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(raddr.String()).To(Equal("127.0.0.1:17890"))
+			Expect(conn.LocalAddr().String()).To(Equal(localAddr.String()))
+		})
+
+		It("calls the Control function before binding the UDP socket", func() {
+			var network, address string
+			control := func(n, a string, _ syscall.RawConn) error {
+				network = n
+				address = a
+				return nil
+			}
+			d := &udpDialer{config: &Config{
+				LocalAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+				Control:   control,
+			}}
+			conn, _, err := d.DialPacketConn(context.Background(), "localhost:17890")
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(network).To(Equal("udp4"))
+			Expect(address).To(Equal("127.0.0.1:0"))
+		})
+
+		It("returns errors from the Control function", func() {
+			testErr := errors.New("SO_BINDTODEVICE failed")
+			d := &udpDialer{config: &Config{
+				Control: func(string, string, syscall.RawConn) error { return testErr },
+			}}
+			_, _, err := d.DialPacketConn(context.Background(), "localhost:17890")
+			Expect(err).To(MatchError(ContainSubstring(testErr.Error())))
+		})
+
 		It("uses the tls.Config.ServerName as the hostname, if present", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			manager.EXPECT().Close()
-			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			hostnameChan := make(chan string, 1)
 			newClientSession = func(
@@ -189,7 +280,7 @@ var _ = Describe("Client", func() {
 		It("returns after the handshake is complete", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
-			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			run := make(chan struct{})
 			newClientSession = func(
@@ -225,7 +316,7 @@ var _ = Describe("Client", func() {
 		It("returns an error that occurs while waiting for the connection to become secure", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
-			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			testErr := errors.New("early handshake error")
 			newClientSession = func(
@@ -259,7 +350,7 @@ var _ = Describe("Client", func() {
 		It("closes the session when the context is canceled", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
-			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			sessionRunning := make(chan struct{})
 			defer close(sessionRunning)
@@ -307,7 +398,7 @@ var _ = Describe("Client", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(connID, gomock.Any())
 			manager.EXPECT().Retire(connID)
-			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			var runner sessionRunner
 			sess := NewMockQuicSession(mockCtrl)
@@ -348,7 +439,7 @@ var _ = Describe("Client", func() {
 			}
 
 			manager := NewMockPacketHandlerManager(mockCtrl)
-			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 
 			var conn connection
@@ -398,24 +489,56 @@ var _ = Describe("Client", func() {
 
 		Context("quic.Config", func() {
 			It("setups with the right values", func() {
+				pathHealthChanged := func(Session, PathHealth, error) {}
+				control := func(string, string, syscall.RawConn) error { return nil }
 				config := &Config{
-					HandshakeTimeout:      1337 * time.Minute,
-					IdleTimeout:           42 * time.Hour,
-					MaxIncomingStreams:    1234,
-					MaxIncomingUniStreams: 4321,
-					ConnectionIDLength:    13,
+					PathHealthChanged:           pathHealthChanged,
+					HandshakeTimeout:            1337 * time.Minute,
+					IdleTimeout:                 42 * time.Hour,
+					MaxIncomingStreams:          1234,
+					MaxIncomingUniStreams:       4321,
+					MaxAckRanges:                42,
+					ConnectionIDLength:          13,
+					HyStartPlusPlus:             true,
+					LocalAddr:                   &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4242},
+					Control:                     control,
+					Clock:                       offsetClock{utils.DefaultClock},
+					DecryptionWorkers:           4,
+					FECGroupSize:                100,
+					KernelPacing:                true,
+					MaxConcurrentStreamHandlers: 7,
+					MaxReadAhead:                1 << 20,
+					StreamCompression:           true,
+					StreamCompressionDictionary: []byte("foobar"),
+					PacketPadding:               256,
+					CoverTrafficInterval:        time.Second,
 				}
 				c := populateClientConfig(config, false)
 				Expect(c.HandshakeTimeout).To(Equal(1337 * time.Minute))
 				Expect(c.IdleTimeout).To(Equal(42 * time.Hour))
 				Expect(c.MaxIncomingStreams).To(Equal(1234))
 				Expect(c.MaxIncomingUniStreams).To(Equal(4321))
+				Expect(c.MaxAckRanges).To(Equal(42))
 				Expect(c.ConnectionIDLength).To(Equal(13))
+				Expect(c.HyStartPlusPlus).To(BeTrue())
+				Expect(reflect.ValueOf(c.PathHealthChanged)).To(Equal(reflect.ValueOf(pathHealthChanged)))
+				Expect(c.LocalAddr).To(Equal(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4242}))
+				Expect(reflect.ValueOf(c.Control)).To(Equal(reflect.ValueOf(control)))
+				Expect(c.Clock).To(Equal(offsetClock{utils.DefaultClock}))
+				Expect(c.DecryptionWorkers).To(Equal(4))
+				Expect(c.FECGroupSize).To(Equal(protocol.MaxFECGroupSize))
+				Expect(c.KernelPacing).To(BeTrue())
+				Expect(c.MaxConcurrentStreamHandlers).To(Equal(7))
+				Expect(c.MaxReadAhead).To(BeEquivalentTo(1 << 20))
+				Expect(c.StreamCompression).To(BeTrue())
+				Expect(c.StreamCompressionDictionary).To(Equal([]byte("foobar")))
+				Expect(c.PacketPadding).To(Equal(256))
+				Expect(c.CoverTrafficInterval).To(Equal(time.Second))
 			})
 
 			It("errors when the Config contains an invalid version", func() {
 				manager := NewMockPacketHandlerManager(mockCtrl)
-				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any()).Return(manager, nil)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 				version := protocol.VersionNumber(0x1234)
 				_, err := Dial(packetConn, nil, "localhost:1234", &tls.Config{}, &Config{Versions: []protocol.VersionNumber{version}})
@@ -453,13 +576,23 @@ var _ = Describe("Client", func() {
 				Expect(c.Versions).To(Equal(protocol.SupportedVersions))
 				Expect(c.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 				Expect(c.IdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
+				Expect(c.MaxAckRanges).To(Equal(protocol.DefaultMaxTrackedReceivedAckRanges))
+				Expect(c.ReceiveBufferSize).To(Equal(protocol.DesiredReceiveBufferSize))
+				Expect(c.SendBufferSize).To(Equal(protocol.DesiredSendBufferSize))
+				Expect(c.Clock).To(Equal(utils.DefaultClock))
+			})
+
+			It("doesn't change the socket buffer sizes, if requested", func() {
+				c := populateClientConfig(&Config{ReceiveBufferSize: -1, SendBufferSize: 1337}, false)
+				Expect(c.ReceiveBufferSize).To(Equal(-1))
+				Expect(c.SendBufferSize).To(Equal(1337))
 			})
 		})
 
 		It("creates new TLS sessions with the right parameters", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(connID, gomock.Any())
-			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any()).Return(manager, nil)
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			config := &Config{Versions: []protocol.VersionNumber{protocol.VersionTLS}}
 			c := make(chan struct{})
@@ -511,7 +644,7 @@ var _ = Describe("Client", func() {
 			It("returns an error that occurs during version negotiation", func() {
 				manager := NewMockPacketHandlerManager(mockCtrl)
 				manager.EXPECT().Add(connID, gomock.Any())
-				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any()).Return(manager, nil)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 				testErr := errors.New("early handshake error")
 				newClientSession = func(
@@ -596,6 +729,25 @@ var _ = Describe("Client", func() {
 				Expect(cl.version).To(Equal(protocol.VersionNumber(1234)))
 			})
 
+			It("traces Version Negotiation packets", func() {
+				sess := NewMockQuicSession(mockCtrl)
+				destroyed := make(chan struct{})
+				sess.EXPECT().closeForRecreating().Do(func() { close(destroyed) })
+				cl.session = sess
+				tracer := &eventTracer{}
+				versions := []protocol.VersionNumber{1234, 4321}
+				cl.config = &Config{Versions: versions, QuicTracer: tracer, Clock: utils.DefaultClock}
+				cl.handlePacket(composeVersionNegotiationPacket(connID, versions))
+				Eventually(destroyed).Should(BeClosed())
+				events := tracer.getEvents()
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].EventType).To(Equal(quictrace.VersionNegotiationReceived))
+				// the Version Negotiation packet also contains a reserved version
+				Expect(events[0].SupportedVersions).To(ContainElement(protocol.VersionNumber(1234)))
+				Expect(events[0].SupportedVersions).To(ContainElement(protocol.VersionNumber(4321)))
+				Expect(events[0].Time).To(BeTemporally("~", time.Now(), scaleDuration(50*time.Millisecond)))
+			})
+
 			It("drops version negotiation packets that contain the offered version", func() {
 				cl.config = &Config{}
 				ver := cl.version
@@ -609,4 +761,77 @@ var _ = Describe("Client", func() {
 		Expect(cl.version).ToNot(BeZero())
 		Expect(cl.GetVersion()).To(Equal(cl.version))
 	})
+
+	Context("rebinding", func() {
+		It("replaces the packet conn", func() {
+			cl.config = &Config{ConnectionIDLength: 4}
+			oldManager := NewMockPacketHandlerManager(mockCtrl)
+			cl.packetHandlers = oldManager
+			newPacketConn := newMockPacketConn()
+			dialer := &packetConnDialer{conn: newPacketConn, addr: addr}
+			cl.dialer = dialer
+			cl.dialAddr = "example.com:443"
+			newManager := NewMockPacketHandlerManager(mockCtrl)
+			mockMultiplexer.EXPECT().AddConn(newPacketConn, 4, gomock.Any()).Return(newManager, nil)
+			gomock.InOrder(
+				newManager.EXPECT().Add(connID, cl),
+				oldManager.EXPECT().Remove(connID),
+				oldManager.EXPECT().Close(),
+			)
+			Expect(cl.rebind()).To(Succeed())
+			Expect(dialer.dialed).To(Equal("example.com:443"))
+			Expect(cl.getPacketHandlers()).To(Equal(newManager))
+			Expect(cl.conn.pconn).To(Equal(newPacketConn))
+		})
+
+		It("returns errors from the dialer", func() {
+			testErr := errors.New("no network")
+			cl.dialer = &packetConnDialer{err: testErr}
+			Expect(cl.rebind()).To(MatchError(testErr))
+			Expect(cl.conn.pconn).To(Equal(packetConn))
+		})
+
+		It("doesn't rebind packet conns passed to Dial", func() {
+			Expect(cl.rebind()).To(MatchError("cannot rebind a packet conn that was passed to Dial"))
+		})
+
+		It("only enables rebinding for packet conns created by DialAddr", func() {
+			Expect(populateClientConfig(&Config{AutoRebind: true}, true).AutoRebind).To(BeTrue())
+			Expect(populateClientConfig(&Config{AutoRebind: true}, false).AutoRebind).To(BeFalse())
+		})
+	})
 })
+
+type packetConnDialer struct {
+	conn net.PacketConn
+	addr net.Addr
+	err  error
+
+	dialed string
+}
+
+func (d *packetConnDialer) DialPacketConn(_ context.Context, addr string) (net.PacketConn, net.Addr, error) {
+	d.dialed = addr
+	return d.conn, d.addr, d.err
+}
+
+type eventTracer struct {
+	mutex  sync.Mutex
+	events []quictrace.Event
+}
+
+var _ quictrace.Tracer = &eventTracer{}
+
+func (t *eventTracer) Trace(_ protocol.ConnectionID, ev quictrace.Event) {
+	t.mutex.Lock()
+	t.events = append(t.events, ev)
+	t.mutex.Unlock()
+}
+
+func (t *eventTracer) GetAllTraces() map[string][]byte { panic("not implemented") }
+
+func (t *eventTracer) getEvents() []quictrace.Event {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.events
+}
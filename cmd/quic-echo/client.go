@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+)
+
+// An event is printed for the established session, for every echo, and at the end.
+type event struct {
+	Type string `json:"type"` // "connected", "echo" or "summary"
+
+	// only set for connected events
+	RemoteAddr  string  `json:"remote_addr,omitempty"`
+	Version     string  `json:"version,omitempty"`
+	HandshakeMs float64 `json:"handshake_ms,omitempty"`
+
+	// only set for echo events
+	Seq   int     `json:"seq,omitempty"`
+	Bytes int     `json:"bytes,omitempty"`
+	RTTMs float64 `json:"rtt_ms,omitempty"`
+	Error string  `json:"error,omitempty"`
+
+	// only set for summary events
+	Sent     int     `json:"sent,omitempty"`
+	Received int     `json:"received,omitempty"`
+	MinRTTMs float64 `json:"min_rtt_ms,omitempty"`
+	AvgRTTMs float64 `json:"avg_rtt_ms,omitempty"`
+	MaxRTTMs float64 `json:"max_rtt_ms,omitempty"`
+}
+
+func (e *event) write(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(e)
+	}
+	var err error
+	switch e.Type {
+	case "connected":
+		_, err = fmt.Fprintf(w, "Connected to %s (%s), handshake took %.3f ms\n", e.RemoteAddr, e.Version, e.HandshakeMs)
+	case "echo":
+		if e.Error != "" {
+			_, err = fmt.Fprintf(w, "seq=%d error: %s\n", e.Seq, e.Error)
+		} else {
+			_, err = fmt.Fprintf(w, "%d bytes echoed: seq=%d rtt=%.3f ms\n", e.Bytes, e.Seq, e.RTTMs)
+		}
+	case "summary":
+		_, err = fmt.Fprintf(w, "%d echoes sent, %d received, rtt min/avg/max = %.3f/%.3f/%.3f ms\n", e.Sent, e.Received, e.MinRTTMs, e.AvgRTTMs, e.MaxRTTMs)
+	}
+	return err
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// runClient connects to the server and performs o.count echoes.
+// It returns false if any of the echoes failed.
+func runClient(o *options, w io.Writer) (bool, error) {
+	start := time.Now()
+	sess, err := quic.DialAddr(o.addr, &tls.Config{InsecureSkipVerify: !o.verify}, &quic.Config{HandshakeTimeout: o.timeout})
+	if err != nil {
+		return false, err
+	}
+	defer sess.Close()
+	connected := &event{
+		Type:        "connected",
+		RemoteAddr:  sess.RemoteAddr().String(),
+		Version:     sess.ConnectionState().Version.String(),
+		HandshakeMs: toMs(time.Since(start)),
+	}
+	if err := connected.write(w, o.format); err != nil {
+		return false, err
+	}
+
+	summary := &event{Type: "summary"}
+	var totalRTT time.Duration
+	for i := 1; i <= o.count; i++ {
+		if i > 1 {
+			time.Sleep(o.interval)
+		}
+		summary.Sent++
+		e := &event{Type: "echo", Seq: i, Bytes: len(o.message)}
+		rtt, err := echo(sess, []byte(o.message), o.timeout)
+		if err != nil {
+			e.Error = err.Error()
+		} else {
+			e.RTTMs = toMs(rtt)
+			if summary.Received == 0 || e.RTTMs < summary.MinRTTMs {
+				summary.MinRTTMs = e.RTTMs
+			}
+			if e.RTTMs > summary.MaxRTTMs {
+				summary.MaxRTTMs = e.RTTMs
+			}
+			summary.Received++
+			totalRTT += rtt
+		}
+		if err := e.write(w, o.format); err != nil {
+			return false, err
+		}
+	}
+	if summary.Received > 0 {
+		summary.AvgRTTMs = toMs(totalRTT / time.Duration(summary.Received))
+	}
+	if err := summary.write(w, o.format); err != nil {
+		return false, err
+	}
+	return summary.Received == summary.Sent, nil
+}
+
+// echo sends the message on a new stream, and waits until the server echoed it.
+func echo(sess quic.Session, message []byte, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	str, err := sess.OpenStreamSync()
+	if err != nil {
+		return 0, err
+	}
+	if err := str.SetDeadline(start.Add(timeout)); err != nil {
+		return 0, err
+	}
+	if _, err := str.Write(message); err != nil {
+		return 0, err
+	}
+	if err := str.Close(); err != nil {
+		return 0, err
+	}
+	data, err := ioutil.ReadAll(str)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	if !bytes.Equal(data, message) {
+		return 0, errors.New("received a different message")
+	}
+	return rtt, nil
+}
@@ -0,0 +1,74 @@
+// Command quic-echo is a QUIC echo server and client.
+//
+// It is meant to check that QUIC connections can be established between two machines,
+// for example through firewalls and NATs. The server echoes the data received on every stream.
+// For every echo, the client opens a new stream, sends a message, and measures the time until
+// the message is echoed back:
+//
+//	quic-echo -role server -addr 0.0.0.0:4242
+//	quic-echo -role client -addr example.com:4242 -count 10
+//
+// By default, the server uses a self-signed certificate, and the client doesn't verify the certificate.
+// Results are printed as text, or as one JSON object per line (-format json).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+type options struct {
+	role     string
+	addr     string
+	format   string
+	message  string
+	count    int
+	interval time.Duration
+	timeout  time.Duration
+	certFile string
+	keyFile  string
+	verify   bool
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.role, "role", "client", "role of this instance: server or client")
+	flag.StringVar(&o.addr, "addr", "localhost:4242", "address to listen on (server), or to connect to (client)")
+	flag.StringVar(&o.format, "format", "text", "output format: text or json")
+	flag.StringVar(&o.message, "message", "quic-echo", "message sent for every echo")
+	flag.IntVar(&o.count, "count", 5, "number of echoes")
+	flag.DurationVar(&o.interval, "interval", time.Second, "time between two echoes")
+	flag.DurationVar(&o.timeout, "timeout", 5*time.Second, "timeout for the handshake and for every echo")
+	flag.StringVar(&o.certFile, "cert", "", "certificate file used by the server (a self-signed certificate is generated if not set)")
+	flag.StringVar(&o.keyFile, "key", "", "key file used by the server")
+	flag.BoolVar(&o.verify, "verify", false, "verify the certificate of the server")
+	flag.Parse()
+
+	if err := run(&o); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(o *options) error {
+	if o.format != "text" && o.format != "json" {
+		return fmt.Errorf("unknown output format: %s", o.format)
+	}
+	switch o.role {
+	case "server":
+		return runServer(o)
+	case "client":
+		ok, err := runClient(o, os.Stdout)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown role: %s", o.role)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"log"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/certs"
+)
+
+func runServer(o *options) error {
+	tlsConf, err := certs.LoadTLSConfig(o.certFile, o.keyFile)
+	if err != nil {
+		return err
+	}
+	ln, err := quic.ListenAddr(o.addr, tlsConf, &quic.Config{HandshakeTimeout: o.timeout})
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	log.Printf("Listening on %s", ln.Addr())
+	for {
+		sess, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		log.Printf("Accepted a session from %s (%s)", sess.RemoteAddr(), sess.ConnectionState().Version)
+		go handleSession(sess)
+	}
+}
+
+func handleSession(sess quic.Session) {
+	for {
+		str, err := sess.AcceptStream()
+		if err != nil {
+			log.Printf("Session with %s closed: %s", sess.RemoteAddr(), err)
+			return
+		}
+		go func() {
+			defer str.Close()
+			if _, err := io.Copy(str, str); err != nil {
+				log.Printf("Error echoing on stream %d of %s: %s", str.StreamID(), sess.RemoteAddr(), err)
+			}
+		}()
+	}
+}
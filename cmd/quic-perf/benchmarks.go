@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+)
+
+// A benchmark runs a single run of the handshakes or the connections test.
+type benchmark func(o *options) (*benchmarkReport, error)
+
+func runBenchmark(o *options, w io.Writer, bench benchmark) error {
+	for i := 0; i < o.runs; i++ {
+		res, err := bench(o)
+		if err != nil {
+			return err
+		}
+		res.Run = i + 1
+		if err := res.write(w, o.format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handshakes dials new sessions for o.duration, using o.concurrency parallel workers.
+// Every session is closed right after the handshake completes.
+func handshakes(o *options) (*benchmarkReport, error) {
+	var mutex sync.Mutex
+	var latencies []time.Duration
+	var failed int
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(o.duration)
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				t := time.Now()
+				sess, err := dial(o)
+				latency := time.Since(t)
+				mutex.Lock()
+				if err != nil {
+					failed++
+				} else {
+					latencies = append(latencies, latency)
+				}
+				mutex.Unlock()
+				if err == nil {
+					sess.Close()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	res := newBenchmarkReport("handshakes")
+	res.Parameters["concurrency"] = o.concurrency
+	res.Duration = elapsed
+	res.Values["handshakes"] = float64(len(latencies))
+	res.Values["failed"] = float64(failed)
+	res.Values["handshakes_per_second"] = float64(len(latencies)) / elapsed.Seconds()
+	res.addLatencies("handshake_latency", latencies)
+	return res, nil
+}
+
+// connections establishes o.conns sessions, and keeps them open.
+// It then performs small request-response exchanges on all sessions for o.duration.
+func connections(o *options) (*benchmarkReport, error) {
+	sessions := make([]quic.Session, 0, o.conns)
+	defer func() {
+		for _, sess := range sessions {
+			sess.Close()
+		}
+	}()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var dialErr error
+	work := make(chan struct{}, o.conns)
+	for i := 0; i < o.conns; i++ {
+		work <- struct{}{}
+	}
+	close(work)
+	start := time.Now()
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range work {
+				sess, err := dial(o)
+				mutex.Lock()
+				if err != nil {
+					dialErr = err
+				} else {
+					sessions = append(sessions, sess)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	setupTime := time.Since(start)
+	if dialErr != nil {
+		return nil, dialErr
+	}
+
+	runtime.GC()
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	var latencies []time.Duration
+	var failed int
+	start = time.Now()
+	deadline := start.Add(o.duration)
+	for _, sess := range sessions {
+		wg.Add(1)
+		go func(sess quic.Session) {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				t := time.Now()
+				err := request(sess)
+				latency := time.Since(t)
+				mutex.Lock()
+				if err != nil {
+					failed++
+				} else {
+					latencies = append(latencies, latency)
+				}
+				mutex.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}(sess)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	res := newBenchmarkReport("connections")
+	res.Parameters["conns"] = o.conns
+	res.Parameters["concurrency"] = o.concurrency
+	res.Duration = elapsed
+	res.Values["setup_time_seconds"] = setupTime.Seconds()
+	res.Values["heap_in_use_bytes"] = float64(memStats.HeapInuse)
+	res.Values["goroutines"] = float64(runtime.NumGoroutine())
+	res.Values["requests_per_second"] = float64(len(latencies)) / elapsed.Seconds()
+	res.Values["failed"] = float64(failed)
+	res.addLatencies("request_latency", latencies)
+	return res, nil
+}
+
+// request sends a single ping on a new stream, and waits until the server echoed it.
+func request(sess quic.Session) error {
+	str, err := sess.OpenStreamSync()
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 9)
+	b[0] = streamTypePing
+	binary.BigEndian.PutUint64(b[1:], uint64(time.Now().UnixNano()))
+	if _, err := str.Write(b); err != nil {
+		return err
+	}
+	if err := str.Close(); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(str, b[1:])
+	return err
+}
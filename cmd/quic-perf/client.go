@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+)
+
+// A pingRecorder collects the RTTs measured by ping.
+type pingRecorder struct {
+	mutex    sync.Mutex
+	interval []time.Duration // RTTs measured since the last call to popInterval
+	all      []time.Duration
+}
+
+func (r *pingRecorder) add(rtt time.Duration) {
+	r.mutex.Lock()
+	r.interval = append(r.interval, rtt)
+	r.all = append(r.all, rtt)
+	r.mutex.Unlock()
+}
+
+func (r *pingRecorder) popInterval() []time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	rtts := r.interval
+	r.interval = nil
+	return rtts
+}
+
+func (r *pingRecorder) getAll() []time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]time.Duration{}, r.all...)
+}
+
+func runClient(o *options, w io.Writer) error {
+	switch o.test {
+	case "handshakes":
+		return runBenchmark(o, w, handshakes)
+	case "connections":
+		return runBenchmark(o, w, connections)
+	default:
+		return runTransfer(o, w)
+	}
+}
+
+func dial(o *options) (quic.Session, error) {
+	return quic.DialAddr(o.addr, &tls.Config{InsecureSkipVerify: !o.verify}, &quic.Config{KeepAlive: true})
+}
+
+func runTransfer(o *options, w io.Writer) error {
+	start := time.Now()
+	sess, err := dial(o)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	if err := newConnectedReport(sess.RemoteAddr().String(), sess.ConnectionState().Version.String(), time.Since(start)).write(w, o.format); err != nil {
+		return err
+	}
+
+	var pings pingRecorder
+	stopPinging := make(chan struct{})
+	pingerDone := make(chan struct{})
+	if o.pingInterval > 0 {
+		go func() {
+			defer close(pingerDone)
+			ping(sess, o.pingInterval, &pings, stopPinging)
+		}()
+	} else {
+		close(pingerDone)
+	}
+
+	type streamResult struct {
+		bytes uint64
+		err   error
+	}
+	var transferred uint64 // accessed atomically, used for the interval reports
+	results := make(chan streamResult, o.streams)
+	start = time.Now()
+	for i := 0; i < o.streams; i++ {
+		go func() {
+			var res streamResult
+			if o.direction == "upload" {
+				res.bytes, res.err = upload(sess, o.duration, &transferred)
+			} else {
+				res.bytes, res.err = download(sess, o.duration, &transferred)
+			}
+			results <- res
+		}()
+	}
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+	var total, lastBytes uint64
+	var lastTime time.Duration
+	var firstErr error
+	for remaining := o.streams; remaining > 0; {
+		select {
+		case res := <-results:
+			remaining--
+			total += res.bytes
+			if res.err != nil && firstErr == nil {
+				firstErr = res.err
+			}
+		case <-ticker.C:
+			now := time.Since(start)
+			n := atomic.LoadUint64(&transferred)
+			if err := newTransferReport("interval", lastTime, now, n-lastBytes, pings.popInterval()).write(w, o.format); err != nil {
+				return err
+			}
+			lastBytes = n
+			lastTime = now
+		}
+	}
+	elapsed := time.Since(start)
+	close(stopPinging)
+	<-pingerDone
+	if firstErr != nil {
+		return firstErr
+	}
+	if n := atomic.LoadUint64(&transferred); n > lastBytes {
+		if err := newTransferReport("interval", lastTime, elapsed, n-lastBytes, pings.popInterval()).write(w, o.format); err != nil {
+			return err
+		}
+	}
+	summary := newTransferReport("summary", 0, elapsed, total, pings.getAll())
+	summary.Direction = o.direction
+	summary.Streams = o.streams
+	return summary.write(w, o.format)
+}
+
+// upload sends data for the duration d.
+// It returns the number of bytes received by the server.
+func upload(sess quic.Session, d time.Duration, transferred *uint64) (uint64, error) {
+	str, err := sess.OpenStreamSync()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := str.Write([]byte{streamTypeUpload}); err != nil {
+		return 0, err
+	}
+	// Write blocks if the stream is blocked by flow control.
+	// Use a deadline to make sure that the transfer stops in time.
+	if err := str.SetWriteDeadline(time.Now().Add(d)); err != nil {
+		return 0, err
+	}
+	for {
+		n, err := str.Write(chunk)
+		atomic.AddUint64(transferred, uint64(n))
+		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := str.Close(); err != nil {
+		return 0, err
+	}
+	var b [8]byte
+	if _, err := io.ReadFull(str, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// download requests the server to send data for the duration d.
+// It returns the number of bytes received.
+func download(sess quic.Session, d time.Duration, transferred *uint64) (uint64, error) {
+	str, err := sess.OpenStreamSync()
+	if err != nil {
+		return 0, err
+	}
+	b := make([]byte, 9)
+	b[0] = streamTypeDownload
+	binary.BigEndian.PutUint64(b[1:], uint64(d))
+	if _, err := str.Write(b); err != nil {
+		return 0, err
+	}
+	if err := str.Close(); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, chunkSize)
+	var received uint64
+	for {
+		n, err := str.Read(buf)
+		received += uint64(n)
+		atomic.AddUint64(transferred, uint64(n))
+		if err == io.EOF {
+			return received, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// ping sends a ping every interval, until stop is closed.
+// The server echoes the pings, and the RTTs are added to the pingRecorder.
+// Errors are ignored, since they cause the transfer to fail as well.
+func ping(sess quic.Session, interval time.Duration, pings *pingRecorder, stop <-chan struct{}) {
+	str, err := sess.OpenStreamSync()
+	if err != nil {
+		return
+	}
+	defer str.Close()
+	if _, err := str.Write([]byte{streamTypePing}); err != nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var b [8]byte
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		start := time.Now()
+		binary.BigEndian.PutUint64(b[:], uint64(start.UnixNano()))
+		if _, err := str.Write(b[:]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(str, b[:]); err != nil {
+			return
+		}
+		pings.add(time.Since(start))
+	}
+}
@@ -0,0 +1,139 @@
+// Command quic-perf measures the throughput and latency of QUIC connections.
+//
+// Like iperf, one instance runs as a server, and another instance runs as the client:
+//
+//	quic-perf -role server -addr 0.0.0.0:4242
+//	quic-perf -role client -addr example.com:4242 -direction download -streams 4 -duration 10s
+//
+// With -role both, the server and the client run in the same process, which makes it possible
+// to reproducibly compare the performance of different versions of quic-go.
+//
+// The client runs one of the following tests (-test):
+//
+// transfer: The client sends (upload) or receives (download) data on one or more parallel streams (-streams),
+// and reports the throughput for every interval (-interval).
+// At the same time, it measures the round trip time on a separate stream, by sending small pings
+// that are echoed by the server. The latency therefore includes the queueing delay caused by the transfer.
+//
+// handshakes: The client dials new connections for the duration of the test, using -concurrency parallel workers,
+// and reports the handshake rate and latency.
+//
+// connections: The client establishes -conns connections and keeps them open.
+// It then performs small request-response exchanges on all connections for the duration of the test,
+// and reports the memory usage, the request rate and the request latency.
+//
+// By default, the server uses a self-signed certificate, and the client doesn't verify the certificate.
+// Results are printed as text, or as one JSON object per line (-format json).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+type options struct {
+	role         string
+	addr         string
+	test         string
+	format       string
+	direction    string
+	streams      int
+	duration     time.Duration
+	maxDuration  time.Duration
+	interval     time.Duration
+	pingInterval time.Duration
+	runs         int
+	conns        int
+	concurrency  int
+	certFile     string
+	keyFile      string
+	verify       bool
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.role, "role", "client", "role of this instance: server, client or both")
+	flag.StringVar(&o.addr, "addr", "localhost:4242", "address to listen on (server), or to connect to (client)")
+	flag.StringVar(&o.test, "test", "transfer", "test to run: transfer, handshakes or connections")
+	flag.StringVar(&o.format, "format", "text", "output format: text or json")
+	flag.StringVar(&o.direction, "direction", "download", "direction of the transfer: upload or download")
+	flag.IntVar(&o.streams, "streams", 1, "number of parallel streams used for the transfer")
+	flag.DurationVar(&o.duration, "duration", 10*time.Second, "duration of the test")
+	flag.DurationVar(&o.maxDuration, "max-duration", time.Minute, "maximum duration of a download that the server accepts")
+	flag.DurationVar(&o.interval, "interval", time.Second, "interval between two throughput reports")
+	flag.DurationVar(&o.pingInterval, "ping-interval", 100*time.Millisecond, "interval between two pings during the transfer (0 disables pings)")
+	flag.IntVar(&o.runs, "runs", 1, "number of runs of the handshakes and connections tests")
+	flag.IntVar(&o.conns, "conns", 1000, "number of connections established in the connections test")
+	flag.IntVar(&o.concurrency, "concurrency", 16, "number of connections dialed in parallel in the handshakes and connections tests")
+	flag.StringVar(&o.certFile, "cert", "", "certificate file used by the server (a self-signed certificate is generated if not set)")
+	flag.StringVar(&o.keyFile, "key", "", "key file used by the server")
+	flag.BoolVar(&o.verify, "verify", false, "verify the certificate of the server")
+	flag.Parse()
+
+	if err := run(&o); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(o *options) error {
+	if o.format != "text" && o.format != "json" {
+		return fmt.Errorf("unknown output format: %s", o.format)
+	}
+	switch o.role {
+	case "server":
+		ln, err := listen(o)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		log.Printf("Listening on %s", ln.Addr())
+		return serve(ln, o.maxDuration)
+	case "client":
+		if err := checkClientOptions(o); err != nil {
+			return err
+		}
+		return runClient(o, os.Stdout)
+	case "both":
+		if err := checkClientOptions(o); err != nil {
+			return err
+		}
+		ln, err := listen(o)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		go serve(ln, o.maxDuration)
+		o.addr = ln.Addr().String()
+		return runClient(o, os.Stdout)
+	default:
+		return fmt.Errorf("unknown role: %s", o.role)
+	}
+}
+
+func checkClientOptions(o *options) error {
+	switch o.test {
+	case "transfer":
+		if o.direction != "upload" && o.direction != "download" {
+			return fmt.Errorf("unknown direction: %s", o.direction)
+		}
+		if o.streams < 1 {
+			return fmt.Errorf("invalid number of streams: %d", o.streams)
+		}
+		if o.interval <= 0 {
+			return fmt.Errorf("invalid interval: %s", o.interval)
+		}
+	case "handshakes", "connections":
+		if o.concurrency < 1 {
+			return fmt.Errorf("invalid concurrency: %d", o.concurrency)
+		}
+		if o.conns < 1 {
+			return fmt.Errorf("invalid number of connections: %d", o.conns)
+		}
+	default:
+		return fmt.Errorf("unknown test: %s", o.test)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// A connectedReport is printed when the session is established.
+type connectedReport struct {
+	Type        string  `json:"type"` // always "connected"
+	RemoteAddr  string  `json:"remote_addr"`
+	Version     string  `json:"version"`
+	GoVersion   string  `json:"go_version"`
+	HandshakeMs float64 `json:"handshake_ms"`
+}
+
+func newConnectedReport(remoteAddr, version string, handshake time.Duration) *connectedReport {
+	return &connectedReport{
+		Type:        "connected",
+		RemoteAddr:  remoteAddr,
+		Version:     version,
+		GoVersion:   runtime.Version(),
+		HandshakeMs: toMs(handshake),
+	}
+}
+
+func (r *connectedReport) write(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(r)
+	}
+	_, err := fmt.Fprintf(w, "Connected to %s (%s, %s), handshake took %.3f ms\n", r.RemoteAddr, r.Version, r.GoVersion, r.HandshakeMs)
+	return err
+}
+
+// A transferReport is printed for every interval, and at the end of the test.
+type transferReport struct {
+	Type string `json:"type"` // "interval" or "summary"
+
+	// only set for summary reports
+	Direction string `json:"direction,omitempty"`
+	Streams   int    `json:"streams,omitempty"`
+
+	// relative to the start of the transfer
+	Start          float64 `json:"start_s"`
+	End            float64 `json:"end_s"`
+	Bytes          uint64  `json:"bytes"`
+	ThroughputMbps float64 `json:"throughput_mbps"`
+	Pings          int     `json:"pings"`
+	MinRTTMs       float64 `json:"min_rtt_ms,omitempty"`
+	MedianRTTMs    float64 `json:"median_rtt_ms,omitempty"`
+	P99RTTMs       float64 `json:"p99_rtt_ms,omitempty"`
+	MaxRTTMs       float64 `json:"max_rtt_ms,omitempty"`
+}
+
+// newTransferReport creates a report for the data transferred and the RTTs measured between start and end.
+func newTransferReport(typ string, start, end time.Duration, bytes uint64, rtts []time.Duration) *transferReport {
+	r := &transferReport{
+		Type:  typ,
+		Start: start.Seconds(),
+		End:   end.Seconds(),
+		Bytes: bytes,
+		Pings: len(rtts),
+	}
+	if end > start {
+		r.ThroughputMbps = float64(bytes) * 8 / 1e6 / (end - start).Seconds()
+	}
+	if len(rtts) > 0 {
+		sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+		r.MinRTTMs = toMs(rtts[0])
+		r.MedianRTTMs = toMs(rtts[(len(rtts)-1)/2])
+		r.P99RTTMs = toMs(rtts[(len(rtts)-1)*99/100])
+		r.MaxRTTMs = toMs(rtts[len(rtts)-1])
+	}
+	return r
+}
+
+func (r *transferReport) write(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(r)
+	}
+	if r.Type == "summary" {
+		if _, err := fmt.Fprintf(w, "Summary (%s, %d streams):\n", r.Direction, r.Streams); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "[%6.2f-%6.2f s] %10.3f MB %10.3f Mbit/s", r.Start, r.End, float64(r.Bytes)/1e6, r.ThroughputMbps); err != nil {
+		return err
+	}
+	if r.Pings > 0 {
+		if _, err := fmt.Fprintf(w, "   rtt min/median/p99/max = %.3f/%.3f/%.3f/%.3f ms", r.MinRTTMs, r.MedianRTTMs, r.P99RTTMs, r.MaxRTTMs); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// A benchmarkReport is printed for every run of the handshakes and connections tests.
+type benchmarkReport struct {
+	Type       string             `json:"type"` // always "benchmark"
+	Test       string             `json:"test"`
+	Run        int                `json:"run"`
+	GoVersion  string             `json:"go_version"`
+	GOMAXPROCS int                `json:"gomaxprocs"`
+	Parameters map[string]int     `json:"parameters"`
+	Duration   time.Duration      `json:"duration_ns"`
+	Values     map[string]float64 `json:"values"`
+}
+
+func newBenchmarkReport(test string) *benchmarkReport {
+	return &benchmarkReport{
+		Type:       "benchmark",
+		Test:       test,
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Parameters: make(map[string]int),
+		Values:     make(map[string]float64),
+	}
+}
+
+// addLatencies adds the median and the 90th and 99th percentile of the latencies (in milliseconds).
+func (r *benchmarkReport) addLatencies(name string, latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	for _, p := range []int{50, 90, 99} {
+		r.Values[fmt.Sprintf("%s_p%d_ms", name, p)] = toMs(latencies[(len(latencies)-1)*p/100])
+	}
+}
+
+func (r *benchmarkReport) write(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(r)
+	}
+	if _, err := fmt.Fprintf(w, "%s (run %d, %s, GOMAXPROCS=%d) took %s\n", r.Test, r.Run, r.GoVersion, r.GOMAXPROCS, r.Duration); err != nil {
+		return err
+	}
+	params := make([]string, 0, len(r.Parameters))
+	for k := range r.Parameters {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		if _, err := fmt.Fprintf(w, "  %-30s %d\n", k, r.Parameters[k]); err != nil {
+			return err
+		}
+	}
+	values := make([]string, 0, len(r.Values))
+	for k := range r.Values {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	for _, k := range values {
+		if _, err := fmt.Fprintf(w, "  %-30s %.3f\n", k, r.Values[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/certs"
+)
+
+// The first byte of every stream opened by the client determines what the server does on the stream.
+const (
+	// The client sends data until it closes the stream.
+	// The server discards the data, and responds with the number of bytes received (as a 64 bit big endian integer).
+	streamTypeUpload byte = 'u'
+	// The client sends the duration of the transfer (in nanoseconds, as a 64 bit big endian integer).
+	// The server sends data for that duration (but at most for its maximum duration), and closes the stream.
+	streamTypeDownload byte = 'd'
+	// The server echoes all data until the client closes the stream.
+	streamTypePing byte = 'p'
+)
+
+const chunkSize = 32 * 1024
+
+var chunk = make([]byte, chunkSize)
+
+func listen(o *options) (quic.Listener, error) {
+	tlsConf, err := certs.LoadTLSConfig(o.certFile, o.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return quic.ListenAddr(o.addr, tlsConf, nil)
+}
+
+// serve accepts sessions until the listener is closed.
+// Downloads are limited to maxDuration, so that clients can't make the server send data forever.
+func serve(ln quic.Listener, maxDuration time.Duration) error {
+	for {
+		sess, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		log.Printf("Accepted a session from %s", sess.RemoteAddr())
+		go handleSession(sess, maxDuration)
+	}
+}
+
+func handleSession(sess quic.Session, maxDuration time.Duration) {
+	for {
+		str, err := sess.AcceptStream()
+		if err != nil {
+			return
+		}
+		go func() {
+			err := handleStream(str, maxDuration)
+			select {
+			case <-sess.Context().Done():
+				// Errors are expected when the client closes the session.
+			default:
+				if err != nil {
+					log.Printf("Error on stream %d of %s: %s", str.StreamID(), sess.RemoteAddr(), err)
+				}
+			}
+		}()
+	}
+}
+
+func handleStream(str quic.Stream, maxDuration time.Duration) error {
+	defer str.Close()
+	var t [1]byte
+	if _, err := io.ReadFull(str, t[:]); err != nil {
+		return err
+	}
+	switch t[0] {
+	case streamTypeUpload:
+		n, err := io.Copy(ioutil.Discard, str)
+		if err != nil {
+			return err
+		}
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(n))
+		_, err = str.Write(b[:])
+		return err
+	case streamTypeDownload:
+		var b [8]byte
+		if _, err := io.ReadFull(str, b[:]); err != nil {
+			return err
+		}
+		d := time.Duration(binary.BigEndian.Uint64(b[:]))
+		if d > maxDuration || d < 0 {
+			d = maxDuration
+		}
+		deadline := time.Now().Add(d)
+		for time.Now().Before(deadline) {
+			if _, err := str.Write(chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	case streamTypePing:
+		_, err := io.Copy(str, str)
+		return err
+	default:
+		str.CancelRead(0)
+		return fmt.Errorf("unknown stream type: %#x", t[0])
+	}
+}
@@ -3,6 +3,10 @@ package quic
 import (
 	"net"
 	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 type connection interface {
@@ -12,6 +16,17 @@ type connection interface {
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
 	SetCurrentRemoteAddr(net.Addr)
+	// MaxPacketSize is the maximum size of a packet that can be sent to the remote address.
+	MaxPacketSize() protocol.ByteCount
+}
+
+// A txTimeConnection can pass packets to the kernel, which transmits them at a later time (see Config.KernelPacing).
+type txTimeConnection interface {
+	// EnableTxTime enables SO_TXTIME on the socket.
+	// It returns false if this is not supported.
+	EnableTxTime() bool
+	// WriteWithDelay writes a packet, which the kernel transmits after delay.
+	WriteWithDelay([]byte, time.Duration) error
 }
 
 type conn struct {
@@ -22,14 +37,50 @@ type conn struct {
 }
 
 var _ connection = &conn{}
+var _ txTimeConnection = &conn{}
 
 func (c *conn) Write(p []byte) error {
-	_, err := c.pconn.WriteTo(p, c.currentAddr)
+	c.mutex.RLock()
+	pconn := c.pconn
+	addr := c.currentAddr
+	c.mutex.RUnlock()
+	_, err := pconn.WriteTo(p, addr)
 	return err
 }
 
+func (c *conn) EnableTxTime() bool {
+	c.mutex.RLock()
+	pconn := c.pconn
+	c.mutex.RUnlock()
+	return enableTxTime(pconn)
+}
+
+func (c *conn) WriteWithDelay(p []byte, delay time.Duration) error {
+	if delay <= 0 {
+		return c.Write(p)
+	}
+	c.mutex.RLock()
+	pconn := c.pconn
+	addr := c.currentAddr
+	c.mutex.RUnlock()
+	return writeWithTxTime(pconn, p, addr, delay)
+}
+
 func (c *conn) Read(p []byte) (int, net.Addr, error) {
-	return c.pconn.ReadFrom(p)
+	c.mutex.RLock()
+	pconn := c.pconn
+	c.mutex.RUnlock()
+	return pconn.ReadFrom(p)
+}
+
+// setPacketConn replaces the underlying net.PacketConn.
+// It returns the net.PacketConn that was used before.
+func (c *conn) setPacketConn(pconn net.PacketConn) net.PacketConn {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	old := c.pconn
+	c.pconn = pconn
+	return old
 }
 
 func (c *conn) SetCurrentRemoteAddr(addr net.Addr) {
@@ -39,6 +90,8 @@ func (c *conn) SetCurrentRemoteAddr(addr net.Addr) {
 }
 
 func (c *conn) LocalAddr() net.Addr {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	return c.pconn.LocalAddr()
 }
 
@@ -49,6 +102,19 @@ func (c *conn) RemoteAddr() net.Addr {
 	return addr
 }
 
+func (c *conn) MaxPacketSize() protocol.ByteCount {
+	c.mutex.RLock()
+	pconn := c.pconn
+	addr := c.currentAddr
+	c.mutex.RUnlock()
+	if s, ok := pconn.(Substrate); ok {
+		return utils.MinByteCount(protocol.ByteCount(s.MaxDatagramSize(addr)), protocol.MaxReceivePacketSize)
+	}
+	return getMaxPacketSize(addr)
+}
+
 func (c *conn) Close() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	return c.pconn.Close()
 }
@@ -122,6 +122,16 @@ var _ = Describe("Connection", func() {
 		Expect(c.RemoteAddr().String()).To(Equal(addr.String()))
 	})
 
+	It("replaces the packet conn", func() {
+		newPacketConn := newMockPacketConn()
+		Expect(c.setPacketConn(newPacketConn)).To(Equal(packetConn))
+		Expect(c.Write([]byte("foobar"))).To(Succeed())
+		Expect(packetConn.dataWritten).ToNot(Receive())
+		var write mockPacketConnWrite
+		Expect(newPacketConn.dataWritten).To(Receive(&write))
+		Expect(write.to.String()).To(Equal("192.168.100.200:1337"))
+	})
+
 	It("closes", func() {
 		err := c.Close()
 		Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,128 @@
+package quic
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+)
+
+// A TransportErrorCode is an error code defined by the QUIC transport.
+type TransportErrorCode = qerr.ErrorCode
+
+// HandshakeState is the progress of the handshake.
+type HandshakeState = handshake.HandshakeState
+
+// The states of a handshake, see HandshakeError.
+const (
+	HandshakeStateStarted             = handshake.HandshakeStateStarted
+	HandshakeStateReceivedHello       = handshake.HandshakeStateReceivedHello
+	HandshakeStateReceivedCertificate = handshake.HandshakeStateReceivedCertificate
+	HandshakeStateReceivedFinished    = handshake.HandshakeStateReceivedFinished
+	HandshakeStateComplete            = handshake.HandshakeStateComplete
+)
+
+// A HandshakeError is returned by Dial (and its variants) if the session is closed before the handshake completes.
+// The error that caused the handshake to fail can be inspected using AsTransportError, AsCryptoError, AsApplicationError and IsRemoteError,
+// e.g. a TLS alert sent by the server is a remote crypto error.
+type HandshakeError struct {
+	// Err is the error that caused the handshake to fail.
+	Err error
+	// State is the last state that the handshake reached before it failed.
+	// If no handshake message was received from the server (HandshakeStateStarted),
+	// the server is probably not reachable.
+	State HandshakeState
+	// CertificateError is set if the verification of the server's certificate failed.
+	// It is the error returned by x509.Certificate.Verify, e.g. a x509.UnknownAuthorityError or a x509.HostnameError.
+	CertificateError error
+	// OfferedVersions are the versions that the server offered in a Version Negotiation packet,
+	// if none of them is supported by the client.
+	OfferedVersions []VersionNumber
+}
+
+var _ net.Error = &HandshakeError{}
+
+func newHandshakeError(err error, state HandshakeState) *HandshakeError {
+	e := &HandshakeError{Err: err, State: state}
+	switch err.(type) {
+	case x509.CertificateInvalidError,
+		x509.HostnameError,
+		x509.UnknownAuthorityError,
+		x509.SystemRootsError,
+		x509.ConstraintViolationError,
+		x509.InsecureAlgorithmError:
+		e.CertificateError = err
+	}
+	return e
+}
+
+func (e *HandshakeError) Error() string {
+	if len(e.OfferedVersions) > 0 {
+		return fmt.Sprintf("handshake failed (state: %s, server offered versions %s): %s", e.State, e.OfferedVersions, e.Err)
+	}
+	return fmt.Sprintf("handshake failed (state: %s): %s", e.State, e.Err)
+}
+
+// Timeout says if the handshake timed out.
+func (e *HandshakeError) Timeout() bool {
+	nerr, ok := e.Err.(net.Error)
+	return ok && nerr.Timeout()
+}
+
+// Temporary says if the error is temporary.
+// A HandshakeError closes the session, so it never is.
+func (e *HandshakeError) Temporary() bool {
+	return false
+}
+
+// toQuicError returns the QuicError that err was caused by.
+func toQuicError(err error) (*qerr.QuicError, bool) {
+	if hErr, ok := err.(*HandshakeError); ok {
+		err = hErr.Err
+	}
+	switch e := err.(type) {
+	case *qerr.QuicError:
+		return e, true
+	case qerr.ErrorCode:
+		return qerr.Error(e, ""), true
+	}
+	return nil, false
+}
+
+// AsApplicationError returns the application error code, if err was caused by
+// a session being closed with an application error, either locally (using CloseWithError) or by the peer.
+func AsApplicationError(err error) (ErrorCode, bool) {
+	quicErr, ok := toQuicError(err)
+	if !ok || !quicErr.IsApplicationError() {
+		return 0, false
+	}
+	return ErrorCode(quicErr.ErrorCode), true
+}
+
+// AsTransportError returns the transport error code, if err was caused by an error on the transport level.
+// Errors caused by TLS alerts are transport errors as well, and can be further inspected using AsCryptoError.
+func AsTransportError(err error) (TransportErrorCode, bool) {
+	quicErr, ok := toQuicError(err)
+	if !ok || quicErr.IsApplicationError() {
+		return 0, false
+	}
+	return quicErr.ErrorCode, true
+}
+
+// IsRemoteError says if err was caused by the peer closing the session.
+// This includes sessions closed by a stateless reset sent by the peer.
+func IsRemoteError(err error) bool {
+	quicErr, ok := toQuicError(err)
+	return ok && quicErr.IsRemote()
+}
+
+// AsCryptoError returns the TLS alert, if err was caused by an error during the TLS handshake.
+func AsCryptoError(err error) (uint8, bool) {
+	quicErr, ok := toQuicError(err)
+	if !ok {
+		return 0, false
+	}
+	return quicErr.TLSAlert()
+}
@@ -0,0 +1,95 @@
+package quic
+
+import (
+	"crypto/x509"
+	"errors"
+
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Errors", func() {
+	It("inspects application errors", func() {
+		err := qerr.ApplicationError(0x42, "foobar")
+		code, ok := AsApplicationError(err)
+		Expect(ok).To(BeTrue())
+		Expect(code).To(BeEquivalentTo(0x42))
+		_, ok = AsTransportError(err)
+		Expect(ok).To(BeFalse())
+		_, ok = AsCryptoError(err)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("inspects transport errors", func() {
+		err := qerr.Error(qerr.NetworkIdleTimeout, "foobar")
+		code, ok := AsTransportError(err)
+		Expect(ok).To(BeTrue())
+		Expect(code).To(Equal(qerr.NetworkIdleTimeout))
+		_, ok = AsApplicationError(err)
+		Expect(ok).To(BeFalse())
+		_, ok = AsCryptoError(err)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("inspects crypto errors", func() {
+		err := qerr.CryptoError(0x2a, "bad certificate")
+		alert, ok := AsCryptoError(err)
+		Expect(ok).To(BeTrue())
+		Expect(alert).To(BeEquivalentTo(0x2a))
+		_, ok = AsTransportError(err)
+		Expect(ok).To(BeTrue())
+		_, ok = AsApplicationError(err)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("tells if an error was sent by the peer", func() {
+		err := qerr.ApplicationError(0x42, "foobar")
+		Expect(IsRemoteError(err)).To(BeFalse())
+		Expect(IsRemoteError(qerr.RemoteError(err))).To(BeTrue())
+		code, ok := AsApplicationError(qerr.RemoteError(err))
+		Expect(ok).To(BeTrue())
+		Expect(code).To(BeEquivalentTo(0x42))
+	})
+
+	It("doesn't inspect other errors", func() {
+		err := errors.New("foobar")
+		_, ok := AsApplicationError(err)
+		Expect(ok).To(BeFalse())
+		_, ok = AsTransportError(err)
+		Expect(ok).To(BeFalse())
+		_, ok = AsCryptoError(err)
+		Expect(ok).To(BeFalse())
+		Expect(IsRemoteError(err)).To(BeFalse())
+	})
+
+	Context("handshake errors", func() {
+		It("has a string representation", func() {
+			err := newHandshakeError(errors.New("foobar"), HandshakeStateReceivedHello)
+			Expect(err.Error()).To(Equal("handshake failed (state: received Hello): foobar"))
+			err.OfferedVersions = []VersionNumber{0x1337}
+			Expect(err.Error()).To(ContainSubstring("server offered versions"))
+		})
+
+		It("detects certificate errors", func() {
+			err := newHandshakeError(x509.HostnameError{Host: "quic.clemente.io"}, HandshakeStateReceivedCertificate)
+			Expect(err.CertificateError).To(Equal(x509.HostnameError{Host: "quic.clemente.io"}))
+			Expect(newHandshakeError(errors.New("foobar"), HandshakeStateReceivedCertificate).CertificateError).ToNot(HaveOccurred())
+		})
+
+		It("tells if the handshake timed out", func() {
+			Expect(newHandshakeError(qerr.Error(qerr.HandshakeTimeout, "timeout"), HandshakeStateStarted).Timeout()).To(BeTrue())
+			Expect(newHandshakeError(errors.New("foobar"), HandshakeStateStarted).Timeout()).To(BeFalse())
+			Expect(newHandshakeError(errors.New("foobar"), HandshakeStateStarted).Temporary()).To(BeFalse())
+		})
+
+		It("inspects the underlying error", func() {
+			err := newHandshakeError(qerr.RemoteError(qerr.CryptoError(0x2a, "bad certificate")), HandshakeStateReceivedHello)
+			alert, ok := AsCryptoError(err)
+			Expect(ok).To(BeTrue())
+			Expect(alert).To(BeEquivalentTo(0x2a))
+			Expect(IsRemoteError(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+type fecReceivedPacket struct {
+	packetNumber protocol.PacketNumber
+	payload      []byte
+	valid        bool
+}
+
+// The fecDecoder recovers lost 1-RTT packets using FEC_REPAIR frames.
+// It keeps the payloads of the last protocol.FECReceiveWindow packets received.
+type fecDecoder struct {
+	packets [protocol.FECReceiveWindow]fecReceivedPacket
+}
+
+func newFECDecoder() *fecDecoder {
+	return &fecDecoder{}
+}
+
+// ReceivedPacket saves the payload of a received packet.
+func (d *fecDecoder) ReceivedPacket(pn protocol.PacketNumber, payload []byte) {
+	p := &d.packets[pn%protocol.FECReceiveWindow]
+	p.packetNumber = pn
+	p.payload = append(p.payload[:0], payload...)
+	p.valid = true
+}
+
+func (d *fecDecoder) getPacket(pn protocol.PacketNumber) *fecReceivedPacket {
+	p := &d.packets[pn%protocol.FECReceiveWindow]
+	if !p.valid || p.packetNumber != pn {
+		return nil
+	}
+	return p
+}
+
+// HandleRepairFrame recovers the lost packet of the group protected by a FEC_REPAIR frame.
+// A packet can only be recovered if all other packets of the group were received.
+// If no packet was recovered, the returned payload is nil.
+func (d *fecDecoder) HandleRepairFrame(f *wire.FECRepairFrame) (protocol.PacketNumber, []byte, error) {
+	if f.NumPackets == 0 || f.NumPackets > protocol.MaxFECGroupSize {
+		return 0, nil, qerr.Error(qerr.InvalidFecData, "invalid number of packets")
+	}
+	var missing protocol.PacketNumber
+	var numMissing int
+	for i := uint64(0); i < f.NumPackets; i++ {
+		pn := f.FirstPacketNumber + protocol.PacketNumber(i)
+		if d.getPacket(pn) == nil {
+			missing = pn
+			numMissing++
+		}
+	}
+	// Either all packets were received, or too many packets were lost.
+	if numMissing != 1 {
+		return 0, nil, nil
+	}
+
+	data := make([]byte, len(f.Data))
+	copy(data, f.Data)
+	length := f.PayloadLengths
+	for i := uint64(0); i < f.NumPackets; i++ {
+		pn := f.FirstPacketNumber + protocol.PacketNumber(i)
+		if pn == missing {
+			continue
+		}
+		p := d.getPacket(pn)
+		if len(p.payload) > len(data) {
+			return 0, nil, qerr.Error(qerr.InvalidFecData, "repair data too short")
+		}
+		for j, b := range p.payload {
+			data[j] ^= b
+		}
+		length ^= uint64(len(p.payload))
+	}
+	if length == 0 || length > uint64(len(data)) {
+		return 0, nil, qerr.Error(qerr.InvalidFecData, "invalid payload length")
+	}
+	payload := data[:length]
+	// make sure that the same packet isn't recovered twice
+	d.ReceivedPacket(missing, payload)
+	return missing, payload, nil
+}
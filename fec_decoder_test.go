@@ -0,0 +1,121 @@
+package quic
+
+import (
+	"math/rand"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FEC decoder", func() {
+	var decoder *fecDecoder
+
+	BeforeEach(func() {
+		decoder = newFECDecoder()
+	})
+
+	getPayload := func() []byte {
+		payload := make([]byte, 1+rand.Intn(500))
+		rand.Read(payload)
+		return payload
+	}
+
+	It("recovers a lost packet", func() {
+		encoder := newFECEncoder(5)
+		payloads := make(map[protocol.PacketNumber][]byte)
+		for pn := protocol.PacketNumber(100); pn < 105; pn++ {
+			payloads[pn] = getPayload()
+			encoder.AddPacket(pn, payloads[pn])
+			if pn != 102 {
+				decoder.ReceivedPacket(pn, payloads[pn])
+			}
+		}
+		f := encoder.PopRepairFrame()
+		Expect(f).ToNot(BeNil())
+		pn, payload, err := decoder.HandleRepairFrame(f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pn).To(Equal(protocol.PacketNumber(102)))
+		Expect(payload).To(Equal(payloads[102]))
+	})
+
+	It("recovers a packet only once", func() {
+		encoder := newFECEncoder(2)
+		encoder.AddPacket(1, []byte("foo"))
+		encoder.AddPacket(2, []byte("foobar"))
+		decoder.ReceivedPacket(2, []byte("foobar"))
+		f := encoder.PopRepairFrame()
+		_, payload, err := decoder.HandleRepairFrame(f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(payload).To(Equal([]byte("foo")))
+		_, payload, err = decoder.HandleRepairFrame(f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(payload).To(BeNil())
+	})
+
+	It("doesn't recover anything if all packets were received", func() {
+		encoder := newFECEncoder(2)
+		for pn := protocol.PacketNumber(1); pn <= 2; pn++ {
+			encoder.AddPacket(pn, []byte("foobar"))
+			decoder.ReceivedPacket(pn, []byte("foobar"))
+		}
+		_, payload, err := decoder.HandleRepairFrame(encoder.PopRepairFrame())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(payload).To(BeNil())
+	})
+
+	It("doesn't recover anything if more than one packet was lost", func() {
+		encoder := newFECEncoder(3)
+		for pn := protocol.PacketNumber(1); pn <= 3; pn++ {
+			encoder.AddPacket(pn, []byte("foobar"))
+		}
+		decoder.ReceivedPacket(2, []byte("foobar"))
+		_, payload, err := decoder.HandleRepairFrame(encoder.PopRepairFrame())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(payload).To(BeNil())
+	})
+
+	It("forgets old packets", func() {
+		encoder := newFECEncoder(2)
+		encoder.AddPacket(1, []byte("foo"))
+		encoder.AddPacket(2, []byte("bar"))
+		decoder.ReceivedPacket(1, []byte("foo"))
+		// overwrites packet 1
+		decoder.ReceivedPacket(1+protocol.FECReceiveWindow, []byte("foobar"))
+		_, payload, err := decoder.HandleRepairFrame(encoder.PopRepairFrame())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(payload).To(BeNil())
+	})
+
+	It("errors on an invalid number of packets", func() {
+		_, _, err := decoder.HandleRepairFrame(&wire.FECRepairFrame{NumPackets: protocol.MaxFECGroupSize + 1})
+		Expect(err).To(MatchError(qerr.Error(qerr.InvalidFecData, "invalid number of packets")))
+		_, _, err = decoder.HandleRepairFrame(&wire.FECRepairFrame{NumPackets: 0})
+		Expect(err).To(MatchError(qerr.Error(qerr.InvalidFecData, "invalid number of packets")))
+	})
+
+	It("errors if the repair data is shorter than a received packet", func() {
+		decoder.ReceivedPacket(1, []byte("foobar"))
+		_, _, err := decoder.HandleRepairFrame(&wire.FECRepairFrame{
+			FirstPacketNumber: 1,
+			NumPackets:        2,
+			PayloadLengths:    6 ^ 3,
+			Data:              []byte("foo"),
+		})
+		Expect(err).To(MatchError(qerr.Error(qerr.InvalidFecData, "repair data too short")))
+	})
+
+	It("errors on an invalid payload length", func() {
+		decoder.ReceivedPacket(1, []byte("foo"))
+		_, _, err := decoder.HandleRepairFrame(&wire.FECRepairFrame{
+			FirstPacketNumber: 1,
+			NumPackets:        2,
+			PayloadLengths:    3 ^ 10,
+			Data:              []byte("foobar"),
+		})
+		Expect(err).To(MatchError(qerr.Error(qerr.InvalidFecData, "invalid payload length")))
+	})
+})
@@ -0,0 +1,70 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// The fecEncoder generates FEC_REPAIR frames for groups of consecutive 1-RTT packets.
+// A FEC_REPAIR frame allows the peer to recover a single lost packet of a group,
+// without waiting for the retransmission.
+type fecEncoder struct {
+	groupSize int
+
+	firstPacketNumber protocol.PacketNumber
+	numPackets        int
+	payloadLengths    uint64
+	data              []byte
+
+	repairFrame *wire.FECRepairFrame
+}
+
+func newFECEncoder(groupSize int) *fecEncoder {
+	return &fecEncoder{groupSize: groupSize}
+}
+
+// AddPacket adds the payload of a packet to the current group.
+// Packets have to be added in the order of their packet numbers.
+func (e *fecEncoder) AddPacket(pn protocol.PacketNumber, payload []byte) {
+	// A group only consists of consecutive packets.
+	// If a packet number is missing (e.g. because that packet carried a FEC_REPAIR frame), start a new group.
+	if e.numPackets > 0 && pn != e.firstPacketNumber+protocol.PacketNumber(e.numPackets) {
+		e.reset()
+	}
+	if e.numPackets == 0 {
+		e.firstPacketNumber = pn
+	}
+	if len(payload) > len(e.data) {
+		e.data = append(e.data, make([]byte, len(payload)-len(e.data))...)
+	}
+	for i, b := range payload {
+		e.data[i] ^= b
+	}
+	e.payloadLengths ^= uint64(len(payload))
+	e.numPackets++
+
+	if e.numPackets == e.groupSize {
+		e.repairFrame = &wire.FECRepairFrame{
+			FirstPacketNumber: e.firstPacketNumber,
+			NumPackets:        uint64(e.numPackets),
+			PayloadLengths:    e.payloadLengths,
+			Data:              e.data,
+		}
+		e.data = nil
+		e.reset()
+	}
+}
+
+// PopRepairFrame returns the FEC_REPAIR frame for the last complete group.
+// It returns nil if no group was completed since the last call.
+func (e *fecEncoder) PopRepairFrame() *wire.FECRepairFrame {
+	f := e.repairFrame
+	e.repairFrame = nil
+	return f
+}
+
+func (e *fecEncoder) reset() {
+	e.numPackets = 0
+	e.payloadLengths = 0
+	e.data = e.data[:0]
+}
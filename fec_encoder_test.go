@@ -0,0 +1,66 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FEC encoder", func() {
+	var encoder *fecEncoder
+
+	BeforeEach(func() {
+		encoder = newFECEncoder(3)
+	})
+
+	It("doesn't return a FEC_REPAIR frame before a group is complete", func() {
+		encoder.AddPacket(10, []byte("foo"))
+		encoder.AddPacket(11, []byte("bar"))
+		Expect(encoder.PopRepairFrame()).To(BeNil())
+	})
+
+	It("returns a FEC_REPAIR frame when a group is complete", func() {
+		encoder.AddPacket(10, []byte{0x1, 0x2})
+		encoder.AddPacket(11, []byte{0x4})
+		encoder.AddPacket(12, []byte{0x10, 0x20, 0x40})
+		f := encoder.PopRepairFrame()
+		Expect(f).ToNot(BeNil())
+		Expect(f.FirstPacketNumber).To(Equal(protocol.PacketNumber(10)))
+		Expect(f.NumPackets).To(BeEquivalentTo(3))
+		Expect(f.PayloadLengths).To(BeEquivalentTo(2 ^ 1 ^ 3))
+		Expect(f.Data).To(Equal([]byte{0x1 ^ 0x4 ^ 0x10, 0x2 ^ 0x20, 0x40}))
+		Expect(encoder.PopRepairFrame()).To(BeNil())
+	})
+
+	It("starts a new group after a group is complete", func() {
+		for pn := protocol.PacketNumber(1); pn <= 3; pn++ {
+			encoder.AddPacket(pn, []byte("foobar"))
+		}
+		first := encoder.PopRepairFrame()
+		Expect(first).ToNot(BeNil())
+		for pn := protocol.PacketNumber(5); pn <= 7; pn++ {
+			encoder.AddPacket(pn, []byte{byte(pn)})
+		}
+		f := encoder.PopRepairFrame()
+		Expect(f).ToNot(BeNil())
+		Expect(f.FirstPacketNumber).To(Equal(protocol.PacketNumber(5)))
+		Expect(f.Data).To(Equal([]byte{5 ^ 6 ^ 7}))
+		// the data of the first frame is not modified
+		Expect(first.Data).To(Equal([]byte("foobar")))
+	})
+
+	It("starts a new group when a packet number is skipped", func() {
+		encoder.AddPacket(1, []byte("foo"))
+		encoder.AddPacket(2, []byte("bar"))
+		encoder.AddPacket(4, []byte{0x1})
+		encoder.AddPacket(5, []byte{0x2})
+		Expect(encoder.PopRepairFrame()).To(BeNil())
+		encoder.AddPacket(6, []byte{0x4})
+		f := encoder.PopRepairFrame()
+		Expect(f).ToNot(BeNil())
+		Expect(f.FirstPacketNumber).To(Equal(protocol.PacketNumber(4)))
+		Expect(f.PayloadLengths).To(BeEquivalentTo(1))
+		Expect(f.Data).To(Equal([]byte{0x7}))
+	})
+})
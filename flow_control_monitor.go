@@ -0,0 +1,251 @@
+package quic
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
+)
+
+// FlowControlState is the flow control state of a stream, or of the connection.
+type FlowControlState = quictrace.FlowControlState
+
+// FlowControlStall is the reason why a transfer is stalled by flow control.
+type FlowControlStall uint8
+
+const (
+	// FlowControlBlockedByPeer means that sending is blocked, and the peer didn't send a window update.
+	// This happens if the application on the peer's side doesn't read the data, or if the peer fails to send a window update.
+	FlowControlBlockedByPeer FlowControlStall = 1 + iota
+	// FlowControlWindowUpdateMissing means that the peer is blocked, and the application read enough data,
+	// but no window update was sent.
+	FlowControlWindowUpdateMissing
+	// FlowControlApplicationNotReading means that the peer is blocked, because the application doesn't read the data.
+	FlowControlApplicationNotReading
+)
+
+func (s FlowControlStall) String() string {
+	switch s {
+	case FlowControlBlockedByPeer:
+		return "blocked by the peer"
+	case FlowControlWindowUpdateMissing:
+		return "window update missing"
+	case FlowControlApplicationNotReading:
+		return "application not reading"
+	default:
+		return fmt.Sprintf("unknown flow control stall: %d", s)
+	}
+}
+
+// A FlowControlDeadlock is a stream, or the connection, that is stalled by flow control.
+type FlowControlDeadlock struct {
+	Reason FlowControlStall
+	// Duration is the time since the transfer stalled.
+	Duration time.Duration
+	State    FlowControlState
+}
+
+func (d FlowControlDeadlock) String() string {
+	name := "connection"
+	if !d.State.Connection {
+		name = fmt.Sprintf("stream %d", d.State.StreamID)
+	}
+	switch d.Reason {
+	case FlowControlBlockedByPeer:
+		return fmt.Sprintf("%s: %s at offset %d for %s", name, d.Reason, d.State.SendWindow, d.Duration)
+	case FlowControlApplicationNotReading:
+		return fmt.Sprintf("%s: %s for %s, %d bytes buffered", name, d.Reason, d.Duration, d.State.HighestReceived-d.State.BytesRead)
+	default:
+		return fmt.Sprintf("%s: %s for %s, peer blocked at offset %d, read %d bytes", name, d.Reason, d.Duration, d.State.ReceiveWindow, d.State.BytesRead)
+	}
+}
+
+// The receiveStateGetter is the part of a flow controller used by the flowControlMonitor.
+type receiveStateGetter interface {
+	GetReceiveState() flowcontrol.ReceiveState
+}
+
+type flowControlEntry struct {
+	fc    receiveStateGetter
+	state FlowControlState
+	// the offset at which the peer is blocked, valid if state.PeerBlockedSince is set
+	peerBlockedAt protocol.ByteCount
+}
+
+func (e *flowControlEntry) snapshot() FlowControlState {
+	state := e.state
+	rs := e.fc.GetReceiveState()
+	state.BytesRead = rs.BytesRead
+	state.HighestReceived = rs.HighestReceived
+	state.ReceiveWindow = rs.ReceiveWindow
+	state.WindowUpdatePending = rs.WindowUpdatePending
+	return state
+}
+
+// The flowControlMonitor records the window updates and blocked frames sent and received,
+// such that stalled transfers can be diagnosed on a live session.
+// The send side of the flow controllers must only be accessed by the run loop,
+// so the monitor derives it from the frames.
+type flowControlMonitor struct {
+	clock utils.Clock
+
+	mutex      sync.Mutex
+	connection flowControlEntry
+	streams    map[protocol.StreamID]*flowControlEntry
+}
+
+func newFlowControlMonitor(clock utils.Clock, connFC flowcontrol.ConnectionFlowController) *flowControlMonitor {
+	return &flowControlMonitor{
+		clock: clock,
+		connection: flowControlEntry{
+			fc:    connFC,
+			state: FlowControlState{Connection: true},
+		},
+		streams: make(map[protocol.StreamID]*flowControlEntry),
+	}
+}
+
+func (m *flowControlMonitor) AddStream(id protocol.StreamID, fc flowcontrol.StreamFlowController) {
+	m.mutex.Lock()
+	m.streams[id] = &flowControlEntry{
+		fc:    fc,
+		state: FlowControlState{StreamID: id},
+	}
+	m.mutex.Unlock()
+}
+
+func (m *flowControlMonitor) RemoveStream(id protocol.StreamID) {
+	m.mutex.Lock()
+	delete(m.streams, id)
+	m.mutex.Unlock()
+}
+
+// RemoveStreams removes all streams.
+// It is called when the session is closed, since streams of a closed session never complete.
+func (m *flowControlMonitor) RemoveStreams() {
+	m.mutex.Lock()
+	m.streams = make(map[protocol.StreamID]*flowControlEntry)
+	m.mutex.Unlock()
+}
+
+// must be called with the mutex held
+func (m *flowControlMonitor) getEntry(f wire.Frame) (*flowControlEntry, protocol.ByteCount) {
+	switch frame := f.(type) {
+	case *wire.MaxDataFrame:
+		return &m.connection, frame.ByteOffset
+	case *wire.DataBlockedFrame:
+		return &m.connection, frame.DataLimit
+	case *wire.MaxStreamDataFrame:
+		return m.streams[frame.StreamID], frame.ByteOffset
+	case *wire.StreamDataBlockedFrame:
+		return m.streams[frame.StreamID], frame.DataLimit
+	default:
+		return nil, 0
+	}
+}
+
+// OnFrameSent is called when a frame is queued for sending.
+// If the frame changed the flow control state, it returns the transition and the new state.
+func (m *flowControlMonitor) OnFrameSent(f wire.Frame) (quictrace.FlowControlTransition, *FlowControlState) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	e, offset := m.getEntry(f)
+	if e == nil {
+		return 0, nil
+	}
+	now := m.clock.Now()
+	var transition quictrace.FlowControlTransition
+	switch f.(type) {
+	case *wire.MaxDataFrame, *wire.MaxStreamDataFrame:
+		transition = quictrace.WindowUpdateSent
+		e.state.LastWindowUpdateSent = now
+		if offset > e.peerBlockedAt {
+			e.state.PeerBlockedSince = time.Time{}
+		}
+	default:
+		transition = quictrace.Blocked
+		if offset > e.state.SendWindow {
+			e.state.SendWindow = offset
+		}
+		e.state.BlockedSince = now
+	}
+	state := e.snapshot()
+	return transition, &state
+}
+
+// OnFrameReceived is called when a frame was received.
+// If the frame changed the flow control state, it returns the transition and the new state.
+func (m *flowControlMonitor) OnFrameReceived(f wire.Frame) (quictrace.FlowControlTransition, *FlowControlState) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	e, offset := m.getEntry(f)
+	if e == nil {
+		return 0, nil
+	}
+	now := m.clock.Now()
+	var transition quictrace.FlowControlTransition
+	switch f.(type) {
+	case *wire.MaxDataFrame, *wire.MaxStreamDataFrame:
+		transition = quictrace.WindowUpdateReceived
+		e.state.LastWindowUpdateReceived = now
+		if offset > e.state.SendWindow {
+			e.state.SendWindow = offset
+			e.state.BlockedSince = time.Time{}
+		}
+	default:
+		transition = quictrace.PeerBlocked
+		// Ignore blocked frames that were sent before our last window update arrived at the peer.
+		if offset >= e.fc.GetReceiveState().ReceiveWindow && e.state.PeerBlockedSince.IsZero() {
+			e.state.PeerBlockedSince = now
+			e.peerBlockedAt = offset
+		}
+	}
+	state := e.snapshot()
+	return transition, &state
+}
+
+// State returns the state of the connection, followed by the state of the streams, sorted by stream ID.
+func (m *flowControlMonitor) State() []FlowControlState {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	states := make([]FlowControlState, 0, 1+len(m.streams))
+	states = append(states, m.connection.snapshot())
+	for _, e := range m.streams {
+		states = append(states, e.snapshot())
+	}
+	streams := states[1:]
+	sort.Slice(streams, func(i, j int) bool { return streams[i].StreamID < streams[j].StreamID })
+	return states
+}
+
+// Deadlocks returns the streams (and the connection) that have been stalled by flow control for at least threshold.
+func (m *flowControlMonitor) Deadlocks(threshold time.Duration) []FlowControlDeadlock {
+	now := m.clock.Now()
+	var deadlocks []FlowControlDeadlock
+	for _, state := range m.State() {
+		if !state.BlockedSince.IsZero() {
+			if d := now.Sub(state.BlockedSince); d >= threshold {
+				deadlocks = append(deadlocks, FlowControlDeadlock{Reason: FlowControlBlockedByPeer, Duration: d, State: state})
+			}
+		}
+		if !state.PeerBlockedSince.IsZero() {
+			if d := now.Sub(state.PeerBlockedSince); d >= threshold {
+				reason := FlowControlWindowUpdateMissing
+				if state.HighestReceived > state.BytesRead && !state.WindowUpdatePending {
+					reason = FlowControlApplicationNotReading
+				}
+				deadlocks = append(deadlocks, FlowControlDeadlock{Reason: reason, Duration: d, State: state})
+			}
+		}
+	}
+	return deadlocks
+}
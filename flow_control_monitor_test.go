@@ -0,0 +1,176 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fixedClock struct {
+	utils.Clock
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
+var _ = Describe("Flow Control Monitor", func() {
+	var (
+		monitor         *flowControlMonitor
+		clock           *fixedClock
+		connFC          *mocks.MockConnectionFlowController
+		streamFC        *mocks.MockStreamFlowController
+		connRecvState   flowcontrol.ReceiveState
+		streamRecvState flowcontrol.ReceiveState
+	)
+
+	BeforeEach(func() {
+		clock = &fixedClock{Clock: utils.DefaultClock, now: time.Now()}
+		connRecvState = flowcontrol.ReceiveState{ReceiveWindow: 10000}
+		streamRecvState = flowcontrol.ReceiveState{ReceiveWindow: 1000}
+		connFC = mocks.NewMockConnectionFlowController(mockCtrl)
+		connFC.EXPECT().GetReceiveState().DoAndReturn(func() flowcontrol.ReceiveState { return connRecvState }).AnyTimes()
+		streamFC = mocks.NewMockStreamFlowController(mockCtrl)
+		streamFC.EXPECT().GetReceiveState().DoAndReturn(func() flowcontrol.ReceiveState { return streamRecvState }).AnyTimes()
+		monitor = newFlowControlMonitor(clock, connFC)
+		monitor.AddStream(5, streamFC)
+	})
+
+	It("has a string representation for the stall reasons", func() {
+		Expect(FlowControlBlockedByPeer.String()).To(Equal("blocked by the peer"))
+		Expect(FlowControlWindowUpdateMissing.String()).To(Equal("window update missing"))
+		Expect(FlowControlApplicationNotReading.String()).To(Equal("application not reading"))
+		Expect(FlowControlStall(42).String()).To(Equal("unknown flow control stall: 42"))
+	})
+
+	It("returns the state of the connection and of the streams", func() {
+		monitor.AddStream(1, mocks.NewMockStreamFlowController(mockCtrl))
+		monitor.RemoveStream(1)
+		monitor.AddStream(3, streamFC)
+		states := monitor.State()
+		Expect(states).To(HaveLen(3))
+		Expect(states[0].Connection).To(BeTrue())
+		Expect(states[0].ReceiveWindow).To(BeEquivalentTo(10000))
+		Expect(states[1].StreamID).To(BeEquivalentTo(3))
+		Expect(states[2].StreamID).To(BeEquivalentTo(5))
+		Expect(states[2].ReceiveWindow).To(BeEquivalentTo(1000))
+	})
+
+	It("removes all streams", func() {
+		monitor.AddStream(3, streamFC)
+		monitor.RemoveStreams()
+		states := monitor.State()
+		Expect(states).To(HaveLen(1))
+		Expect(states[0].Connection).To(BeTrue())
+		_, state := monitor.OnFrameReceived(&wire.MaxStreamDataFrame{StreamID: 5, ByteOffset: 1000})
+		Expect(state).To(BeNil())
+	})
+
+	It("ignores frames that are not flow control frames", func() {
+		_, state := monitor.OnFrameSent(&wire.PingFrame{})
+		Expect(state).To(BeNil())
+		_, state = monitor.OnFrameReceived(&wire.StopSendingFrame{StreamID: 5})
+		Expect(state).To(BeNil())
+	})
+
+	It("ignores frames for unknown streams", func() {
+		_, state := monitor.OnFrameReceived(&wire.MaxStreamDataFrame{StreamID: 7, ByteOffset: 1000})
+		Expect(state).To(BeNil())
+	})
+
+	Context("sending", func() {
+		It("records when sending is blocked", func() {
+			transition, state := monitor.OnFrameSent(&wire.StreamDataBlockedFrame{StreamID: 5, DataLimit: 500})
+			Expect(transition).To(Equal(quictrace.Blocked))
+			Expect(state.StreamID).To(BeEquivalentTo(5))
+			Expect(state.SendWindow).To(BeEquivalentTo(500))
+			Expect(state.BlockedSince).To(Equal(clock.now))
+			Expect(state.ReceiveWindow).To(BeEquivalentTo(1000))
+		})
+
+		It("unblocks when a window update is received", func() {
+			monitor.OnFrameSent(&wire.DataBlockedFrame{DataLimit: 500})
+			clock.now = clock.now.Add(time.Second)
+			transition, state := monitor.OnFrameReceived(&wire.MaxDataFrame{ByteOffset: 1500})
+			Expect(transition).To(Equal(quictrace.WindowUpdateReceived))
+			Expect(state.Connection).To(BeTrue())
+			Expect(state.SendWindow).To(BeEquivalentTo(1500))
+			Expect(state.BlockedSince).To(BeZero())
+			Expect(state.LastWindowUpdateReceived).To(Equal(clock.now))
+		})
+
+		It("stays blocked when a window update doesn't increase the limit", func() {
+			monitor.OnFrameSent(&wire.DataBlockedFrame{DataLimit: 500})
+			_, state := monitor.OnFrameReceived(&wire.MaxDataFrame{ByteOffset: 500})
+			Expect(state.BlockedSince).ToNot(BeZero())
+		})
+
+		It("reports when sending was blocked for too long", func() {
+			monitor.OnFrameSent(&wire.StreamDataBlockedFrame{StreamID: 5, DataLimit: 500})
+			clock.now = clock.now.Add(2 * time.Second)
+			Expect(monitor.Deadlocks(3 * time.Second)).To(BeEmpty())
+			deadlocks := monitor.Deadlocks(time.Second)
+			Expect(deadlocks).To(HaveLen(1))
+			Expect(deadlocks[0].Reason).To(Equal(FlowControlBlockedByPeer))
+			Expect(deadlocks[0].Duration).To(Equal(2 * time.Second))
+			Expect(deadlocks[0].String()).To(Equal("stream 5: blocked by the peer at offset 500 for 2s"))
+		})
+	})
+
+	Context("receiving", func() {
+		It("records when the peer is blocked", func() {
+			transition, state := monitor.OnFrameReceived(&wire.StreamDataBlockedFrame{StreamID: 5, DataLimit: 1000})
+			Expect(transition).To(Equal(quictrace.PeerBlocked))
+			Expect(state.PeerBlockedSince).To(Equal(clock.now))
+		})
+
+		It("ignores blocked frames for a limit that was already increased", func() {
+			_, state := monitor.OnFrameReceived(&wire.StreamDataBlockedFrame{StreamID: 5, DataLimit: 800})
+			Expect(state.PeerBlockedSince).To(BeZero())
+		})
+
+		It("unblocks the peer when a window update is sent", func() {
+			monitor.OnFrameReceived(&wire.DataBlockedFrame{DataLimit: 10000})
+			clock.now = clock.now.Add(time.Second)
+			transition, state := monitor.OnFrameSent(&wire.MaxDataFrame{ByteOffset: 20000})
+			Expect(transition).To(Equal(quictrace.WindowUpdateSent))
+			Expect(state.PeerBlockedSince).To(BeZero())
+			Expect(state.LastWindowUpdateSent).To(Equal(clock.now))
+		})
+
+		It("reports a missing window update", func() {
+			monitor.OnFrameReceived(&wire.StreamDataBlockedFrame{StreamID: 5, DataLimit: 1000})
+			streamRecvState = flowcontrol.ReceiveState{
+				BytesRead:           1000,
+				HighestReceived:     1000,
+				ReceiveWindow:       1000,
+				WindowUpdatePending: true,
+			}
+			clock.now = clock.now.Add(time.Second)
+			deadlocks := monitor.Deadlocks(time.Second)
+			Expect(deadlocks).To(HaveLen(1))
+			Expect(deadlocks[0].Reason).To(Equal(FlowControlWindowUpdateMissing))
+			Expect(deadlocks[0].String()).To(Equal("stream 5: window update missing for 1s, peer blocked at offset 1000, read 1000 bytes"))
+		})
+
+		It("reports when the application doesn't read", func() {
+			monitor.OnFrameReceived(&wire.DataBlockedFrame{DataLimit: 10000})
+			connRecvState = flowcontrol.ReceiveState{
+				BytesRead:       2000,
+				HighestReceived: 10000,
+				ReceiveWindow:   10000,
+			}
+			clock.now = clock.now.Add(time.Second)
+			deadlocks := monitor.Deadlocks(time.Second)
+			Expect(deadlocks).To(HaveLen(1))
+			Expect(deadlocks[0].Reason).To(Equal(FlowControlApplicationNotReading))
+			Expect(deadlocks[0].String()).To(Equal("connection: application not reading for 1s, 8000 bytes buffered"))
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -13,8 +14,13 @@ type framer interface {
 
 	AddActiveStream(protocol.StreamID)
 	AppendStreamFrames([]wire.Frame, protocol.ByteCount) []wire.Frame
+
+	SetGroupWeight(group string, weight int)
 }
 
+// The number of bytes a stream group is allowed to send per round, multiplied by its weight.
+const streamGroupQuantum = protocol.MaxPacketSizeIPv4
+
 type framerI struct {
 	mutex sync.Mutex
 
@@ -24,6 +30,11 @@ type framerI struct {
 	activeStreams map[protocol.StreamID]struct{}
 	streamQueue   []protocol.StreamID
 
+	// only set once SetGroupWeight is called
+	groupWeights map[string]int
+	// the number of bytes a group is still allowed to send in the current round
+	groupCredits map[string]int64
+
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
 }
@@ -73,9 +84,50 @@ func (f *framerI) AddActiveStream(id protocol.StreamID) {
 	f.mutex.Unlock()
 }
 
+func (f *framerI) SetGroupWeight(group string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	f.mutex.Lock()
+	if f.groupWeights == nil {
+		f.groupWeights = make(map[string]int)
+		f.groupCredits = make(map[string]int64)
+	}
+	f.groupWeights[group] = weight
+	f.mutex.Unlock()
+}
+
 func (f *framerI) AppendStreamFrames(frames []wire.Frame, maxLen protocol.ByteCount) []wire.Frame {
-	var length protocol.ByteCount
 	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.groupWeights == nil {
+		frames, _, _ = f.appendStreamFrames(frames, maxLen, false)
+		return frames
+	}
+	// Weighted fair queueing between stream groups, using deficit round robin:
+	// Every round, each group is allowed to send a quantum of data (multiplied by its weight).
+	// A new round is started once all groups with data to send have used up their credit.
+	var length protocol.ByteCount
+	for {
+		fs, l, skipped := f.appendStreamFrames(frames, maxLen-length, true)
+		frames = fs
+		length += l
+		if !skipped || maxLen-length < protocol.MinStreamFrameSize {
+			break
+		}
+		if l == 0 {
+			f.startNewRound()
+		}
+	}
+	return frames
+}
+
+// appendStreamFrames pops STREAM frames from the active streams in a round robin fashion.
+// If useCredit is set, it skips streams belonging to groups that have no credit left.
+func (f *framerI) appendStreamFrames(frames []wire.Frame, maxLen protocol.ByteCount, useCredit bool) ([]wire.Frame, protocol.ByteCount, bool /* skipped streams */) {
+	var length protocol.ByteCount
+	var skipped bool
 	// pop STREAM frames, until less than MinStreamFrameSize bytes are left in the packet
 	numActiveStreams := len(f.streamQueue)
 	for i := 0; i < numActiveStreams; i++ {
@@ -92,6 +144,20 @@ func (f *framerI) AppendStreamFrames(frames []wire.Frame, maxLen protocol.ByteCo
 			delete(f.activeStreams, id)
 			continue
 		}
+		var group string
+		if useCredit {
+			group = str.getGroup()
+			credit, ok := f.groupCredits[group]
+			if !ok {
+				credit = f.getGroupQuantum(group)
+				f.groupCredits[group] = credit
+			}
+			if credit <= 0 {
+				f.streamQueue = append(f.streamQueue, id)
+				skipped = true
+				continue
+			}
+		}
 		frame, hasMoreData := str.popStreamFrame(maxLen - length)
 		if hasMoreData { // put the stream back in the queue (at the end)
 			f.streamQueue = append(f.streamQueue, id)
@@ -102,8 +168,27 @@ func (f *framerI) AppendStreamFrames(frames []wire.Frame, maxLen protocol.ByteCo
 			continue
 		}
 		frames = append(frames, frame)
-		length += frame.Length(f.version)
+		frameLen := frame.Length(f.version)
+		length += frameLen
+		if useCredit {
+			f.groupCredits[group] -= int64(frameLen)
+		}
 	}
-	f.mutex.Unlock()
-	return frames
+	return frames, length, skipped
+}
+
+func (f *framerI) startNewRound() {
+	for group, credit := range f.groupCredits {
+		quantum := f.getGroupQuantum(group)
+		// Don't allow groups to accumulate credit while they're not sending.
+		f.groupCredits[group] = utils.MinInt64(credit+quantum, quantum)
+	}
+}
+
+func (f *framerI) getGroupQuantum(group string) int64 {
+	weight, ok := f.groupWeights[group]
+	if !ok {
+		weight = 1
+	}
+	return int64(weight) * streamGroupQuantum
 }
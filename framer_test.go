@@ -226,4 +226,65 @@ var _ = Describe("Stream Framer", func() {
 			Expect(fs).To(Equal([]wire.Frame{f}))
 		})
 	})
+
+	Context("stream groups", func() {
+		popFrame := func(id protocol.StreamID) func(protocol.ByteCount) (*wire.StreamFrame, bool) {
+			return func(protocol.ByteCount) (*wire.StreamFrame, bool) {
+				return &wire.StreamFrame{StreamID: id, Data: make([]byte, 100)}, true
+			}
+		}
+
+		// countBytes sends a number of packets and returns the number of bytes sent on each stream
+		countBytes := func(numPackets int) map[protocol.StreamID]protocol.ByteCount {
+			bytesSent := make(map[protocol.StreamID]protocol.ByteCount)
+			for i := 0; i < numPackets; i++ {
+				for _, f := range framer.AppendStreamFrames(nil, 1000) {
+					sf := f.(*wire.StreamFrame)
+					bytesSent[sf.StreamID] += sf.DataLen()
+				}
+			}
+			return bytesSent
+		}
+
+		BeforeEach(func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).AnyTimes()
+			stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFrame(id1)).AnyTimes()
+			stream2.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFrame(id2)).AnyTimes()
+		})
+
+		It("shares the bandwidth according to the weights", func() {
+			stream1.EXPECT().getGroup().Return("foo").AnyTimes()
+			stream2.EXPECT().getGroup().Return("bar").AnyTimes()
+			framer.SetGroupWeight("foo", 2)
+			framer.SetGroupWeight("bar", 1)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			bytesSent := countBytes(500)
+			Expect(bytesSent[id1]).ToNot(BeZero())
+			Expect(bytesSent[id2]).ToNot(BeZero())
+			Expect(float64(bytesSent[id1]) / float64(bytesSent[id2])).To(BeNumerically("~", 2, 0.1))
+		})
+
+		It("uses a weight of 1 for groups that don't have a weight set", func() {
+			stream1.EXPECT().getGroup().Return("foo").AnyTimes()
+			stream2.EXPECT().getGroup().Return("").AnyTimes()
+			framer.SetGroupWeight("foo", 3)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			bytesSent := countBytes(500)
+			Expect(float64(bytesSent[id1]) / float64(bytesSent[id2])).To(BeNumerically("~", 3, 0.15))
+		})
+
+		It("gives the whole bandwidth to a group, if it is the only one sending", func() {
+			stream1.EXPECT().getGroup().Return("foo").AnyTimes()
+			framer.SetGroupWeight("foo", 1)
+			framer.SetGroupWeight("bar", 10)
+			framer.AddActiveStream(id1)
+			// no packet is sent without a STREAM frame, even after the group used up its credit
+			for i := 0; i < 100; i++ {
+				Expect(framer.AppendStreamFrames(nil, 1000)).To(HaveLen(1))
+			}
+		})
+	})
 })
@@ -165,6 +165,8 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 	}
 
 	hasBody := (req.Body != nil)
+	// For CONNECT requests, the request body is the tunnel payload, and is sent after the response arrived.
+	isTunnel := isConnectMethod(req.Method)
 
 	responseChan := make(chan *http.Response)
 	dataStream, err := c.session.OpenStreamSync()
@@ -177,7 +179,7 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 	c.mutex.Unlock()
 
 	var requestedGzip bool
-	if !c.opts.DisableCompression && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != "HEAD" {
+	if !c.opts.DisableCompression && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != "HEAD" && !isTunnel {
 		requestedGzip = true
 	}
 	// TODO: add support for trailers
@@ -200,7 +202,7 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 	var receivedResponse bool
 	var bodySent bool
 
-	if !hasBody {
+	if !hasBody || isTunnel {
 		bodySent = true
 	}
 
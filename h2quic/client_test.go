@@ -439,6 +439,26 @@ var _ = Describe("Client", func() {
 				Expect(request.Body.(*mockBody).closed).To(BeTrue())
 			})
 
+			It("returns the response for CONNECT-UDP requests before the body is sent", func() {
+				pr, pw := io.Pipe()
+				defer pw.Close()
+				request.Method = MethodConnectUDP
+				request.Body = pr
+				rspChan := make(chan *http.Response)
+				go func() {
+					defer GinkgoRecover()
+					rsp, err := client.RoundTrip(request)
+					Expect(err).ToNot(HaveOccurred())
+					rspChan <- rsp
+				}()
+				Eventually(func() []byte { return headerStream.dataWritten.Bytes() }).ShouldNot(BeEmpty())
+				hfMap := getHeaderFields(getRequest(headerStream.dataWritten.Bytes()))
+				Expect(hfMap).ToNot(HaveKey("accept-encoding"))
+				injectResponse(5, response)
+				Eventually(rspChan).Should(Receive(Equal(response)))
+				Expect(dataStream.closed).To(BeFalse())
+			})
+
 			It("returns the error that occurred when reading the body", func() {
 				testErr := errors.New("testErr")
 				request.Body.(*mockBody).readErr = testErr
@@ -0,0 +1,293 @@
+package h2quic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// MethodConnectUDP is the method of a request that opens a UDP tunnel through a proxy (MASQUE).
+// The :authority of the request is the host:port of the UDP target.
+// Once the proxy responds with a 2xx status, UDP payloads are exchanged on the request stream,
+// each payload encoded as a DATAGRAM capsule.
+const MethodConnectUDP = "CONNECT-UDP"
+
+const capsuleTypeDatagram = 0x0
+
+// maxCapsuleLength is the maximum length of a DATAGRAM capsule we accept.
+// It accounts for the context ID preceding the UDP payload.
+const maxCapsuleLength = uint64(protocol.MaxReceivePacketSize) + 8
+
+func isConnectMethod(method string) bool {
+	return method == "CONNECT" || method == MethodConnectUDP
+}
+
+// writeDatagramCapsule writes a DATAGRAM capsule using a single Write call.
+func writeDatagramCapsule(w io.Writer, payload []byte) error {
+	b := &bytes.Buffer{}
+	utils.WriteVarInt(b, capsuleTypeDatagram)
+	utils.WriteVarInt(b, uint64(len(payload))+1) // the context ID takes one byte
+	utils.WriteVarInt(b, 0)                      // context ID 0 is used for UDP payloads
+	b.Write(payload)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// readDatagramCapsule reads the next DATAGRAM capsule, skipping capsules of unknown types.
+func readDatagramCapsule(r *bufio.Reader) ([]byte, error) {
+	for {
+		capsuleType, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		length, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if length > maxCapsuleLength {
+			return nil, fmt.Errorf("capsule too large: %d bytes", length)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if capsuleType != capsuleTypeDatagram {
+			continue
+		}
+		br := bytes.NewReader(data)
+		contextID, err := utils.ReadVarInt(br)
+		if err != nil {
+			return nil, err
+		}
+		// we only use context ID 0, which means that the datagram contains a UDP payload
+		if contextID != 0 {
+			continue
+		}
+		return data[len(data)-br.Len():], nil
+	}
+}
+
+// ConnectUDPHandler is a http.Handler that proxies CONNECT-UDP requests.
+// All other requests are passed to Next.
+type ConnectUDPHandler struct {
+	// Next handles all requests that are not CONNECT-UDP requests.
+	// If nil, these requests are answered with a 405 status.
+	Next http.Handler
+	// AllowTarget is called for every CONNECT-UDP request.
+	// If it returns false, the request is answered with a 403 status.
+	// If nil, all requests are rejected: without a policy, the proxy would relay UDP packets to any host,
+	// including hosts in the proxy's local network.
+	AllowTarget func(r *http.Request, target *net.UDPAddr) bool
+}
+
+var _ http.Handler = &ConnectUDPHandler{}
+
+// ServeHTTP proxies the UDP payloads sent on the request stream to the target, and vice versa.
+// It returns when either the request stream or the UDP socket returns an error, or when the request context is done.
+func (h *ConnectUDPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != MethodConnectUDP {
+		if h.Next != nil {
+			h.Next.ServeHTTP(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	logger := utils.DefaultLogger.WithPrefix("connect-udp")
+
+	target, err := net.ResolveUDPAddr("udp", r.Host)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if h.AllowTarget == nil || !h.AllowTarget(r, target) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	conn, err := net.DialUDP("udp", nil, target)
+	if err != nil {
+		logger.Debugf("Dialing %s failed: %s", target, err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	errChan := make(chan error, 2)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b := make([]byte, protocol.MaxReceivePacketSize)
+		for {
+			n, err := conn.Read(b)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if err := writeDatagramCapsule(w, b[:n]); err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		br := bufio.NewReader(r.Body)
+		for {
+			payload, err := readDatagramCapsule(br)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := conn.Write(payload); err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+	select {
+	case err = <-errChan:
+	case <-r.Context().Done():
+		err = r.Context().Err()
+	}
+	logger.Debugf("Closing UDP tunnel to %s: %s", target, err)
+	// Closing the UDP socket stops the go routine reading from it.
+	// It must not use the ResponseWriter after ServeHTTP returned.
+	// The go routine reading the request body returns when the server cancels reading from the request stream.
+	conn.Close()
+	wg.Wait()
+}
+
+// DialConnectUDP opens a UDP tunnel to target through the CONNECT-UDP proxy at proxyURL.
+// The proxyURL must use the https scheme, e.g. https://proxy.example.com:443.
+// The returned net.PacketConn sends all packets to target, independent of the address passed to WriteTo.
+func (r *RoundTripper) DialConnectUDP(ctx context.Context, proxyURL, target string) (net.PacketConn, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	req := (&http.Request{
+		Method:     MethodConnectUDP,
+		URL:        u,
+		Host:       target,
+		Header:     http.Header{},
+		Body:       pr,
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+	}).WithContext(ctx)
+	rsp, err := r.RoundTrip(req)
+	if err != nil {
+		pw.Close()
+		return nil, err
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		pw.Close()
+		rsp.Body.Close()
+		return nil, fmt.Errorf("h2quic: CONNECT-UDP to %s failed with status %d", target, rsp.StatusCode)
+	}
+	return &connectUDPConn{
+		body:       rsp.Body,
+		reader:     bufio.NewReader(rsp.Body),
+		writer:     pw,
+		localAddr:  &tunnelAddr{addr: u.Host},
+		remoteAddr: &tunnelAddr{addr: target},
+	}, nil
+}
+
+// A ConnectUDPDialer dials QUIC connections through a CONNECT-UDP proxy.
+// It can be used as the quic.Config.PacketConnDialer.
+type ConnectUDPDialer struct {
+	// RoundTripper is used to establish the connection to the proxy.
+	RoundTripper *RoundTripper
+	// ProxyURL is the URL of the proxy, e.g. https://proxy.example.com:443.
+	ProxyURL string
+}
+
+// DialPacketConn opens a UDP tunnel to addr.
+func (d *ConnectUDPDialer) DialPacketConn(ctx context.Context, addr string) (net.PacketConn, net.Addr, error) {
+	conn, err := d.RoundTripper.DialConnectUDP(ctx, d.ProxyURL, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, conn.(*connectUDPConn).remoteAddr, nil
+}
+
+// tunnelAddr is the address of an endpoint of a CONNECT-UDP tunnel
+type tunnelAddr struct {
+	addr string
+}
+
+var _ net.Addr = &tunnelAddr{}
+
+func (a *tunnelAddr) Network() string { return "connect-udp" }
+func (a *tunnelAddr) String() string  { return a.addr }
+
+var errWriteDeadlineNotSupported = errors.New("h2quic: write deadlines are not supported on CONNECT-UDP tunnels")
+
+type connectUDPConn struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+	writer     *io.PipeWriter
+
+	localAddr, remoteAddr net.Addr
+}
+
+var _ net.PacketConn = &connectUDPConn{}
+
+func (c *connectUDPConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	payload, err := readDatagramCapsule(c.reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Just like reading from a UDP socket, packets larger than p are truncated.
+	return copy(p, payload), c.remoteAddr, nil
+}
+
+func (c *connectUDPConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if err := writeDatagramCapsule(c.writer, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *connectUDPConn) Close() error {
+	c.writer.Close()
+	return c.body.Close()
+}
+
+func (c *connectUDPConn) LocalAddr() net.Addr  { return c.localAddr }
+func (c *connectUDPConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+func (c *connectUDPConn) SetDeadline(t time.Time) error {
+	return errWriteDeadlineNotSupported
+}
+
+func (c *connectUDPConn) SetReadDeadline(t time.Time) error {
+	if b, ok := c.body.(*responseBody); ok {
+		return b.SetReadDeadline(t)
+	}
+	return errors.New("h2quic: read deadlines are not supported on this CONNECT-UDP tunnel")
+}
+
+func (c *connectUDPConn) SetWriteDeadline(t time.Time) error {
+	return errWriteDeadlineNotSupported
+}
@@ -0,0 +1,279 @@
+package h2quic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// pipeResponseWriter is a http.ResponseWriter that writes the response body to a pipe
+type pipeResponseWriter struct {
+	header http.Header
+	status chan int
+	io.Writer
+}
+
+func (w *pipeResponseWriter) Header() http.Header { return w.header }
+func (w *pipeResponseWriter) WriteHeader(s int)   { w.status <- s }
+
+var _ = Describe("CONNECT-UDP", func() {
+	Context("DATAGRAM capsules", func() {
+		It("writes and reads capsules", func() {
+			b := &bytes.Buffer{}
+			Expect(writeDatagramCapsule(b, []byte("foo"))).To(Succeed())
+			Expect(writeDatagramCapsule(b, []byte("foobar"))).To(Succeed())
+			r := bufio.NewReader(b)
+			payload, err := readDatagramCapsule(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload).To(Equal([]byte("foo")))
+			payload, err = readDatagramCapsule(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload).To(Equal([]byte("foobar")))
+			_, err = readDatagramCapsule(r)
+			Expect(err).To(MatchError(io.EOF))
+		})
+
+		It("skips capsules of unknown types", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, 0x1337)
+			utils.WriteVarInt(b, 3)
+			b.Write([]byte("foo"))
+			Expect(writeDatagramCapsule(b, []byte("bar"))).To(Succeed())
+			payload, err := readDatagramCapsule(bufio.NewReader(b))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload).To(Equal([]byte("bar")))
+		})
+
+		It("skips datagrams with a non-zero context ID", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, capsuleTypeDatagram)
+			utils.WriteVarInt(b, 4)
+			utils.WriteVarInt(b, 2)
+			b.Write([]byte("foo"))
+			Expect(writeDatagramCapsule(b, []byte("bar"))).To(Succeed())
+			payload, err := readDatagramCapsule(bufio.NewReader(b))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload).To(Equal([]byte("bar")))
+		})
+
+		It("errors on capsules that are too large", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, capsuleTypeDatagram)
+			utils.WriteVarInt(b, maxCapsuleLength+1)
+			_, err := readDatagramCapsule(bufio.NewReader(b))
+			Expect(err).To(MatchError("capsule too large: 1461 bytes"))
+		})
+
+		It("errors on truncated capsules", func() {
+			b := &bytes.Buffer{}
+			Expect(writeDatagramCapsule(b, []byte("foobar"))).To(Succeed())
+			_, err := readDatagramCapsule(bufio.NewReader(bytes.NewReader(b.Bytes()[:b.Len()-1])))
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		})
+	})
+
+	Context("handler", func() {
+		var handler *ConnectUDPHandler
+
+		BeforeEach(func() {
+			handler = &ConnectUDPHandler{}
+		})
+
+		newRequest := func(target string, body io.Reader) *http.Request {
+			req := httptest.NewRequest(MethodConnectUDP, "https://proxy.example.com", body)
+			req.Host = target
+			return req
+		}
+
+		It("passes other requests to the next handler", func() {
+			var called bool
+			handler.Next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusTeapot)
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "https://proxy.example.com", nil))
+			Expect(called).To(BeTrue())
+			Expect(w.Code).To(Equal(http.StatusTeapot))
+		})
+
+		It("rejects other requests if there's no next handler", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "https://proxy.example.com", nil))
+			Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("rejects invalid targets", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newRequest("localhost", nil))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects all targets if no policy is set", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newRequest("127.0.0.1:1337", nil))
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("rejects targets that are not allowed", func() {
+			var target *net.UDPAddr
+			handler.AllowTarget = func(_ *http.Request, addr *net.UDPAddr) bool {
+				target = addr
+				return false
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newRequest("127.0.0.1:1337", nil))
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+			Expect(target.String()).To(Equal("127.0.0.1:1337"))
+		})
+
+		It("proxies UDP packets", func() {
+			handler.AllowTarget = func(*http.Request, *net.UDPAddr) bool { return true }
+			echoConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+			Expect(err).ToNot(HaveOccurred())
+			defer echoConn.Close()
+			go func() {
+				defer GinkgoRecover()
+				b := make([]byte, 1500)
+				for {
+					n, addr, err := echoConn.ReadFrom(b)
+					if err != nil {
+						return
+					}
+					_, err = echoConn.WriteTo(bytes.ToUpper(b[:n]), addr)
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}()
+
+			reqBodyReader, reqBodyWriter := io.Pipe()
+			rspBodyReader, rspBodyWriter := io.Pipe()
+			w := &pipeResponseWriter{
+				header: http.Header{},
+				status: make(chan int, 1),
+				Writer: rspBodyWriter,
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				handler.ServeHTTP(w, newRequest(echoConn.LocalAddr().String(), reqBodyReader))
+				close(done)
+			}()
+			Eventually(w.status).Should(Receive(Equal(http.StatusOK)))
+
+			rspBody := bufio.NewReader(rspBodyReader)
+			for _, msg := range []string{"foo", "bar"} {
+				Expect(writeDatagramCapsule(reqBodyWriter, []byte(msg))).To(Succeed())
+				payload, err := readDatagramCapsule(rspBody)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(payload).To(Equal(bytes.ToUpper([]byte(msg))))
+			}
+			// closing the request body terminates the tunnel
+			reqBodyWriter.Close()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the tunnel when the request context is done", func() {
+			handler.AllowTarget = func(*http.Request, *net.UDPAddr) bool { return true }
+			targetConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+			Expect(err).ToNot(HaveOccurred())
+			defer targetConn.Close()
+			reqBodyReader, reqBodyWriter := io.Pipe()
+			defer reqBodyWriter.Close()
+			_, rspBodyWriter := io.Pipe()
+			w := &pipeResponseWriter{
+				header: http.Header{},
+				status: make(chan int, 1),
+				Writer: rspBodyWriter,
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				handler.ServeHTTP(w, newRequest(targetConn.LocalAddr().String(), reqBodyReader).WithContext(ctx))
+				close(done)
+			}()
+			Eventually(w.status).Should(Receive(Equal(http.StatusOK)))
+			Consistently(done).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("tunnel conn", func() {
+		var (
+			conn       *connectUDPConn
+			rspBody    *bytes.Buffer
+			pipeReader *io.PipeReader
+		)
+
+		BeforeEach(func() {
+			rspBody = &bytes.Buffer{}
+			var pw *io.PipeWriter
+			pipeReader, pw = io.Pipe()
+			conn = &connectUDPConn{
+				body:       &mockBody{},
+				reader:     bufio.NewReader(rspBody),
+				writer:     pw,
+				localAddr:  &tunnelAddr{addr: "proxy.example.com:443"},
+				remoteAddr: &tunnelAddr{addr: "target.example.com:443"},
+			}
+		})
+
+		It("has the right addresses", func() {
+			Expect(conn.LocalAddr().Network()).To(Equal("connect-udp"))
+			Expect(conn.LocalAddr().String()).To(Equal("proxy.example.com:443"))
+			Expect(conn.RemoteAddr().String()).To(Equal("target.example.com:443"))
+		})
+
+		It("reads packets", func() {
+			Expect(writeDatagramCapsule(rspBody, []byte("foobar"))).To(Succeed())
+			b := make([]byte, 100)
+			n, addr, err := conn.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+			Expect(addr).To(Equal(conn.RemoteAddr()))
+		})
+
+		It("truncates packets that are larger than the buffer", func() {
+			Expect(writeDatagramCapsule(rspBody, []byte("foobar"))).To(Succeed())
+			b := make([]byte, 3)
+			n, _, err := conn.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(3))
+			Expect(b).To(Equal([]byte("foo")))
+		})
+
+		It("writes packets", func() {
+			go func() {
+				defer GinkgoRecover()
+				n, err := conn.WriteTo([]byte("foobar"), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(6))
+			}()
+			payload, err := readDatagramCapsule(bufio.NewReader(pipeReader))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload).To(Equal([]byte("foobar")))
+		})
+
+		It("closes", func() {
+			Expect(conn.Close()).To(Succeed())
+			Expect(conn.body.(*mockBody).closed).To(BeTrue())
+			_, err := conn.WriteTo([]byte("foobar"), nil)
+			Expect(err).To(MatchError(io.ErrClosedPipe))
+		})
+
+		It("doesn't support write deadlines", func() {
+			Expect(conn.SetWriteDeadline(time.Now())).To(MatchError(errWriteDeadlineNotSupported))
+			Expect(conn.SetDeadline(time.Now())).To(MatchError(errWriteDeadlineNotSupported))
+		})
+	})
+})
@@ -37,13 +37,25 @@ func requestFromHeaders(headers []hpack.HeaderField) (*http.Request, error) {
 		httpHeaders.Set("Cookie", strings.Join(httpHeaders["Cookie"], "; "))
 	}
 
-	if len(path) == 0 || len(authority) == 0 || len(method) == 0 {
-		return nil, errors.New(":path, :authority and :method must not be empty")
-	}
-
-	u, err := url.Parse(path)
-	if err != nil {
-		return nil, err
+	var u *url.URL
+	var requestURI string
+	var err error
+	if isConnectMethod(method) {
+		// CONNECT requests don't carry a :path, the target is specified by the :authority
+		if len(authority) == 0 {
+			return nil, errors.New(":authority must not be empty for " + method + " requests")
+		}
+		u = &url.URL{Host: authority}
+		requestURI = authority
+	} else {
+		if len(path) == 0 || len(authority) == 0 || len(method) == 0 {
+			return nil, errors.New(":path, :authority and :method must not be empty")
+		}
+		u, err = url.Parse(path)
+		if err != nil {
+			return nil, err
+		}
+		requestURI = path
 	}
 
 	var contentLength int64
@@ -64,7 +76,7 @@ func requestFromHeaders(headers []hpack.HeaderField) (*http.Request, error) {
 		Body:          nil,
 		ContentLength: contentLength,
 		Host:          authority,
-		RequestURI:    path,
+		RequestURI:    requestURI,
 		TLS:           &tls.ConnectionState{},
 	}, nil
 }
@@ -33,6 +33,25 @@ var _ = Describe("Request", func() {
 		Expect(req.TLS).ToNot(BeNil())
 	})
 
+	It("populates CONNECT-UDP requests, which don't have a :path", func() {
+		headers := []hpack.HeaderField{
+			{Name: ":authority", Value: "target.example.com:443"},
+			{Name: ":method", Value: MethodConnectUDP},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Method).To(Equal(MethodConnectUDP))
+		Expect(req.Host).To(Equal("target.example.com:443"))
+		Expect(req.URL.Host).To(Equal("target.example.com:443"))
+		Expect(req.RequestURI).To(Equal("target.example.com:443"))
+	})
+
+	It("errors on CONNECT-UDP requests without an :authority", func() {
+		headers := []hpack.HeaderField{{Name: ":method", Value: MethodConnectUDP}}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":authority must not be empty for CONNECT-UDP requests"))
+	})
+
 	It("concatenates the cookie headers", func() {
 		headers := []hpack.HeaderField{
 			{Name: ":path", Value: "/foo"},
@@ -71,7 +71,7 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	}
 
 	var path string
-	if req.Method != "CONNECT" {
+	if !isConnectMethod(req.Method) {
 		path = req.URL.RequestURI()
 		if !validPseudoPath(path) {
 			orig := path
@@ -106,7 +106,7 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// [RFC3986]).
 	w.writeHeader(":authority", host)
 	w.writeHeader(":method", req.Method)
-	if req.Method != "CONNECT" {
+	if !isConnectMethod(req.Method) {
 		w.writeHeader(":path", path)
 		w.writeHeader(":scheme", req.URL.Scheme)
 	}
@@ -56,6 +56,18 @@ var _ = Describe("Request", func() {
 		Expect(headerFields).ToNot(HaveKey("accept-encoding"))
 	})
 
+	It("writes a CONNECT-UDP request", func() {
+		req, err := http.NewRequest(MethodConnectUDP, "https://proxy.example.com/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Host = "target.example.com:443"
+		rw.WriteRequest(req, 1337, false, false)
+		_, headerFields := decode(headerStream.dataWritten.Bytes())
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "target.example.com:443"))
+		Expect(headerFields).To(HaveKeyWithValue(":method", MethodConnectUDP))
+		Expect(headerFields).ToNot(HaveKey(":path"))
+		Expect(headerFields).ToNot(HaveKey(":scheme"))
+	})
+
 	It("sets the EndStream header", func() {
 		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
 		Expect(err).ToNot(HaveOccurred())
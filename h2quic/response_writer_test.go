@@ -52,6 +52,11 @@ func (s *mockStream) Context() context.Context              { return s.ctx }
 func (s *mockStream) SetDeadline(time.Time) error           { panic("not implemented") }
 func (s *mockStream) SetReadDeadline(time.Time) error       { panic("not implemented") }
 func (s *mockStream) SetWriteDeadline(time.Time) error      { panic("not implemented") }
+func (s *mockStream) ReadChunk() ([]byte, error)            { panic("not implemented") }
+func (s *mockStream) SetGroup(string)                       { panic("not implemented") }
+func (s *mockStream) SetReceiveWindow(uint64)               { panic("not implemented") }
+func (s *mockStream) WriteAcked() uint64                    { panic("not implemented") }
+func (s *mockStream) OnWriteAcked(func(uint64))             { panic("not implemented") }
 
 func (s *mockStream) Read(p []byte) (int, error) {
 	n, _ := s.dataToRead.Read(p)
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strings"
@@ -47,6 +48,7 @@ func (s *mockSession) GetOrOpenStream(id protocol.StreamID) (quic.Stream, error)
 	return s.dataStream, nil
 }
 func (s *mockSession) AcceptStream() (quic.Stream, error) { return s.streamToAccept, nil }
+func (s *mockSession) OnStream(func(quic.Stream)) error   { panic("not implemented") }
 func (s *mockSession) OpenStream() (quic.Stream, error) {
 	if s.streamOpenErr != nil {
 		return nil, s.streamOpenErr
@@ -69,6 +71,15 @@ func (s *mockSession) Close() error {
 	s.closed = true
 	return nil
 }
+func (s *mockSession) SetStreamGroupWeight(string, int) {
+	panic("not implemented")
+}
+func (s *mockSession) CloseSync(context.Context) error {
+	return s.Close()
+}
+func (s *mockSession) RetireKeys() error {
+	return s.Close()
+}
 func (s *mockSession) CloseWithError(_ quic.ErrorCode, e error) error {
 	s.closedWithError = e
 	return s.Close()
@@ -86,6 +97,15 @@ func (s *mockSession) ConnectionState() quic.ConnectionState        { panic("not
 func (s *mockSession) AcceptUniStream() (quic.ReceiveStream, error) { panic("not implemented") }
 func (s *mockSession) OpenUniStream() (quic.SendStream, error)      { panic("not implemented") }
 func (s *mockSession) OpenUniStreamSync() (quic.SendStream, error)  { panic("not implemented") }
+func (s *mockSession) PathHealth() quic.PathHealth                  { panic("not implemented") }
+func (s *mockSession) FlowControlState() []quic.FlowControlState    { panic("not implemented") }
+func (s *mockSession) FlowControlDeadlocks(time.Duration) []quic.FlowControlDeadlock {
+	panic("not implemented")
+}
+func (s *mockSession) PeerTransportParameters() *quic.TransportParameters { panic("not implemented") }
+func (s *mockSession) SetDeadline(time.Time) error                        { panic("not implemented") }
+func (s *mockSession) SetReadDeadline(time.Time) error                    { panic("not implemented") }
+func (s *mockSession) SetWriteDeadline(time.Time) error                   { panic("not implemented") }
 
 var _ = Describe("H2 server", func() {
 	var (
@@ -533,4 +553,48 @@ var _ = Describe("H2 server", func() {
 		err := ListenAndServeQUIC("", fullpem, privkey, nil)
 		Expect(err).To(MatchError(testErr))
 	})
+
+	It("serves requests if both peers use stream compression", func() {
+		origQuicListen := quicListen
+		origDialAddr := dialAddr
+		defer func() {
+			quicListen = origQuicListen
+			dialAddr = origDialAddr
+		}()
+		quicListen = quic.Listen
+		dialAddr = quic.DialAddr
+
+		conf := &quic.Config{StreamCompression: true}
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		server := &Server{
+			Server: &http.Server{
+				TLSConfig: testdata.GetTLSConfig(),
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					body, err := ioutil.ReadAll(r.Body)
+					Expect(err).ToNot(HaveOccurred())
+					w.Write(bytes.ToUpper(body))
+				}),
+			},
+			QuicConfig: conf,
+		}
+		go server.Serve(conn)
+		defer server.Close()
+
+		rt := &RoundTripper{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			QuicConfig:      conf,
+		}
+		defer rt.Close()
+		port := conn.LocalAddr().(*net.UDPAddr).Port
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("https://localhost:%d/", port), strings.NewReader(strings.Repeat("foobar", 100)))
+		Expect(err).ToNot(HaveOccurred())
+		rsp, err := rt.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		body, err := ioutil.ReadAll(rsp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(strings.Repeat("FOOBAR", 100)))
+	})
 })
@@ -4,10 +4,13 @@ import (
 	"context"
 	"io"
 	"net"
+	"syscall"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 // The StreamID is the ID of a QUIC stream.
@@ -25,6 +28,33 @@ type Cookie struct {
 // ConnectionState records basic details about the QUIC connection.
 type ConnectionState = handshake.ConnectionState
 
+// ClientHelloInfo contains information from the ClientHello sent by a client.
+type ClientHelloInfo = handshake.ClientHelloInfo
+
+// TransportParameters are the limits that the peer advertised during the handshake.
+type TransportParameters struct {
+	// IdleTimeout is the idle timeout of the peer.
+	IdleTimeout time.Duration
+	// MaxPacketSize is the maximum size of a packet that the peer is willing to receive.
+	MaxPacketSize uint64
+	// InitialMaxData is the initial connection-level flow control window.
+	InitialMaxData uint64
+	// InitialMaxStreamDataBidiLocal, InitialMaxStreamDataBidiRemote and InitialMaxStreamDataUni
+	// are the values of the initial_max_stream_data_* transport parameters,
+	// the initial stream-level flow control windows.
+	InitialMaxStreamDataBidiLocal  uint64
+	InitialMaxStreamDataBidiRemote uint64
+	InitialMaxStreamDataUni        uint64
+	// MaxBidiStreams is the initial number of bidirectional streams we are allowed to open.
+	MaxBidiStreams uint64
+	// MaxUniStreams is the initial number of unidirectional streams we are allowed to open.
+	MaxUniStreams uint64
+	// AckDelayExponent is the exponent used by the peer to encode the ACK delay.
+	AckDelayExponent uint8
+	// DisableMigration is set if the peer doesn't support connection migration.
+	DisableMigration bool
+}
+
 // An ErrorCode is an application-defined error code.
 type ErrorCode = protocol.ApplicationErrorCode
 
@@ -38,6 +68,12 @@ type Stream interface {
 	// If the stream was canceled by the peer, the error implements the StreamError
 	// interface, and Canceled() == true.
 	io.Reader
+	// ReadChunk reads the next chunk of data from the stream, without copying it into a buffer.
+	// It blocks until data is available, and returns (at most) the data received in a single STREAM frame.
+	// The returned slice is not used by quic-go afterwards, so the application may retain and modify it.
+	// Like Read, it may return data together with an error (e.g. io.EOF).
+	// It must not be called concurrently with Read.
+	ReadChunk() ([]byte, error)
 	// Write writes data to the stream.
 	// Write can be made to time out and return a net.Error with Timeout() == true
 	// after a fixed time limit; see SetDeadline and SetWriteDeadline.
@@ -67,12 +103,33 @@ type Stream interface {
 	// any currently-blocked Read call.
 	// A zero value for t means Read will not time out.
 	SetReadDeadline(t time.Time) error
+	// SetReceiveWindow sets the size of the flow control window for receiving data on this stream,
+	// overriding Config.MaxReceiveStreamFlowControlWindow.
+	// It should be called right after opening or accepting the stream.
+	// If the window is increased, a MAX_STREAM_DATA frame is sent right away, and the window is not auto-tuned beyond this size.
+	// The window can't be reduced below the offset that was already granted to the peer.
+	// The amount of data in flight is still limited by the connection-level window (see Config.MaxReceiveConnectionFlowControlWindow).
+	SetReceiveWindow(size uint64)
 	// SetWriteDeadline sets the deadline for future Write calls
 	// and any currently-blocked Write call.
 	// Even if write times out, it may return n > 0, indicating that
 	// some of the data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SetGroup assigns the stream to a group.
+	// The bandwidth is shared between groups according to their weight (see Session.SetStreamGroupWeight),
+	// and between the streams of a group in a round-robin fashion.
+	// By default, streams belong to the group "".
+	SetGroup(group string)
+	// WriteAcked returns the number of bytes written to the stream that were acknowledged by the peer.
+	// Data is only counted once all data written before it was acknowledged as well,
+	// i.e. the first WriteAcked() bytes passed to Write were received by the peer, and won't be retransmitted.
+	// This doesn't mean that the peer's application has already read these bytes.
+	WriteAcked() uint64
+	// OnWriteAcked registers a callback that is called with the new value of WriteAcked every time it increases.
+	// It is called from the session's run loop, and must not block.
+	// Calling OnWriteAcked again replaces the callback.
+	OnWriteAcked(callback func(acked uint64))
 	// SetDeadline sets the read and write deadlines associated
 	// with the connection. It is equivalent to calling both
 	// SetReadDeadline and SetWriteDeadline.
@@ -85,10 +142,14 @@ type ReceiveStream interface {
 	StreamID() StreamID
 	// see Stream.Read
 	io.Reader
+	// see Stream.ReadChunk
+	ReadChunk() ([]byte, error)
 	// see Stream.CancelRead
 	CancelRead(ErrorCode)
 	// see Stream.SetReadDealine
 	SetReadDeadline(t time.Time) error
+	// see Stream.SetReceiveWindow
+	SetReceiveWindow(size uint64)
 }
 
 // A SendStream is a unidirectional Send Stream.
@@ -105,6 +166,20 @@ type SendStream interface {
 	Context() context.Context
 	// see Stream.SetWriteDeadline
 	SetWriteDeadline(t time.Time) error
+	// see Stream.SetGroup
+	SetGroup(group string)
+	// see Stream.WriteAcked
+	WriteAcked() uint64
+	// see Stream.OnWriteAcked
+	OnWriteAcked(callback func(acked uint64))
+}
+
+// A CompressibleStream is a Stream or a SendStream of a session that uses the experimental stream compression extension
+// (see Config.StreamCompression).
+type CompressibleStream interface {
+	// SetCompression selects if the data written to the stream is compressed. By default, it is.
+	// The choice is sent to the peer with the first data written, so it can't be changed after the first Write or Close.
+	SetCompression(enabled bool) error
 }
 
 // StreamError is returned by Read and Write when the peer cancels the stream.
@@ -120,6 +195,15 @@ type Session interface {
 	AcceptStream() (Stream, error)
 	// AcceptUniStream returns the next unidirectional stream opened by the peer, blocking until one is available.
 	AcceptUniStream() (ReceiveStream, error)
+	// OnStream registers a handler that is called for every bidirectional stream opened by the peer.
+	// Every call runs on its own go routine. At most Config.MaxConcurrentStreamHandlers handlers run concurrently,
+	// new streams are only accepted when a running handler returns.
+	// The handler is responsible for closing the stream.
+	// If the handler panics, the panic is recovered, and the session is closed with an InternalError.
+	// When the session is closed, no more streams are accepted. Handlers that are still running are not interrupted,
+	// but all operations on their streams return errors.
+	// OnStream can only be called once, and AcceptStream must not be used after calling it.
+	OnStream(handler func(Stream)) error
 	// OpenStream opens a new bidirectional QUIC stream.
 	// There is no signaling to the peer about new streams:
 	// The peer can only accept the stream after data has been sent on the stream.
@@ -144,15 +228,62 @@ type Session interface {
 	RemoteAddr() net.Addr
 	// Close the connection.
 	io.Closer
+	// SetStreamGroupWeight sets the weight of a group of streams (see Stream.SetGroup).
+	// The bandwidth available for sending stream data is shared between the groups that have data to send,
+	// proportionally to their weights. Groups that don't have a weight set have a weight of 1.
+	// Stream groups only take effect once a weight was set for at least one group.
+	// The weight must be at least 1.
+	SetStreamGroupWeight(group string, weight int)
+	// CloseSync closes the connection, and blocks until the closing period has ended.
+	// During the closing period (3 PTOs), the CONNECTION_CLOSE is retransmitted when packets from the peer are received.
+	// This should be used by applications that exit right after closing the connection.
+	// It returns early with the context's error if the context is canceled.
+	// If the connection was already closed, it doesn't wait for the closing period.
+	CloseSync(ctx context.Context) error
 	// Close the connection with an error.
 	// The error must not be nil.
 	CloseWithError(ErrorCode, error) error
+	// RetireKeys closes the connection, and retires its keys and connection ID immediately.
+	// This QUIC version supports neither key updates nor switching to a new connection ID,
+	// so keys and connection IDs can only be retired by closing the connection.
+	// Other than Close, the connection ID is forgotten right away: packets sent by the peer
+	// are not answered with a retransmission of the CONNECTION_CLOSE.
+	// It returns when the key material has been erased.
+	// The traffic secrets, and the keys and IVs derived from them, are overwritten with zeros.
+	// Ciphers implemented by the standard library (like AES-GCM) keep a copy of the key
+	// that can't be erased, it is released to the garbage collector.
+	// The Initial keys are not erased, since they are derived from the connection ID, and are not secret.
+	RetireKeys() error
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
 	// ConnectionState returns basic details about the QUIC connection.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// PathHealth returns the health of the network path, as derived from write errors and lost packets.
+	PathHealth() PathHealth
+	// FlowControlState returns the flow control state of the connection, followed by the state of all open streams.
+	FlowControlState() []FlowControlState
+	// FlowControlDeadlocks returns the streams (and the connection) that have been stalled by flow control
+	// for at least the threshold, together with the likely cause.
+	// It is meant for diagnosing stalled transfers, e.g. when a window update was never sent.
+	FlowControlDeadlocks(threshold time.Duration) []FlowControlDeadlock
+	// PeerTransportParameters returns the transport parameters that the peer sent during the handshake.
+	// It returns nil if the peer's transport parameters were not received yet.
+	PeerTransportParameters() *TransportParameters
+	// SetReadDeadline sets the deadline for future and currently-blocked Read calls on all streams of the session,
+	// including streams that are opened or accepted later.
+	// It applies in addition to the deadlines set on the streams: Read times out when the earlier deadline is reached.
+	// A zero value for t means that Read calls are only bounded by the deadlines set on the streams.
+	SetReadDeadline(t time.Time) error
+	// SetWriteDeadline sets the deadline for future and currently-blocked Write calls on all streams of the session,
+	// including streams that are opened or accepted later.
+	// It applies in addition to the deadlines set on the streams: Write times out when the earlier deadline is reached.
+	// A zero value for t means that Write calls are only bounded by the deadlines set on the streams.
+	SetWriteDeadline(t time.Time) error
+	// SetDeadline sets the read and write deadlines for all streams of the session.
+	// It is equivalent to calling both SetReadDeadline and SetWriteDeadline.
+	SetDeadline(t time.Time) error
 }
 
 // Config contains all configuration data needed for a QUIC server or client.
@@ -174,20 +305,102 @@ type Config struct {
 	HandshakeTimeout time.Duration
 	// IdleTimeout is the maximum duration that may pass without any incoming network activity.
 	// This value only applies after the handshake has completed.
+	// It is advertised to the peer in the transport parameters. If the peer advertises a shorter timeout,
+	// the peer's timeout is used. The timeout used is available in the ConnectionState.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 30 seconds.
 	IdleTimeout time.Duration
 	// AcceptCookie determines if a Cookie is accepted.
 	// It is called with cookie = nil if the client didn't send an Cookie.
-	// If not set, it verifies that the address matches, and that the Cookie was issued within the last 24 hours.
+	// Cookies that were issued more than CookieLifetime ago are treated as if the client didn't send a Cookie.
+	// If not set, it verifies that the address matches.
 	// This option is only valid for the server.
 	AcceptCookie func(clientAddr net.Addr, cookie *Cookie) bool
+	// CookieLifetime is the maximum age of a Cookie.
+	// If this value is zero, Cookies are valid for 24 hours.
+	// This option is only valid for the server.
+	CookieLifetime time.Duration
+	// CookieKeys returns the keys used to protect Cookies.
+	// New Cookies are protected using the first key, Cookies protected with any of the keys are accepted.
+	// Keys should be 32 random bytes.
+	// Returning the same keys on multiple servers allows clients to use a Cookie issued by one server on the other servers,
+	// for example when the servers are behind a load balancer, or when a server is restarted.
+	// To rotate the keys, add a new key at the first position, and keep the old key for (at least) CookieLifetime.
+	// When rotating keys on multiple servers, first add the new key as a secondary key on all servers, then make it the primary key.
+	// It is called for every Cookie that is issued or decoded, and must be safe for concurrent use.
+	// If not set, a random key is generated when the server is created.
+	// This option is only valid for the server.
+	CookieKeys func() [][]byte
+	// OffloadInitials disables the handling of Initial packets received on the PacketConn.
+	// If set, new connections are only accepted when the Initial packet is passed to Listener.HandOver.
+	// This option is only valid for the server.
+	OffloadInitials bool
+	// ReceiveBufferSize is the size of the receive buffer that is requested for UDP sockets created by quic-go (by ListenAddr and DialAddr).
+	// The OS might grant a smaller buffer, see GetUDPSocketStats.
+	// If this value is zero, it will default to 2 MB.
+	// If this value is negative, the receive buffer size is not changed.
+	ReceiveBufferSize int
+	// SendBufferSize is the size of the send buffer that is requested for UDP sockets created by quic-go (by ListenAddr and DialAddr).
+	// If this value is zero, it will default to 2 MB.
+	// If this value is negative, the send buffer size is not changed.
+	SendBufferSize int
+	// AcceptClientHello determines if a connection is accepted, based on the ClientHello sent by the client.
+	// It is called as soon as the ClientHello was received, before the server performs any expensive cryptographic operation.
+	// If it returns false, the handshake is aborted, and the connection is closed with a HandshakeFailed error.
+	// If not set, all ClientHellos are accepted.
+	// This option is only valid for the server.
+	AcceptClientHello func(clientAddr net.Addr, hello *ClientHelloInfo) bool
+	// AcceptVersion determines if a client is allowed to use a QUIC version.
+	// It is only called for versions contained in Versions. Versions that are not accepted are treated
+	// as if the server didn't support them: they are not included in the Version Negotiation packet
+	// and in the transport parameters sent to this client, but they are still advertised to other clients.
+	// This allows accepting a new version for a fraction of the clients only.
+	// Since the version negotiation is validated during the handshake, it must return the same result
+	// for the same client address.
+	// The version used by a session is available in the ConnectionState.
+	// If not set, all versions are accepted.
+	// This option is only valid for the server.
+	AcceptVersion func(clientAddr net.Addr, version VersionNumber) bool
+	// AcceptSession determines if a session is accepted.
+	// It is called after the handshake completed, before the session is returned by Listener.Accept.
+	// If it returns a non-nil error, the session is closed using the error code and the error (see Session.CloseWithError),
+	// and it is not returned by Listener.Accept.
+	// It is called on a separate go routine for every session, and may block (e.g. to perform authentication).
+	// If not set, all sessions are accepted.
+	// This option is only valid for the server.
+	AcceptSession func(sess Session) (ErrorCode, error)
+	// PathHealthChanged is called when the health of the network path of a session changes.
+	// If the path is broken due to persistent write errors, it is called before the session is closed.
+	// The reason is the error that caused the change, or nil if the path is healthy again.
+	// It is called from the session's run loop, and must not block.
+	PathHealthChanged func(sess Session, health PathHealth, reason error)
 	// MaxReceiveStreamFlowControlWindow is the maximum stream-level flow control window for receiving data.
 	// If this value is zero, it will default to 1 MB for the server and 6 MB for the client.
 	MaxReceiveStreamFlowControlWindow uint64
 	// MaxReceiveConnectionFlowControlWindow is the connection-level flow control window for receiving data.
 	// If this value is zero, it will default to 1.5 MB for the server and 15 MB for the client.
 	MaxReceiveConnectionFlowControlWindow uint64
+	// MaxReceiveBufferMemory is the maximum number of bytes of received data that a server buffers across all sessions,
+	// i.e. data that was received, but not yet read by the application.
+	// When this budget is nearly exhausted, new connection attempts are rejected, and the flow control windows of sessions
+	// that didn't receive any data for a while are shrunk when the peer resumes sending. Active sessions keep their windows.
+	// This value only applies to the server. If this value is zero, the memory used is not limited.
+	MaxReceiveBufferMemory uint64
+	// MaxReadAhead is the maximum number of bytes of received data that a session buffers,
+	// i.e. data that was received on any stream (including streams that were not accepted yet), but not yet read by the application.
+	// When this limit is reached, the connection-level flow control window is not increased until the application reads data.
+	// Since the peer might already have flow control credit, more data than this limit might be buffered.
+	// Note that streams that are not read from can block the whole session, so the application has to either read or cancel every stream.
+	// If this value is zero, the data buffered is only limited by the flow control windows.
+	MaxReadAhead uint64
+	// MaxConcurrentHandshakes is the maximum number of handshakes that a server processes in parallel.
+	// This bounds the CPU time spent on signing certificates and deriving keys during a burst of new connections,
+	// such that packets for established sessions can still be processed without additional delay.
+	// A session whose handshake exceeds this limit waits until a previous ClientHello has been processed,
+	// and doesn't process any other packets in the meantime.
+	// A value of half the value of GOMAXPROCS is a good starting point.
+	// This value only applies to the server. If this value is zero (or negative), the number of concurrent handshakes is not limited.
+	MaxConcurrentHandshakes int
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any bidirectional streams.
@@ -196,8 +409,134 @@ type Config struct {
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	MaxIncomingUniStreams int
+	// MaxConcurrentStreamHandlers is the maximum number of handlers registered with Session.OnStream that run concurrently.
+	// If not set, it defaults to MaxIncomingStreams.
+	MaxConcurrentStreamHandlers int
+	// MaxAckRanges is the maximum number of ACK ranges that are tracked for received packets.
+	// When packets are heavily reordered, and more ranges would be needed, the ranges with the lowest packet numbers are dropped.
+	// Packets in those ranges won't be acknowledged anymore.
+	// If not set, it will default to 500.
+	MaxAckRanges int
 	// KeepAlive defines whether this peer will periodically send PING frames to keep the connection alive.
 	KeepAlive bool
+	// HyStartPlusPlus enables HyStart++ for the slow start phase of the congestion controller.
+	// Instead of exiting slow start as soon as an increase of the RTT is detected, it first grows the
+	// congestion window more conservatively, avoiding large loss episodes caused by overshooting.
+	HyStartPlusPlus bool
+	// QuicTracer is used to record quic-trace events of all connections.
+	// The traces can be exported as protobufs using the tracer's GetAllTraces method.
+	// If not set, no events are recorded.
+	QuicTracer quictrace.Tracer
+	// PacketConnDialer is used by DialAddr to obtain the net.PacketConn for a new connection.
+	// If not set, a new UDP socket is used.
+	// This option is only valid for the client.
+	PacketConnDialer PacketConnDialer
+	// LocalAddr is the local address that DialAddr binds the UDP socket to.
+	// This can be used to select a fixed source port, or a source address on a multi-homed host.
+	// If not set, the socket is bound to an unspecified address and a random port.
+	// It is not used if a PacketConnDialer is set.
+	// This option is only valid for the client.
+	LocalAddr *net.UDPAddr
+	// Control is called by DialAddr after creating the UDP socket, but before binding it.
+	// It has the same semantics as net.Dialer.Control, and can be used to set socket options like SO_BINDTODEVICE.
+	// It requires Go 1.11 or later.
+	// It is not used if a PacketConnDialer is set.
+	// This option is only valid for the client.
+	Control func(network, address string, c syscall.RawConn) error
+	// AutoRebind enables replacing the UDP socket when the local address used by the session becomes unavailable,
+	// for example when a VPN is turned on or off.
+	// This is detected by errors when sending packets, and by repeated PTOs.
+	// The session is migrated to the new socket, if the server permits migration.
+	// Servers implemented by this package don't permit migration, so this only works with other server implementations.
+	// It is only used for sessions established with DialAddr, and not if a PacketConnDialer is set.
+	// This option is only valid for the client.
+	AutoRebind bool
+	// Clock is the source of time used for timers, RTT measurements, loss detection, timeouts and the expiry of Cookies.
+	// It can be replaced to control time in tests and simulations.
+	// All sessions using the same net.PacketConn must use the same Clock.
+	// If not set, the system clock is used.
+	Clock Clock
+	// DecryptionWorkers is the number of go routines used to decrypt 1-RTT packets of a session.
+	// Decrypting packets on a single core limits the throughput of a single session,
+	// so this can be increased for sessions that transfer a lot of data.
+	// Packets are still processed in the order they were received.
+	// If 0 or 1, packets are decrypted by the go routine that processes them.
+	DecryptionWorkers int
+	// FECGroupSize enables the experimental forward error correction extension, if the peer supports it.
+	// After every FECGroupSize 1-RTT packets, a packet with repair data is sent.
+	// This allows the peer to recover one lost packet out of every group without waiting for a retransmission,
+	// at the cost of sending one additional packet per group. The maximum value is 32.
+	// If 0, no repair data is sent.
+	FECGroupSize int
+	// StreamCompression enables the experimental stream compression extension, if the peer supports it.
+	// Data sent on streams is then compressed using DEFLATE, transparently to the application.
+	// Compression can be disabled for individual streams, e.g. for data that is already compressed (see CompressibleStream).
+	// This only works with peers that also use quic-go, and is worthwhile for streams carrying repetitive data (e.g. JSON).
+	// Every Write is flushed, so that the peer can read it right away. Later Writes refer to the data of earlier ones.
+	// A stream holds a DEFLATE writer (using several hundred kB of memory) from the first Write until it is closed or canceled.
+	// If a Read or a Write on a compressed stream fails (e.g. because a deadline expired), the stream can't be used any more.
+	// Stream.WriteAcked counts the compressed bytes, as they are sent on the wire.
+	StreamCompression bool
+	// StreamCompressionDictionary is a preset dictionary used for compressing streams.
+	// Small messages compress a lot better if the dictionary contains data they have in common (e.g. field names).
+	// Compression is only used if the peer uses the same dictionary.
+	StreamCompressionDictionary []byte
+	// PacketPadding pads datagrams to hide the size of the data they carry.
+	// Every datagram (including the Initial and Handshake packets of the first flight) is padded
+	// such that its size is a multiple of PacketPadding bytes, up to the maximum packet size.
+	// A value of at least 1500 pads all datagrams to the maximum packet size.
+	// Version Negotiation and Retry packets are not padded.
+	// If 0, datagrams are only padded where the protocol requires it.
+	PacketPadding int
+	// CoverTrafficInterval enables sending cover packets after the handshake completes.
+	// If no packet was sent for CoverTrafficInterval, a packet containing only PADDING is sent,
+	// so that an observer can't tell when a connection is idle.
+	// Cover packets have the maximum packet size. They are not acknowledged and not retransmitted,
+	// but they keep the peer's idle timeout from expiring.
+	// If 0, no cover packets are sent.
+	CoverTrafficInterval time.Duration
+	// KernelPacing lets the kernel enforce the pacing of packets, using the SO_TXTIME socket option.
+	// Packets that are due within a short horizon are passed to the kernel immediately,
+	// together with the time at which they should be transmitted.
+	// Timestamps use CLOCK_MONOTONIC, as required by the fq qdisc.
+	// This is only supported on Linux, for sessions using a *net.UDPConn.
+	// If not supported, packets are paced by quic-go.
+	KernelPacing bool
+	// ParkIdleSessions reduces the resources used by idle sessions, for servers handling a large number of mostly idle connections.
+	// A session is parked when it has nothing to send, and none of its timers expires within the next second.
+	// A parked session doesn't occupy a go routine, and instead of a timer per session,
+	// a single timer wheel serves all parked sessions of the server. Its resolution is 50ms.
+	// The session is resumed when a packet is received, when the application uses it, or when its timer expires.
+	// This option is only valid for the server.
+	ParkIdleSessions bool
+}
+
+// A Clock is a source of time.
+type Clock = utils.Clock
+
+// A ClockTimer is a timer created by a Clock.
+type ClockTimer = utils.ClockTimer
+
+// A PacketConnDialer creates the net.PacketConn used by DialAddr.
+// It can be used to send QUIC packets over a custom transport, e.g. through a proxy.
+type PacketConnDialer interface {
+	// DialPacketConn returns a net.PacketConn and the address of the server for addr.
+	// The net.PacketConn is owned by the QUIC session, and is closed when the session is closed.
+	DialPacketConn(ctx context.Context, addr string) (net.PacketConn, net.Addr, error)
+}
+
+// A Substrate is a datagram-based transport that QUIC can be run over instead of UDP,
+// for example an in-process pipe, a tunnel or an overlay network.
+// It can be passed to Listen and Dial like any other net.PacketConn.
+// Addresses are defined by the Substrate. quic-go only compares them (using their String method),
+// so they don't need to be IP addresses.
+// The deadline methods of the net.PacketConn are not used by quic-go.
+type Substrate interface {
+	net.PacketConn
+	// MaxDatagramSize returns the maximum size of a datagram that can be sent to addr.
+	// QUIC requires datagrams of at least 1200 bytes.
+	// quic-go never sends datagrams larger than 1452 bytes.
+	MaxDatagramSize(addr net.Addr) int
 }
 
 // A Listener for incoming QUIC connections
@@ -208,4 +547,45 @@ type Listener interface {
 	Addr() net.Addr
 	// Accept returns new sessions. It should be called in a loop.
 	Accept() (Session, error)
+	// Stats returns statistics about the sessions handled by the Listener.
+	Stats() ListenerStats
+	// HandOver passes an Initial packet that was received and validated by an external component to the Listener.
+	// This allows offloading address validation (and sending of Retry packets) to a frontend process,
+	// or to a packet filter running in front of the Listener.
+	// The packet is accepted without checking the Cookie, and the session uses the Listener's PacketConn.
+	HandOver(*HandedOverInitial) error
+}
+
+// A HandedOverInitial is an Initial packet that was validated by an external component.
+type HandedOverInitial struct {
+	// Data is the Initial packet, as received from the client.
+	// It is copied, and can be reused as soon as HandOver returns.
+	Data []byte
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+	// OriginalDestConnectionID is the destination connection ID of the client's first Initial packet.
+	// It must be set if the external component performed a Retry,
+	// since the server needs to send it to the client in the transport parameters.
+	OriginalDestConnectionID []byte
+}
+
+// ListenerStats contains statistics about a Listener.
+type ListenerStats struct {
+	// ActiveSessions is the number of sessions that were not yet closed, including sessions that are still handshaking.
+	ActiveSessions int
+	// HandshakingSessions is the number of sessions for which the handshake hasn't completed yet.
+	HandshakingSessions int
+	// AcceptQueueLength is the number of sessions that completed the handshake, but were not yet returned by Accept.
+	AcceptQueueLength int
+	// BufferedBytes is the number of bytes held in flow-control buffers,
+	// i.e. data that was received on any of the sessions, but not yet read by the application.
+	BufferedBytes uint64
+	// RetriesSent is the number of Retry packets sent to clients that didn't present a valid Cookie.
+	RetriesSent uint64
+	// RejectedServerBusy is the number of connection attempts that were rejected because the accept queue was full.
+	RejectedServerBusy uint64
+	// RejectedByApplication is the number of sessions that were rejected by the AcceptSession callback.
+	RejectedByApplication uint64
+	// ParkedSessions is the number of sessions that are currently parked (see Config.ParkIdleSessions).
+	ParkedSessions int
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 // SentPacketHandler handles ACKs received for outgoing packets
@@ -38,6 +39,10 @@ type SentPacketHandler interface {
 
 	GetAlarmTimeout() time.Time
 	OnAlarm() error
+	// GetPTOCount returns the number of consecutive PTOs, i.e. PTOs since the last packet was acknowledged.
+	GetPTOCount() uint32
+
+	GetStats() *quictrace.TransportState
 }
 
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
@@ -41,16 +41,19 @@ type receivedPacketHandler struct {
 
 var _ ReceivedPacketHandler = &receivedPacketHandler{}
 
-// NewReceivedPacketHandler creates a new receivedPacketHandler
+// NewReceivedPacketHandler creates a new receivedPacketHandler.
+// For each packet number space, at most maxAckRanges ACK ranges are tracked.
 func NewReceivedPacketHandler(
 	rttStats *congestion.RTTStats,
+	maxAckRanges int,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		oneRTTPackets:    newReceivedPacketTracker(rttStats, logger, version),
+		initialPackets:   newReceivedPacketTracker(rttStats, maxAckRanges, clock, logger, version),
+		handshakePackets: newReceivedPacketTracker(rttStats, maxAckRanges, clock, logger, version),
+		oneRTTPackets:    newReceivedPacketTracker(rttStats, maxAckRanges, clock, logger, version),
 	}
 }
 
@@ -18,6 +18,8 @@ var _ = Describe("Received Packet Handler", func() {
 	BeforeEach(func() {
 		handler = NewReceivedPacketHandler(
 			&congestion.RTTStats{},
+			protocol.DefaultMaxTrackedReceivedAckRanges,
+			utils.DefaultClock,
 			utils.DefaultLogger,
 			protocol.VersionWhatever,
 		)
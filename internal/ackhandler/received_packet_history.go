@@ -2,7 +2,6 @@ package ackhandler
 
 import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
-	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -10,36 +9,48 @@ import (
 // The receivedPacketHistory stores if a packet number has already been received.
 // It generates ACK ranges which can be used to assemble an ACK frame.
 // It does not store packet contents.
+// The number of ranges is limited to maxRanges. When a new range is created,
+// the range with the lowest packet numbers is dropped, and packets below that range are not tracked any more.
+// This bounds the memory and CPU usage, no matter how much packets are reordered.
 type receivedPacketHistory struct {
-	ranges *utils.PacketIntervalList
+	ranges    *utils.PacketIntervalList
+	maxRanges int
 
 	lowestInReceivedPacketNumbers protocol.PacketNumber
 }
 
-var errTooManyOutstandingReceivedAckRanges = qerr.Error(qerr.TooManyOutstandingReceivedPackets, "Too many outstanding received ACK ranges")
-
 // newReceivedPacketHistory creates a new received packet history
-func newReceivedPacketHistory() *receivedPacketHistory {
+func newReceivedPacketHistory(maxRanges int) *receivedPacketHistory {
 	return &receivedPacketHistory{
-		ranges: utils.NewPacketIntervalList(),
+		ranges:    utils.NewPacketIntervalList(),
+		maxRanges: maxRanges,
 	}
 }
 
 // ReceivedPacket registers a packet with PacketNumber p and updates the ranges
-func (h *receivedPacketHistory) ReceivedPacket(p protocol.PacketNumber) error {
-	if h.ranges.Len() >= protocol.MaxTrackedReceivedAckRanges {
-		return errTooManyOutstandingReceivedAckRanges
+func (h *receivedPacketHistory) ReceivedPacket(p protocol.PacketNumber) {
+	if p < h.lowestInReceivedPacketNumbers {
+		return
 	}
-
 	if h.ranges.Len() == 0 {
 		h.ranges.PushBack(utils.PacketInterval{Start: p, End: p})
-		return nil
+		return
+	}
+	// This packet would create a new lowest range, which would be dropped right away.
+	if h.ranges.Len() >= h.maxRanges && p+1 < h.ranges.Front().Value.Start {
+		return
+	}
+	h.addToRanges(p)
+	if h.ranges.Len() > h.maxRanges {
+		h.pruneLowestRange()
 	}
+}
 
+func (h *receivedPacketHistory) addToRanges(p protocol.PacketNumber) {
 	for el := h.ranges.Back(); el != nil; el = el.Prev() {
 		// p already included in an existing range. Nothing to do here
 		if p >= el.Value.Start && p <= el.Value.End {
-			return nil
+			return
 		}
 
 		var rangeExtended bool
@@ -57,22 +68,28 @@ func (h *receivedPacketHistory) ReceivedPacket(p protocol.PacketNumber) error {
 			if prev != nil && prev.Value.End+1 == el.Value.Start { // merge two ranges
 				prev.Value.End = el.Value.End
 				h.ranges.Remove(el)
-				return nil
+				return
 			}
-			return nil // if the two ranges were not merge, we're done here
+			return // if the two ranges were not merge, we're done here
 		}
 
 		// create a new range at the end
 		if p > el.Value.End {
 			h.ranges.InsertAfter(utils.PacketInterval{Start: p, End: p}, el)
-			return nil
+			return
 		}
 	}
 
 	// create a new range at the beginning
 	h.ranges.InsertBefore(utils.PacketInterval{Start: p, End: p}, h.ranges.Front())
+}
 
-	return nil
+// pruneLowestRange drops the range with the lowest packet numbers.
+// Packets below the next range won't be tracked from now on.
+func (h *receivedPacketHistory) pruneLowestRange() {
+	front := h.ranges.Front()
+	h.ranges.Remove(front)
+	h.lowestInReceivedPacketNumbers = h.ranges.Front().Value.Start
 }
 
 // DeleteBelow deletes all entries below (but not including) p
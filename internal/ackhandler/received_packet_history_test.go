@@ -14,7 +14,7 @@ var _ = Describe("receivedPacketHistory", func() {
 	)
 
 	BeforeEach(func() {
-		hist = newReceivedPacketHistory()
+		hist = newReceivedPacketHistory(protocol.DefaultMaxTrackedReceivedAckRanges)
 	})
 
 	Context("ranges", func() {
@@ -175,25 +175,59 @@ var _ = Describe("receivedPacketHistory", func() {
 		})
 
 		Context("DoS protection", func() {
-			It("doesn't create more than MaxTrackedReceivedAckRanges ranges", func() {
-				for i := protocol.PacketNumber(1); i <= protocol.MaxTrackedReceivedAckRanges; i++ {
-					err := hist.ReceivedPacket(2 * i)
-					Expect(err).ToNot(HaveOccurred())
+			BeforeEach(func() {
+				hist = newReceivedPacketHistory(10)
+			})
+
+			It("drops the lowest range when creating more than maxRanges ranges", func() {
+				for i := protocol.PacketNumber(1); i <= 10; i++ {
+					hist.ReceivedPacket(2 * i)
+				}
+				Expect(hist.ranges.Len()).To(Equal(10))
+				hist.ReceivedPacket(22)
+				Expect(hist.ranges.Len()).To(Equal(10))
+				Expect(hist.ranges.Front().Value).To(Equal(utils.PacketInterval{Start: 4, End: 4}))
+				Expect(hist.ranges.Back().Value).To(Equal(utils.PacketInterval{Start: 22, End: 22}))
+			})
+
+			It("doesn't track packets below a dropped range", func() {
+				for i := protocol.PacketNumber(1); i <= 11; i++ {
+					hist.ReceivedPacket(2 * i)
+				}
+				Expect(hist.ranges.Front().Value).To(Equal(utils.PacketInterval{Start: 4, End: 4}))
+				hist.ReceivedPacket(1)
+				hist.ReceivedPacket(3)
+				Expect(hist.ranges.Len()).To(Equal(10))
+				Expect(hist.ranges.Front().Value).To(Equal(utils.PacketInterval{Start: 4, End: 4}))
+			})
+
+			It("drops a new range, if it is the lowest range", func() {
+				for i := protocol.PacketNumber(10); i < 20; i++ {
+					hist.ReceivedPacket(2 * i)
+				}
+				hist.ReceivedPacket(2)
+				Expect(hist.ranges.Len()).To(Equal(10))
+				Expect(hist.ranges.Front().Value).To(Equal(utils.PacketInterval{Start: 20, End: 20}))
+			})
+
+			It("doesn't consider already deleted ranges for maxRanges", func() {
+				for i := protocol.PacketNumber(1); i <= 10; i++ {
+					hist.ReceivedPacket(2 * i)
 				}
-				err := hist.ReceivedPacket(2*protocol.MaxTrackedReceivedAckRanges + 2)
-				Expect(err).To(MatchError(errTooManyOutstandingReceivedAckRanges))
+				hist.DeleteBelow(10) // deletes 4 ranges
+				hist.ReceivedPacket(22)
+				Expect(hist.ranges.Len()).To(Equal(7))
+				Expect(hist.ranges.Front().Value).To(Equal(utils.PacketInterval{Start: 10, End: 10}))
 			})
 
-			It("doesn't consider already deleted ranges for MaxTrackedReceivedAckRanges", func() {
-				for i := protocol.PacketNumber(1); i <= protocol.MaxTrackedReceivedAckRanges; i++ {
-					err := hist.ReceivedPacket(2 * i)
-					Expect(err).ToNot(HaveOccurred())
+			It("handles heavy reordering in linear time", func() {
+				hist = newReceivedPacketHistory(protocol.DefaultMaxTrackedReceivedAckRanges)
+				// receive packets in descending order, skipping every other packet
+				const num = 100000
+				for i := protocol.PacketNumber(num); i > 0; i-- {
+					hist.ReceivedPacket(2 * i)
 				}
-				err := hist.ReceivedPacket(2*protocol.MaxTrackedReceivedAckRanges + 2)
-				Expect(err).To(MatchError(errTooManyOutstandingReceivedAckRanges))
-				hist.DeleteBelow(protocol.MaxTrackedReceivedAckRanges) // deletes about half of the ranges
-				err = hist.ReceivedPacket(2*protocol.MaxTrackedReceivedAckRanges + 4)
-				Expect(err).ToNot(HaveOccurred())
+				Expect(hist.ranges.Len()).To(Equal(protocol.DefaultMaxTrackedReceivedAckRanges))
 			})
 		})
 	})
@@ -25,6 +25,7 @@ type receivedPacketTracker struct {
 	ackAlarm                                   time.Time
 	lastAck                                    *wire.AckFrame
 
+	clock  utils.Clock
 	logger utils.Logger
 
 	version protocol.VersionNumber
@@ -32,13 +33,16 @@ type receivedPacketTracker struct {
 
 func newReceivedPacketTracker(
 	rttStats *congestion.RTTStats,
+	maxAckRanges int,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
+		packetHistory: newReceivedPacketHistory(maxAckRanges),
 		ackSendDelay:  ackSendDelay,
 		rttStats:      rttStats,
+		clock:         clock,
 		logger:        logger,
 		version:       version,
 	}
@@ -55,9 +59,7 @@ func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumbe
 		h.largestObservedReceivedTime = rcvTime
 	}
 
-	if err := h.packetHistory.ReceivedPacket(packetNumber); err != nil {
-		return err
-	}
+	h.packetHistory.ReceivedPacket(packetNumber)
 	h.maybeQueueAck(packetNumber, rcvTime, shouldInstigateAck, isMissing)
 	return nil
 }
@@ -129,7 +131,7 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 				ackDelay := utils.MinDuration(ackSendDelay, time.Duration(float64(h.rttStats.MinRTT())*float64(ackDecimationDelay)))
 				h.ackAlarm = rcvTime.Add(ackDelay)
 				if h.logger.Debug() {
-					h.logger.Debugf("\tSetting ACK timer to min(1/4 min-RTT, max ack delay): %s (%s from now)", ackDelay, time.Until(h.ackAlarm))
+					h.logger.Debugf("\tSetting ACK timer to min(1/4 min-RTT, max ack delay): %s (%s from now)", ackDelay, h.ackAlarm.Sub(h.clock.Now()))
 				}
 			}
 		} else {
@@ -154,7 +156,7 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 			if h.ackAlarm.IsZero() || h.ackAlarm.After(ackTime) {
 				h.ackAlarm = ackTime
 				if h.logger.Debug() {
-					h.logger.Debugf("\tSetting ACK timer to 1/8 min-RTT: %s (%s from now)", ackDelay, time.Until(h.ackAlarm))
+					h.logger.Debugf("\tSetting ACK timer to 1/8 min-RTT: %s (%s from now)", ackDelay, h.ackAlarm.Sub(h.clock.Now()))
 				}
 			}
 		}
@@ -167,7 +169,7 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 }
 
 func (h *receivedPacketTracker) GetAckFrame() *wire.AckFrame {
-	now := time.Now()
+	now := h.clock.Now()
 	if !h.ackQueued && (h.ackAlarm.IsZero() || h.ackAlarm.After(now)) {
 		return nil
 	}
@@ -16,7 +16,7 @@ func stripNonRetransmittableFrames(fs []wire.Frame) []wire.Frame {
 // IsFrameRetransmittable returns true if the frame should be retransmitted.
 func IsFrameRetransmittable(f wire.Frame) bool {
 	switch f.(type) {
-	case *wire.AckFrame:
+	case *wire.AckFrame, *wire.FECRepairFrame, *wire.PaddingFrame:
 		return false
 	default:
 		return true
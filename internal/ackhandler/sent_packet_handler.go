@@ -11,6 +11,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 const (
@@ -19,6 +20,9 @@ const (
 	timeReorderingFraction = 1.0 / 8
 	// Timer granularity. The timer will not be set to a value smaller than granularity.
 	granularity = time.Millisecond
+	// If more than this number of consecutive PTOs fire before an ACK is received,
+	// the connection is considered to be in persistent congestion.
+	persistentCongestionThreshold = 2
 )
 
 type packetNumberSpace struct {
@@ -75,6 +79,11 @@ type sentPacketHandler struct {
 	// The alarm timeout
 	alarm time.Time
 
+	traceCallback func(quictrace.Event)
+	// called for every frame of an acknowledged packet
+	frameAckedCallback func(wire.Frame)
+
+	clock  utils.Clock
 	logger utils.Logger
 }
 
@@ -82,23 +91,31 @@ type sentPacketHandler struct {
 func NewSentPacketHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
+	useHyStartPlusPlus bool,
+	clock utils.Clock,
+	traceCallback func(quictrace.Event),
+	frameAckedCallback func(wire.Frame),
 	logger utils.Logger,
 ) SentPacketHandler {
 	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
+		clock,
 		rttStats,
 		false, /* don't use reno since chromium doesn't (why?) */
 		protocol.InitialCongestionWindow,
 		protocol.DefaultMaxCongestionWindow,
 	)
+	congestion.SetHyStartPlusPlus(useHyStartPlusPlus)
 
 	return &sentPacketHandler{
-		initialPackets:   newPacketNumberSpace(initialPacketNumber),
-		handshakePackets: newPacketNumberSpace(0),
-		oneRTTPackets:    newPacketNumberSpace(0),
-		rttStats:         rttStats,
-		congestion:       congestion,
-		logger:           logger,
+		initialPackets:     newPacketNumberSpace(initialPacketNumber),
+		handshakePackets:   newPacketNumberSpace(0),
+		oneRTTPackets:      newPacketNumberSpace(0),
+		rttStats:           rttStats,
+		congestion:         congestion,
+		traceCallback:      traceCallback,
+		frameAckedCallback: frameAckedCallback,
+		clock:              clock,
+		logger:             logger,
 	}
 }
 
@@ -194,6 +211,9 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* isRetransmitt
 }
 
 func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumber protocol.PacketNumber, encLevel protocol.EncryptionLevel, rcvTime time.Time) error {
+	if h.traceCallback != nil {
+		defer h.traceCongestionStateChanges(rcvTime, h.getCongestionState())
+	}
 	pnSpace := h.getPacketNumberSpace(encLevel)
 
 	largestAcked := ackFrame.LargestAcked()
@@ -244,6 +264,17 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 		return err
 	}
 
+	if h.ptoCount > persistentCongestionThreshold {
+		h.logger.Debugf("\tpersistent congestion detected (PTO count: %d)", h.ptoCount)
+		if h.traceCallback != nil {
+			h.traceCallback(quictrace.Event{
+				Time:                  rcvTime,
+				EventType:             quictrace.CongestionStateUpdated,
+				CongestionStateChange: quictrace.PersistentCongestion,
+				TransportState:        h.GetStats(),
+			})
+		}
+	}
 	h.ptoCount = 0
 	h.cryptoCount = 0
 
@@ -374,6 +405,17 @@ func (h *sentPacketHandler) detectLostPackets(
 			h.bytesInFlight -= p.Length
 			h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 		}
+		if h.traceCallback != nil {
+			h.traceCallback(quictrace.Event{
+				Time:            now,
+				EventType:       quictrace.PacketLost,
+				EncryptionLevel: p.EncryptionLevel,
+				PacketNumber:    p.PacketNumber,
+				PacketSize:      p.Length,
+				Frames:          p.Frames,
+				TransportState:  h.GetStats(),
+			})
+		}
 		if p.canBeRetransmitted {
 			// queue the packet for retransmission, and report the loss to the congestion controller
 			if err := h.queuePacketForRetransmission(p, pnSpace); err != nil {
@@ -412,7 +454,11 @@ func (h *sentPacketHandler) onVerifiedAlarm() error {
 			h.logger.Debugf("Loss detection alarm fired in loss timer mode. Loss time: %s", h.lossTime)
 		}
 		// Early retransmit or time loss detection
-		err = h.detectLostPackets(time.Now(), protocol.Encryption1RTT, h.bytesInFlight)
+		now := h.clock.Now()
+		if h.traceCallback != nil {
+			defer h.traceCongestionStateChanges(now, h.getCongestionState())
+		}
+		err = h.detectLostPackets(now, protocol.Encryption1RTT, h.bytesInFlight)
 	} else { // PTO
 		if h.logger.Debug() {
 			h.logger.Debugf("Loss detection alarm fired in PTO mode. PTO count: %d", h.ptoCount)
@@ -427,6 +473,10 @@ func (h *sentPacketHandler) GetAlarmTimeout() time.Time {
 	return h.alarm
 }
 
+func (h *sentPacketHandler) GetPTOCount() uint32 {
+	return h.ptoCount
+}
+
 func (h *sentPacketHandler) onPacketAcked(p *Packet, rcvTime time.Time) error {
 	pnSpace := h.getPacketNumberSpace(p.EncryptionLevel)
 	// This happens if a packet and its retransmissions is acked in the same ACK.
@@ -435,6 +485,9 @@ func (h *sentPacketHandler) onPacketAcked(p *Packet, rcvTime time.Time) error {
 	if packet := pnSpace.history.GetPacket(p.PacketNumber); packet == nil {
 		return nil
 	}
+	if h.frameAckedCallback != nil {
+		h.reportAckedFrames(p, pnSpace)
+	}
 
 	// only report the acking of this packet to the congestion controller if:
 	// * it is a retransmittable packet
@@ -466,6 +519,20 @@ func (h *sentPacketHandler) onPacketAcked(p *Packet, rcvTime time.Time) error {
 	return pnSpace.history.Remove(p.PacketNumber)
 }
 
+// reportAckedFrames reports the frames of an acknowledged packet.
+// If a packet is acknowledged after it was retransmitted, the frames of the retransmissions are reported as well.
+// This is necessary since STREAM frames are modified when they are split for a retransmission.
+func (h *sentPacketHandler) reportAckedFrames(p *Packet, pnSpace *packetNumberSpace) {
+	for _, f := range p.Frames {
+		h.frameAckedCallback(f)
+	}
+	for _, r := range p.retransmittedAs {
+		if packet := pnSpace.history.GetPacket(r); packet != nil {
+			h.reportAckedFrames(packet, pnSpace)
+		}
+	}
+}
+
 func (h *sentPacketHandler) stopRetransmissionsFor(p *Packet, pnSpace *packetNumberSpace) error {
 	if err := pnSpace.history.MarkCannotBeRetransmitted(p.PacketNumber); err != nil {
 		return err
@@ -626,6 +693,53 @@ func (h *sentPacketHandler) computePTOTimeout() time.Duration {
 	return duration << h.ptoCount
 }
 
+func (h *sentPacketHandler) GetStats() *quictrace.TransportState {
+	return &quictrace.TransportState{
+		MinRTT:           h.rttStats.MinRTT(),
+		SmoothedRTT:      h.rttStats.SmoothedOrInitialRTT(),
+		LatestRTT:        h.rttStats.LatestRTT(),
+		BytesInFlight:    h.bytesInFlight,
+		CongestionWindow: h.congestion.GetCongestionWindow(),
+		InSlowStart:      h.congestion.InSlowStart(),
+		InRecovery:       h.congestion.InRecovery(),
+	}
+}
+
+type congestionState struct {
+	inSlowStart bool
+	inRecovery  bool
+}
+
+func (h *sentPacketHandler) getCongestionState() congestionState {
+	return congestionState{
+		inSlowStart: h.congestion.InSlowStart(),
+		inRecovery:  h.congestion.InRecovery(),
+	}
+}
+
+// traceCongestionStateChanges traces the changes of the congestion state compared to a previous state
+func (h *sentPacketHandler) traceCongestionStateChanges(now time.Time, prev congestionState) {
+	cur := h.getCongestionState()
+	var changes []quictrace.CongestionStateChange
+	if prev.inSlowStart && !cur.inSlowStart {
+		changes = append(changes, quictrace.SlowStartExited)
+	}
+	if !prev.inRecovery && cur.inRecovery {
+		changes = append(changes, quictrace.RecoveryEntered)
+	}
+	if prev.inRecovery && !cur.inRecovery {
+		changes = append(changes, quictrace.RecoveryExited)
+	}
+	for _, c := range changes {
+		h.traceCallback(quictrace.Event{
+			Time:                  now,
+			EventType:             quictrace.CongestionStateUpdated,
+			CongestionStateChange: c,
+			TransportState:        h.GetStats(),
+		})
+	}
+}
+
 func (h *sentPacketHandler) ResetForRetry() error {
 	h.cryptoCount = 0
 	h.bytesInFlight = 0
@@ -9,6 +9,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -49,7 +50,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 	BeforeEach(func() {
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(42, rttStats, utils.DefaultLogger).(*sentPacketHandler)
+		handler = NewSentPacketHandler(42, rttStats, false, utils.DefaultClock, nil, nil, utils.DefaultLogger).(*sentPacketHandler)
 		handler.SetHandshakeComplete()
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
@@ -392,6 +393,46 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("reporting acknowledged frames", func() {
+		var ackedFrames []wire.Frame
+
+		BeforeEach(func() {
+			ackedFrames = nil
+			handler.frameAckedCallback = func(f wire.Frame) { ackedFrames = append(ackedFrames, f) }
+		})
+
+		It("reports the frames of acknowledged packets", func() {
+			f1 := &wire.StreamFrame{StreamID: 5, Data: []byte("foo")}
+			f2 := &wire.StreamFrame{StreamID: 5, Offset: 3, Data: []byte("bar")}
+			handler.SentPacket(&Packet{PacketNumber: 1, Frames: []wire.Frame{f1}, Length: 1, EncryptionLevel: protocol.Encryption1RTT, SendTime: time.Now()})
+			handler.SentPacket(&Packet{PacketNumber: 2, Frames: []wire.Frame{f2}, Length: 1, EncryptionLevel: protocol.Encryption1RTT, SendTime: time.Now()})
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(ackedFrames).To(Equal([]wire.Frame{f1}))
+			// frames are only reported when the packet is acknowledged for the first time
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(ackedFrames).To(Equal([]wire.Frame{f1, f2}))
+		})
+
+		It("reports the frames of the retransmissions, if the original packet is acknowledged", func() {
+			f := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+			handler.SentPacket(&Packet{PacketNumber: 5, Frames: []wire.Frame{f}, Length: 1, EncryptionLevel: protocol.Encryption1RTT, SendTime: time.Now()})
+			losePacket(5, protocol.Encryption1RTT)
+			// the frame was split when it was retransmitted
+			f1 := &wire.StreamFrame{StreamID: 5, Data: []byte("foo")}
+			f.Offset = 3
+			f.Data = []byte("bar")
+			handler.SentPacketsAsRetransmission([]*Packet{
+				{PacketNumber: 6, Frames: []wire.Frame{f1}, Length: 1, EncryptionLevel: protocol.Encryption1RTT, SendTime: time.Now()},
+				{PacketNumber: 7, Frames: []wire.Frame{f}, Length: 1, EncryptionLevel: protocol.Encryption1RTT, SendTime: time.Now()},
+			}, 5)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(ackedFrames).To(ConsistOf(f, f1, f))
+		})
+	})
+
 	It("does not dequeue a packet if no ACK has been received", func() {
 		handler.SentPacket(&Packet{PacketNumber: 1, EncryptionLevel: protocol.Encryption1RTT, SendTime: time.Now().Add(-time.Hour)})
 		Expect(handler.DequeuePacketForRetransmission()).To(BeNil())
@@ -486,6 +527,92 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		Context("tracing congestion state changes", func() {
+			var (
+				events                  []quictrace.Event
+				inSlowStart, inRecovery bool
+			)
+
+			BeforeEach(func() {
+				events = nil
+				handler.traceCallback = func(ev quictrace.Event) { events = append(events, ev) }
+				inSlowStart = true
+				inRecovery = false
+				cong.EXPECT().InSlowStart().DoAndReturn(func() bool { return inSlowStart }).AnyTimes()
+				cong.EXPECT().InRecovery().DoAndReturn(func() bool { return inRecovery }).AnyTimes()
+				cong.EXPECT().GetCongestionWindow().AnyTimes()
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				cong.EXPECT().TimeUntilSend(gomock.Any()).AnyTimes()
+				cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			})
+
+			It("traces when slow start is exited and recovery is entered", func() {
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 2}))
+				cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(protocol.PacketNumber, protocol.ByteCount, protocol.ByteCount) {
+					inSlowStart = false
+					inRecovery = true
+				})
+				now := time.Now()
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+				Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, now)).To(Succeed())
+				Expect(events).To(HaveLen(3))
+				Expect(events[0].EventType).To(Equal(quictrace.PacketLost))
+				Expect(events[1].EventType).To(Equal(quictrace.CongestionStateUpdated))
+				Expect(events[1].CongestionStateChange).To(Equal(quictrace.SlowStartExited))
+				Expect(events[1].Time).To(Equal(now))
+				Expect(events[1].TransportState).ToNot(BeNil())
+				Expect(events[2].EventType).To(Equal(quictrace.CongestionStateUpdated))
+				Expect(events[2].CongestionStateChange).To(Equal(quictrace.RecoveryEntered))
+			})
+
+			It("traces when recovery is exited", func() {
+				inSlowStart = false
+				inRecovery = true
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1}))
+				cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(protocol.PacketNumber, protocol.ByteCount, protocol.ByteCount, time.Time) {
+					inRecovery = false
+				})
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+				Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].CongestionStateChange).To(Equal(quictrace.RecoveryExited))
+			})
+
+			It("traces persistent congestion", func() {
+				inSlowStart = false
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1}))
+				handler.ptoCount = persistentCongestionThreshold + 1
+				// don't EXPECT a call to OnRetransmissionTimeout, the congestion window is not reduced
+				cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+				Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].EventType).To(Equal(quictrace.CongestionStateUpdated))
+				Expect(events[0].CongestionStateChange).To(Equal(quictrace.PersistentCongestion))
+				Expect(handler.ptoCount).To(BeZero())
+			})
+
+			It("doesn't trace persistent congestion if only few PTOs fired", func() {
+				inSlowStart = false
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1}))
+				handler.ptoCount = persistentCongestionThreshold
+				cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+				Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(events).To(BeEmpty())
+			})
+
+			It("doesn't trace anything if the state didn't change", func() {
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1}))
+				cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+				Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(events).To(BeEmpty())
+			})
+		})
+
 		It("only allows sending of ACKs when congestion limited", func() {
 			handler.bytesInFlight = 100
 			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(200))
@@ -654,6 +781,7 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(2)))
 
 			Expect(handler.ptoCount).To(BeEquivalentTo(3))
+			Expect(handler.GetPTOCount()).To(BeEquivalentTo(3))
 		})
 
 		It("doesn't delete packets transmitted as PTO from the history", func() {
@@ -746,6 +874,34 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.bytesInFlight).To(BeZero())
 		})
 
+		It("traces lost packets", func() {
+			// use a congestion controller that doesn't change its state when the packet is lost
+			cong := mocks.NewMockSendAlgorithm(mockCtrl)
+			cong.EXPECT().InSlowStart().AnyTimes()
+			cong.EXPECT().InRecovery().AnyTimes()
+			cong.EXPECT().GetCongestionWindow().AnyTimes()
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().TimeUntilSend(gomock.Any()).AnyTimes()
+			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			handler.congestion = cong
+			var events []quictrace.Event
+			handler.traceCallback = func(ev quictrace.Event) { events = append(events, ev) }
+			now := time.Now()
+			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 2, SendTime: now.Add(-time.Second)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, now)).To(Succeed())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].EventType).To(Equal(quictrace.PacketLost))
+			Expect(events[0].Time).To(Equal(now))
+			Expect(events[0].PacketNumber).To(Equal(protocol.PacketNumber(1)))
+			Expect(events[0].EncryptionLevel).To(Equal(protocol.Encryption1RTT))
+			Expect(events[0].TransportState).ToNot(BeNil())
+			Expect(events[0].TransportState.BytesInFlight).To(BeZero())
+		})
+
 		It("sets the early retransmit alarm", func() {
 			now := time.Now()
 			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1, SendTime: now.Add(-2 * time.Second), EncryptionLevel: protocol.Encryption1RTT}))
@@ -15,6 +15,8 @@ const (
 
 type cubicSender struct {
 	hybridSlowStart HybridSlowStart
+	// hyStartPlusPlus is used instead of the hybridSlowStart, if HyStart++ is enabled
+	hyStartPlusPlus *hyStartPlusPlus
 	prr             PrrSender
 	rttStats        *RTTStats
 	stats           connectionStats
@@ -112,6 +114,9 @@ func (c *cubicSender) OnPacketSent(
 	}
 	c.largestSentPacketNumber = packetNumber
 	c.hybridSlowStart.OnPacketSent(packetNumber)
+	if c.hyStartPlusPlus != nil {
+		c.hyStartPlusPlus.OnPacketSent(packetNumber)
+	}
 }
 
 func (c *cubicSender) InRecovery() bool {
@@ -139,6 +144,12 @@ func (c *cubicSender) SlowstartThreshold() protocol.ByteCount {
 }
 
 func (c *cubicSender) MaybeExitSlowStart() {
+	if c.hyStartPlusPlus != nil {
+		if c.InSlowStart() && c.hyStartPlusPlus.ShouldExitSlowStart(c.rttStats.LatestRTT()) {
+			c.ExitSlowstart()
+		}
+		return
+	}
 	if c.InSlowStart() && c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/protocol.DefaultTCPMSS) {
 		c.ExitSlowstart()
 	}
@@ -159,6 +170,9 @@ func (c *cubicSender) OnPacketAcked(
 	c.maybeIncreaseCwnd(ackedPacketNumber, ackedBytes, priorInFlight, eventTime)
 	if c.InSlowStart() {
 		c.hybridSlowStart.OnPacketAcked(ackedPacketNumber)
+		if c.hyStartPlusPlus != nil {
+			c.hyStartPlusPlus.OnPacketAcked(ackedPacketNumber)
+		}
 	}
 }
 
@@ -235,6 +249,11 @@ func (c *cubicSender) maybeIncreaseCwnd(
 		return
 	}
 	if c.InSlowStart() {
+		if c.hyStartPlusPlus != nil && c.hyStartPlusPlus.InConservativeSlowStart() {
+			// HyStart++ conservative slow start, increase by a fraction of a packet for each ACK.
+			c.congestionWindow += protocol.DefaultTCPMSS / hyStartPlusPlusCSSGrowthDivisor
+			return
+		}
 		// TCP slow start, exponential growth, increase by one for each ACK.
 		c.congestionWindow += protocol.DefaultTCPMSS
 		return
@@ -292,6 +311,9 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 		return
 	}
 	c.hybridSlowStart.Restart()
+	if c.hyStartPlusPlus != nil {
+		c.hyStartPlusPlus.Restart()
+	}
 	c.cubic.Reset()
 	c.slowstartThreshold = c.congestionWindow / 2
 	c.congestionWindow = c.minCongestionWindow
@@ -300,6 +322,9 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 // OnConnectionMigration is called when the connection is migrated (?)
 func (c *cubicSender) OnConnectionMigration() {
 	c.hybridSlowStart.Restart()
+	if c.hyStartPlusPlus != nil {
+		c.hyStartPlusPlus.Restart()
+	}
 	c.prr = PrrSender{}
 	c.largestSentPacketNumber = 0
 	c.largestAckedPacketNumber = 0
@@ -316,3 +341,14 @@ func (c *cubicSender) OnConnectionMigration() {
 func (c *cubicSender) SetSlowStartLargeReduction(enabled bool) {
 	c.slowStartLargeReduction = enabled
 }
+
+// SetHyStartPlusPlus allows using HyStart++ instead of hybrid slow start
+func (c *cubicSender) SetHyStartPlusPlus(enabled bool) {
+	if !enabled {
+		c.hyStartPlusPlus = nil
+		return
+	}
+	if c.hyStartPlusPlus == nil {
+		c.hyStartPlusPlus = &hyStartPlusPlus{lastSentPacketNumber: c.largestSentPacketNumber}
+	}
+}
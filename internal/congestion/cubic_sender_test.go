@@ -637,4 +637,57 @@ var _ = Describe("Cubic Sender", func() {
 		AckNPackets(2)
 		Expect(sender.GetCongestionWindow()).To(Equal(savedCwnd + protocol.DefaultTCPMSS))
 	})
+	Context("HyStart++", func() {
+		BeforeEach(func() {
+			sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*protocol.DefaultTCPMSS, MaxCongestionWindow)
+			sender.SetHyStartPlusPlus(true)
+		})
+
+		// ackRound acknowledges all packets in flight, using the same RTT for every ACK.
+		// The sender is treated as congestion window limited.
+		ackRound := func(rtt time.Duration) {
+			for bytesInFlight > 0 {
+				rttStats.UpdateRTT(rtt, 0, clock.Now())
+				sender.MaybeExitSlowStart()
+				ackedPacketNumber++
+				sender.OnPacketAcked(ackedPacketNumber, protocol.DefaultTCPMSS, sender.GetCongestionWindow(), clock.Now())
+				bytesInFlight -= protocol.DefaultTCPMSS
+			}
+			clock.Advance(rtt)
+		}
+
+		It("grows the congestion window conservatively after an RTT increase, and then exits slow start", func() {
+			for i := 0; i < 2; i++ {
+				SendAvailableSendWindow()
+				ackRound(60 * time.Millisecond)
+				Expect(sender.InSlowStart()).To(BeTrue())
+			}
+			// increase the RTT, this leads us to enter conservative slow start
+			SendAvailableSendWindow()
+			ackRound(100 * time.Millisecond)
+			Expect(sender.InSlowStart()).To(BeTrue())
+			cwnd := sender.GetCongestionWindow()
+			numSent := SendAvailableSendWindow()
+			ackRound(100 * time.Millisecond)
+			Expect(sender.InSlowStart()).To(BeTrue())
+			// in regular slow start, the congestion window would have doubled
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd + protocol.ByteCount(numSent)*protocol.DefaultTCPMSS/4))
+
+			for i := 0; i < hyStartPlusPlusCSSRounds; i++ {
+				SendAvailableSendWindow()
+				ackRound(100 * time.Millisecond)
+			}
+			Expect(sender.InSlowStart()).To(BeFalse())
+		})
+
+		It("uses regular slow start as long as the RTT doesn't increase", func() {
+			for i := 0; i < 3; i++ {
+				cwnd := sender.GetCongestionWindow()
+				SendAvailableSendWindow()
+				ackRound(60 * time.Millisecond)
+				Expect(sender.GetCongestionWindow()).To(Equal(2 * cwnd))
+			}
+			Expect(sender.InSlowStart()).To(BeTrue())
+		})
+	})
 })
@@ -0,0 +1,116 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// Number of RTT samples per round needed to detect an increase of delay.
+const hyStartPlusPlusMinSamples = uint32(8)
+
+// Exit slow start if the min RTT has increased by more than 1/8th (clamped to the thresholds below).
+const hyStartPlusPlusDelayFactorExp = 3 // 2^3 = 8
+const hyStartPlusPlusMinRTTThreshold = 4 * time.Millisecond
+const hyStartPlusPlusMaxRTTThreshold = 16 * time.Millisecond
+
+// During conservative slow start, the congestion window grows by 1/4 of the slow start growth.
+const hyStartPlusPlusCSSGrowthDivisor = 4
+
+// Number of rounds spent in conservative slow start before exiting slow start.
+const hyStartPlusPlusCSSRounds = 5
+
+// hyStartPlusPlus implements HyStart++, as described in draft-ietf-tcpm-hystartplusplus.
+// When an increase of the RTT is detected, it doesn't exit slow start immediately,
+// but enters conservative slow start (CSS) first, which increases the congestion window more slowly.
+// If the RTT increase persists for a number of rounds, slow start is exited.
+// If the RTT decreases, the RTT increase is considered spurious, and it returns to slow start.
+type hyStartPlusPlus struct {
+	lastSentPacketNumber protocol.PacketNumber
+	// the round ends when a packet sent after endPacketNumber is acknowledged
+	endPacketNumber protocol.PacketNumber
+	started         bool
+
+	lastRoundMinRTT    time.Duration
+	currentRoundMinRTT time.Duration
+	rttSampleCount     uint32
+
+	inCSS             bool
+	cssBaselineMinRTT time.Duration
+	cssRounds         int
+}
+
+func (s *hyStartPlusPlus) startRound() {
+	s.endPacketNumber = s.lastSentPacketNumber
+	s.lastRoundMinRTT = s.currentRoundMinRTT
+	s.currentRoundMinRTT = 0
+	s.rttSampleCount = 0
+	s.started = true
+}
+
+// ShouldExitSlowStart should be called on every new ack frame, since a new
+// RTT measurement can be made then.
+func (s *hyStartPlusPlus) ShouldExitSlowStart(latestRTT time.Duration) bool {
+	if s.inCSS && s.cssRounds >= hyStartPlusPlusCSSRounds {
+		return true
+	}
+	if !s.started {
+		s.startRound()
+	}
+	s.rttSampleCount++
+	if s.currentRoundMinRTT == 0 || latestRTT < s.currentRoundMinRTT {
+		s.currentRoundMinRTT = latestRTT
+	}
+	if s.rttSampleCount < hyStartPlusPlusMinSamples {
+		return false
+	}
+	if !s.inCSS {
+		// We need the min RTT of the previous round to detect an RTT increase.
+		if s.lastRoundMinRTT == 0 {
+			return false
+		}
+		threshold := utils.MaxDuration(
+			hyStartPlusPlusMinRTTThreshold,
+			utils.MinDuration(s.lastRoundMinRTT>>hyStartPlusPlusDelayFactorExp, hyStartPlusPlusMaxRTTThreshold),
+		)
+		if s.currentRoundMinRTT >= s.lastRoundMinRTT+threshold {
+			s.inCSS = true
+			s.cssBaselineMinRTT = s.currentRoundMinRTT
+			s.cssRounds = 0
+		}
+		return false
+	}
+	// The RTT increase was spurious. Resume slow start.
+	if s.currentRoundMinRTT < s.cssBaselineMinRTT {
+		s.inCSS = false
+		s.cssBaselineMinRTT = 0
+	}
+	return false
+}
+
+// InConservativeSlowStart returns true if the congestion window should be grown conservatively.
+func (s *hyStartPlusPlus) InConservativeSlowStart() bool {
+	return s.inCSS
+}
+
+// OnPacketSent is called when a packet was sent
+func (s *hyStartPlusPlus) OnPacketSent(packetNumber protocol.PacketNumber) {
+	s.lastSentPacketNumber = packetNumber
+}
+
+// OnPacketAcked gets invoked after ShouldExitSlowStart.
+// The round ends when the final packet of the burst is acknowledged, and the next one is started on the next incoming ack.
+func (s *hyStartPlusPlus) OnPacketAcked(ackedPacketNumber protocol.PacketNumber) {
+	if s.started && s.endPacketNumber < ackedPacketNumber {
+		s.started = false
+		if s.inCSS {
+			s.cssRounds++
+		}
+	}
+}
+
+// Restart the slow start phase
+func (s *hyStartPlusPlus) Restart() {
+	*s = hyStartPlusPlus{lastSentPacketNumber: s.lastSentPacketNumber}
+}
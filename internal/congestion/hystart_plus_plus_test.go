@@ -0,0 +1,100 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HyStart++", func() {
+	var (
+		slowStart    *hyStartPlusPlus
+		packetNumber protocol.PacketNumber
+	)
+
+	BeforeEach(func() {
+		slowStart = &hyStartPlusPlus{}
+		packetNumber = 0
+	})
+
+	// runRound sends a round of 10 packets, and acknowledges them one by one using the same RTT.
+	// It returns true if HyStart++ decided to exit slow start.
+	runRound := func(rtt time.Duration) bool {
+		first := packetNumber + 1
+		for i := 0; i < 10; i++ {
+			packetNumber++
+			slowStart.OnPacketSent(packetNumber)
+		}
+		var exit bool
+		for pn := first; pn <= packetNumber; pn++ {
+			if slowStart.ShouldExitSlowStart(rtt) {
+				exit = true
+			}
+			slowStart.OnPacketAcked(pn)
+		}
+		return exit
+	}
+
+	It("doesn't exit slow start if the RTT doesn't increase", func() {
+		for i := 0; i < 20; i++ {
+			Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+			Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		}
+	})
+
+	It("enters conservative slow start when the RTT increases, and exits slow start after a few rounds", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		// The threshold is 60ms / 8 = 7.5ms.
+		Expect(runRound(67 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		// The threshold is now 67ms / 8 = 8.375ms.
+		Expect(runRound(76 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+		// The round in which the RTT increase was detected is the first round of conservative slow start.
+		for i := 1; i < hyStartPlusPlusCSSRounds; i++ {
+			Expect(runRound(76 * time.Millisecond)).To(BeFalse())
+			Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+		}
+		Expect(runRound(76 * time.Millisecond)).To(BeTrue())
+	})
+
+	It("resumes slow start if the RTT increase was spurious", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(100 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+		Expect(runRound(80 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+	})
+
+	It("caps the RTT threshold", func() {
+		Expect(runRound(400 * time.Millisecond)).To(BeFalse())
+		// 1/8 of 400ms would be 50ms, but the threshold is capped at 16ms
+		Expect(runRound(415 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		Expect(runRound(432 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+	})
+
+	It("uses a minimum RTT threshold", func() {
+		Expect(runRound(10 * time.Millisecond)).To(BeFalse())
+		// 1/8 of 10ms would be 1.25ms, but the threshold is at least 4ms
+		Expect(runRound(13 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		Expect(runRound(17 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+	})
+
+	It("restarts", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(100 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+		slowStart.Restart()
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		// the min RTT of the previous round was reset
+		Expect(runRound(100 * time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+	})
+})
@@ -12,6 +12,8 @@ type SendAlgorithm interface {
 	OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool)
 	GetCongestionWindow() protocol.ByteCount
 	MaybeExitSlowStart()
+	InSlowStart() bool
+	InRecovery() bool
 	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time)
 	OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount)
 	SetNumEmulatedConnections(n int)
@@ -20,6 +22,7 @@ type SendAlgorithm interface {
 
 	// Experiments
 	SetSlowStartLargeReduction(enabled bool)
+	SetHyStartPlusPlus(enabled bool)
 }
 
 // SendAlgorithmWithDebugInfo adds some debug functions to SendAlgorithm
@@ -32,5 +35,4 @@ type SendAlgorithmWithDebugInfo interface {
 	HybridSlowStart() *HybridSlowStart
 	SlowstartThreshold() protocol.ByteCount
 	RenoBeta() float32
-	InRecovery() bool
 }
@@ -27,6 +27,7 @@ type baseFlowController struct {
 	epochStartOffset protocol.ByteCount
 	rttStats         *congestion.RTTStats
 
+	clock  utils.Clock
 	logger utils.Logger
 }
 
@@ -73,6 +74,17 @@ func (c *baseFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.bytesRead += n
 }
 
+// getReceiveState must be called with the mutex held.
+// The window update is pending if hasWindowUpdate is true.
+func (c *baseFlowController) getReceiveState(hasWindowUpdate bool) ReceiveState {
+	return ReceiveState{
+		BytesRead:           c.bytesRead,
+		HighestReceived:     c.highestReceived,
+		ReceiveWindow:       c.receiveWindow,
+		WindowUpdatePending: hasWindowUpdate,
+	}
+}
+
 func (c *baseFlowController) hasWindowUpdate() bool {
 	bytesRemaining := c.receiveWindow - c.bytesRead
 	// update the window when more than the threshold was consumed
@@ -105,7 +117,7 @@ func (c *baseFlowController) maybeAdjustWindowSize() {
 	}
 
 	fraction := float64(bytesReadInEpoch) / float64(c.receiveWindowSize)
-	if time.Since(c.epochStartTime) < time.Duration(4*fraction*float64(rtt)) {
+	if c.clock.Now().Sub(c.epochStartTime) < time.Duration(4*fraction*float64(rtt)) {
 		// window is consumed too fast, try to increase the window size
 		c.receiveWindowSize = utils.MinByteCount(2*c.receiveWindowSize, c.maxReceiveWindowSize)
 	}
@@ -113,7 +125,7 @@ func (c *baseFlowController) maybeAdjustWindowSize() {
 }
 
 func (c *baseFlowController) startNewAutoTuningEpoch() {
-	c.epochStartTime = time.Now()
+	c.epochStartTime = c.clock.Now()
 	c.epochStartOffset = c.bytesRead
 }
 
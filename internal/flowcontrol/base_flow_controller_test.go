@@ -7,6 +7,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -27,6 +28,7 @@ var _ = Describe("Base Flow controller", func() {
 	BeforeEach(func() {
 		controller = &baseFlowController{}
 		controller.rttStats = &congestion.RTTStats{}
+		controller.clock = utils.DefaultClock
 	})
 
 	Context("send flow control", func() {
@@ -2,6 +2,7 @@ package flowcontrol
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -12,6 +13,18 @@ import (
 type connectionFlowController struct {
 	baseFlowController
 
+	initialReceiveWindowSize protocol.ByteCount
+
+	memoryBudget *MemoryBudget
+	budgetUsed   protocol.ByteCount // the number of bytes accounted for in the memory budget
+	abandoned    bool
+	// the time when the last window update was sent, used to detect idle sessions
+	lastWindowUpdateTime time.Time
+
+	// no window updates are sent while this number of bytes (received, but not yet read) is buffered
+	// 0 if not limited
+	maxReadAhead protocol.ByteCount
+
 	queueWindowUpdate func()
 }
 
@@ -23,7 +36,10 @@ func NewConnectionFlowController(
 	receiveWindow protocol.ByteCount,
 	maxReceiveWindow protocol.ByteCount,
 	queueWindowUpdate func(),
+	memoryBudget *MemoryBudget,
+	maxReadAhead protocol.ByteCount,
 	rttStats *congestion.RTTStats,
+	clock utils.Clock,
 	logger utils.Logger,
 ) ConnectionFlowController {
 	return &connectionFlowController{
@@ -32,9 +48,13 @@ func NewConnectionFlowController(
 			receiveWindow:        receiveWindow,
 			receiveWindowSize:    receiveWindow,
 			maxReceiveWindowSize: maxReceiveWindow,
+			clock:                clock,
 			logger:               logger,
 		},
-		queueWindowUpdate: queueWindowUpdate,
+		initialReceiveWindowSize: receiveWindow,
+		memoryBudget:             memoryBudget,
+		maxReadAhead:             maxReadAhead,
+		queueWindowUpdate:        queueWindowUpdate,
 	}
 }
 
@@ -48,6 +68,10 @@ func (c *connectionFlowController) IncrementHighestReceived(increment protocol.B
 	defer c.mutex.Unlock()
 
 	c.highestReceived += increment
+	if !c.abandoned {
+		c.memoryBudget.add(increment)
+		c.budgetUsed += increment
+	}
 	if c.checkFlowControlViolation() {
 		return qerr.Error(qerr.FlowControlReceivedTooMuchData, fmt.Sprintf("Received %d bytes for the connection, allowed %d bytes", c.highestReceived, c.receiveWindow))
 	}
@@ -56,22 +80,72 @@ func (c *connectionFlowController) IncrementHighestReceived(increment protocol.B
 
 func (c *connectionFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.baseFlowController.AddBytesRead(n)
+	c.mutex.Lock()
+	if !c.abandoned {
+		c.memoryBudget.release(n)
+		c.budgetUsed -= n
+	}
+	c.mutex.Unlock()
 	c.maybeQueueWindowUpdate()
 }
 
+func (c *connectionFlowController) BufferedBytes() protocol.ByteCount {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.highestReceived - c.bytesRead
+}
+
+// Abandon releases the memory budget used for data that was not yet read by the application.
+func (c *connectionFlowController) Abandon() {
+	c.mutex.Lock()
+	c.memoryBudget.release(c.budgetUsed)
+	c.budgetUsed = 0
+	c.abandoned = true
+	c.mutex.Unlock()
+}
+
+// readAheadLimitReached says if the application needs to read data before the window can be increased.
+func (c *connectionFlowController) readAheadLimitReached() bool {
+	return c.maxReadAhead > 0 && c.highestReceived-c.bytesRead >= c.maxReadAhead
+}
+
 func (c *connectionFlowController) maybeQueueWindowUpdate() {
 	c.mutex.Lock()
-	hasWindowUpdate := c.hasWindowUpdate()
+	hasWindowUpdate := c.hasWindowUpdate() && !c.readAheadLimitReached()
 	c.mutex.Unlock()
 	if hasWindowUpdate {
 		c.queueWindowUpdate()
 	}
 }
 
+func (c *connectionFlowController) GetReceiveState() ReceiveState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.getReceiveState(c.hasWindowUpdate() && !c.readAheadLimitReached())
+}
+
 func (c *connectionFlowController) GetWindowUpdate() protocol.ByteCount {
 	c.mutex.Lock()
+	if c.readAheadLimitReached() {
+		c.mutex.Unlock()
+		return 0
+	}
 	oldWindowSize := c.receiveWindowSize
+	now := c.clock.Now()
+	// Only sessions that were idle are shrunk, such that active transfers are not slowed down.
+	// The window of a session that stays idle is not shrunk, but it doesn't use any memory either,
+	// and it will be shrunk when the peer resumes sending.
+	idle := !c.lastWindowUpdateTime.IsZero() && now.Sub(c.lastWindowUpdateTime) >= protocol.MemoryPressureIdleTime
+	if idle && c.memoryBudget.UnderPressure() && c.receiveWindowSize > c.initialReceiveWindowSize {
+		c.logger.Debugf("Memory budget nearly exhausted. Shrinking receive flow control window for the idle connection to %d kB", c.initialReceiveWindowSize/(1<<10))
+		c.receiveWindowSize = c.initialReceiveWindowSize
+		// prevent auto-tuning from increasing the window right away
+		c.startNewAutoTuningEpoch()
+	}
 	offset := c.baseFlowController.getWindowUpdate()
+	if offset > 0 {
+		c.lastWindowUpdateTime = now
+	}
 	if oldWindowSize < c.receiveWindowSize {
 		c.logger.Debugf("Increasing receive flow control window for the connection to %d kB", c.receiveWindowSize/(1<<10))
 	}
@@ -83,7 +157,7 @@ func (c *connectionFlowController) GetWindowUpdate() protocol.ByteCount {
 // it should make sure that the connection-level window is increased when a stream-level window grows
 func (c *connectionFlowController) EnsureMinimumWindowSize(inc protocol.ByteCount) {
 	c.mutex.Lock()
-	if inc > c.receiveWindowSize {
+	if inc > c.receiveWindowSize && !c.memoryBudget.UnderPressure() {
 		c.logger.Debugf("Increasing receive flow control window for the connection to %d kB, in response to stream flow control window increase", c.receiveWindowSize/(1<<10))
 		c.receiveWindowSize = utils.MinByteCount(inc, c.maxReceiveWindowSize)
 		c.startNewAutoTuningEpoch()
@@ -26,6 +26,7 @@ var _ = Describe("Connection Flow controller", func() {
 		queuedWindowUpdate = false
 		controller = &connectionFlowController{}
 		controller.rttStats = &congestion.RTTStats{}
+		controller.clock = utils.DefaultClock
 		controller.logger = utils.DefaultLogger
 		controller.queueWindowUpdate = func() { queuedWindowUpdate = true }
 	})
@@ -37,7 +38,7 @@ var _ = Describe("Connection Flow controller", func() {
 			receiveWindow := protocol.ByteCount(2000)
 			maxReceiveWindow := protocol.ByteCount(3000)
 
-			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, nil, rttStats, utils.DefaultLogger).(*connectionFlowController)
+			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, nil, nil, 0, rttStats, utils.DefaultClock, utils.DefaultLogger).(*connectionFlowController)
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
 		})
@@ -50,6 +51,13 @@ var _ = Describe("Connection Flow controller", func() {
 			Expect(controller.highestReceived).To(Equal(protocol.ByteCount(1337 + 123)))
 		})
 
+		It("says how many bytes are buffered", func() {
+			controller.IncrementHighestReceived(100)
+			Expect(controller.BufferedBytes()).To(Equal(protocol.ByteCount(100)))
+			controller.AddBytesRead(30)
+			Expect(controller.BufferedBytes()).To(Equal(protocol.ByteCount(70)))
+		})
+
 		Context("getting window updates", func() {
 			BeforeEach(func() {
 				controller.receiveWindow = 100
@@ -129,5 +137,114 @@ var _ = Describe("Connection Flow controller", func() {
 			controller.EnsureMinimumWindowSize(1912)
 			Expect(controller.epochStartTime).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
 		})
+
+		It("doesn't increase the window size if the memory budget is under pressure", func() {
+			controller.memoryBudget = NewMemoryBudget(100)
+			controller.memoryBudget.add(90)
+			controller.EnsureMinimumWindowSize(1800)
+			Expect(controller.receiveWindowSize).To(Equal(oldWindowSize))
+		})
+	})
+
+	Context("using a memory budget", func() {
+		var budget *MemoryBudget
+
+		BeforeEach(func() {
+			budget = NewMemoryBudget(100000)
+			controller.memoryBudget = budget
+			controller.receiveWindow = 9500
+			controller.receiveWindowSize = 4000
+			controller.initialReceiveWindowSize = 1000
+			controller.maxReceiveWindowSize = 10000
+		})
+
+		It("accounts for received and read data", func() {
+			Expect(controller.IncrementHighestReceived(300)).To(Succeed())
+			Expect(budget.Used()).To(Equal(protocol.ByteCount(300)))
+			controller.AddBytesRead(200)
+			Expect(budget.Used()).To(Equal(protocol.ByteCount(100)))
+		})
+
+		It("releases the data that wasn't read when abandoned", func() {
+			Expect(controller.IncrementHighestReceived(300)).To(Succeed())
+			controller.AddBytesRead(100)
+			controller.Abandon()
+			Expect(budget.Used()).To(BeZero())
+			// reads and receives after abandoning don't change the budget
+			controller.AddBytesRead(100)
+			Expect(controller.IncrementHighestReceived(100)).To(Succeed())
+			Expect(budget.Used()).To(BeZero())
+		})
+
+		It("shrinks the window of idle sessions when the budget is under pressure", func() {
+			budget.add(80000) // memory used by other connections
+			Expect(budget.UnderPressure()).To(BeTrue())
+			controller.lastWindowUpdateTime = time.Now().Add(-protocol.MemoryPressureIdleTime)
+			Expect(controller.IncrementHighestReceived(9000)).To(Succeed())
+			controller.AddBytesRead(9000)
+			Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(9000 + 1000)))
+			Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(1000)))
+		})
+
+		It("doesn't shrink the window of active sessions when the budget is under pressure", func() {
+			budget.add(80000) // memory used by other connections
+			controller.lastWindowUpdateTime = time.Now().Add(-protocol.MemoryPressureIdleTime / 2)
+			Expect(controller.IncrementHighestReceived(9000)).To(Succeed())
+			controller.AddBytesRead(9000)
+			Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(9000 + 4000)))
+			Expect(controller.lastWindowUpdateTime).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+		})
+
+		It("doesn't shrink the window when the budget is not under pressure", func() {
+			controller.lastWindowUpdateTime = time.Now().Add(-protocol.MemoryPressureIdleTime)
+			Expect(controller.IncrementHighestReceived(9000)).To(Succeed())
+			controller.AddBytesRead(9000)
+			Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(9000 + 4000)))
+		})
+	})
+
+	Context("limiting the read-ahead", func() {
+		BeforeEach(func() {
+			controller.maxReadAhead = 500
+			controller.receiveWindow = 1000
+			controller.receiveWindowSize = 1000
+			controller.maxReceiveWindowSize = 1000
+		})
+
+		It("doesn't send window updates while too much data is buffered", func() {
+			Expect(controller.IncrementHighestReceived(900)).To(Succeed())
+			controller.AddBytesRead(300) // 600 bytes still buffered
+			Expect(queuedWindowUpdate).To(BeFalse())
+			Expect(controller.GetWindowUpdate()).To(BeZero())
+		})
+
+		It("sends a window update once the application read enough data", func() {
+			Expect(controller.IncrementHighestReceived(900)).To(Succeed())
+			controller.AddBytesRead(300)
+			Expect(queuedWindowUpdate).To(BeFalse())
+			controller.AddBytesRead(200) // 400 bytes still buffered
+			Expect(queuedWindowUpdate).To(BeTrue())
+			Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(500 + 1000)))
+		})
+
+		It("doesn't report a pending window update while too much data is buffered", func() {
+			Expect(controller.IncrementHighestReceived(900)).To(Succeed())
+			controller.AddBytesRead(300)
+			Expect(controller.GetReceiveState()).To(Equal(ReceiveState{
+				BytesRead:       300,
+				HighestReceived: 900,
+				ReceiveWindow:   1000,
+			}))
+			controller.AddBytesRead(200)
+			Expect(controller.GetReceiveState().WindowUpdatePending).To(BeTrue())
+		})
+
+		It("doesn't limit the window updates if not set", func() {
+			controller.maxReadAhead = 0
+			Expect(controller.IncrementHighestReceived(900)).To(Succeed())
+			controller.AddBytesRead(300)
+			Expect(queuedWindowUpdate).To(BeTrue())
+			Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(300 + 1000)))
+		})
 	})
 })
@@ -11,6 +11,20 @@ type flowController interface {
 	AddBytesRead(protocol.ByteCount)
 	GetWindowUpdate() protocol.ByteCount // returns 0 if no update is necessary
 	IsNewlyBlocked() (bool, protocol.ByteCount)
+	// GetReceiveState returns the state of the receive side.
+	// In contrast to the other methods, it may be called concurrently with receiving and reading data.
+	GetReceiveState() ReceiveState
+}
+
+// ReceiveState is a snapshot of the receive side of a flow controller.
+type ReceiveState struct {
+	BytesRead       protocol.ByteCount
+	HighestReceived protocol.ByteCount
+	// ReceiveWindow is the highest offset that the peer is allowed to send.
+	ReceiveWindow protocol.ByteCount
+	// WindowUpdatePending is true if enough data was read to send a window update,
+	// but GetWindowUpdate wasn't called yet.
+	WindowUpdatePending bool
 }
 
 // A StreamFlowController is a flow controller for a QUIC stream.
@@ -24,11 +38,19 @@ type StreamFlowController interface {
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
+	// SetReceiveWindowSize overrides the size of the receive window.
+	// Auto-tuning won't increase the window beyond this size.
+	SetReceiveWindowSize(protocol.ByteCount)
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
 type ConnectionFlowController interface {
 	flowController
+	// BufferedBytes returns the number of bytes that were received, but not yet read by the application
+	BufferedBytes() protocol.ByteCount
+	// Abandon should be called when the connection is closed.
+	// It releases the memory budget used for data that was not yet read.
+	Abandon()
 }
 
 type connectionFlowControllerI interface {
@@ -0,0 +1,49 @@
+package flowcontrol
+
+import (
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A MemoryBudget limits the amount of memory used for buffering received data across multiple connections.
+// All methods can be called on a nil MemoryBudget, which doesn't impose any limit.
+type MemoryBudget struct {
+	used uint64 // to be used as an atomic, needs to be the first field for 64 bit alignment
+	max  uint64
+}
+
+// NewMemoryBudget creates a new MemoryBudget
+func NewMemoryBudget(max protocol.ByteCount) *MemoryBudget {
+	return &MemoryBudget{max: uint64(max)}
+}
+
+func (b *MemoryBudget) add(n protocol.ByteCount) {
+	if b == nil {
+		return
+	}
+	atomic.AddUint64(&b.used, uint64(n))
+}
+
+func (b *MemoryBudget) release(n protocol.ByteCount) {
+	if b == nil {
+		return
+	}
+	atomic.AddUint64(&b.used, ^uint64(n-1))
+}
+
+// Used returns the number of bytes currently accounted for
+func (b *MemoryBudget) Used() protocol.ByteCount {
+	if b == nil {
+		return 0
+	}
+	return protocol.ByteCount(atomic.LoadUint64(&b.used))
+}
+
+// UnderPressure says if the budget is close to being exhausted.
+func (b *MemoryBudget) UnderPressure() bool {
+	if b == nil {
+		return false
+	}
+	return float64(atomic.LoadUint64(&b.used)) >= protocol.MemoryBudgetPressureThreshold*float64(b.max)
+}
@@ -0,0 +1,35 @@
+package flowcontrol
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory Budget", func() {
+	It("tracks the memory used", func() {
+		b := NewMemoryBudget(1000)
+		b.add(600)
+		b.add(100)
+		Expect(b.Used()).To(Equal(protocol.ByteCount(700)))
+		b.release(300)
+		Expect(b.Used()).To(Equal(protocol.ByteCount(400)))
+	})
+
+	It("says when it's under pressure", func() {
+		b := NewMemoryBudget(1000)
+		b.add(749)
+		Expect(b.UnderPressure()).To(BeFalse())
+		b.add(1)
+		Expect(b.UnderPressure()).To(BeTrue())
+		b.release(100)
+		Expect(b.UnderPressure()).To(BeFalse())
+	})
+
+	It("doesn't impose a limit if nil", func() {
+		var b *MemoryBudget
+		b.add(1 << 40)
+		Expect(b.UnderPressure()).To(BeFalse())
+		Expect(b.Used()).To(BeZero())
+	})
+})
@@ -32,6 +32,7 @@ func NewStreamFlowController(
 	initialSendWindow protocol.ByteCount,
 	queueWindowUpdate func(protocol.StreamID),
 	rttStats *congestion.RTTStats,
+	clock utils.Clock,
 	logger utils.Logger,
 ) StreamFlowController {
 	return &streamFlowController{
@@ -44,6 +45,7 @@ func NewStreamFlowController(
 			receiveWindowSize:    receiveWindow,
 			maxReceiveWindowSize: maxReceiveWindow,
 			sendWindow:           initialSendWindow,
+			clock:                clock,
 			logger:               logger,
 		},
 	}
@@ -119,6 +121,27 @@ func (c *streamFlowController) maybeQueueWindowUpdate() {
 	}
 }
 
+func (c *streamFlowController) GetReceiveState() ReceiveState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.getReceiveState(!c.receivedFinalOffset && c.hasWindowUpdate())
+}
+
+func (c *streamFlowController) SetReceiveWindowSize(size protocol.ByteCount) {
+	c.mutex.Lock()
+	oldWindowSize := c.receiveWindowSize
+	c.receiveWindowSize = size
+	c.maxReceiveWindowSize = size
+	hasWindowUpdate := !c.receivedFinalOffset && c.hasWindowUpdate()
+	c.mutex.Unlock()
+	if size > oldWindowSize {
+		c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(size) * protocol.ConnectionFlowControlMultiplier))
+	}
+	if hasWindowUpdate {
+		c.queueWindowUpdate()
+	}
+}
+
 func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 	// don't use defer for unlocking the mutex here, GetWindowUpdate() is called frequently and defer shows up in the profiler
 	c.mutex.Lock()
@@ -21,10 +21,11 @@ var _ = Describe("Stream Flow controller", func() {
 		rttStats := &congestion.RTTStats{}
 		controller = &streamFlowController{
 			streamID:   10,
-			connection: NewConnectionFlowController(1000, 1000, func() {}, rttStats, utils.DefaultLogger).(*connectionFlowController),
+			connection: NewConnectionFlowController(1000, 1000, func() {}, nil, 0, rttStats, utils.DefaultClock, utils.DefaultLogger).(*connectionFlowController),
 		}
 		controller.maxReceiveWindowSize = 10000
 		controller.rttStats = rttStats
+		controller.clock = utils.DefaultClock
 		controller.logger = utils.DefaultLogger
 		controller.queueWindowUpdate = func() { queuedWindowUpdate = true }
 	})
@@ -36,8 +37,8 @@ var _ = Describe("Stream Flow controller", func() {
 		sendWindow := protocol.ByteCount(4000)
 
 		It("sets the send and receive windows", func() {
-			cc := NewConnectionFlowController(0, 0, nil, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, nil, nil, 0, nil, utils.DefaultClock, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, rttStats, utils.DefaultClock, utils.DefaultLogger).(*streamFlowController)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(5)))
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
@@ -51,8 +52,8 @@ var _ = Describe("Stream Flow controller", func() {
 				queued = true
 			}
 
-			cc := NewConnectionFlowController(0, 0, nil, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, nil, nil, 0, nil, utils.DefaultClock, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, rttStats, utils.DefaultClock, utils.DefaultLogger).(*streamFlowController)
 			fc.AddBytesRead(receiveWindow)
 			Expect(queued).To(BeTrue())
 		})
@@ -188,6 +189,34 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(controller.connection.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(float64(controller.receiveWindowSize) * protocol.ConnectionFlowControlMultiplier)))
 			})
 
+			It("increases the window, if a larger window size is set", func() {
+				controller.SetReceiveWindowSize(400)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(40 + 400)))
+				Expect(controller.connection.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(400 * protocol.ConnectionFlowControlMultiplier)))
+			})
+
+			It("doesn't auto-tune the window beyond the window size that was set", func() {
+				controller.SetReceiveWindowSize(oldWindowSize)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				oldOffset := controller.bytesRead
+				setRtt(scaleDuration(20 * time.Millisecond))
+				controller.epochStartOffset = oldOffset
+				controller.epochStartTime = time.Now().Add(-time.Millisecond)
+				controller.AddBytesRead(55)
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(oldOffset + 55 + oldWindowSize)))
+				Expect(controller.receiveWindowSize).To(Equal(oldWindowSize))
+			})
+
+			It("reduces the window size", func() {
+				controller.SetReceiveWindowSize(20)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				controller.AddBytesRead(50)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(90 + 20)))
+			})
+
 			It("sends a connection-level window update when a large stream is abandoned", func() {
 				Expect(controller.UpdateHighestReceived(90, true)).To(Succeed())
 				Expect(controller.connection.GetWindowUpdate()).To(BeZero())
@@ -202,6 +231,19 @@ var _ = Describe("Stream Flow controller", func() {
 				offset := controller.GetWindowUpdate()
 				Expect(offset).To(BeZero())
 			})
+
+			It("reports the receive state", func() {
+				Expect(controller.UpdateHighestReceived(80, false)).To(Succeed())
+				Expect(controller.GetReceiveState()).To(Equal(ReceiveState{
+					BytesRead:       40,
+					HighestReceived: 80,
+					ReceiveWindow:   100,
+				}))
+				controller.AddBytesRead(30)
+				Expect(controller.GetReceiveState().WindowUpdatePending).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).ToNot(BeZero())
+				Expect(controller.GetReceiveState().WindowUpdatePending).To(BeFalse())
+			})
 		})
 	})
 
@@ -0,0 +1,36 @@
+package handshake
+
+import (
+	"crypto/tls"
+
+	"github.com/marten-seemann/qtls"
+)
+
+// A ServerConfigCache converts the tls.Config of a server to a qtls.Config once,
+// and shares the result between all connections accepted by that server.
+// Without it, every connection would convert the tls.Config and initialize the session ticket keys
+// (reading a random key and deriving the ticket encryption keys from it) on its own.
+// It also means that session tickets issued on one connection are valid for all other connections of the server.
+// Signatures can't be cached, since they cover the transcript of every single handshake.
+// The Certificate message isn't cached either: qtls serializes the certificate chain for every handshake,
+// and doesn't offer a way to pass it a serialized message.
+// The tls.Config must not be modified after it was passed to NewServerConfigCache.
+// All methods can be called on a nil ServerConfigCache, which doesn't cache anything.
+type ServerConfigCache struct {
+	qtlsConf *qtls.Config
+}
+
+// NewServerConfigCache creates a new ServerConfigCache for a tls.Config.
+func NewServerConfigCache(tlsConf *tls.Config) *ServerConfigCache {
+	return &ServerConfigCache{qtlsConf: tlsConfigToQtlsConfig(tlsConf)}
+}
+
+// get returns a qtls.Config that can be modified for a single connection.
+func (c *ServerConfigCache) get() *qtls.Config {
+	if c == nil {
+		return tlsConfigToQtlsConfig(nil)
+	}
+	// Clone initializes the session ticket keys of the cached config (only on the first call),
+	// and copies them to the new config.
+	return c.qtlsConf.Clone()
+}
@@ -0,0 +1,35 @@
+package handshake
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server Config Cache", func() {
+	It("returns a new config for every connection", func() {
+		cache := NewServerConfigCache(testdata.GetTLSConfig())
+		conf1 := cache.get()
+		conf2 := cache.get()
+		Expect(conf1).ToNot(BeIdenticalTo(conf2))
+		Expect(conf1.Certificates).To(Equal(testdata.GetTLSConfig().Certificates))
+		conf1.NextProtos = []string{"foobar"}
+		Expect(conf2.NextProtos).To(BeEmpty())
+	})
+
+	It("uses the same session ticket key for all connections", func() {
+		cache := NewServerConfigCache(testdata.GetTLSConfig())
+		conf1 := cache.get()
+		conf2 := cache.get()
+		Expect(conf1.SessionTicketKey).ToNot(Equal([32]byte{}))
+		Expect(conf1.SessionTicketKey).To(Equal(conf2.SessionTicketKey))
+	})
+
+	It("doesn't cache anything if the cache is nil", func() {
+		var cache *ServerConfigCache
+		conf := cache.get()
+		Expect(conf).ToNot(BeNil())
+		Expect(conf.Certificates).To(BeEmpty())
+	})
+})
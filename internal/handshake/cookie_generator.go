@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 const (
@@ -33,16 +34,21 @@ type token struct {
 // A CookieGenerator generates Cookies
 type CookieGenerator struct {
 	cookieProtector cookieProtector
+	clock           utils.Clock
 }
 
-// NewCookieGenerator initializes a new CookieGenerator
-func NewCookieGenerator() (*CookieGenerator, error) {
-	cookieProtector, err := newCookieProtector()
+// NewCookieGenerator initializes a new CookieGenerator.
+// getKeys returns the keys used to protect Cookies, the first key is used to generate new Cookies.
+// If getKeys is nil, a random key is used.
+// The clock is used to set the time at which a Cookie was issued.
+func NewCookieGenerator(getKeys func() [][]byte, clock utils.Clock) (*CookieGenerator, error) {
+	cookieProtector, err := newCookieProtector(getKeys)
 	if err != nil {
 		return nil, err
 	}
 	return &CookieGenerator{
 		cookieProtector: cookieProtector,
+		clock:           clock,
 	}, nil
 }
 
@@ -51,7 +57,7 @@ func (g *CookieGenerator) NewToken(raddr net.Addr, origConnID protocol.Connectio
 	data, err := asn1.Marshal(token{
 		RemoteAddr:               encodeRemoteAddr(raddr),
 		OriginalDestConnectionID: origConnID,
-		Timestamp:                time.Now().Unix(),
+		Timestamp:                g.clock.Now().Unix(),
 	})
 	if err != nil {
 		return nil, err
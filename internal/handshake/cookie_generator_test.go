@@ -6,17 +6,25 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+type fixedClock struct {
+	utils.Clock
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
 var _ = Describe("Cookie Generator", func() {
 	var cookieGen *CookieGenerator
 
 	BeforeEach(func() {
 		var err error
-		cookieGen, err = NewCookieGenerator()
+		cookieGen, err = NewCookieGenerator(nil, utils.DefaultClock)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -27,6 +35,17 @@ var _ = Describe("Cookie Generator", func() {
 		Expect(token).ToNot(BeEmpty())
 	})
 
+	It("uses the clock to set the time a Cookie was issued", func() {
+		sentTime := time.Unix(1e9, 0)
+		cookieGen, err := NewCookieGenerator(nil, &fixedClock{Clock: utils.DefaultClock, now: sentTime})
+		Expect(err).ToNot(HaveOccurred())
+		token, err := cookieGen.NewToken(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		cookie, err := cookieGen.DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cookie.SentTime).To(Equal(sentTime))
+	})
+
 	It("works with nil tokens", func() {
 		cookie, err := cookieGen.DecodeToken(nil)
 		Expect(err).ToNot(HaveOccurred())
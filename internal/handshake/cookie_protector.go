@@ -5,6 +5,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 
@@ -24,18 +25,27 @@ const (
 	cookieNonceSize  = 32
 )
 
+var errNoCookieSecret = errors.New("no cookie secret")
+
 // cookieProtector is used to create and verify a cookie
 type cookieProtectorImpl struct {
-	secret []byte
+	// getSecrets returns the secrets used to protect tokens.
+	// The first secret is used to create new tokens, all of them are used to decode tokens.
+	getSecrets func() [][]byte
 }
 
-// newCookieProtector creates a source for source address tokens
-func newCookieProtector() (cookieProtector, error) {
-	secret := make([]byte, cookieSecretSize)
-	if _, err := rand.Read(secret); err != nil {
-		return nil, err
+// newCookieProtector creates a source for source address tokens.
+// If getSecrets is nil, a random secret is generated.
+func newCookieProtector(getSecrets func() [][]byte) (cookieProtector, error) {
+	if getSecrets == nil {
+		secret := make([]byte, cookieSecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		secrets := [][]byte{secret}
+		getSecrets = func() [][]byte { return secrets }
 	}
-	return &cookieProtectorImpl{secret: secret}, nil
+	return &cookieProtectorImpl{getSecrets: getSecrets}, nil
 }
 
 // NewToken encodes data into a new token.
@@ -44,7 +54,11 @@ func (s *cookieProtectorImpl) NewToken(data []byte) ([]byte, error) {
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, err
 	}
-	aead, aeadNonce, err := s.createAEAD(nonce)
+	secrets := s.getSecrets()
+	if len(secrets) == 0 {
+		return nil, errNoCookieSecret
+	}
+	aead, aeadNonce, err := s.createAEAD(secrets[0], nonce)
 	if err != nil {
 		return nil, err
 	}
@@ -52,20 +66,33 @@ func (s *cookieProtectorImpl) NewToken(data []byte) ([]byte, error) {
 }
 
 // DecodeToken decodes a token.
+// It tries all secrets, such that tokens created with a previous secret can still be decoded.
 func (s *cookieProtectorImpl) DecodeToken(p []byte) ([]byte, error) {
 	if len(p) < cookieNonceSize {
 		return nil, fmt.Errorf("Token too short: %d", len(p))
 	}
+	secrets := s.getSecrets()
+	if len(secrets) == 0 {
+		return nil, errNoCookieSecret
+	}
 	nonce := p[:cookieNonceSize]
-	aead, aeadNonce, err := s.createAEAD(nonce)
-	if err != nil {
-		return nil, err
+	var lastErr error
+	for _, secret := range secrets {
+		aead, aeadNonce, err := s.createAEAD(secret, nonce)
+		if err != nil {
+			return nil, err
+		}
+		data, err := aead.Open(nil, aeadNonce, p[cookieNonceSize:], nil)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
 	}
-	return aead.Open(nil, aeadNonce, p[cookieNonceSize:], nil)
+	return nil, lastErr
 }
 
-func (s *cookieProtectorImpl) createAEAD(nonce []byte) (cipher.AEAD, []byte, error) {
-	h := hkdf.New(sha256.New, s.secret, nonce, []byte("quic-go cookie source"))
+func (s *cookieProtectorImpl) createAEAD(secret, nonce []byte) (cipher.AEAD, []byte, error) {
+	h := hkdf.New(sha256.New, secret, nonce, []byte("quic-go cookie source"))
 	key := make([]byte, 32) // use a 32 byte key, in order to select AES-256
 	if _, err := io.ReadFull(h, key); err != nil {
 		return nil, nil, err
@@ -1,6 +1,8 @@
 package handshake
 
 import (
+	"bytes"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -10,7 +12,7 @@ var _ = Describe("Cookie Protector", func() {
 
 	BeforeEach(func() {
 		var err error
-		cp, err = newCookieProtector()
+		cp, err = newCookieProtector(nil)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -36,4 +38,60 @@ var _ = Describe("Cookie Protector", func() {
 		_, err := cp.DecodeToken([]byte("foobar"))
 		Expect(err).To(MatchError("Token too short: 6"))
 	})
+
+	Context("using configured secrets", func() {
+		var secrets [][]byte
+
+		BeforeEach(func() {
+			secrets = [][]byte{bytes.Repeat([]byte{1}, 32)}
+			var err error
+			cp, err = newCookieProtector(func() [][]byte { return secrets })
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("decodes tokens created by a different protector using the same secret", func() {
+			cp2, err := newCookieProtector(func() [][]byte { return [][]byte{bytes.Repeat([]byte{1}, 32)} })
+			Expect(err).ToNot(HaveOccurred())
+			token, err := cp2.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			decoded, err := cp.DecodeToken(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal([]byte("foobar")))
+		})
+
+		It("decodes tokens created with a previous secret", func() {
+			token, err := cp.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			secrets = [][]byte{bytes.Repeat([]byte{2}, 32), secrets[0]}
+			decoded, err := cp.DecodeToken(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal([]byte("foobar")))
+			// new tokens are created using the new secret
+			token, err = cp.NewToken([]byte("raboof"))
+			Expect(err).ToNot(HaveOccurred())
+			secrets = secrets[:1]
+			decoded, err = cp.DecodeToken(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal([]byte("raboof")))
+		})
+
+		It("rejects tokens created with a secret that was removed", func() {
+			token, err := cp.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			secrets = [][]byte{bytes.Repeat([]byte{2}, 32)}
+			_, err = cp.DecodeToken(token)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("message authentication failed"))
+		})
+
+		It("errors if no secret is available", func() {
+			token, err := cp.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			secrets = nil
+			_, err = cp.NewToken([]byte("foobar"))
+			Expect(err).To(MatchError(errNoCookieSecret))
+			_, err = cp.DecodeToken(token)
+			Expect(err).To(MatchError(errNoCookieSecret))
+		})
+	})
 })
@@ -1,13 +1,16 @@
 package handshake
 
 import (
-	"crypto/aes"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qtls"
 )
@@ -55,6 +58,8 @@ type cryptoSetup struct {
 	tlsConf *qtls.Config
 	conn    *qtls.Conn
 
+	handshakeState uint32 // a HandshakeState, to be used as an atomic
+
 	messageChan chan []byte
 
 	readEncLevel  protocol.EncryptionLevel
@@ -92,9 +97,20 @@ type cryptoSetup struct {
 	sealer       Sealer
 	// TODO: add a 1-RTT stream (used for session tickets)
 
+	// newOneRTTOpener creates additional 1-RTT openers, see NewOneRTTOpener
+	newOneRTTOpenerMutex sync.Mutex
+	newOneRTTOpener      func() Opener
+
+	// secrets are the traffic secrets passed to us by qtls, and the keys that are kept in memory.
+	// They are erased by Close.
+	secrets [][]byte
+
 	receivedWriteKey chan struct{}
 	receivedReadKey  chan struct{}
 
+	// limiter limits the number of ClientHellos processed in parallel (only used by the server)
+	limiter *Limiter
+
 	logger utils.Logger
 
 	perspective protocol.Perspective
@@ -121,7 +137,7 @@ func NewCryptoSetupClient(
 		connID,
 		chtp.Marshal(),
 		handleParams,
-		tlsConf,
+		tlsConfigToQtlsConfig(tlsConf),
 		logger,
 		protocol.PerspectiveClient,
 	)
@@ -140,7 +156,9 @@ func NewCryptoSetupServer(
 	connID protocol.ConnectionID,
 	eetp *EncryptedExtensionsTransportParameters,
 	handleParams func([]byte),
-	tlsConf *tls.Config,
+	tlsConf *ServerConfigCache,
+	acceptClientHello func(*ClientHelloInfo) bool,
+	limiter *Limiter,
 	logger utils.Logger,
 ) (CryptoSetup, error) {
 	cs, _, err := newCryptoSetup(
@@ -150,13 +168,27 @@ func NewCryptoSetupServer(
 		connID,
 		eetp.Marshal(),
 		handleParams,
-		tlsConf,
+		tlsConf.get(),
 		logger,
 		protocol.PerspectiveServer,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if acceptClientHello != nil {
+		cs.tlsConf.GetConfigForClient = func(chi *qtls.ClientHelloInfo) (*qtls.Config, error) {
+			if !acceptClientHello(&ClientHelloInfo{
+				ServerName:        chi.ServerName,
+				SupportedProtos:   chi.SupportedProtos,
+				SupportedVersions: chi.SupportedVersions,
+				CipherSuites:      chi.CipherSuites,
+			}) {
+				return nil, qerr.Error(qerr.HandshakeFailed, "ClientHello rejected")
+			}
+			return nil, nil
+		}
+	}
+	cs.limiter = limiter
 	cs.conn = qtls.Server(nil, cs.tlsConf)
 	return cs, nil
 }
@@ -168,7 +200,7 @@ func newCryptoSetup(
 	connID protocol.ConnectionID,
 	paramBytes []byte, // the marshaled transport parameters
 	handleParams func([]byte),
-	tlsConf *tls.Config,
+	qtlsConf *qtls.Config,
 	logger utils.Logger,
 	perspective protocol.Perspective,
 ) (*cryptoSetup, <-chan struct{} /* ClientHello written */, error) {
@@ -198,7 +230,6 @@ func newCryptoSetup(
 		receivedWriteKey:       make(chan struct{}),
 		closeChan:              make(chan struct{}),
 	}
-	qtlsConf := tlsConfigToQtlsConfig(tlsConf)
 	qtlsConf.AlternativeRecordLayer = cs
 	qtlsConf.GetExtensions = extHandler.GetExtensions
 	qtlsConf.ReceivedExtensions = extHandler.ReceivedExtensions
@@ -222,6 +253,13 @@ func (h *cryptoSetup) RunHandshake() error {
 	handshakeComplete := make(chan struct{})
 	go func() {
 		defer close(h.handshakeDone)
+		defer func() {
+			// Don't crash the process if qtls panics while processing a malformed message.
+			if e := recover(); e != nil {
+				h.logger.Errorf("Recovered from a panic during the handshake: %v\n%s", e, debug.Stack())
+				handshakeErrChan <- qerr.Error(qerr.InternalError, fmt.Sprintf("panic during the handshake: %v", e))
+			}
+		}()
 		if err := h.conn.Handshake(); err != nil {
 			handshakeErrChan <- err
 			return
@@ -236,6 +274,7 @@ func (h *cryptoSetup) RunHandshake() error {
 		<-handshakeErrChan
 		return errors.New("Handshake aborted")
 	case <-handshakeComplete: // return when the handshake is done
+		h.setHandshakeState(HandshakeStateComplete)
 		return nil
 	case err := <-handshakeErrChan:
 		// if handleMessageFor{server,client} are waiting for some qtls action, make them return
@@ -252,10 +291,29 @@ func (h *cryptoSetup) RunHandshake() error {
 	}
 }
 
+// Close aborts the handshake, and erases all key material.
+// The sealers and openers must not be used after Close was called.
 func (h *cryptoSetup) Close() error {
 	close(h.closeChan)
 	// wait until qtls.Handshake() actually returned
 	<-h.handshakeDone
+
+	h.newOneRTTOpenerMutex.Lock()
+	h.newOneRTTOpener = nil
+	h.newOneRTTOpenerMutex.Unlock()
+	// The traffic secrets are slices owned by qtls.
+	// Erasing them also erases the copies that qtls keeps for the lifetime of the connection.
+	for _, secret := range h.secrets {
+		zeroize(secret)
+	}
+	h.secrets = nil
+	for _, v := range []interface{}{
+		h.initialSealer, h.initialOpener,
+		h.handshakeSealer, h.handshakeOpener,
+		h.sealer, h.opener,
+	} {
+		zeroizeIfPossible(v)
+	}
 	return nil
 }
 
@@ -269,6 +327,22 @@ func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLev
 		h.messageErrChan <- err
 		return false
 	}
+	switch msgType {
+	case typeClientHello, typeServerHello:
+		h.setHandshakeState(HandshakeStateReceivedHello)
+	case typeCertificate:
+		h.setHandshakeState(HandshakeStateReceivedCertificate)
+	case typeFinished:
+		h.setHandshakeState(HandshakeStateReceivedFinished)
+	}
+	if msgType == typeClientHello && h.perspective == protocol.PerspectiveServer {
+		// Processing the ClientHello is the expensive part of the handshake for the server:
+		// It involves signing the certificate and deriving the handshake and 1-RTT keys.
+		if !h.limiter.acquire(h.closeChan) {
+			return false
+		}
+		defer h.limiter.release()
+	}
 	h.messageChan <- data
 	switch h.perspective {
 	case protocol.PerspectiveClient:
@@ -280,6 +354,14 @@ func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLev
 	}
 }
 
+func (h *cryptoSetup) setHandshakeState(s HandshakeState) {
+	atomic.StoreUint32(&h.handshakeState, uint32(s))
+}
+
+func (h *cryptoSetup) HandshakeState() HandshakeState {
+	return HandshakeState(atomic.LoadUint32(&h.handshakeState))
+}
+
 func (h *cryptoSetup) checkEncryptionLevel(msgType messageType, encLevel protocol.EncryptionLevel) error {
 	var expected protocol.EncryptionLevel
 	switch msgType {
@@ -408,19 +490,41 @@ func (h *cryptoSetup) SetReadKey(suite *qtls.CipherSuite, trafficSecret []byte)
 	key := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic key", suite.KeyLen())
 	iv := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic iv", suite.IVLen())
 	hpKey := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic hp", suite.KeyLen())
-	hpDecrypter, err := aes.NewCipher(hpKey)
+	hpDecrypter, err := newHeaderProtector(suite, hpKey)
 	if err != nil {
-		panic(fmt.Sprintf("error creating new AES cipher: %s", err))
+		panic(fmt.Sprintf("error creating header protection cipher: %s", err))
 	}
+	zeroize(hpKey)
+	aead, err := newAEAD(suite, key, iv)
+	if err != nil {
+		panic(fmt.Sprintf("error creating AEAD: %s", err))
+	}
+	h.secrets = append(h.secrets, trafficSecret)
 
 	switch h.readEncLevel {
 	case protocol.EncryptionInitial:
 		h.readEncLevel = protocol.EncryptionHandshake
-		h.handshakeOpener = newOpener(suite.AEAD(key, iv), hpDecrypter, false)
+		h.handshakeOpener = newOpener(aead, hpDecrypter, false)
+		// The AEAD copies the key and the IV.
+		zeroize(key)
+		zeroize(iv)
 		h.logger.Debugf("Installed Handshake Read keys")
 	case protocol.EncryptionHandshake:
 		h.readEncLevel = protocol.Encryption1RTT
-		h.opener = newOpener(suite.AEAD(key, iv), hpDecrypter, true)
+		h.opener = newOpener(aead, hpDecrypter, true)
+		// The header protection cipher doesn't keep any state, so it can be shared between openers.
+		// The AEAD can't, it uses a buffer for the nonce.
+		h.newOneRTTOpenerMutex.Lock()
+		h.newOneRTTOpener = func() Opener {
+			aead, err := newAEAD(suite, key, iv)
+			if err != nil {
+				panic(fmt.Sprintf("error creating AEAD: %s", err))
+			}
+			return newOpener(aead, hpDecrypter, true)
+		}
+		h.newOneRTTOpenerMutex.Unlock()
+		// The key and the IV are needed to create more openers.
+		h.secrets = append(h.secrets, key, iv)
 		h.logger.Debugf("Installed 1-RTT Read keys")
 	default:
 		panic("unexpected read encryption level")
@@ -432,23 +536,32 @@ func (h *cryptoSetup) SetWriteKey(suite *qtls.CipherSuite, trafficSecret []byte)
 	key := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic key", suite.KeyLen())
 	iv := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic iv", suite.IVLen())
 	hpKey := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic hp", suite.KeyLen())
-	hpEncrypter, err := aes.NewCipher(hpKey)
+	hpEncrypter, err := newHeaderProtector(suite, hpKey)
+	if err != nil {
+		panic(fmt.Sprintf("error creating header protection cipher: %s", err))
+	}
+	zeroize(hpKey)
+	aead, err := newAEAD(suite, key, iv)
 	if err != nil {
-		panic(fmt.Sprintf("error creating new AES cipher: %s", err))
+		panic(fmt.Sprintf("error creating AEAD: %s", err))
 	}
+	h.secrets = append(h.secrets, trafficSecret)
 
 	switch h.writeEncLevel {
 	case protocol.EncryptionInitial:
 		h.writeEncLevel = protocol.EncryptionHandshake
-		h.handshakeSealer = newSealer(suite.AEAD(key, iv), hpEncrypter, false)
+		h.handshakeSealer = newSealer(aead, hpEncrypter, false)
 		h.logger.Debugf("Installed Handshake Write keys")
 	case protocol.EncryptionHandshake:
 		h.writeEncLevel = protocol.Encryption1RTT
-		h.sealer = newSealer(suite.AEAD(key, iv), hpEncrypter, true)
+		h.sealer = newSealer(aead, hpEncrypter, true)
 		h.logger.Debugf("Installed 1-RTT Write keys")
 	default:
 		panic("unexpected write encryption level")
 	}
+	// The AEAD copies the key and the IV.
+	zeroize(key)
+	zeroize(iv)
 	h.receivedWriteKey <- struct{}{}
 }
 
@@ -522,11 +635,21 @@ func (h *cryptoSetup) GetOpener(level protocol.EncryptionLevel) (Opener, error)
 	}
 }
 
+func (h *cryptoSetup) NewOneRTTOpener() (Opener, error) {
+	h.newOneRTTOpenerMutex.Lock()
+	defer h.newOneRTTOpenerMutex.Unlock()
+	if h.newOneRTTOpener == nil {
+		return nil, ErrOpenerNotYetAvailable
+	}
+	return h.newOneRTTOpener(), nil
+}
+
 func (h *cryptoSetup) ConnectionState() ConnectionState {
 	connState := h.conn.ConnectionState()
 	return ConnectionState{
 		HandshakeComplete: connState.HandshakeComplete,
 		ServerName:        connState.ServerName,
 		PeerCertificates:  connState.PeerCertificates,
+		CipherSuite:       connState.CipherSuite,
 	}
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/testdata"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qtls"
@@ -77,7 +78,9 @@ var _ = Describe("Crypto Setup TLS", func() {
 				SupportedVersions: []protocol.VersionNumber{protocol.VersionTLS},
 			},
 			func([]byte) {},
-			testdata.GetTLSConfig(),
+			NewServerConfigCache(testdata.GetTLSConfig()),
+			nil,
+			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
 		Expect(err).ToNot(HaveOccurred())
@@ -96,6 +99,28 @@ var _ = Describe("Crypto Setup TLS", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("doesn't create 1-RTT openers before the 1-RTT keys are available", func() {
+		_, sInitialStream, sHandshakeStream := initStreams()
+		server, err := NewCryptoSetupServer(
+			sInitialStream,
+			sHandshakeStream,
+			ioutil.Discard,
+			protocol.ConnectionID{},
+			&EncryptedExtensionsTransportParameters{
+				NegotiatedVersion: protocol.VersionTLS,
+				SupportedVersions: []protocol.VersionNumber{protocol.VersionTLS},
+			},
+			func([]byte) {},
+			NewServerConfigCache(testdata.GetTLSConfig()),
+			nil,
+			nil,
+			utils.DefaultLogger.WithPrefix("server"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = server.NewOneRTTOpener()
+		Expect(err).To(MatchError(ErrOpenerNotYetAvailable))
+	})
+
 	It("returns Handshake() when handling a message fails", func() {
 		_, sInitialStream, sHandshakeStream := initStreams()
 		server, err := NewCryptoSetupServer(
@@ -108,7 +133,9 @@ var _ = Describe("Crypto Setup TLS", func() {
 				SupportedVersions: []protocol.VersionNumber{protocol.VersionTLS},
 			},
 			func([]byte) {},
-			testdata.GetTLSConfig(),
+			NewServerConfigCache(testdata.GetTLSConfig()),
+			nil,
+			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
 		Expect(err).ToNot(HaveOccurred())
@@ -138,7 +165,9 @@ var _ = Describe("Crypto Setup TLS", func() {
 				SupportedVersions: []protocol.VersionNumber{protocol.VersionTLS},
 			},
 			func([]byte) {},
-			testdata.GetTLSConfig(),
+			NewServerConfigCache(testdata.GetTLSConfig()),
+			nil,
+			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
 		Expect(err).ToNot(HaveOccurred())
@@ -154,6 +183,120 @@ var _ = Describe("Crypto Setup TLS", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("rejects ClientHellos", func() {
+		cChunkChan, cInitialStream, cHandshakeStream := initStreams()
+		client, _, err := NewCryptoSetupClient(
+			cInitialStream,
+			cHandshakeStream,
+			ioutil.Discard,
+			protocol.ConnectionID{},
+			&ClientHelloTransportParameters{
+				InitialVersion: protocol.VersionTLS,
+			},
+			func([]byte) {},
+			clientConf,
+			utils.DefaultLogger.WithPrefix("client"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		go client.RunHandshake()
+		defer client.Close()
+
+		_, sInitialStream, sHandshakeStream := initStreams()
+		var chi *ClientHelloInfo
+		server, err := NewCryptoSetupServer(
+			sInitialStream,
+			sHandshakeStream,
+			ioutil.Discard,
+			protocol.ConnectionID{},
+			&EncryptedExtensionsTransportParameters{
+				NegotiatedVersion: protocol.VersionTLS,
+				SupportedVersions: []protocol.VersionNumber{protocol.VersionTLS},
+			},
+			func([]byte) {},
+			NewServerConfigCache(testdata.GetTLSConfig()),
+			func(info *ClientHelloInfo) bool {
+				chi = info
+				return false
+			},
+			nil,
+			utils.DefaultLogger.WithPrefix("server"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			err := server.RunHandshake()
+			Expect(err).To(MatchError(qerr.Error(qerr.HandshakeFailed, "ClientHello rejected")))
+			close(done)
+		}()
+
+		var c chunk
+		Eventually(cChunkChan).Should(Receive(&c))
+		Expect(c.encLevel).To(Equal(protocol.EncryptionInitial))
+		server.HandleMessage(c.data, c.encLevel)
+		Eventually(done).Should(BeClosed())
+		Expect(chi).ToNot(BeNil())
+		Expect(chi.ServerName).To(Equal("localhost"))
+		Expect(chi.SupportedVersions).To(ContainElement(uint16(qtls.VersionTLS13)))
+		Expect(chi.CipherSuites).ToNot(BeEmpty())
+	})
+
+	It("waits for the limiter before processing the ClientHello", func() {
+		cChunkChan, cInitialStream, cHandshakeStream := initStreams()
+		client, _, err := NewCryptoSetupClient(
+			cInitialStream,
+			cHandshakeStream,
+			ioutil.Discard,
+			protocol.ConnectionID{},
+			&ClientHelloTransportParameters{
+				InitialVersion: protocol.VersionTLS,
+			},
+			func([]byte) {},
+			clientConf,
+			utils.DefaultLogger.WithPrefix("client"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		go client.RunHandshake()
+		defer client.Close()
+
+		limiter := NewLimiter(1)
+		Expect(limiter.acquire(nil)).To(BeTrue())
+		_, sInitialStream, sHandshakeStream := initStreams()
+		server, err := NewCryptoSetupServer(
+			sInitialStream,
+			sHandshakeStream,
+			ioutil.Discard,
+			protocol.ConnectionID{},
+			&EncryptedExtensionsTransportParameters{
+				NegotiatedVersion: protocol.VersionTLS,
+				SupportedVersions: []protocol.VersionNumber{protocol.VersionTLS},
+			},
+			func([]byte) {},
+			NewServerConfigCache(testdata.GetTLSConfig()),
+			func(*ClientHelloInfo) bool { return false },
+			limiter,
+			utils.DefaultLogger.WithPrefix("server"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			err := server.RunHandshake()
+			Expect(err).To(MatchError(qerr.Error(qerr.HandshakeFailed, "ClientHello rejected")))
+			close(done)
+		}()
+
+		var c chunk
+		Eventually(cChunkChan).Should(Receive(&c))
+		go server.HandleMessage(c.data, c.encLevel)
+		Consistently(done).ShouldNot(BeClosed())
+		limiter.release()
+		Eventually(done).Should(BeClosed())
+		Eventually(limiter.InProgress).Should(BeZero())
+	})
+
 	Context("doing the handshake", func() {
 		generateCert := func() tls.Certificate {
 			priv, err := rsa.GenerateKey(rand.Reader, 2048)
@@ -235,7 +378,9 @@ var _ = Describe("Crypto Setup TLS", func() {
 					Parameters:        TransportParameters{StatelessResetToken: bytes.Repeat([]byte{42}, 16)},
 				},
 				func([]byte) {},
-				serverConf,
+				NewServerConfigCache(serverConf),
+				nil,
+				nil,
 				utils.DefaultLogger.WithPrefix("server"),
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -323,7 +468,9 @@ var _ = Describe("Crypto Setup TLS", func() {
 				protocol.ConnectionID{},
 				&EncryptedExtensionsTransportParameters{Parameters: *sTransportParameters},
 				func(p []byte) { cTransportParametersRcvd = p },
-				testdata.GetTLSConfig(),
+				NewServerConfigCache(testdata.GetTLSConfig()),
+				nil,
+				nil,
 				utils.DefaultLogger.WithPrefix("server"),
 			)
 			Expect(err).ToNot(HaveOccurred())
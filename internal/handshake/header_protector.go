@@ -0,0 +1,102 @@
+package handshake
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/marten-seemann/qtls"
+)
+
+// newHeaderProtector creates the cipher used for header protection.
+// The header protection algorithm is determined by the AEAD of the cipher suite.
+// qtls doesn't expose the ID of the cipher suite, but for the TLS 1.3 cipher suites supported by qtls,
+// the suite is uniquely identified by the key length and the hash function:
+// TLS_CHACHA20_POLY1305_SHA256 is the only suite using a 32 byte key with SHA-256.
+func newHeaderProtector(suite *qtls.CipherSuite, hpKey []byte) (cipher.Block, error) {
+	if suite.KeyLen() == 32 && suite.Hash() == crypto.SHA256 {
+		return newChaChaHeaderProtector(hpKey)
+	}
+	return aes.NewCipher(hpKey)
+}
+
+const chachaHeaderProtectorSampleSize = 16
+
+// chachaHeaderProtector implements ChaCha20 header protection.
+// It satisfies the cipher.Block interface, such that it can be used in the same way as AES:
+// "encrypting" the sample returns the header protection mask.
+type chachaHeaderProtector struct {
+	key [8]uint32
+}
+
+var _ cipher.Block = &chachaHeaderProtector{}
+
+func newChaChaHeaderProtector(key []byte) (*chachaHeaderProtector, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid ChaCha20 header protection key length: %d", len(key))
+	}
+	hp := &chachaHeaderProtector{}
+	for i := range hp.key {
+		hp.key[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	return hp, nil
+}
+
+func (hp *chachaHeaderProtector) BlockSize() int { return chachaHeaderProtectorSampleSize }
+
+func (hp *chachaHeaderProtector) zeroize() {
+	for i := range hp.key {
+		hp.key[i] = 0
+	}
+}
+
+// Encrypt computes the header protection mask for the sample in src.
+// The first 4 bytes of the sample are the block counter, the remaining 12 bytes the nonce.
+// The mask is the ChaCha20 key stream for that counter and nonce.
+func (hp *chachaHeaderProtector) Encrypt(dst, src []byte) {
+	if len(src) != chachaHeaderProtectorSampleSize || len(dst) < chachaHeaderProtectorSampleSize {
+		panic("invalid sample size")
+	}
+	var state [16]uint32
+	state[0], state[1], state[2], state[3] = 0x61707865, 0x3320646e, 0x79622d32, 0x6b206574
+	copy(state[4:12], hp.key[:])
+	for i := 0; i < 4; i++ {
+		state[12+i] = binary.LittleEndian.Uint32(src[4*i:])
+	}
+	x := state
+	for i := 0; i < 10; i++ {
+		// column rounds
+		chachaQuarterRound(&x, 0, 4, 8, 12)
+		chachaQuarterRound(&x, 1, 5, 9, 13)
+		chachaQuarterRound(&x, 2, 6, 10, 14)
+		chachaQuarterRound(&x, 3, 7, 11, 15)
+		// diagonal rounds
+		chachaQuarterRound(&x, 0, 5, 10, 15)
+		chachaQuarterRound(&x, 1, 6, 11, 12)
+		chachaQuarterRound(&x, 2, 7, 8, 13)
+		chachaQuarterRound(&x, 3, 4, 9, 14)
+	}
+	// only the first 5 bytes of the mask are used, but the caller expects a full block
+	for i := 0; i < chachaHeaderProtectorSampleSize/4; i++ {
+		binary.LittleEndian.PutUint32(dst[4*i:], x[i]+state[i])
+	}
+}
+
+// Decrypt is not needed for header protection.
+func (hp *chachaHeaderProtector) Decrypt(dst, src []byte) {
+	panic("chachaHeaderProtector doesn't implement Decrypt")
+}
+
+func chachaQuarterRound(x *[16]uint32, a, b, c, d int) {
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+}
@@ -0,0 +1,87 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChaCha20 header protection", func() {
+	decodeHex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		Expect(err).ToNot(HaveOccurred())
+		return b
+	}
+
+	It("rejects keys with the wrong length", func() {
+		_, err := newChaChaHeaderProtector(make([]byte, 16))
+		Expect(err).To(MatchError("invalid ChaCha20 header protection key length: 16"))
+	})
+
+	// test vector from RFC 7539, section 2.3.2
+	It("computes the ChaCha20 key stream", func() {
+		hp, err := newChaChaHeaderProtector(decodeHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"))
+		Expect(err).ToNot(HaveOccurred())
+		mask := make([]byte, hp.BlockSize())
+		hp.Encrypt(mask, decodeHex("01000000"+"000000090000004a00000000"))
+		Expect(mask).To(Equal(decodeHex("10f1e7e4d13b5915500fdd1fa32071c4")))
+	})
+
+	// test vector from the QUIC-TLS specification, ChaCha20-Poly1305 short header packet
+	It("computes the header protection mask", func() {
+		hp, err := newChaChaHeaderProtector(decodeHex("25a282b9e82f06f21f488917a4fc8f1b73573685608597d0efcb076b0ab7a7a4"))
+		Expect(err).ToNot(HaveOccurred())
+		mask := make([]byte, hp.BlockSize())
+		hp.Encrypt(mask, decodeHex("5e5cd55c41f69080575d7999c25a5bfb"))
+		Expect(mask[:5]).To(Equal(decodeHex("aefefe7d03")))
+	})
+
+	It("protects and unprotects headers", func() {
+		key := make([]byte, 16)
+		rand.Read(key)
+		block, err := aes.NewCipher(key)
+		Expect(err).ToNot(HaveOccurred())
+		hpKey := make([]byte, 32)
+		rand.Read(hpKey)
+		hp, err := newChaChaHeaderProtector(hpKey)
+		Expect(err).ToNot(HaveOccurred())
+		aead, err := cipher.NewGCM(block)
+		Expect(err).ToNot(HaveOccurred())
+		sealer := newSealer(aead, hp, true)
+		opener := newOpener(aead, hp, true)
+
+		sample := make([]byte, 16)
+		rand.Read(sample)
+		header := []byte{0xb5, 1, 2, 3, 4}
+		sealer.EncryptHeader(sample, &header[0], header[1:])
+		Expect(header).ToNot(Equal([]byte{0xb5, 1, 2, 3, 4}))
+		opener.DecryptHeader(sample, &header[0], header[1:])
+		Expect(header).To(Equal([]byte{0xb5, 1, 2, 3, 4}))
+	})
+	It("erases the keys when zeroizing sealers and openers", func() {
+		hp, err := newChaChaHeaderProtector(bytes.Repeat([]byte{0x42}, 32))
+		Expect(err).ToNot(HaveOccurred())
+		aead, err := chacha20poly1305.New(make([]byte, 32))
+		Expect(err).ToNot(HaveOccurred())
+		iv := bytes.Repeat([]byte{0x13}, 12)
+		sealer := newSealer(&xorNonceAEAD{aead: aead, nonceMask: iv}, hp, true)
+		zeroizeIfPossible(sealer)
+		Expect(hp.key).To(Equal([8]uint32{}))
+		Expect(iv).To(Equal(make([]byte, 12)))
+
+		hp, err = newChaChaHeaderProtector(bytes.Repeat([]byte{0x42}, 32))
+		Expect(err).ToNot(HaveOccurred())
+		iv = bytes.Repeat([]byte{0x13}, 12)
+		opener := newOpener(&xorNonceAEAD{aead: aead, nonceMask: iv}, hp, true)
+		zeroizeIfPossible(opener)
+		Expect(hp.key).To(Equal([8]uint32{}))
+		Expect(iv).To(Equal(make([]byte, 12)))
+	})
+})
@@ -2,7 +2,9 @@ package handshake
 
 import (
 	"crypto/x509"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/marten-seemann/qtls"
@@ -40,6 +42,57 @@ type CryptoSetup interface {
 	GetSealer() (protocol.EncryptionLevel, Sealer)
 	GetSealerWithEncryptionLevel(protocol.EncryptionLevel) (Sealer, error)
 	GetOpener(protocol.EncryptionLevel) (Opener, error)
+	// NewOneRTTOpener creates a new opener for 1-RTT packets.
+	// Other than the opener returned by GetOpener, it can be used concurrently with other openers.
+	NewOneRTTOpener() (Opener, error)
+	// HandshakeState returns how far the handshake progressed.
+	// It can be called concurrently with all other methods.
+	HandshakeState() HandshakeState
+}
+
+// HandshakeState is the progress of the TLS handshake, from the perspective of the local endpoint.
+type HandshakeState uint8
+
+const (
+	// HandshakeStateStarted means that no handshake message was received from the peer yet.
+	// This is usually caused by the peer not being reachable.
+	HandshakeStateStarted HandshakeState = iota
+	// HandshakeStateReceivedHello means that the ClientHello (or ServerHello) was received.
+	HandshakeStateReceivedHello
+	// HandshakeStateReceivedCertificate means that the peer's certificate was received.
+	HandshakeStateReceivedCertificate
+	// HandshakeStateReceivedFinished means that the peer's Finished message was received.
+	HandshakeStateReceivedFinished
+	// HandshakeStateComplete means that the handshake completed.
+	HandshakeStateComplete
+)
+
+func (s HandshakeState) String() string {
+	switch s {
+	case HandshakeStateStarted:
+		return "started"
+	case HandshakeStateReceivedHello:
+		return "received Hello"
+	case HandshakeStateReceivedCertificate:
+		return "received Certificate"
+	case HandshakeStateReceivedFinished:
+		return "received Finished"
+	case HandshakeStateComplete:
+		return "complete"
+	default:
+		return fmt.Sprintf("unknown handshake state: %d", s)
+	}
+}
+
+// ClientHelloInfo contains information from a ClientHello message.
+// Warning: This API should not be considered stable and might change soon.
+type ClientHelloInfo struct {
+	ServerName        string   // server name requested by the client (SNI), if any
+	SupportedProtos   []string // application protocols offered by the client (ALPN), if any
+	SupportedVersions []uint16 // TLS versions offered by the client
+	CipherSuites      []uint16 // cipher suites offered by the client
+
+	Version protocol.VersionNumber // QUIC version used for the connection
 }
 
 // ConnectionState records basic details about the QUIC connection.
@@ -48,4 +101,11 @@ type ConnectionState struct {
 	HandshakeComplete bool                // handshake is complete
 	ServerName        string              // server name requested by client, if any (server side only)
 	PeerCertificates  []*x509.Certificate // certificate chain presented by remote peer
+	CipherSuite       uint16              // cipher suite negotiated for the connection (e.g. tls.TLS_CHACHA20_POLY1305_SHA256)
+
+	Version                 protocol.VersionNumber   // QUIC version used for the connection
+	UsedVersionNegotiation  bool                     // whether the server sent a Version Negotiation packet (client side only)
+	ServerSupportedVersions []protocol.VersionNumber // versions the server supports, as sent in its transport parameters (client side only)
+	IdleTimeout             time.Duration            // idle timeout used for the connection, the minimum of the local and the peer's idle timeout
+	StreamCompression       bool                     // whether the experimental stream compression extension is used
 }
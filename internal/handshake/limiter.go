@@ -0,0 +1,44 @@
+package handshake
+
+// A Limiter limits the number of handshakes that are processed in parallel.
+// It is used to bound the CPU time spent on the expensive parts of the handshake
+// (signing the certificate and deriving keys), so that a burst of new connections
+// doesn't add latency to the processing of packets for established sessions.
+// All methods can be called on a nil Limiter, which doesn't impose any limit.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter creates a new Limiter that allows n handshakes to be processed in parallel.
+func NewLimiter(n int) *Limiter {
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// acquire blocks until a slot is available, or until abort is closed.
+// It returns false if it was aborted.
+func (l *Limiter) acquire(abort <-chan struct{}) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-abort:
+		return false
+	}
+}
+
+func (l *Limiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// InProgress returns the number of handshakes that are currently being processed.
+func (l *Limiter) InProgress() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
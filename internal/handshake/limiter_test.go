@@ -0,0 +1,50 @@
+package handshake
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	It("limits the number of handshakes processed in parallel", func() {
+		l := NewLimiter(2)
+		Expect(l.acquire(nil)).To(BeTrue())
+		Expect(l.acquire(nil)).To(BeTrue())
+		Expect(l.InProgress()).To(Equal(2))
+		acquired := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			Expect(l.acquire(nil)).To(BeTrue())
+			close(acquired)
+		}()
+		Consistently(acquired).ShouldNot(BeClosed())
+		l.release()
+		Eventually(acquired).Should(BeClosed())
+		Expect(l.InProgress()).To(Equal(2))
+	})
+
+	It("aborts waiting", func() {
+		l := NewLimiter(1)
+		Expect(l.acquire(nil)).To(BeTrue())
+		abort := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			Expect(l.acquire(abort)).To(BeFalse())
+			close(done)
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		close(abort)
+		Eventually(done).Should(BeClosed())
+		Expect(l.InProgress()).To(Equal(1))
+	})
+
+	It("doesn't limit anything if nil", func() {
+		var l *Limiter
+		for i := 0; i < 100; i++ {
+			Expect(l.acquire(nil)).To(BeTrue())
+		}
+		l.release()
+		Expect(l.InProgress()).To(BeZero())
+	})
+})
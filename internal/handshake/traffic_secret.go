@@ -0,0 +1,47 @@
+package handshake
+
+import (
+	"crypto"
+	"crypto/aes"
+
+	"github.com/marten-seemann/qtls"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// NewOpenersFromTrafficSecret creates Openers from a TLS 1.3 traffic secret, as written to a key log.
+// The key log doesn't contain the cipher suite that was negotiated,
+// so an Opener is returned for every cipher suite that the secret could have been derived for.
+func NewOpenersFromTrafficSecret(trafficSecret []byte, is1RTT bool) []Opener {
+	var openers []Opener
+	switch len(trafficSecret) {
+	case crypto.SHA256.Size():
+		// TLS_AES_128_GCM_SHA256
+		key, iv, hpKey := computeKeyAndIV(crypto.SHA256, trafficSecret, 16)
+		if hpDecrypter, err := aes.NewCipher(hpKey); err == nil {
+			openers = append(openers, newOpener(qtls.AEADAESGCM13(key, iv), hpDecrypter, is1RTT))
+		}
+		// TLS_CHACHA20_POLY1305_SHA256
+		key, iv, hpKey = computeKeyAndIV(crypto.SHA256, trafficSecret, 32)
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			break
+		}
+		if hpDecrypter, err := newChaChaHeaderProtector(hpKey); err == nil {
+			openers = append(openers, newOpener(newXorNonceAEAD(aead, iv), hpDecrypter, is1RTT))
+		}
+	case crypto.SHA384.Size():
+		// TLS_AES_256_GCM_SHA384
+		key, iv, hpKey := computeKeyAndIV(crypto.SHA384, trafficSecret, 32)
+		if hpDecrypter, err := aes.NewCipher(hpKey); err == nil {
+			openers = append(openers, newOpener(qtls.AEADAESGCM13(key, iv), hpDecrypter, is1RTT))
+		}
+	}
+	return openers
+}
+
+func computeKeyAndIV(hash crypto.Hash, trafficSecret []byte, keyLen int) (key, iv, hpKey []byte) {
+	key = qtls.HkdfExpandLabel(hash, trafficSecret, []byte{}, "quic key", keyLen)
+	iv = qtls.HkdfExpandLabel(hash, trafficSecret, []byte{}, "quic iv", 12)
+	hpKey = qtls.HkdfExpandLabel(hash, trafficSecret, []byte{}, "quic hp", keyLen)
+	return
+}
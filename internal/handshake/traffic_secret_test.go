@@ -0,0 +1,100 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/marten-seemann/qtls"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Openers from traffic secrets", func() {
+	connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+
+	It("returns an Opener for AES-128-GCM and ChaCha20-Poly1305 for SHA-256 secrets", func() {
+		Expect(NewOpenersFromTrafficSecret(make([]byte, 32), true)).To(HaveLen(2))
+	})
+
+	It("returns an Opener for AES-256-GCM for SHA-384 secrets", func() {
+		Expect(NewOpenersFromTrafficSecret(make([]byte, 48), true)).To(HaveLen(1))
+	})
+
+	It("doesn't return any Openers for secrets with an invalid length", func() {
+		Expect(NewOpenersFromTrafficSecret(make([]byte, 42), true)).To(BeEmpty())
+	})
+
+	It("derives the same keys as for the Initial encryption level", func() {
+		clientSecret, _ := computeSecrets(connID)
+		key, iv, hpKey := computeKeyAndIV(crypto.SHA256, clientSecret, 16)
+		initialKey, initialHPKey, initialIV := computeInitialKeyAndIV(clientSecret)
+		Expect(key).To(Equal(initialKey))
+		Expect(iv).To(Equal(initialIV))
+		Expect(hpKey).To(Equal(initialHPKey))
+	})
+
+	It("opens packets sealed with AES-128-GCM", func() {
+		sealer, _, err := NewInitialAEAD(connID, protocol.PerspectiveClient)
+		Expect(err).ToNot(HaveOccurred())
+		clientSecret, _ := computeSecrets(connID)
+		opener := NewOpenersFromTrafficSecret(clientSecret, false)[0]
+		sealed := sealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
+		opened, err := opener.Open(nil, sealed, 42, []byte("aad"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opened).To(Equal([]byte("foobar")))
+	})
+
+	It("XORs the nonce with the IV for ChaCha20-Poly1305", func() {
+		key := make([]byte, 32)
+		rand.Read(key)
+		iv := make([]byte, 12)
+		rand.Read(iv)
+		aead, err := chacha20poly1305.New(key)
+		Expect(err).ToNot(HaveOccurred())
+		xorAEAD := newXorNonceAEAD(aead, iv)
+		seq := []byte{0, 0, 0, 0, 0, 0, 0x13, 0x37}
+		nonce := make([]byte, 12)
+		copy(nonce, iv)
+		nonce[10] ^= 0x13
+		nonce[11] ^= 0x37
+		sealed := xorAEAD.Seal(nil, seq, []byte("foobar"), []byte("aad"))
+		Expect(sealed).To(Equal(aead.Seal(nil, nonce, []byte("foobar"), []byte("aad"))))
+		opened, err := xorAEAD.Open(nil, seq, sealed, []byte("aad"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opened).To(Equal([]byte("foobar")))
+	})
+
+	It("seals AES-GCM packets like qtls", func() {
+		key := make([]byte, 16)
+		rand.Read(key)
+		iv := make([]byte, 12)
+		rand.Read(iv)
+		block, err := aes.NewCipher(key)
+		Expect(err).ToNot(HaveOccurred())
+		gcm, err := cipher.NewGCM(block)
+		Expect(err).ToNot(HaveOccurred())
+		xorAEAD := newXorNonceAEAD(gcm, iv)
+		seq := []byte{0, 0, 0, 0, 0, 0, 0x13, 0x37}
+		sealed := xorAEAD.Seal(nil, seq, []byte("foobar"), []byte("aad"))
+		Expect(sealed).To(Equal(qtls.AEADAESGCM13(key, iv).Seal(nil, seq, []byte("foobar"), []byte("aad"))))
+	})
+
+	It("copies and erases the IV", func() {
+		aead, err := chacha20poly1305.New(make([]byte, 32))
+		Expect(err).ToNot(HaveOccurred())
+		iv := bytes.Repeat([]byte{0x13}, 12)
+		xorAEAD := newXorNonceAEAD(aead, iv)
+		zeroize(iv)
+		xorAEAD.Seal(nil, make([]byte, 8), []byte("foobar"), nil)
+		Expect(xorAEAD.nonceMask).To(Equal(bytes.Repeat([]byte{0x13}, 12)))
+		xorAEAD.zeroize()
+		Expect(xorAEAD.nonceMask).To(Equal(make([]byte, 12)))
+		Expect(xorAEAD.nonceBuf).To(Equal(make([]byte, 12)))
+	})
+})
@@ -44,6 +44,9 @@ var _ = Describe("Transport Parameters", func() {
 			MaxBidiStreams:                 getRandomValue(),
 			MaxUniStreams:                  getRandomValue(),
 			DisableMigration:               true,
+			EnableFEC:                      true,
+			EnableStreamCompression:        true,
+			StreamCompressionDictionaryID:  0xdecafbad,
 			StatelessResetToken:            bytes.Repeat([]byte{100}, 16),
 			OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
 			AckDelayExponent:               13,
@@ -61,6 +64,9 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxBidiStreams).To(Equal(params.MaxBidiStreams))
 		Expect(p.IdleTimeout).To(Equal(params.IdleTimeout))
 		Expect(p.DisableMigration).To(Equal(params.DisableMigration))
+		Expect(p.EnableFEC).To(BeTrue())
+		Expect(p.EnableStreamCompression).To(BeTrue())
+		Expect(p.StreamCompressionDictionaryID).To(Equal(uint32(0xdecafbad)))
 		Expect(p.StatelessResetToken).To(Equal(params.StatelessResetToken))
 		Expect(p.OriginalConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
 		Expect(p.AckDelayExponent).To(Equal(uint8(13)))
@@ -92,6 +98,32 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("wrong length for disable_migration: 6 (expected empty)"))
 	})
 
+	It("errors when enable_fec has content", func() {
+		b := &bytes.Buffer{}
+		utils.BigEndian.WriteUint16(b, uint16(enableFECParameterID))
+		utils.BigEndian.WriteUint16(b, 6)
+		b.Write([]byte("foobar"))
+		p := &TransportParameters{}
+		Expect(p.unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("wrong length for enable_fec: 6 (expected empty)"))
+	})
+
+	It("doesn't send stream_compression, if it's not enabled", func() {
+		b := &bytes.Buffer{}
+		(&TransportParameters{}).marshal(b)
+		p := &TransportParameters{}
+		Expect(p.unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.EnableStreamCompression).To(BeFalse())
+	})
+
+	It("errors when stream_compression has the wrong length", func() {
+		b := &bytes.Buffer{}
+		utils.BigEndian.WriteUint16(b, uint16(streamCompressionParameterID))
+		utils.BigEndian.WriteUint16(b, 6)
+		b.Write([]byte("foobar"))
+		p := &TransportParameters{}
+		Expect(p.unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("wrong length for stream_compression: 6 (expected 4)"))
+	})
+
 	It("errors when the ack_delay_exponenent is too large", func() {
 		b := &bytes.Buffer{}
 		(&TransportParameters{AckDelayExponent: 21}).marshal(b)
@@ -27,6 +27,10 @@ const (
 	initialMaxStreamsUniParameterID           transportParameterID = 0x9
 	ackDelayExponentParameterID               transportParameterID = 0xa
 	disableMigrationParameterID               transportParameterID = 0xc
+	// experimental forward error correction extension
+	enableFECParameterID transportParameterID = 0xfec
+	// experimental stream compression extension
+	streamCompressionParameterID transportParameterID = 0xdef1
 )
 
 // TransportParameters are parameters sent to the peer during the handshake
@@ -45,6 +49,12 @@ type TransportParameters struct {
 
 	IdleTimeout      time.Duration
 	DisableMigration bool
+	// EnableFEC says if the endpoint supports the experimental forward error correction extension
+	EnableFEC bool
+	// EnableStreamCompression says if the endpoint supports the experimental stream compression extension.
+	// StreamCompressionDictionaryID identifies the dictionary it uses (0 if it doesn't use a dictionary).
+	EnableStreamCompression       bool
+	StreamCompressionDictionaryID uint32
 
 	StatelessResetToken  []byte
 	OriginalConnectionID protocol.ConnectionID
@@ -87,6 +97,17 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 					return fmt.Errorf("wrong length for disable_migration: %d (expected empty)", paramLen)
 				}
 				p.DisableMigration = true
+			case enableFECParameterID:
+				if paramLen != 0 {
+					return fmt.Errorf("wrong length for enable_fec: %d (expected empty)", paramLen)
+				}
+				p.EnableFEC = true
+			case streamCompressionParameterID:
+				if paramLen != 4 {
+					return fmt.Errorf("wrong length for stream_compression: %d (expected 4)", paramLen)
+				}
+				p.EnableStreamCompression = true
+				p.StreamCompressionDictionaryID, _ = utils.BigEndian.ReadUint32(r)
 			case statelessResetTokenParameterID:
 				if sentBy == protocol.PerspectiveClient {
 					return errors.New("client sent a stateless_reset_token")
@@ -215,6 +236,17 @@ func (p *TransportParameters) marshal(b *bytes.Buffer) {
 		utils.BigEndian.WriteUint16(b, uint16(disableMigrationParameterID))
 		utils.BigEndian.WriteUint16(b, 0)
 	}
+	// enable_fec
+	if p.EnableFEC {
+		utils.BigEndian.WriteUint16(b, uint16(enableFECParameterID))
+		utils.BigEndian.WriteUint16(b, 0)
+	}
+	// stream_compression
+	if p.EnableStreamCompression {
+		utils.BigEndian.WriteUint16(b, uint16(streamCompressionParameterID))
+		utils.BigEndian.WriteUint16(b, 4)
+		utils.BigEndian.WriteUint32(b, p.StreamCompressionDictionaryID)
+	}
 	if len(p.StatelessResetToken) > 0 {
 		utils.BigEndian.WriteUint16(b, uint16(statelessResetTokenParameterID))
 		utils.BigEndian.WriteUint16(b, uint16(len(p.StatelessResetToken))) // should always be 16 bytes
@@ -0,0 +1,84 @@
+package handshake
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+
+	"github.com/marten-seemann/qtls"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// newAEAD creates the AEAD used for packet protection.
+// It is equivalent to suite.AEAD, but the AEAD returned by qtls keeps a copy of the IV that can't be erased.
+// The cipher suite is identified in the same way as in newHeaderProtector.
+func newAEAD(suite *qtls.CipherSuite, key, iv []byte) (cipher.AEAD, error) {
+	var aead cipher.AEAD
+	if suite.KeyLen() == 32 && suite.Hash() == crypto.SHA256 {
+		var err error
+		aead, err = chacha20poly1305.New(key)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newXorNonceAEAD(aead, iv), nil
+}
+
+// xorNonceAEAD XORs the nonce with the IV before passing it to the AEAD, as done by TLS 1.3.
+// It is used instead of the AEADs returned by qtls, since it allows erasing the IV.
+// The key is held by the AEAD implementation of the standard library, which doesn't allow erasing it.
+// Like the AEADs of qtls, it is not safe for concurrent use.
+type xorNonceAEAD struct {
+	aead      cipher.AEAD
+	nonceMask []byte
+
+	nonceBuf []byte
+}
+
+var _ cipher.AEAD = &xorNonceAEAD{}
+
+// newXorNonceAEAD creates a new xorNonceAEAD.
+// It copies the IV, so the caller can erase iv afterwards.
+func newXorNonceAEAD(aead cipher.AEAD, iv []byte) *xorNonceAEAD {
+	return &xorNonceAEAD{
+		aead:      aead,
+		nonceMask: append([]byte{}, iv...),
+	}
+}
+
+func (f *xorNonceAEAD) NonceSize() int { return 8 } // 64-bit sequence number
+func (f *xorNonceAEAD) Overhead() int  { return f.aead.Overhead() }
+
+// zeroize erases the IV.
+func (f *xorNonceAEAD) zeroize() {
+	zeroize(f.nonceMask)
+	zeroize(f.nonceBuf)
+}
+
+func (f *xorNonceAEAD) nonce(seq []byte) []byte {
+	if f.nonceBuf == nil {
+		f.nonceBuf = make([]byte, len(f.nonceMask))
+	}
+	copy(f.nonceBuf, f.nonceMask)
+	for i, b := range seq {
+		f.nonceBuf[len(f.nonceBuf)-len(seq)+i] ^= b
+	}
+	return f.nonceBuf
+}
+
+func (f *xorNonceAEAD) Seal(out, nonce, plaintext, additionalData []byte) []byte {
+	return f.aead.Seal(out, f.nonce(nonce), plaintext, additionalData)
+}
+
+func (f *xorNonceAEAD) Open(out, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return f.aead.Open(out, f.nonce(nonce), ciphertext, additionalData)
+}
@@ -0,0 +1,23 @@
+package handshake
+
+// A zeroizer holds key material that can be erased.
+// After zeroize was called, it must not be used any more.
+type zeroizer interface {
+	zeroize()
+}
+
+// zeroize overwrites b with zeros.
+func zeroize(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// zeroizeIfPossible erases the key material held by v, if v is a zeroizer.
+// Ciphers created by the standard library (e.g. the AES key schedule) don't allow erasing their state.
+// They can only be released to the garbage collector.
+func zeroizeIfPossible(v interface{}) {
+	if z, ok := v.(zeroizer); ok {
+		z.zeroize()
+	}
+}
@@ -12,6 +12,7 @@ import (
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
+	quictrace "github.com/lucas-clemente/quic-go/quictrace"
 )
 
 // MockSentPacketHandler is a mock of SentPacketHandler interface
@@ -86,6 +87,30 @@ func (mr *MockSentPacketHandlerMockRecorder) GetLowestPacketNotConfirmedAcked()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLowestPacketNotConfirmedAcked", reflect.TypeOf((*MockSentPacketHandler)(nil).GetLowestPacketNotConfirmedAcked))
 }
 
+// GetPTOCount mocks base method
+func (m *MockSentPacketHandler) GetPTOCount() uint32 {
+	ret := m.ctrl.Call(m, "GetPTOCount")
+	ret0, _ := ret[0].(uint32)
+	return ret0
+}
+
+// GetPTOCount indicates an expected call of GetPTOCount
+func (mr *MockSentPacketHandlerMockRecorder) GetPTOCount() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPTOCount", reflect.TypeOf((*MockSentPacketHandler)(nil).GetPTOCount))
+}
+
+// GetStats mocks base method
+func (m *MockSentPacketHandler) GetStats() *quictrace.TransportState {
+	ret := m.ctrl.Call(m, "GetStats")
+	ret0, _ := ret[0].(*quictrace.TransportState)
+	return ret0
+}
+
+// GetStats indicates an expected call of GetStats
+func (mr *MockSentPacketHandlerMockRecorder) GetStats() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockSentPacketHandler)(nil).GetStats))
+}
+
 // OnAlarm mocks base method
 func (m *MockSentPacketHandler) OnAlarm() error {
 	ret := m.ctrl.Call(m, "OnAlarm")
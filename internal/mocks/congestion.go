@@ -47,6 +47,30 @@ func (mr *MockSendAlgorithmMockRecorder) GetCongestionWindow() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCongestionWindow", reflect.TypeOf((*MockSendAlgorithm)(nil).GetCongestionWindow))
 }
 
+// InRecovery mocks base method
+func (m *MockSendAlgorithm) InRecovery() bool {
+	ret := m.ctrl.Call(m, "InRecovery")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// InRecovery indicates an expected call of InRecovery
+func (mr *MockSendAlgorithmMockRecorder) InRecovery() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InRecovery", reflect.TypeOf((*MockSendAlgorithm)(nil).InRecovery))
+}
+
+// InSlowStart mocks base method
+func (m *MockSendAlgorithm) InSlowStart() bool {
+	ret := m.ctrl.Call(m, "InSlowStart")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// InSlowStart indicates an expected call of InSlowStart
+func (mr *MockSendAlgorithmMockRecorder) InSlowStart() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InSlowStart", reflect.TypeOf((*MockSendAlgorithm)(nil).InSlowStart))
+}
+
 // MaybeExitSlowStart mocks base method
 func (m *MockSendAlgorithm) MaybeExitSlowStart() {
 	m.ctrl.Call(m, "MaybeExitSlowStart")
@@ -107,6 +131,16 @@ func (mr *MockSendAlgorithmMockRecorder) OnRetransmissionTimeout(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRetransmissionTimeout", reflect.TypeOf((*MockSendAlgorithm)(nil).OnRetransmissionTimeout), arg0)
 }
 
+// SetHyStartPlusPlus mocks base method
+func (m *MockSendAlgorithm) SetHyStartPlusPlus(arg0 bool) {
+	m.ctrl.Call(m, "SetHyStartPlusPlus", arg0)
+}
+
+// SetHyStartPlusPlus indicates an expected call of SetHyStartPlusPlus
+func (mr *MockSendAlgorithmMockRecorder) SetHyStartPlusPlus(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHyStartPlusPlus", reflect.TypeOf((*MockSendAlgorithm)(nil).SetHyStartPlusPlus), arg0)
+}
+
 // SetNumEmulatedConnections mocks base method
 func (m *MockSendAlgorithm) SetNumEmulatedConnections(arg0 int) {
 	m.ctrl.Call(m, "SetNumEmulatedConnections", arg0)
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -34,6 +35,16 @@ func (m *MockConnectionFlowController) EXPECT() *MockConnectionFlowControllerMoc
 	return m.recorder
 }
 
+// Abandon mocks base method
+func (m *MockConnectionFlowController) Abandon() {
+	m.ctrl.Call(m, "Abandon")
+}
+
+// Abandon indicates an expected call of Abandon
+func (mr *MockConnectionFlowControllerMockRecorder) Abandon() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abandon", reflect.TypeOf((*MockConnectionFlowController)(nil).Abandon))
+}
+
 // AddBytesRead mocks base method
 func (m *MockConnectionFlowController) AddBytesRead(arg0 protocol.ByteCount) {
 	m.ctrl.Call(m, "AddBytesRead", arg0)
@@ -54,6 +65,30 @@ func (mr *MockConnectionFlowControllerMockRecorder) AddBytesSent(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBytesSent", reflect.TypeOf((*MockConnectionFlowController)(nil).AddBytesSent), arg0)
 }
 
+// BufferedBytes mocks base method
+func (m *MockConnectionFlowController) BufferedBytes() protocol.ByteCount {
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes
+func (mr *MockConnectionFlowControllerMockRecorder) BufferedBytes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockConnectionFlowController)(nil).BufferedBytes))
+}
+
+// GetReceiveState mocks base method
+func (m *MockConnectionFlowController) GetReceiveState() flowcontrol.ReceiveState {
+	ret := m.ctrl.Call(m, "GetReceiveState")
+	ret0, _ := ret[0].(flowcontrol.ReceiveState)
+	return ret0
+}
+
+// GetReceiveState indicates an expected call of GetReceiveState
+func (mr *MockConnectionFlowControllerMockRecorder) GetReceiveState() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiveState", reflect.TypeOf((*MockConnectionFlowController)(nil).GetReceiveState))
+}
+
 // GetWindowUpdate mocks base method
 func (m *MockConnectionFlowController) GetWindowUpdate() protocol.ByteCount {
 	ret := m.ctrl.Call(m, "GetWindowUpdate")
@@ -122,6 +122,31 @@ func (mr *MockCryptoSetupMockRecorder) HandleMessage(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockCryptoSetup)(nil).HandleMessage), arg0, arg1)
 }
 
+// HandshakeState mocks base method
+func (m *MockCryptoSetup) HandshakeState() handshake.HandshakeState {
+	ret := m.ctrl.Call(m, "HandshakeState")
+	ret0, _ := ret[0].(handshake.HandshakeState)
+	return ret0
+}
+
+// HandshakeState indicates an expected call of HandshakeState
+func (mr *MockCryptoSetupMockRecorder) HandshakeState() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeState", reflect.TypeOf((*MockCryptoSetup)(nil).HandshakeState))
+}
+
+// NewOneRTTOpener mocks base method
+func (m *MockCryptoSetup) NewOneRTTOpener() (handshake.Opener, error) {
+	ret := m.ctrl.Call(m, "NewOneRTTOpener")
+	ret0, _ := ret[0].(handshake.Opener)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewOneRTTOpener indicates an expected call of NewOneRTTOpener
+func (mr *MockCryptoSetupMockRecorder) NewOneRTTOpener() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewOneRTTOpener", reflect.TypeOf((*MockCryptoSetup)(nil).NewOneRTTOpener))
+}
+
 // RunHandshake mocks base method
 func (m *MockCryptoSetup) RunHandshake() error {
 	ret := m.ctrl.Call(m, "RunHandshake")
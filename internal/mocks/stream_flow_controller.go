@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -64,6 +65,18 @@ func (mr *MockStreamFlowControllerMockRecorder) AddBytesSent(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBytesSent", reflect.TypeOf((*MockStreamFlowController)(nil).AddBytesSent), arg0)
 }
 
+// GetReceiveState mocks base method
+func (m *MockStreamFlowController) GetReceiveState() flowcontrol.ReceiveState {
+	ret := m.ctrl.Call(m, "GetReceiveState")
+	ret0, _ := ret[0].(flowcontrol.ReceiveState)
+	return ret0
+}
+
+// GetReceiveState indicates an expected call of GetReceiveState
+func (mr *MockStreamFlowControllerMockRecorder) GetReceiveState() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiveState", reflect.TypeOf((*MockStreamFlowController)(nil).GetReceiveState))
+}
+
 // GetWindowUpdate mocks base method
 func (m *MockStreamFlowController) GetWindowUpdate() protocol.ByteCount {
 	ret := m.ctrl.Call(m, "GetWindowUpdate")
@@ -101,6 +114,16 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// SetReceiveWindowSize mocks base method
+func (m *MockStreamFlowController) SetReceiveWindowSize(arg0 protocol.ByteCount) {
+	m.ctrl.Call(m, "SetReceiveWindowSize", arg0)
+}
+
+// SetReceiveWindowSize indicates an expected call of SetReceiveWindowSize
+func (mr *MockStreamFlowControllerMockRecorder) SetReceiveWindowSize(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SetReceiveWindowSize), arg0)
+}
+
 // UpdateHighestReceived mocks base method
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	ret := m.ctrl.Call(m, "UpdateHighestReceived", arg0, arg1)
@@ -16,6 +16,12 @@ const DefaultMaxCongestionWindow ByteCount = defaultMaxCongestionWindowPackets *
 // InitialCongestionWindow is the initial congestion window in QUIC packets
 const InitialCongestionWindow ByteCount = 32 * DefaultTCPMSS
 
+// DesiredReceiveBufferSize is the size of the UDP receive buffer that we try to obtain for the sockets we create
+const DesiredReceiveBufferSize = 2 * (1 << 20) // 2 MB
+
+// DesiredSendBufferSize is the size of the UDP send buffer that we try to obtain for the sockets we create
+const DesiredSendBufferSize = 2 * (1 << 20) // 2 MB
+
 // MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the session.
 const MaxUndecryptablePackets = 10
 
@@ -38,6 +44,10 @@ const DefaultMaxReceiveConnectionFlowControlWindow = 15 * (1 << 20) // 12 MB
 // WindowUpdateThreshold is the fraction of the receive window that has to be consumed before an higher offset is advertised to the client
 const WindowUpdateThreshold = 0.25
 
+// MemoryBudgetPressureThreshold is the fraction of the memory budget that has to be used before
+// receive windows are shrunk and new connection attempts are rejected.
+const MemoryBudgetPressureThreshold = 0.75
+
 // DefaultMaxIncomingStreams is the maximum number of streams that a peer may open
 const DefaultMaxIncomingStreams = 100
 
@@ -70,8 +80,9 @@ const MaxOutstandingSentPackets = 2 * defaultMaxCongestionWindowPackets
 // This value *must* be larger than MaxOutstandingSentPackets.
 const MaxTrackedSentPackets = MaxOutstandingSentPackets * 5 / 4
 
-// MaxTrackedReceivedAckRanges is the maximum number of ACK ranges tracked
-const MaxTrackedReceivedAckRanges = defaultMaxCongestionWindowPackets
+// DefaultMaxTrackedReceivedAckRanges is the default maximum number of ACK ranges tracked.
+// An ACK frame can't contain more than MaxAckFrameSize/2 ranges anyway.
+const DefaultMaxTrackedReceivedAckRanges = int(MaxAckFrameSize / 2)
 
 // MaxNonRetransmittableAcks is the maximum number of packets containing an ACK, but no retransmittable frames, that we send in a row
 const MaxNonRetransmittableAcks = 19
@@ -84,6 +95,23 @@ const MaxStreamFrameSorterGaps = 1000
 // This limits the size of the ClientHello and Certificates that can be received.
 const MaxCryptoStreamOffset = 16 * (1 << 10)
 
+// PathDegradedPTOCount is the number of consecutive PTOs after which the path is considered degraded
+const PathDegradedPTOCount = 2
+
+// PathBrokenPTOCount is the number of consecutive PTOs after which the path is considered broken
+const PathBrokenPTOCount = 5
+
+// MaxConsecutiveWriteErrors is the number of consecutive errors writing to the PacketConn after which the path is considered broken.
+// The session is then closed.
+const MaxConsecutiveWriteErrors = 5
+
+// MinRebindInterval is the minimum time between two attempts to replace the socket of a client session.
+const MinRebindInterval = time.Second
+
+// MemoryPressureIdleTime is the time without window updates after which the connection-level flow control window of a session
+// is shrunk when the memory budget is under pressure. Sessions that are actively transferring data keep their window.
+const MemoryPressureIdleTime = time.Second
+
 // MinRemoteIdleTimeout is the minimum value that we accept for the remote idle timeout
 const MinRemoteIdleTimeout = 5 * time.Second
 
@@ -93,6 +121,16 @@ const DefaultIdleTimeout = 30 * time.Second
 // DefaultHandshakeTimeout is the default timeout for a connection until the crypto handshake succeeds.
 const DefaultHandshakeTimeout = 10 * time.Second
 
+// MinSessionParkDuration is the minimum time until the next timer of a session expires for the session to be parked.
+const MinSessionParkDuration = time.Second
+
+// TimerWheelTick is the resolution of the timer wheel used for parked sessions.
+const TimerWheelTick = 50 * time.Millisecond
+
+// TimerWheelSlots is the number of slots of the timer wheel used for parked sessions.
+// Together with the TimerWheelTick, it determines the time span covered by one rotation of the wheel.
+const TimerWheelSlots = 1024
+
 // RetiredConnectionIDDeleteTimeout is the time we keep closed sessions around in order to retransmit the CONNECTION_CLOSE.
 // after this time all information about the old connection will be deleted
 const RetiredConnectionIDDeleteTimeout = 5 * time.Second
@@ -103,6 +141,9 @@ const RetiredConnectionIDDeleteTimeout = 5 * time.Second
 // 2. it reduces the head-of-line blocking, when a packet is lost
 const MinStreamFrameSize ByteCount = 128
 
+// MinCoalescedPacketSize is the minimum size that has to be left in a datagram, so that we coalesce another packet into it.
+const MinCoalescedPacketSize ByteCount = 128
+
 // MaxPostHandshakeCryptoFrameSize is the maximum size of CRYPTO frames
 // we send after the handshake completes.
 const MaxPostHandshakeCryptoFrameSize ByteCount = 1000
@@ -118,9 +159,23 @@ const MaxAckFrameSize ByteCount = 1000
 // Example: For a packet pacing delay of 20 microseconds, we would send 5 packets at once, wait for 100 microseconds, and so forth.
 const MinPacingDelay time.Duration = 100 * time.Microsecond
 
+// KernelPacingHorizon is how long in advance packets are passed to the kernel, when pacing is done by the kernel (using SO_TXTIME).
+const KernelPacingHorizon = 2 * time.Millisecond
+
 // DefaultConnectionIDLength is the connection ID length that is used for multiplexed connections
 // if no other value is configured.
 const DefaultConnectionIDLength = 4
 
 // AckDelayExponent is the ack delay exponent used when sending ACKs.
 const AckDelayExponent = 3
+
+// MaxFECGroupSize is the maximum number of packets protected by a single FEC_REPAIR frame.
+const MaxFECGroupSize = 32
+
+// FECRepairFrameMaxOverhead is the number of bytes a FEC_REPAIR frame needs in addition to the repair data.
+// Every packet protected by FEC leaves this much space (plus space for a longer packet number),
+// such that the FEC_REPAIR frame always fits into a packet.
+const FECRepairFrameMaxOverhead ByteCount = 1 /* type byte */ + 8 /* first packet number */ + 1 /* number of packets */ + 2 /* payload lengths */ + 2 /* data length */
+
+// FECReceiveWindow is the number of received packets that are kept to recover lost packets using FEC.
+const FECReceiveWindow = 2 * MaxFECGroupSize
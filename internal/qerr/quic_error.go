@@ -7,14 +7,33 @@ import (
 // ErrorCode can be used as a normal error without reason.
 type ErrorCode uint16
 
+// The range of error codes used for TLS alerts.
+const (
+	cryptoErrorBase ErrorCode = 0x100
+	cryptoErrorMax  ErrorCode = 0x1ff
+)
+
 func (e ErrorCode) Error() string {
+	if e.isCryptoError() {
+		return fmt.Sprintf("CryptoError(%#x)", uint8(e-cryptoErrorBase))
+	}
 	return e.String()
 }
 
+func (e ErrorCode) isCryptoError() bool {
+	return e >= cryptoErrorBase && e <= cryptoErrorMax
+}
+
 // A QuicError consists of an error code plus a error reason
 type QuicError struct {
 	ErrorCode    ErrorCode
 	ErrorMessage string
+
+	// isApplicationError is set if the error code was defined by the application protocol.
+	// The error code then doesn't have any meaning on the transport level.
+	isApplicationError bool
+	// remote is set if the error was sent by the peer
+	remote bool
 }
 
 // Error creates a new QuicError instance
@@ -25,12 +44,62 @@ func Error(errorCode ErrorCode, errorMessage string) *QuicError {
 	}
 }
 
+// ApplicationError creates a new QuicError for an error code defined by the application protocol.
+func ApplicationError(errorCode ErrorCode, errorMessage string) *QuicError {
+	return &QuicError{
+		ErrorCode:          errorCode,
+		ErrorMessage:       errorMessage,
+		isApplicationError: true,
+	}
+}
+
+// CryptoError creates a new QuicError for a TLS alert.
+func CryptoError(tlsAlert uint8, errorMessage string) *QuicError {
+	return Error(cryptoErrorBase+ErrorCode(tlsAlert), errorMessage)
+}
+
 func (e *QuicError) Error() string {
-	return fmt.Sprintf("%s: %s", e.ErrorCode.String(), e.ErrorMessage)
+	if e.isApplicationError {
+		return fmt.Sprintf("Application error %#x: %s", uint16(e.ErrorCode), e.ErrorMessage)
+	}
+	return fmt.Sprintf("%s: %s", e.ErrorCode.Error(), e.ErrorMessage)
+}
+
+// RemoteError returns a copy of the error, marked as sent by the peer.
+func RemoteError(e *QuicError) *QuicError {
+	err := *e
+	err.remote = true
+	return &err
+}
+
+// IsRemote says if the error was sent by the peer.
+func (e *QuicError) IsRemote() bool {
+	return e.remote
+}
+
+// IsApplicationError says if this error uses an error code defined by the application protocol.
+func (e *QuicError) IsApplicationError() bool {
+	return e.isApplicationError
+}
+
+// IsCryptoError says if this error was caused by a TLS alert.
+func (e *QuicError) IsCryptoError() bool {
+	return !e.isApplicationError && e.ErrorCode.isCryptoError()
+}
+
+// TLSAlert returns the TLS alert, if this is a crypto error.
+func (e *QuicError) TLSAlert() (uint8, bool) {
+	if !e.IsCryptoError() {
+		return 0, false
+	}
+	return uint8(e.ErrorCode - cryptoErrorBase), true
 }
 
 // Timeout says if this error is a timeout.
 func (e *QuicError) Timeout() bool {
+	if e.isApplicationError {
+		return false
+	}
 	switch e.ErrorCode {
 	case NetworkIdleTimeout,
 		HandshakeTimeout,
@@ -40,6 +109,12 @@ func (e *QuicError) Timeout() bool {
 	return false
 }
 
+// Temporary says if this error is temporary.
+// A QuicError closes the connection, so it never is.
+func (e *QuicError) Temporary() bool {
+	return false
+}
+
 // ToQuicError converts an arbitrary error to a QuicError. It leaves QuicErrors
 // unchanged, and properly handles `ErrorCode`s.
 func ToQuicError(err error) *QuicError {
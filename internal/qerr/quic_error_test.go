@@ -2,6 +2,7 @@ package qerr
 
 import (
 	"io"
+	"net"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,6 +16,61 @@ var _ = Describe("Quic error", func() {
 		})
 	})
 
+	Context("application errors", func() {
+		It("has a string representation", func() {
+			err := ApplicationError(0x42, "foobar")
+			Expect(err.Error()).To(Equal("Application error 0x42: foobar"))
+		})
+
+		It("is not a transport or crypto error", func() {
+			err := ApplicationError(NetworkIdleTimeout, "foobar")
+			Expect(err.IsApplicationError()).To(BeTrue())
+			Expect(err.IsCryptoError()).To(BeFalse())
+			Expect(err.Timeout()).To(BeFalse())
+			err = ApplicationError(0x142, "foobar")
+			Expect(err.IsCryptoError()).To(BeFalse())
+		})
+
+		It("distinguishes application from transport errors", func() {
+			Expect(Error(DecryptionFailure, "foobar").IsApplicationError()).To(BeFalse())
+		})
+	})
+
+	Context("remote errors", func() {
+		It("marks errors as sent by the peer", func() {
+			err := ApplicationError(0x42, "foobar")
+			Expect(err.IsRemote()).To(BeFalse())
+			remoteErr := RemoteError(err)
+			Expect(remoteErr.IsRemote()).To(BeTrue())
+			Expect(remoteErr.IsApplicationError()).To(BeTrue())
+			Expect(remoteErr.ErrorCode).To(Equal(err.ErrorCode))
+			Expect(remoteErr.ErrorMessage).To(Equal("foobar"))
+			// the original error is not modified
+			Expect(err.IsRemote()).To(BeFalse())
+		})
+	})
+
+	Context("crypto errors", func() {
+		It("has a string representation", func() {
+			err := CryptoError(0x2a, "bad certificate")
+			Expect(err.Error()).To(Equal("CryptoError(0x2a): bad certificate"))
+		})
+
+		It("tells the TLS alert", func() {
+			err := CryptoError(0x2a, "bad certificate")
+			Expect(err.IsCryptoError()).To(BeTrue())
+			Expect(err.IsApplicationError()).To(BeFalse())
+			alert, ok := err.TLSAlert()
+			Expect(ok).To(BeTrue())
+			Expect(alert).To(BeEquivalentTo(0x2a))
+		})
+
+		It("doesn't tell the TLS alert for other errors", func() {
+			_, ok := Error(DecryptionFailure, "foobar").TLSAlert()
+			Expect(ok).To(BeFalse())
+		})
+	})
+
 	Context("ErrorCode", func() {
 		It("works as error", func() {
 			var err error = DecryptionFailure
@@ -27,6 +83,11 @@ var _ = Describe("Quic error", func() {
 			err := Error(HandshakeTimeout, "handshake timeout")
 			Expect(err.Timeout()).Should(BeTrue())
 		})
+
+		It("is a net.Error", func() {
+			var err net.Error = Error(HandshakeTimeout, "handshake timeout")
+			Expect(err.Temporary()).To(BeFalse())
+		})
 	})
 
 	Context("ToQuicError", func() {
@@ -0,0 +1,44 @@
+package utils
+
+import "time"
+
+// A Clock is a source of time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a new timer that fires after duration d.
+	NewTimer(d time.Duration) ClockTimer
+	// AfterFunc calls f in its own goroutine after duration d.
+	// The returned timer can be used to cancel the call using its Stop method.
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+// A ClockTimer is a timer created by a Clock.
+// It behaves like a time.Timer.
+type ClockTimer interface {
+	// Chan returns the channel on which the time is delivered when the timer fires.
+	Chan() <-chan time.Time
+	// Reset changes the timer to fire after duration d.
+	Reset(d time.Duration) bool
+	// Stop prevents the timer from firing.
+	Stop() bool
+}
+
+// DefaultClock is the clock of the Go standard library.
+var DefaultClock Clock = defaultClock{}
+
+type defaultClock struct{}
+
+func (defaultClock) Now() time.Time { return time.Now() }
+
+func (defaultClock) NewTimer(d time.Duration) ClockTimer {
+	return &defaultTimer{time.NewTimer(d)}
+}
+
+func (defaultClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return &defaultTimer{time.AfterFunc(d, f)}
+}
+
+type defaultTimer struct{ *time.Timer }
+
+func (t *defaultTimer) Chan() <-chan time.Time { return t.C }
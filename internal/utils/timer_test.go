@@ -7,6 +7,11 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// A clock that runs an hour ahead of the system clock
+type offsetClock struct{ Clock }
+
+func (c offsetClock) Now() time.Time { return c.Clock.Now().Add(time.Hour) }
+
 var _ = Describe("Timer", func() {
 	const d = 10 * time.Millisecond
 
@@ -60,6 +65,14 @@ var _ = Describe("Timer", func() {
 		Consistently(t.Chan()).ShouldNot(Receive())
 	})
 
+	It("fires the timer after it was stopped by resetting it to the zero value", func() {
+		t := NewTimer()
+		t.Reset(time.Now().Add(time.Hour))
+		t.Reset(time.Time{})
+		t.Reset(time.Now().Add(-time.Millisecond))
+		Eventually(t.Chan()).Should(Receive())
+	})
+
 	It("fires the timer twice, if reset to the same deadline", func() {
 		deadline := time.Now().Add(-time.Millisecond)
 		t := NewTimer()
@@ -77,4 +90,15 @@ var _ = Describe("Timer", func() {
 		Eventually(t.Chan()).Should(Receive())
 		Consistently(t.Chan()).ShouldNot(Receive())
 	})
+
+	It("uses the clock to determine when the timer fires", func() {
+		clock := offsetClock{DefaultClock}
+		t := NewTimerWithClock(clock)
+		// this deadline has already passed, according to the clock
+		t.Reset(time.Now().Add(30 * time.Minute))
+		Eventually(t.Chan()).Should(Receive())
+		t.SetRead()
+		t.Reset(clock.Now().Add(time.Hour))
+		Consistently(t.Chan()).ShouldNot(Receive())
+	})
 })
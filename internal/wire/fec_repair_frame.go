@@ -0,0 +1,68 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A FECRepairFrame is a FEC_REPAIR frame of the experimental forward error correction extension.
+// It allows the receiver to recover a single lost packet of NumPackets consecutive packets,
+// starting at FirstPacketNumber.
+// Data is the XOR of the (zero-padded) payloads of these packets,
+// PayloadLengths is the XOR of the lengths of the payloads.
+type FECRepairFrame struct {
+	FirstPacketNumber protocol.PacketNumber
+	NumPackets        uint64
+	PayloadLengths    uint64
+	Data              []byte
+}
+
+func parseFECRepairFrame(r *bytes.Reader, _ protocol.VersionNumber) (*FECRepairFrame, error) {
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+	f := &FECRepairFrame{}
+	pn, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	f.FirstPacketNumber = protocol.PacketNumber(pn)
+	f.NumPackets, err = utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	f.PayloadLengths, err = utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	dataLen, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(r.Len()) < dataLen {
+		return nil, io.EOF
+	}
+	f.Data = make([]byte, int(dataLen))
+	if _, err := io.ReadFull(r, f.Data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FECRepairFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	b.WriteByte(0x30)
+	utils.WriteVarInt(b, uint64(f.FirstPacketNumber))
+	utils.WriteVarInt(b, f.NumPackets)
+	utils.WriteVarInt(b, f.PayloadLengths)
+	utils.WriteVarInt(b, uint64(len(f.Data)))
+	b.Write(f.Data)
+	return nil
+}
+
+// Length of a written frame
+func (f *FECRepairFrame) Length(protocol.VersionNumber) protocol.ByteCount {
+	return 1 + utils.VarIntLen(uint64(f.FirstPacketNumber)) + utils.VarIntLen(f.NumPackets) + utils.VarIntLen(f.PayloadLengths) + utils.VarIntLen(uint64(len(f.Data))) + protocol.ByteCount(len(f.Data))
+}
@@ -0,0 +1,87 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FEC_REPAIR frame", func() {
+	Context("parsing", func() {
+		It("accepts a sample frame", func() {
+			data := []byte{0x30}
+			data = append(data, encodeVarInt(0x1337)...) // first packet number
+			data = append(data, encodeVarInt(8)...)      // number of packets
+			data = append(data, encodeVarInt(0x123)...)  // payload lengths
+			data = append(data, encodeVarInt(6)...)      // data length
+			data = append(data, []byte("foobar")...)
+			b := bytes.NewReader(data)
+			f, err := parseFECRepairFrame(b, protocol.VersionWhatever)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.FirstPacketNumber).To(Equal(protocol.PacketNumber(0x1337)))
+			Expect(f.NumPackets).To(BeEquivalentTo(8))
+			Expect(f.PayloadLengths).To(BeEquivalentTo(0x123))
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x30}
+			data = append(data, encodeVarInt(0x1337)...)
+			data = append(data, encodeVarInt(8)...)
+			data = append(data, encodeVarInt(0x123)...)
+			data = append(data, encodeVarInt(6)...)
+			data = append(data, []byte("foobar")...)
+			_, err := parseFECRepairFrame(bytes.NewReader(data), protocol.VersionWhatever)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseFECRepairFrame(bytes.NewReader(data[0:i]), protocol.VersionWhatever)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("writing", func() {
+		It("writes a sample frame", func() {
+			f := &FECRepairFrame{
+				FirstPacketNumber: 0x1337,
+				NumPackets:        8,
+				PayloadLengths:    0x123,
+				Data:              []byte("foobar"),
+			}
+			b := &bytes.Buffer{}
+			Expect(f.Write(b, protocol.VersionWhatever)).To(Succeed())
+			expected := []byte{0x30}
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(8)...)
+			expected = append(expected, encodeVarInt(0x123)...)
+			expected = append(expected, encodeVarInt(6)...)
+			expected = append(expected, []byte("foobar")...)
+			Expect(b.Bytes()).To(Equal(expected))
+		})
+
+		It("has the correct length", func() {
+			f := &FECRepairFrame{
+				FirstPacketNumber: 0x1337,
+				NumPackets:        8,
+				PayloadLengths:    0x123,
+				Data:              []byte("foobar"),
+			}
+			Expect(f.Length(protocol.VersionWhatever)).To(Equal(1 + utils.VarIntLen(0x1337) + 1 + utils.VarIntLen(0x123) + 1 + 6))
+		})
+
+		It("doesn't exceed the maximum overhead", func() {
+			f := &FECRepairFrame{
+				FirstPacketNumber: 1<<62 - 1,
+				NumPackets:        protocol.MaxFECGroupSize,
+				PayloadLengths:    uint64(protocol.MaxReceivePacketSize),
+				Data:              make([]byte, protocol.MaxReceivePacketSize),
+			}
+			Expect(f.Length(protocol.VersionWhatever)).To(BeNumerically("<=", protocol.MaxReceivePacketSize+protocol.FECRepairFrameMaxOverhead))
+		})
+	})
+})
@@ -83,6 +83,8 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 		frame, err = parsePathResponseFrame(r, p.version)
 	case 0x1c, 0x1d:
 		frame, err = parseConnectionCloseFrame(r, p.version)
+	case 0x30:
+		frame, err = parseFECRepairFrame(r, p.version)
 	default:
 		err = fmt.Errorf("unknown type byte 0x%x", typeByte)
 	}
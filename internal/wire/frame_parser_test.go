@@ -126,6 +126,20 @@ var _ = Describe("Frame parsing", func() {
 		Expect(frame).To(Equal(f))
 	})
 
+	It("unpacks FEC_REPAIR frames", func() {
+		f := &FECRepairFrame{
+			FirstPacketNumber: 0x1337,
+			NumPackets:        10,
+			PayloadLengths:    0x42,
+			Data:              []byte("foobar"),
+		}
+		err := f.Write(buf, versionIETFFrames)
+		Expect(err).ToNot(HaveOccurred())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
 	It("unpacks STREAM frames", func() {
 		f := &StreamFrame{
 			StreamID: 0x42,
@@ -37,6 +37,8 @@ func LogFrame(logger utils.Logger, frame Frame, sent bool) {
 		logger.Debugf("\t%s &wire.NewConnectionIDFrame{SequenceNumber: %d, ConnectionID: %s, StatelessResetToken: %#x}", dir, f.SequenceNumber, f.ConnectionID, f.StatelessResetToken)
 	case *NewTokenFrame:
 		logger.Debugf("\t%s &wire.NewTokenFrame{Token: %#x}", dir, f.Token)
+	case *FECRepairFrame:
+		logger.Debugf("\t%s &wire.FECRepairFrame{FirstPacketNumber: %#x, NumPackets: %d, Data length: 0x%x}", dir, f.FirstPacketNumber, f.NumPackets, len(f.Data))
 	default:
 		logger.Debugf("\t%s %#v", dir, frame)
 	}
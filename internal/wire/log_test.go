@@ -102,4 +102,13 @@ var _ = Describe("Frame logging", func() {
 		}, true)
 		Expect(buf.String()).To(ContainSubstring("\t-> &wire.NewTokenFrame{Token: 0xdeadbeef"))
 	})
+
+	It("logs FEC_REPAIR frames", func() {
+		LogFrame(logger, &FECRepairFrame{
+			FirstPacketNumber: 0x1337,
+			NumPackets:        4,
+			Data:              make([]byte, 0x100),
+		}, false)
+		Expect(buf.String()).To(ContainSubstring("\t<- &wire.FECRepairFrame{FirstPacketNumber: 0x1337, NumPackets: 4, Data length: 0x100}"))
+	})
 })
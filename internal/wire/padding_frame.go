@@ -0,0 +1,22 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A PaddingFrame is a single PADDING frame.
+// It is used for packets that don't carry any other frames (e.g. cover packets).
+// When parsing, PADDING frames are skipped, so a PaddingFrame is never returned by the FrameParser.
+type PaddingFrame struct{}
+
+func (f *PaddingFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
+	b.WriteByte(0x0)
+	return nil
+}
+
+// Length of a written frame
+func (f *PaddingFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
+	return 1
+}
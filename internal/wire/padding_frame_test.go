@@ -0,0 +1,32 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PaddingFrame", func() {
+	It("writes a sample frame", func() {
+		b := &bytes.Buffer{}
+		frame := PaddingFrame{}
+		Expect(frame.Write(b, protocol.VersionWhatever)).To(Succeed())
+		Expect(b.Bytes()).To(Equal([]byte{0x0}))
+	})
+
+	It("has the correct length", func() {
+		frame := PaddingFrame{}
+		Expect(frame.Length(0)).To(Equal(protocol.ByteCount(1)))
+	})
+
+	It("is skipped by the frame parser", func() {
+		b := &bytes.Buffer{}
+		Expect((&PaddingFrame{}).Write(b, protocol.VersionWhatever)).To(Succeed())
+		Expect((&PingFrame{}).Write(b, protocol.VersionWhatever)).To(Succeed())
+		frame, err := NewFrameParser(protocol.VersionWhatever).ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&PingFrame{}))
+	})
+})
@@ -0,0 +1,19 @@
+// +build !go1.11
+
+package quic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// net.ListenConfig was added in Go 1.11.
+// Before that, there's no way to run the Control function before the socket is bound.
+func listenUDP(_ context.Context, addr string, control func(network, address string, c syscall.RawConn) error) (net.PacketConn, error) {
+	if control != nil {
+		return nil, errors.New("quic: Config.Control requires Go 1.11")
+	}
+	return net.ListenPacket("udp", addr)
+}
@@ -0,0 +1,14 @@
+// +build go1.11
+
+package quic
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+func listenUDP(ctx context.Context, addr string, control func(network, address string, c syscall.RawConn) error) (net.PacketConn, error) {
+	lc := net.ListenConfig{Control: control}
+	return lc.ListenPacket(ctx, "udp", addr)
+}
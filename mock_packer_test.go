@@ -47,6 +47,16 @@ func (mr *MockPackerMockRecorder) ChangeDestConnectionID(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeDestConnectionID", reflect.TypeOf((*MockPacker)(nil).ChangeDestConnectionID), arg0)
 }
 
+// EnableFEC mocks base method
+func (m *MockPacker) EnableFEC(arg0 int) {
+	m.ctrl.Call(m, "EnableFEC", arg0)
+}
+
+// EnableFEC indicates an expected call of EnableFEC
+func (mr *MockPackerMockRecorder) EnableFEC(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableFEC", reflect.TypeOf((*MockPacker)(nil).EnableFEC), arg0)
+}
+
 // HandleTransportParameters mocks base method
 func (m *MockPacker) HandleTransportParameters(arg0 *handshake.TransportParameters) {
 	m.ctrl.Call(m, "HandleTransportParameters", arg0)
@@ -70,6 +80,19 @@ func (mr *MockPackerMockRecorder) MaybePackAckPacket() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaybePackAckPacket", reflect.TypeOf((*MockPacker)(nil).MaybePackAckPacket))
 }
 
+// PackCoalescedPacket mocks base method
+func (m *MockPacker) PackCoalescedPacket() (*coalescedPacket, error) {
+	ret := m.ctrl.Call(m, "PackCoalescedPacket")
+	ret0, _ := ret[0].(*coalescedPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PackCoalescedPacket indicates an expected call of PackCoalescedPacket
+func (mr *MockPackerMockRecorder) PackCoalescedPacket() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackCoalescedPacket", reflect.TypeOf((*MockPacker)(nil).PackCoalescedPacket))
+}
+
 // PackConnectionClose mocks base method
 func (m *MockPacker) PackConnectionClose(arg0 *wire.ConnectionCloseFrame) (*packedPacket, error) {
 	ret := m.ctrl.Call(m, "PackConnectionClose", arg0)
@@ -83,17 +106,17 @@ func (mr *MockPackerMockRecorder) PackConnectionClose(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackConnectionClose", reflect.TypeOf((*MockPacker)(nil).PackConnectionClose), arg0)
 }
 
-// PackPacket mocks base method
-func (m *MockPacker) PackPacket() (*packedPacket, error) {
-	ret := m.ctrl.Call(m, "PackPacket")
+// PackCoverPacket mocks base method
+func (m *MockPacker) PackCoverPacket() (*packedPacket, error) {
+	ret := m.ctrl.Call(m, "PackCoverPacket")
 	ret0, _ := ret[0].(*packedPacket)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// PackPacket indicates an expected call of PackPacket
-func (mr *MockPackerMockRecorder) PackPacket() *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPacket", reflect.TypeOf((*MockPacker)(nil).PackPacket))
+// PackCoverPacket indicates an expected call of PackCoverPacket
+func (mr *MockPackerMockRecorder) PackCoverPacket() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackCoverPacket", reflect.TypeOf((*MockPacker)(nil).PackCoverPacket))
 }
 
 // PackRetransmission mocks base method
@@ -8,6 +8,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	handshake "github.com/lucas-clemente/quic-go/internal/handshake"
@@ -75,6 +76,18 @@ func (mr *MockQuicSessionMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockQuicSession)(nil).Close))
 }
 
+// CloseSync mocks base method
+func (m *MockQuicSession) CloseSync(arg0 context.Context) error {
+	ret := m.ctrl.Call(m, "CloseSync", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSync indicates an expected call of CloseSync
+func (mr *MockQuicSessionMockRecorder) CloseSync(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSync", reflect.TypeOf((*MockQuicSession)(nil).CloseSync), arg0)
+}
+
 // CloseWithError mocks base method
 func (m *MockQuicSession) CloseWithError(arg0 protocol.ApplicationErrorCode, arg1 error) error {
 	ret := m.ctrl.Call(m, "CloseWithError", arg0, arg1)
@@ -111,6 +124,30 @@ func (mr *MockQuicSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicSession)(nil).Context))
 }
 
+// FlowControlDeadlocks mocks base method
+func (m *MockQuicSession) FlowControlDeadlocks(arg0 time.Duration) []FlowControlDeadlock {
+	ret := m.ctrl.Call(m, "FlowControlDeadlocks", arg0)
+	ret0, _ := ret[0].([]FlowControlDeadlock)
+	return ret0
+}
+
+// FlowControlDeadlocks indicates an expected call of FlowControlDeadlocks
+func (mr *MockQuicSessionMockRecorder) FlowControlDeadlocks(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlDeadlocks", reflect.TypeOf((*MockQuicSession)(nil).FlowControlDeadlocks), arg0)
+}
+
+// FlowControlState mocks base method
+func (m *MockQuicSession) FlowControlState() []FlowControlState {
+	ret := m.ctrl.Call(m, "FlowControlState")
+	ret0, _ := ret[0].([]FlowControlState)
+	return ret0
+}
+
+// FlowControlState indicates an expected call of FlowControlState
+func (mr *MockQuicSessionMockRecorder) FlowControlState() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlState", reflect.TypeOf((*MockQuicSession)(nil).FlowControlState))
+}
+
 // GetVersion mocks base method
 func (m *MockQuicSession) GetVersion() protocol.VersionNumber {
 	ret := m.ctrl.Call(m, "GetVersion")
@@ -135,6 +172,18 @@ func (mr *MockQuicSessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicSession)(nil).LocalAddr))
 }
 
+// OnStream mocks base method
+func (m *MockQuicSession) OnStream(arg0 func(Stream)) error {
+	ret := m.ctrl.Call(m, "OnStream", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// OnStream indicates an expected call of OnStream
+func (mr *MockQuicSessionMockRecorder) OnStream(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStream", reflect.TypeOf((*MockQuicSession)(nil).OnStream), arg0)
+}
+
 // OpenStream mocks base method
 func (m *MockQuicSession) OpenStream() (Stream, error) {
 	ret := m.ctrl.Call(m, "OpenStream")
@@ -187,6 +236,30 @@ func (mr *MockQuicSessionMockRecorder) OpenUniStreamSync() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSync))
 }
 
+// PathHealth mocks base method
+func (m *MockQuicSession) PathHealth() PathHealth {
+	ret := m.ctrl.Call(m, "PathHealth")
+	ret0, _ := ret[0].(PathHealth)
+	return ret0
+}
+
+// PathHealth indicates an expected call of PathHealth
+func (mr *MockQuicSessionMockRecorder) PathHealth() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathHealth", reflect.TypeOf((*MockQuicSession)(nil).PathHealth))
+}
+
+// PeerTransportParameters mocks base method
+func (m *MockQuicSession) PeerTransportParameters() *TransportParameters {
+	ret := m.ctrl.Call(m, "PeerTransportParameters")
+	ret0, _ := ret[0].(*TransportParameters)
+	return ret0
+}
+
+// PeerTransportParameters indicates an expected call of PeerTransportParameters
+func (mr *MockQuicSessionMockRecorder) PeerTransportParameters() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeerTransportParameters", reflect.TypeOf((*MockQuicSession)(nil).PeerTransportParameters))
+}
+
 // RemoteAddr mocks base method
 func (m *MockQuicSession) RemoteAddr() net.Addr {
 	ret := m.ctrl.Call(m, "RemoteAddr")
@@ -199,6 +272,64 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
+// RetireKeys mocks base method
+func (m *MockQuicSession) RetireKeys() error {
+	ret := m.ctrl.Call(m, "RetireKeys")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RetireKeys indicates an expected call of RetireKeys
+func (mr *MockQuicSessionMockRecorder) RetireKeys() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetireKeys", reflect.TypeOf((*MockQuicSession)(nil).RetireKeys))
+}
+
+// SetDeadline mocks base method
+func (m *MockQuicSession) SetDeadline(arg0 time.Time) error {
+	ret := m.ctrl.Call(m, "SetDeadline", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDeadline indicates an expected call of SetDeadline
+func (mr *MockQuicSessionMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockQuicSession)(nil).SetDeadline), arg0)
+}
+
+// SetReadDeadline mocks base method
+func (m *MockQuicSession) SetReadDeadline(arg0 time.Time) error {
+	ret := m.ctrl.Call(m, "SetReadDeadline", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetReadDeadline indicates an expected call of SetReadDeadline
+func (mr *MockQuicSessionMockRecorder) SetReadDeadline(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockQuicSession)(nil).SetReadDeadline), arg0)
+}
+
+// SetStreamGroupWeight mocks base method
+func (m *MockQuicSession) SetStreamGroupWeight(arg0 string, arg1 int) {
+	m.ctrl.Call(m, "SetStreamGroupWeight", arg0, arg1)
+}
+
+// SetStreamGroupWeight indicates an expected call of SetStreamGroupWeight
+func (mr *MockQuicSessionMockRecorder) SetStreamGroupWeight(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStreamGroupWeight", reflect.TypeOf((*MockQuicSession)(nil).SetStreamGroupWeight), arg0, arg1)
+}
+
+// SetWriteDeadline mocks base method
+func (m *MockQuicSession) SetWriteDeadline(arg0 time.Time) error {
+	ret := m.ctrl.Call(m, "SetWriteDeadline", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWriteDeadline indicates an expected call of SetWriteDeadline
+func (mr *MockQuicSessionMockRecorder) SetWriteDeadline(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteDeadline", reflect.TypeOf((*MockQuicSession)(nil).SetWriteDeadline), arg0)
+}
+
 // closeForRecreating mocks base method
 func (m *MockQuicSession) closeForRecreating() protocol.PacketNumber {
 	ret := m.ctrl.Call(m, "closeForRecreating")
@@ -231,6 +362,18 @@ func (mr *MockQuicSessionMockRecorder) destroy(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "destroy", reflect.TypeOf((*MockQuicSession)(nil).destroy), arg0)
 }
 
+// getBufferedBytes mocks base method
+func (m *MockQuicSession) getBufferedBytes() protocol.ByteCount {
+	ret := m.ctrl.Call(m, "getBufferedBytes")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// getBufferedBytes indicates an expected call of getBufferedBytes
+func (mr *MockQuicSessionMockRecorder) getBufferedBytes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getBufferedBytes", reflect.TypeOf((*MockQuicSession)(nil).getBufferedBytes))
+}
+
 // handlePacket mocks base method
 func (m *MockQuicSession) handlePacket(arg0 *receivedPacket) {
 	m.ctrl.Call(m, "handlePacket", arg0)
@@ -59,6 +59,19 @@ func (mr *MockReceiveStreamIMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReceiveStreamI)(nil).Read), arg0)
 }
 
+// ReadChunk mocks base method
+func (m *MockReceiveStreamI) ReadChunk() ([]byte, error) {
+	ret := m.ctrl.Call(m, "ReadChunk")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadChunk indicates an expected call of ReadChunk
+func (mr *MockReceiveStreamIMockRecorder) ReadChunk() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadChunk", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadChunk))
+}
+
 // SetReadDeadline mocks base method
 func (m *MockReceiveStreamI) SetReadDeadline(arg0 time.Time) error {
 	ret := m.ctrl.Call(m, "SetReadDeadline", arg0)
@@ -71,6 +84,16 @@ func (mr *MockReceiveStreamIMockRecorder) SetReadDeadline(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadDeadline), arg0)
 }
 
+// SetReceiveWindow mocks base method
+func (m *MockReceiveStreamI) SetReceiveWindow(arg0 uint64) {
+	m.ctrl.Call(m, "SetReceiveWindow", arg0)
+}
+
+// SetReceiveWindow indicates an expected call of SetReceiveWindow
+func (mr *MockReceiveStreamIMockRecorder) SetReceiveWindow(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindow", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReceiveWindow), arg0)
+}
+
 // StreamID mocks base method
 func (m *MockReceiveStreamI) StreamID() protocol.StreamID {
 	ret := m.ctrl.Call(m, "StreamID")
@@ -71,6 +71,26 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// OnWriteAcked mocks base method
+func (m *MockSendStreamI) OnWriteAcked(arg0 func(uint64)) {
+	m.ctrl.Call(m, "OnWriteAcked", arg0)
+}
+
+// OnWriteAcked indicates an expected call of OnWriteAcked
+func (mr *MockSendStreamIMockRecorder) OnWriteAcked(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnWriteAcked", reflect.TypeOf((*MockSendStreamI)(nil).OnWriteAcked), arg0)
+}
+
+// SetGroup mocks base method
+func (m *MockSendStreamI) SetGroup(arg0 string) {
+	m.ctrl.Call(m, "SetGroup", arg0)
+}
+
+// SetGroup indicates an expected call of SetGroup
+func (mr *MockSendStreamIMockRecorder) SetGroup(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGroup", reflect.TypeOf((*MockSendStreamI)(nil).SetGroup), arg0)
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockSendStreamI) SetWriteDeadline(arg0 time.Time) error {
 	ret := m.ctrl.Call(m, "SetWriteDeadline", arg0)
@@ -108,6 +128,30 @@ func (mr *MockSendStreamIMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendStreamI)(nil).Write), arg0)
 }
 
+// WriteAcked mocks base method
+func (m *MockSendStreamI) WriteAcked() uint64 {
+	ret := m.ctrl.Call(m, "WriteAcked")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// WriteAcked indicates an expected call of WriteAcked
+func (mr *MockSendStreamIMockRecorder) WriteAcked() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAcked", reflect.TypeOf((*MockSendStreamI)(nil).WriteAcked))
+}
+
+// allDataAcked mocks base method
+func (m *MockSendStreamI) allDataAcked() bool {
+	ret := m.ctrl.Call(m, "allDataAcked")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// allDataAcked indicates an expected call of allDataAcked
+func (mr *MockSendStreamIMockRecorder) allDataAcked() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "allDataAcked", reflect.TypeOf((*MockSendStreamI)(nil).allDataAcked))
+}
+
 // closeForShutdown mocks base method
 func (m *MockSendStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.Call(m, "closeForShutdown", arg0)
@@ -118,6 +162,28 @@ func (mr *MockSendStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockSendStreamI)(nil).closeForShutdown), arg0)
 }
 
+// getGroup mocks base method
+func (m *MockSendStreamI) getGroup() string {
+	ret := m.ctrl.Call(m, "getGroup")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// getGroup indicates an expected call of getGroup
+func (mr *MockSendStreamIMockRecorder) getGroup() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getGroup", reflect.TypeOf((*MockSendStreamI)(nil).getGroup))
+}
+
+// handleAckedStreamFrame mocks base method
+func (m *MockSendStreamI) handleAckedStreamFrame(arg0 *wire.StreamFrame) {
+	m.ctrl.Call(m, "handleAckedStreamFrame", arg0)
+}
+
+// handleAckedStreamFrame indicates an expected call of handleAckedStreamFrame
+func (mr *MockSendStreamIMockRecorder) handleAckedStreamFrame(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleAckedStreamFrame", reflect.TypeOf((*MockSendStreamI)(nil).handleAckedStreamFrame), arg0)
+}
+
 // handleMaxStreamDataFrame mocks base method
 func (m *MockSendStreamI) handleMaxStreamDataFrame(arg0 *wire.MaxStreamDataFrame) {
 	m.ctrl.Call(m, "handleMaxStreamDataFrame", arg0)
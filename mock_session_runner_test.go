@@ -44,6 +44,18 @@ func (mr *MockSessionRunnerMockRecorder) onHandshakeComplete(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onHandshakeComplete", reflect.TypeOf((*MockSessionRunner)(nil).onHandshakeComplete), arg0)
 }
 
+// rebind mocks base method
+func (m *MockSessionRunner) rebind() error {
+	ret := m.ctrl.Call(m, "rebind")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// rebind indicates an expected call of rebind
+func (mr *MockSessionRunnerMockRecorder) rebind() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "rebind", reflect.TypeOf((*MockSessionRunner)(nil).rebind))
+}
+
 // removeConnectionID mocks base method
 func (m *MockSessionRunner) removeConnectionID(arg0 protocol.ConnectionID) {
 	m.ctrl.Call(m, "removeConnectionID", arg0)
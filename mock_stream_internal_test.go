@@ -81,6 +81,16 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// OnWriteAcked mocks base method
+func (m *MockStreamI) OnWriteAcked(arg0 func(uint64)) {
+	m.ctrl.Call(m, "OnWriteAcked", arg0)
+}
+
+// OnWriteAcked indicates an expected call of OnWriteAcked
+func (mr *MockStreamIMockRecorder) OnWriteAcked(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnWriteAcked", reflect.TypeOf((*MockStreamI)(nil).OnWriteAcked), arg0)
+}
+
 // Read mocks base method
 func (m *MockStreamI) Read(arg0 []byte) (int, error) {
 	ret := m.ctrl.Call(m, "Read", arg0)
@@ -94,6 +104,19 @@ func (mr *MockStreamIMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), arg0)
 }
 
+// ReadChunk mocks base method
+func (m *MockStreamI) ReadChunk() ([]byte, error) {
+	ret := m.ctrl.Call(m, "ReadChunk")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadChunk indicates an expected call of ReadChunk
+func (mr *MockStreamIMockRecorder) ReadChunk() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadChunk", reflect.TypeOf((*MockStreamI)(nil).ReadChunk))
+}
+
 // SetDeadline mocks base method
 func (m *MockStreamI) SetDeadline(arg0 time.Time) error {
 	ret := m.ctrl.Call(m, "SetDeadline", arg0)
@@ -106,6 +129,16 @@ func (mr *MockStreamIMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), arg0)
 }
 
+// SetGroup mocks base method
+func (m *MockStreamI) SetGroup(arg0 string) {
+	m.ctrl.Call(m, "SetGroup", arg0)
+}
+
+// SetGroup indicates an expected call of SetGroup
+func (mr *MockStreamIMockRecorder) SetGroup(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGroup", reflect.TypeOf((*MockStreamI)(nil).SetGroup), arg0)
+}
+
 // SetReadDeadline mocks base method
 func (m *MockStreamI) SetReadDeadline(arg0 time.Time) error {
 	ret := m.ctrl.Call(m, "SetReadDeadline", arg0)
@@ -118,6 +151,16 @@ func (mr *MockStreamIMockRecorder) SetReadDeadline(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStreamI)(nil).SetReadDeadline), arg0)
 }
 
+// SetReceiveWindow mocks base method
+func (m *MockStreamI) SetReceiveWindow(arg0 uint64) {
+	m.ctrl.Call(m, "SetReceiveWindow", arg0)
+}
+
+// SetReceiveWindow indicates an expected call of SetReceiveWindow
+func (mr *MockStreamIMockRecorder) SetReceiveWindow(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindow", reflect.TypeOf((*MockStreamI)(nil).SetReceiveWindow), arg0)
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockStreamI) SetWriteDeadline(arg0 time.Time) error {
 	ret := m.ctrl.Call(m, "SetWriteDeadline", arg0)
@@ -155,6 +198,30 @@ func (mr *MockStreamIMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStreamI)(nil).Write), arg0)
 }
 
+// WriteAcked mocks base method
+func (m *MockStreamI) WriteAcked() uint64 {
+	ret := m.ctrl.Call(m, "WriteAcked")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// WriteAcked indicates an expected call of WriteAcked
+func (mr *MockStreamIMockRecorder) WriteAcked() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAcked", reflect.TypeOf((*MockStreamI)(nil).WriteAcked))
+}
+
+// allDataAcked mocks base method
+func (m *MockStreamI) allDataAcked() bool {
+	ret := m.ctrl.Call(m, "allDataAcked")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// allDataAcked indicates an expected call of allDataAcked
+func (mr *MockStreamIMockRecorder) allDataAcked() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "allDataAcked", reflect.TypeOf((*MockStreamI)(nil).allDataAcked))
+}
+
 // closeForShutdown mocks base method
 func (m *MockStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.Call(m, "closeForShutdown", arg0)
@@ -165,6 +232,18 @@ func (mr *MockStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockStreamI)(nil).closeForShutdown), arg0)
 }
 
+// getGroup mocks base method
+func (m *MockStreamI) getGroup() string {
+	ret := m.ctrl.Call(m, "getGroup")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// getGroup indicates an expected call of getGroup
+func (mr *MockStreamIMockRecorder) getGroup() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getGroup", reflect.TypeOf((*MockStreamI)(nil).getGroup))
+}
+
 // getWindowUpdate mocks base method
 func (m *MockStreamI) getWindowUpdate() protocol.ByteCount {
 	ret := m.ctrl.Call(m, "getWindowUpdate")
@@ -177,6 +256,16 @@ func (mr *MockStreamIMockRecorder) getWindowUpdate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getWindowUpdate", reflect.TypeOf((*MockStreamI)(nil).getWindowUpdate))
 }
 
+// handleAckedStreamFrame mocks base method
+func (m *MockStreamI) handleAckedStreamFrame(arg0 *wire.StreamFrame) {
+	m.ctrl.Call(m, "handleAckedStreamFrame", arg0)
+}
+
+// handleAckedStreamFrame indicates an expected call of handleAckedStreamFrame
+func (mr *MockStreamIMockRecorder) handleAckedStreamFrame(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleAckedStreamFrame", reflect.TypeOf((*MockStreamI)(nil).handleAckedStreamFrame), arg0)
+}
+
 // handleMaxStreamDataFrame mocks base method
 func (m *MockStreamI) handleMaxStreamDataFrame(arg0 *wire.MaxStreamDataFrame) {
 	m.ctrl.Call(m, "handleMaxStreamDataFrame", arg0)
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	handshake "github.com/lucas-clemente/quic-go/internal/handshake"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -46,3 +47,16 @@ func (m *MockUnpacker) Unpack(arg0 *wire.Header, arg1 []byte) (*unpackedPacket,
 func (mr *MockUnpackerMockRecorder) Unpack(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unpack", reflect.TypeOf((*MockUnpacker)(nil).Unpack), arg0, arg1)
 }
+
+// UnpackShortHeader mocks base method
+func (m *MockUnpacker) UnpackShortHeader(arg0 *wire.Header, arg1 []byte, arg2 handshake.Opener) (*unpackedPacket, error) {
+	ret := m.ctrl.Call(m, "UnpackShortHeader", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*unpackedPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnpackShortHeader indicates an expected call of UnpackShortHeader
+func (mr *MockUnpackerMockRecorder) UnpackShortHeader(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpackShortHeader", reflect.TypeOf((*MockUnpacker)(nil).UnpackShortHeader), arg0, arg1, arg2)
+}
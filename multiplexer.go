@@ -14,7 +14,7 @@ var (
 )
 
 type multiplexer interface {
-	AddConn(net.PacketConn, int) (packetHandlerManager, error)
+	AddConn(net.PacketConn, int, utils.Clock) (packetHandlerManager, error)
 	RemoveConn(net.PacketConn) error
 }
 
@@ -29,7 +29,7 @@ type connMultiplexer struct {
 	mutex sync.Mutex
 
 	conns                   map[net.PacketConn]connManager
-	newPacketHandlerManager func(net.PacketConn, int, utils.Logger) packetHandlerManager // so it can be replaced in the tests
+	newPacketHandlerManager func(net.PacketConn, int, utils.Clock, utils.Logger) packetHandlerManager // so it can be replaced in the tests
 
 	logger utils.Logger
 }
@@ -47,13 +47,15 @@ func getMultiplexer() multiplexer {
 	return connMuxer
 }
 
-func (m *connMultiplexer) AddConn(c net.PacketConn, connIDLen int) (packetHandlerManager, error) {
+// AddConn adds a net.PacketConn.
+// If the connection was already added, the clock of the first call is used.
+func (m *connMultiplexer) AddConn(c net.PacketConn, connIDLen int, clock utils.Clock) (packetHandlerManager, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	p, ok := m.conns[c]
 	if !ok {
-		manager := m.newPacketHandlerManager(c, connIDLen, m.logger)
+		manager := m.newPacketHandlerManager(c, connIDLen, clock, m.logger)
 		p = connManager{connIDLen: connIDLen, manager: manager}
 		m.conns[c] = p
 	}
@@ -1,6 +1,8 @@
 package quic
 
 import (
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -8,15 +10,15 @@ import (
 var _ = Describe("Client Multiplexer", func() {
 	It("adds a new packet conn ", func() {
 		conn := newMockPacketConn()
-		_, err := getMultiplexer().AddConn(conn, 8)
+		_, err := getMultiplexer().AddConn(conn, 8, utils.DefaultClock)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
 	It("errors when adding an existing conn with a different connection ID length", func() {
 		conn := newMockPacketConn()
-		_, err := getMultiplexer().AddConn(conn, 5)
+		_, err := getMultiplexer().AddConn(conn, 5, utils.DefaultClock)
 		Expect(err).ToNot(HaveOccurred())
-		_, err = getMultiplexer().AddConn(conn, 6)
+		_, err = getMultiplexer().AddConn(conn, 6, utils.DefaultClock)
 		Expect(err).To(MatchError("cannot use 6 byte connection IDs on a connection that is already using 5 byte connction IDs"))
 	})
 
@@ -0,0 +1,128 @@
+package quic
+
+import (
+	"net"
+	"sync"
+)
+
+// A NetListenerMode determines which streams are returned as a net.Conn by a net.Listener created by NewNetListener.
+type NetListenerMode int
+
+const (
+	// NetListenerStreamPerSession returns the first stream opened by the client as a net.Conn.
+	// Other streams opened by the client are not accepted.
+	// Closing the net.Conn closes the stream, but not the session, since closing the session would discard
+	// data that was not yet acknowledged by the client. The client is expected to close the session
+	// when it is done reading from the stream. Otherwise, the session is closed when the idle timeout expires.
+	NetListenerStreamPerSession NetListenerMode = iota
+	// NetListenerStreamPerAccept returns every stream opened by the client as a net.Conn.
+	// Closing the net.Conn only closes the stream.
+	NetListenerStreamPerAccept
+)
+
+type netListener struct {
+	ln   Listener
+	mode NetListenerMode
+
+	conns     chan net.Conn
+	errorChan chan struct{} // is closed when ln.Accept returns an error
+	err       error
+}
+
+var _ net.Listener = &netListener{}
+
+// NewNetListener returns a net.Listener that accepts streams from the sessions accepted by ln.
+// This allows running servers that were written for TCP over QUIC.
+// The net.Listener accepts sessions and streams in the background, such that a client that is slow
+// to open a stream doesn't block Accept for other clients.
+// Closing the net.Listener closes ln.
+func NewNetListener(ln Listener, mode NetListenerMode) net.Listener {
+	l := &netListener{
+		ln:        ln,
+		mode:      mode,
+		conns:     make(chan net.Conn),
+		errorChan: make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *netListener) run() {
+	for {
+		sess, err := l.ln.Accept()
+		if err != nil {
+			l.err = err
+			close(l.errorChan)
+			return
+		}
+		go l.handleSession(sess)
+	}
+}
+
+func (l *netListener) handleSession(sess Session) {
+	for {
+		str, err := sess.AcceptStream()
+		if err != nil {
+			return
+		}
+		c := &streamConn{Stream: str, sess: sess}
+		select {
+		case l.conns <- c:
+		case <-l.errorChan:
+			return
+		}
+		if l.mode == NetListenerStreamPerSession {
+			return
+		}
+	}
+}
+
+// Accept waits for the next stream and returns it as a net.Conn.
+func (l *netListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.errorChan:
+		return nil, l.err
+	}
+}
+
+// Close closes the Listener.
+func (l *netListener) Close() error {
+	return l.ln.Close()
+}
+
+// Addr returns the address of the Listener.
+func (l *netListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// A streamConn is a stream that implements the net.Conn interface.
+type streamConn struct {
+	Stream
+
+	sess Session
+
+	closeOnce sync.Once
+}
+
+var _ net.Conn = &streamConn{}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.sess.LocalAddr()
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.sess.RemoteAddr()
+}
+
+// Close closes both directions of the stream.
+// Unlike Stream.Close, it can be called multiple times.
+func (c *streamConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.Stream.Close()
+		c.Stream.CancelRead(0)
+	})
+	return err
+}
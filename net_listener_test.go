@@ -0,0 +1,153 @@
+package quic
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("net.Listener adapter", func() {
+	var ln Listener
+
+	BeforeEach(func() {
+		var err error
+		ln, err = ListenAddr("localhost:0", testdata.GetTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		ln.Close()
+	})
+
+	dial := func() Session {
+		sess, err := DialAddr(ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return sess
+	}
+
+	openStream := func(sess Session, data string) Stream {
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte(data))
+		Expect(err).ToNot(HaveOccurred())
+		return str
+	}
+
+	It("returns the address of the Listener", func() {
+		Expect(NewNetListener(ln, NetListenerStreamPerSession).Addr()).To(Equal(ln.Addr()))
+	})
+
+	It("returns an error from Accept when the Listener is closed", func() {
+		l := NewNetListener(ln, NetListenerStreamPerSession)
+		errChan := make(chan error)
+		go func() {
+			_, err := l.Accept()
+			errChan <- err
+		}()
+		Consistently(errChan).ShouldNot(Receive())
+		Expect(l.Close()).To(Succeed())
+		Eventually(errChan).Should(Receive(MatchError("server closed")))
+	})
+
+	Context("using one stream per session", func() {
+		It("returns the first stream of a session", func() {
+			l := NewNetListener(ln, NetListenerStreamPerSession)
+			sess := dial()
+			str := openStream(sess, "foobar")
+			Expect(str.Close()).To(Succeed())
+			conn, err := l.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.LocalAddr().String()).To(Equal(ln.Addr().String()))
+			Expect(conn.RemoteAddr().(*net.UDPAddr).Port).To(Equal(sess.LocalAddr().(*net.UDPAddr).Port))
+			data, err := ioutil.ReadAll(conn)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			_, err = conn.Write([]byte("raboof"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.Close()).To(Succeed())
+			Expect(conn.Close()).To(Succeed()) // closing multiple times is allowed
+			data, err = ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("raboof")))
+			Expect(sess.Context().Done()).ToNot(BeClosed())
+		})
+
+		It("doesn't return other streams of the session", func() {
+			l := NewNetListener(ln, NetListenerStreamPerSession)
+			sess := dial()
+			openStream(sess, "foo")
+			openStream(sess, "bar")
+			conn, err := l.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 3)
+			_, err = conn.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foo")))
+			connChan := make(chan net.Conn)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				connChan <- conn
+			}()
+			Consistently(connChan).ShouldNot(Receive())
+		})
+	})
+
+	Context("using one stream per accept", func() {
+		It("returns all streams of a session", func() {
+			l := NewNetListener(ln, NetListenerStreamPerAccept)
+			sess := dial()
+			str1 := openStream(sess, "foo")
+			str2 := openStream(sess, "bar")
+			received := make(map[string]net.Conn)
+			for i := 0; i < 2; i++ {
+				conn, err := l.Accept()
+				Expect(err).ToNot(HaveOccurred())
+				b := make([]byte, 3)
+				_, err = conn.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				received[string(b)] = conn
+			}
+			Expect(received).To(HaveKey("foo"))
+			Expect(received).To(HaveKey("bar"))
+			// closing a net.Conn only closes the stream
+			Expect(received["foo"].Close()).To(Succeed())
+			data, err := ioutil.ReadAll(str1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(BeEmpty())
+			_, err = received["bar"].Write([]byte("raboof"))
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 6)
+			_, err = str2.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("raboof")))
+			Expect(sess.Context().Done()).ToNot(BeClosed())
+		})
+
+		It("returns streams of multiple sessions", func() {
+			l := NewNetListener(ln, NetListenerStreamPerAccept)
+			sess1 := dial()
+			sess2 := dial()
+			openStream(sess1, "foo")
+			openStream(sess2, "bar")
+			var remotePorts []int
+			for i := 0; i < 2; i++ {
+				conn, err := l.Accept()
+				Expect(err).ToNot(HaveOccurred())
+				remotePorts = append(remotePorts, conn.RemoteAddr().(*net.UDPAddr).Port)
+			}
+			Expect(remotePorts).To(ConsistOf(
+				sess1.LocalAddr().(*net.UDPAddr).Port,
+				sess2.LocalAddr().(*net.UDPAddr).Port,
+			))
+		})
+	})
+})
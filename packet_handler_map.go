@@ -2,13 +2,13 @@ package quic
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"net"
 	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -37,12 +37,13 @@ type packetHandlerMap struct {
 
 	deleteRetiredSessionsAfter time.Duration
 
+	clock  utils.Clock
 	logger utils.Logger
 }
 
 var _ packetHandlerManager = &packetHandlerMap{}
 
-func newPacketHandlerMap(conn net.PacketConn, connIDLen int, logger utils.Logger) packetHandlerManager {
+func newPacketHandlerMap(conn net.PacketConn, connIDLen int, clock utils.Clock, logger utils.Logger) packetHandlerManager {
 	m := &packetHandlerMap{
 		conn:                       conn,
 		connIDLen:                  connIDLen,
@@ -50,6 +51,7 @@ func newPacketHandlerMap(conn net.PacketConn, connIDLen int, logger utils.Logger
 		handlers:                   make(map[string]packetHandlerEntry),
 		resetTokens:                make(map[[16]byte]packetHandler),
 		deleteRetiredSessionsAfter: protocol.RetiredConnectionIDDeleteTimeout,
+		clock:                      clock,
 		logger:                     logger,
 	}
 	go m.listen()
@@ -89,7 +91,7 @@ func (h *packetHandlerMap) Retire(id protocol.ConnectionID) {
 }
 
 func (h *packetHandlerMap) retireByConnectionIDAsString(id string) {
-	time.AfterFunc(h.deleteRetiredSessionsAfter, func() {
+	h.clock.AfterFunc(h.deleteRetiredSessionsAfter, func() {
 		h.removeByConnectionIDAsString(id)
 	})
 }
@@ -175,6 +177,13 @@ func (h *packetHandlerMap) handlePacket(
 	buffer *packetBuffer,
 	data []byte,
 ) {
+	// A panic while parsing a malformed packet must not take down all sessions handled by this packetHandlerMap.
+	defer func() {
+		if r := recover(); r != nil {
+			e := newPanicError(r)
+			h.logger.Errorf("Recovered from a %s while handling a packet from %s: %s", e, addr, e.stack)
+		}
+	}()
 	packets, err := h.parsePacket(addr, buffer, data)
 	if err != nil {
 		h.logger.Debugf("error parsing packets from %s: %s", addr, err)
@@ -193,7 +202,7 @@ func (h *packetHandlerMap) parsePacket(
 	buffer *packetBuffer,
 	data []byte,
 ) ([]*receivedPacket, error) {
-	rcvTime := time.Now()
+	rcvTime := h.clock.Now()
 	packets := make([]*receivedPacket, 0, 1)
 
 	var counter int
@@ -260,7 +269,7 @@ func (h *packetHandlerMap) handleParsedPackets(packets []*receivedPacket) {
 				var token [16]byte
 				copy(token[:], p.data[len(p.data)-16:])
 				if sess, ok := h.resetTokens[token]; ok {
-					sess.destroy(errors.New("received a stateless reset"))
+					sess.destroy(qerr.RemoteError(qerr.Error(qerr.PublicReset, "received a stateless reset")))
 					continue
 				}
 			}
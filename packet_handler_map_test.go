@@ -8,12 +8,24 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+// afterFuncClock passes the functions scheduled by AfterFunc to the test, instead of calling them.
+type afterFuncClock struct {
+	utils.Clock
+	funcs chan func()
+}
+
+func (c *afterFuncClock) AfterFunc(_ time.Duration, f func()) utils.ClockTimer {
+	c.funcs <- f
+	return nil
+}
+
 var _ = Describe("Packet Handler Map", func() {
 	var (
 		handler *packetHandlerMap
@@ -41,7 +53,7 @@ var _ = Describe("Packet Handler Map", func() {
 
 	BeforeEach(func() {
 		conn = newMockPacketConn()
-		handler = newPacketHandlerMap(conn, 5, utils.DefaultLogger).(*packetHandlerMap)
+		handler = newPacketHandlerMap(conn, 5, utils.DefaultClock, utils.DefaultLogger).(*packetHandlerMap)
 	})
 
 	AfterEach(func() {
@@ -104,6 +116,22 @@ var _ = Describe("Packet Handler Map", func() {
 			Eventually(handledPacket2).Should(BeClosed())
 		})
 
+		It("recovers from a panic while handling a packet", func() {
+			connID1 := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+			connID2 := protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1}
+			packetHandler1 := NewMockPacketHandler(mockCtrl)
+			packetHandler2 := NewMockPacketHandler(mockCtrl)
+			handledPacket2 := make(chan struct{})
+			packetHandler1.EXPECT().handlePacket(gomock.Any()).Do(func(*receivedPacket) { panic("foobar") })
+			packetHandler2.EXPECT().handlePacket(gomock.Any()).Do(func(*receivedPacket) { close(handledPacket2) })
+			handler.Add(connID1, packetHandler1)
+			handler.Add(connID2, packetHandler2)
+
+			conn.dataToRead <- getPacket(connID1)
+			conn.dataToRead <- getPacket(connID2)
+			Eventually(handledPacket2).Should(BeClosed())
+		})
+
 		It("drops unparseable packets", func() {
 			_, err := handler.parsePacket(nil, nil, []byte{0, 1, 2, 3})
 			Expect(err).To(HaveOccurred())
@@ -129,6 +157,20 @@ var _ = Describe("Packet Handler Map", func() {
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
+		It("uses the clock to delete retired session entries", func() {
+			clock := &afterFuncClock{Clock: utils.DefaultClock, funcs: make(chan func(), 1)}
+			handler.clock = clock
+			handler.deleteRetiredSessionsAfter = time.Hour
+			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+			handler.Add(connID, NewMockPacketHandler(mockCtrl))
+			handler.Retire(connID)
+			var f func()
+			Expect(clock.funcs).To(Receive(&f))
+			f()
+			handler.handlePacket(nil, nil, getPacket(connID))
+			// don't EXPECT any calls to handlePacket of the MockPacketHandler
+		})
+
 		It("passes packets arriving late for closed sessions to that session", func() {
 			handler.deleteRetiredSessionsAfter = time.Hour
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
@@ -257,7 +299,7 @@ var _ = Describe("Packet Handler Map", func() {
 			packet := append([]byte{0x40} /* short header packet */, make([]byte, 50)...)
 			packet = append(packet, token[:]...)
 			destroyed := make(chan struct{})
-			packetHandler.EXPECT().destroy(errors.New("received a stateless reset")).Do(func(error) {
+			packetHandler.EXPECT().destroy(qerr.RemoteError(qerr.Error(qerr.PublicReset, "received a stateless reset"))).Do(func(error) {
 				close(destroyed)
 			})
 			conn.dataToRead <- packet
@@ -275,7 +317,7 @@ var _ = Describe("Packet Handler Map", func() {
 			reset = append(reset, make([]byte, 50)...) // add some "random" data
 			reset = append(reset, token[:]...)
 			destroyed := make(chan struct{})
-			packetHandler.EXPECT().destroy(errors.New("received a stateless reset")).Do(func(error) {
+			packetHandler.EXPECT().destroy(qerr.RemoteError(qerr.Error(qerr.PublicReset, "received a stateless reset"))).Do(func(error) {
 				close(destroyed)
 			})
 			conn.dataToRead <- append(packet, reset...)
@@ -15,12 +15,14 @@ import (
 )
 
 type packer interface {
-	PackPacket() (*packedPacket, error)
+	PackCoalescedPacket() (*coalescedPacket, error)
 	MaybePackAckPacket() (*packedPacket, error)
 	PackRetransmission(packet *ackhandler.Packet) ([]*packedPacket, error)
 	PackConnectionClose(*wire.ConnectionCloseFrame) (*packedPacket, error)
+	PackCoverPacket() (*packedPacket, error)
 
 	HandleTransportParameters(*handshake.TransportParameters)
+	EnableFEC(groupSize int)
 	SetToken([]byte)
 	ChangeDestConnectionID(protocol.ConnectionID)
 }
@@ -33,6 +35,25 @@ type packedPacket struct {
 	buffer *packetBuffer
 }
 
+// A coalescedPacket is a UDP datagram containing one or more QUIC packets.
+// The packets share the buffer of the datagram.
+type coalescedPacket struct {
+	raw     []byte
+	packets []*packedPacket
+
+	buffer *packetBuffer
+}
+
+// packetContents is a packet that was composed, but not yet written and sealed.
+type packetContents struct {
+	header   *wire.ExtendedHeader
+	frames   []wire.Frame
+	encLevel protocol.EncryptionLevel
+	sealer   handshake.Sealer
+	// length is an upper bound for the length of the packet
+	length protocol.ByteCount
+}
+
 func (p *packedPacket) EncryptionLevel() protocol.EncryptionLevel {
 	if !p.header.IsLongHeader {
 		return protocol.Encryption1RTT
@@ -47,14 +68,14 @@ func (p *packedPacket) EncryptionLevel() protocol.EncryptionLevel {
 	}
 }
 
-func (p *packedPacket) ToAckHandlerPacket() *ackhandler.Packet {
+func (p *packedPacket) ToAckHandlerPacket(sendTime time.Time) *ackhandler.Packet {
 	return &ackhandler.Packet{
 		PacketNumber:    p.header.PacketNumber,
 		PacketType:      p.header.Type,
 		Frames:          p.frames,
 		Length:          protocol.ByteCount(len(p.raw)),
 		EncryptionLevel: p.EncryptionLevel(),
-		SendTime:        time.Now(),
+		SendTime:        sendTime,
 	}
 }
 
@@ -113,6 +134,12 @@ type packetPacker struct {
 
 	maxPacketSize             protocol.ByteCount
 	numNonRetransmittableAcks int
+	// packetPadding is the multiple that datagrams are padded to (see Config.PacketPadding).
+	// If 0, datagrams are only padded when required by the protocol.
+	packetPadding protocol.ByteCount
+
+	// fecEncoder is only set if the experimental FEC extension was negotiated
+	fecEncoder *fecEncoder
 }
 
 var _ packer = &packetPacker{}
@@ -123,7 +150,8 @@ func newPacketPacker(
 	initialStream cryptoStream,
 	handshakeStream cryptoStream,
 	packetNumberManager packetNumberManager,
-	remoteAddr net.Addr, // only used for determining the max packet size
+	maxPacketSize protocol.ByteCount,
+	packetPadding protocol.ByteCount,
 	cryptoSetup sealingManager,
 	framer frameSource,
 	acks ackFrameSource,
@@ -141,7 +169,8 @@ func newPacketPacker(
 		framer:          framer,
 		acks:            acks,
 		pnManager:       packetNumberManager,
-		maxPacketSize:   getMaxPacketSize(remoteAddr),
+		maxPacketSize:   maxPacketSize,
+		packetPadding:   packetPadding,
 	}
 }
 
@@ -165,6 +194,23 @@ func (p *packetPacker) MaybePackAckPacket() (*packedPacket, error) {
 	return p.writeAndSealPacket(header, frames, encLevel, sealer)
 }
 
+// PackCoverPacket packs a 1-RTT packet that only contains PADDING frames, and has the maximum packet size.
+// Cover packets are not retransmittable, and the peer doesn't acknowledge them.
+func (p *packetPacker) PackCoverPacket() (*packedPacket, error) {
+	encLevel, sealer := p.cryptoSetup.GetSealer()
+	if encLevel != protocol.Encryption1RTT {
+		return nil, nil
+	}
+	header := p.getHeader(encLevel)
+	packetBuffer := getPacketBuffer()
+	packet, err := p.appendPacket(packetBuffer.Slice[:0], header, []wire.Frame{&wire.PaddingFrame{}}, encLevel, sealer, p.maxPacketSize)
+	if err != nil {
+		return nil, err
+	}
+	packet.buffer = packetBuffer
+	return packet, nil
+}
+
 // PackRetransmission packs a retransmission
 // For packets sent after completion of the handshake, it might happen that 2 packets have to be sent.
 // This can happen e.g. when a longer packet number is used in the header.
@@ -195,7 +241,7 @@ func (p *packetPacker) PackRetransmission(packet *ackhandler.Packet) ([]*packedP
 
 		header := p.getHeader(encLevel)
 		headerLen := header.GetLength(p.version)
-		maxSize := p.maxPacketSize - protocol.ByteCount(sealer.Overhead()) - headerLen
+		maxSize := p.maxPacketSize - protocol.ByteCount(sealer.Overhead()) - headerLen - p.fecReservedSize(header, encLevel)
 
 		for len(controlFrames) > 0 {
 			frame := controlFrames[0]
@@ -238,25 +284,106 @@ func (p *packetPacker) PackRetransmission(packet *ackhandler.Packet) ([]*packedP
 	return packets, nil
 }
 
-// PackPacket packs a new packet
-// the other controlFrames are sent in the next packet, but might be queued and sent in the next packet if the packet would overflow MaxPacketSize otherwise
-func (p *packetPacker) PackPacket() (*packedPacket, error) {
-	packet, err := p.maybePackCryptoPacket()
+// PackCoalescedPacket packs a new UDP datagram.
+// During the handshake, packets of multiple encryption levels are coalesced into a single datagram.
+// If the client sends an Initial packet, the datagram is padded to the minimum Initial packet size.
+func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
+	if p.fecEncoder != nil {
+		if f := p.fecEncoder.PopRepairFrame(); f != nil {
+			packet, err := p.maybePackFECRepairPacket(f)
+			if err != nil || packet != nil {
+				return packet, err
+			}
+		}
+	}
+
+	var contents []*packetContents
+	var size protocol.ByteCount
+	for _, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake} {
+		if len(contents) > 0 && p.maxPacketSize-size < protocol.MinCoalescedPacketSize {
+			break
+		}
+		c, err := p.maybeComposeCryptoPacket(encLevel, p.maxPacketSize-size)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			contents = append(contents, c)
+			size += c.length
+		}
+	}
+	if len(contents) == 0 || p.maxPacketSize-size >= protocol.MinCoalescedPacketSize {
+		c, err := p.maybeComposeAppDataPacket(p.maxPacketSize-size, contents)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			contents = append(contents, c)
+		}
+	}
+	if len(contents) == 0 {
+		return nil, nil
+	}
+	return p.writeAndSealCoalescedPacket(contents)
+}
+
+// maybeComposeCryptoPacket composes an Initial or a Handshake packet,
+// if there's crypto data or an ACK to send at this encryption level.
+func (p *packetPacker) maybeComposeCryptoPacket(encLevel protocol.EncryptionLevel, maxPacketSize protocol.ByteCount) (*packetContents, error) {
+	var s cryptoStream
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		s = p.initialStream
+	case protocol.EncryptionHandshake:
+		s = p.handshakeStream
+	default:
+		return nil, fmt.Errorf("packetPacker BUG: no crypto stream for encryption level %s", encLevel)
+	}
+	hasData := s.HasData()
+	ack := p.acks.GetAckFrame(encLevel)
+	if !hasData && ack == nil {
+		return nil, nil
+	}
+	sealer, err := p.cryptoSetup.GetSealerWithEncryptionLevel(encLevel)
 	if err != nil {
 		return nil, err
 	}
-	if packet != nil {
-		return packet, nil
+
+	hdr := p.getHeader(encLevel)
+	length := hdr.GetLength(p.version) + protocol.ByteCount(sealer.Overhead())
+	frames := make([]wire.Frame, 0, 2)
+	if ack != nil {
+		frames = append(frames, ack)
+		length += ack.Length(p.version)
 	}
+	if hasData && length < maxPacketSize {
+		cf := s.PopCryptoFrame(maxPacketSize - length)
+		frames = append(frames, cf)
+		length += cf.Length(p.version)
+	}
+	return &packetContents{
+		header:   hdr,
+		frames:   frames,
+		encLevel: encLevel,
+		sealer:   sealer,
+		length:   length,
+	}, nil
+}
 
+// maybeComposeAppDataPacket composes a packet containing ACKs, control frames and STREAM frames,
+// using the highest encryption level available.
+// If a packet with that encryption level was already composed for this datagram, no packet is composed.
+func (p *packetPacker) maybeComposeAppDataPacket(maxPacketSize protocol.ByteCount, coalesced []*packetContents) (*packetContents, error) {
 	encLevel, sealer := p.cryptoSetup.GetSealer()
+	for _, c := range coalesced {
+		if c.encLevel == encLevel {
+			return nil, nil
+		}
+	}
 	header := p.getHeader(encLevel)
 	headerLen := header.GetLength(p.version)
-	if err != nil {
-		return nil, err
-	}
 
-	maxSize := p.maxPacketSize - protocol.ByteCount(sealer.Overhead()) - headerLen
+	maxSize := maxPacketSize - protocol.ByteCount(sealer.Overhead()) - headerLen - p.fecReservedSize(header, encLevel)
 	frames, err := p.composeNextPacket(maxSize)
 	if err != nil {
 		return nil, err
@@ -278,48 +405,48 @@ func (p *packetPacker) PackPacket() (*packedPacket, error) {
 		p.numNonRetransmittableAcks = 0
 	}
 
-	return p.writeAndSealPacket(header, frames, encLevel, sealer)
+	length := headerLen + protocol.ByteCount(sealer.Overhead())
+	for _, f := range frames {
+		length += f.Length(p.version)
+	}
+	return &packetContents{
+		header:   header,
+		frames:   frames,
+		encLevel: encLevel,
+		sealer:   sealer,
+		length:   length,
+	}, nil
 }
 
-func (p *packetPacker) maybePackCryptoPacket() (*packedPacket, error) {
-	var s cryptoStream
-	var encLevel protocol.EncryptionLevel
-
-	hasData := p.initialStream.HasData()
-	ack := p.acks.GetAckFrame(protocol.EncryptionInitial)
-	if hasData || ack != nil {
-		s = p.initialStream
-		encLevel = protocol.EncryptionInitial
-	} else {
-		hasData = p.handshakeStream.HasData()
-		ack = p.acks.GetAckFrame(protocol.EncryptionHandshake)
-		if hasData || ack != nil {
-			s = p.handshakeStream
-			encLevel = protocol.EncryptionHandshake
-		}
-	}
-	if s == nil {
+// maybePackFECRepairPacket packs a packet that only contains a FEC_REPAIR frame.
+func (p *packetPacker) maybePackFECRepairPacket(f *wire.FECRepairFrame) (*coalescedPacket, error) {
+	encLevel, sealer := p.cryptoSetup.GetSealer()
+	if encLevel != protocol.Encryption1RTT {
 		return nil, nil
 	}
-	sealer, err := p.cryptoSetup.GetSealerWithEncryptionLevel(encLevel)
-	if err != nil {
-		// The sealer
-		return nil, err
+	header := p.getHeader(encLevel)
+	length := header.GetLength(p.version) + f.Length(p.version) + protocol.ByteCount(sealer.Overhead())
+	// This can only happen if the peer reduced the max_packet_size.
+	if length > p.maxPacketSize {
+		return nil, nil
 	}
+	return p.writeAndSealCoalescedPacket([]*packetContents{{
+		header:   header,
+		frames:   []wire.Frame{f},
+		encLevel: encLevel,
+		sealer:   sealer,
+		length:   length,
+	}})
+}
 
-	hdr := p.getHeader(encLevel)
-	hdrLen := hdr.GetLength(p.version)
-	var length protocol.ByteCount
-	frames := make([]wire.Frame, 0, 2)
-	if ack != nil {
-		frames = append(frames, ack)
-		length += ack.Length(p.version)
+// fecReservedSize is the space that has to be left in a packet protected by FEC,
+// such that the FEC_REPAIR frame fits into a packet.
+// The packet carrying the FEC_REPAIR frame might use a longer packet number.
+func (p *packetPacker) fecReservedSize(header *wire.ExtendedHeader, encLevel protocol.EncryptionLevel) protocol.ByteCount {
+	if p.fecEncoder == nil || encLevel != protocol.Encryption1RTT {
+		return 0
 	}
-	if hasData {
-		cf := s.PopCryptoFrame(p.maxPacketSize - hdrLen - protocol.ByteCount(sealer.Overhead()) - length)
-		frames = append(frames, cf)
-	}
-	return p.writeAndSealPacket(hdr, frames, encLevel, sealer)
+	return protocol.FECRepairFrameMaxOverhead + protocol.ByteCount(protocol.PacketNumberLen4-header.PacketNumberLen)
 }
 
 func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount) ([]wire.Frame, error) {
@@ -373,6 +500,9 @@ func (p *packetPacker) getHeader(encLevel protocol.EncryptionLevel) *wire.Extend
 		switch encLevel {
 		case protocol.EncryptionInitial:
 			header.Type = protocol.PacketTypeInitial
+			if p.perspective == protocol.PerspectiveClient {
+				header.Token = p.token
+			}
 		case protocol.EncryptionHandshake:
 			header.Type = protocol.PacketTypeHandshake
 		}
@@ -387,28 +517,156 @@ func (p *packetPacker) writeAndSealPacket(
 	encLevel protocol.EncryptionLevel,
 	sealer handshake.Sealer,
 ) (*packedPacket, error) {
+	var minSize protocol.ByteCount
+	if p.perspective == protocol.PerspectiveClient && header.Type == protocol.PacketTypeInitial {
+		minSize = protocol.MinInitialPacketSize
+	}
+	if p.packetPadding > 0 {
+		size := utils.MaxByteCount(minSize, p.estimatePacketSize(header, frames, sealer))
+		minSize = p.paddedSize(size, header, encLevel)
+	}
 	packetBuffer := getPacketBuffer()
-	buffer := bytes.NewBuffer(packetBuffer.Slice[:0])
+	packet, err := p.appendPacket(packetBuffer.Slice[:0], header, frames, encLevel, sealer, minSize)
+	if err != nil {
+		return nil, err
+	}
+	packet.buffer = packetBuffer
+	return packet, nil
+}
+
+func (p *packetPacker) writeAndSealCoalescedPacket(contents []*packetContents) (*coalescedPacket, error) {
+	// Datagrams containing an Initial packet sent by the client need to be padded.
+	// Initial packets are always the first packet in a datagram.
+	var datagramMinSize protocol.ByteCount
+	if p.perspective == protocol.PerspectiveClient && contents[0].encLevel == protocol.EncryptionInitial {
+		datagramMinSize = protocol.MinInitialPacketSize
+	}
+	if p.packetPadding > 0 {
+		var size protocol.ByteCount
+		for _, c := range contents {
+			size += p.estimatePacketSize(c.header, c.frames, c.sealer)
+		}
+		last := contents[len(contents)-1]
+		datagramMinSize = p.paddedSize(utils.MaxByteCount(datagramMinSize, size), last.header, last.encLevel)
+	}
+	packetBuffer := getPacketBuffer()
+	raw := packetBuffer.Slice[:0]
+	packets := make([]*packedPacket, 0, len(contents))
+	for i, c := range contents {
+		var minSize protocol.ByteCount
+		// the padding is added to the last packet in the datagram
+		if i == len(contents)-1 && datagramMinSize > protocol.ByteCount(len(raw)) {
+			minSize = datagramMinSize - protocol.ByteCount(len(raw))
+		}
+		packet, err := p.appendPacket(raw, c.header, c.frames, c.encLevel, c.sealer, minSize)
+		if err != nil {
+			return nil, err
+		}
+		raw = raw[:len(raw)+len(packet.raw)]
+		packets = append(packets, packet)
+	}
+	return &coalescedPacket{
+		raw:     raw,
+		packets: packets,
+		buffer:  packetBuffer,
+	}, nil
+}
+
+// estimatePacketSize returns an upper bound for the size of a packet.
+// It must be called before appendPacket, since the Length field of long headers is then still set to the maximum packet size.
+func (p *packetPacker) estimatePacketSize(header *wire.ExtendedHeader, frames []wire.Frame, sealer handshake.Sealer) protocol.ByteCount {
+	var payloadLen protocol.ByteCount
+	for _, f := range frames {
+		payloadLen += f.Length(p.version)
+	}
+	// see appendPacket for the padding required for header protection
+	if pnLen := protocol.ByteCount(header.PacketNumberLen); payloadLen+pnLen < 4 {
+		payloadLen = 4 - pnLen
+	}
+	return header.GetLength(p.version) + payloadLen + protocol.ByteCount(sealer.Overhead())
+}
 
-	addPaddingForInitial := p.perspective == protocol.PerspectiveClient && header.Type == protocol.PacketTypeInitial
+// paddedSize rounds the size of a datagram up to the next multiple of the packet padding.
+// When FEC is used, the space needed for a FEC_REPAIR frame is left free, such that padded packets can still be protected.
+// header and encLevel are those of the packet that the padding is added to.
+func (p *packetPacker) paddedSize(size protocol.ByteCount, header *wire.ExtendedHeader, encLevel protocol.EncryptionLevel) protocol.ByteCount {
+	padded := (size + p.packetPadding - 1) / p.packetPadding * p.packetPadding
+	return utils.MinByteCount(padded, p.maxPacketSize-p.fecReservedSize(header, encLevel))
+}
 
-	if header.IsLongHeader {
-		if p.perspective == protocol.PerspectiveClient && header.Type == protocol.PacketTypeInitial {
-			header.Token = p.token
+// appendPacket writes and seals a packet, and appends it to raw.
+// The packet is padded to at least minSize bytes.
+func (p *packetPacker) appendPacket(
+	raw []byte,
+	header *wire.ExtendedHeader,
+	frames []wire.Frame,
+	encLevel protocol.EncryptionLevel,
+	sealer handshake.Sealer,
+	minSize protocol.ByteCount,
+) (*packedPacket, error) {
+	pnLen := protocol.ByteCount(header.PacketNumberLen)
+	overhead := protocol.ByteCount(sealer.Overhead())
+	lastFrame := frames[len(frames)-1]
+
+	var payloadLen, paddingLen protocol.ByteCount
+	for _, frame := range frames {
+		payloadLen += frame.Length(p.version)
+	}
+	// Pad the packet such that packet number length + payload length is 4 bytes.
+	// This is needed to enable the peer to get a 16 byte sample for header protection.
+	if payloadLen+pnLen < 4 {
+		paddingLen = 4 - pnLen - payloadLen
+	}
+	setLength := func() {
+		if header.IsLongHeader {
+			header.Length = pnLen + payloadLen + paddingLen + overhead
 		}
-		if addPaddingForInitial {
-			headerLen := header.GetLength(p.version)
-			header.Length = protocol.ByteCount(header.PacketNumberLen) + protocol.MinInitialPacketSize - headerLen
-		} else {
-			// long header packets always use 4 byte packet number, so we never need to pad short payloads
-			length := protocol.ByteCount(sealer.Overhead()) + protocol.ByteCount(header.PacketNumberLen)
-			for _, frame := range frames {
-				length += frame.Length(p.version)
+	}
+	packetSize := func() protocol.ByteCount {
+		return header.GetLength(p.version) - pnLen + header.Length
+	}
+	if !header.IsLongHeader {
+		packetSize = func() protocol.ByteCount {
+			return header.GetLength(p.version) + payloadLen + paddingLen + overhead
+		}
+	}
+	setLength()
+
+	// Padding needed to reach the minimum size is appended after the last frame.
+	var padAtEnd bool
+	if size := packetSize(); size < minSize {
+		padAtEnd = true
+		// when appending padding, we need to make sure that the last STREAM frames has the data length set
+		if sf, ok := lastFrame.(*wire.StreamFrame); ok && !sf.DataLenPresent {
+			lenWithoutDataLen := sf.Length(p.version)
+			sf.DataLenPresent = true
+			if size+sf.Length(p.version)-lenWithoutDataLen > p.maxPacketSize {
+				// There's no space for the data length, so the padding is inserted before the STREAM frame.
+				sf.DataLenPresent = false
+				padAtEnd = false
+			} else {
+				payloadLen += sf.Length(p.version) - lenWithoutDataLen
+				setLength()
+			}
+		}
+		if size := packetSize(); size < minSize {
+			paddingLen += minSize - size
+			setLength()
+		}
+		// The encoding of the Length field might have grown.
+		if size := packetSize(); size > minSize && paddingLen >= size-minSize {
+			paddingLen -= size - minSize
+			setLength()
+			// This only happens if the Length field now uses a shorter encoding.
+			if packetSize() < minSize {
+				paddingLen++
+				setLength()
 			}
-			header.Length = length
 		}
 	}
 
+	buffer := bytes.NewBuffer(raw)
+	packetOffset := buffer.Len()
 	if err := header.Write(buffer, p.version); err != nil {
 		return nil, err
 	}
@@ -420,44 +678,38 @@ func (p *packetPacker) writeAndSealPacket(
 			return nil, err
 		}
 	}
-	lastFrame := frames[len(frames)-1]
-	if addPaddingForInitial {
-		// when appending padding, we need to make sure that the last STREAM frames has the data length set
-		if sf, ok := lastFrame.(*wire.StreamFrame); ok {
-			sf.DataLenPresent = true
-		}
-	} else {
-		payloadLen := buffer.Len() - payloadOffset + int(lastFrame.Length(p.version))
-		if paddingLen := 4 - int(header.PacketNumberLen) - payloadLen; paddingLen > 0 {
-			// Pad the packet such that packet number length + payload length is 4 bytes.
-			// This is needed to enable the peer to get a 16 byte sample for header protection.
-			buffer.Write(bytes.Repeat([]byte{0}, paddingLen))
-		}
+	if !padAtEnd && paddingLen > 0 {
+		buffer.Write(bytes.Repeat([]byte{0}, int(paddingLen)))
 	}
 	if err := lastFrame.Write(buffer, p.version); err != nil {
 		return nil, err
 	}
-
-	if addPaddingForInitial {
-		paddingLen := protocol.MinInitialPacketSize - sealer.Overhead() - buffer.Len()
-		if paddingLen > 0 {
-			buffer.Write(bytes.Repeat([]byte{0}, paddingLen))
-		}
+	if padAtEnd && paddingLen > 0 {
+		buffer.Write(bytes.Repeat([]byte{0}, int(paddingLen)))
 	}
 
-	if size := protocol.ByteCount(buffer.Len() + sealer.Overhead()); size > p.maxPacketSize {
+	if size := protocol.ByteCount(buffer.Len()) + overhead; size > p.maxPacketSize {
 		return nil, fmt.Errorf("PacketPacker BUG: packet too large (%d bytes, allowed %d bytes)", size, p.maxPacketSize)
 	}
 
-	raw := buffer.Bytes()
-	_ = sealer.Seal(raw[payloadOffset:payloadOffset], raw[payloadOffset:], header.PacketNumber, raw[:payloadOffset])
-	raw = raw[0 : buffer.Len()+sealer.Overhead()]
+	data := buffer.Bytes()
+	if p.fecEncoder != nil && encLevel == protocol.Encryption1RTT {
+		// Packets carrying a FEC_REPAIR frame and cover packets are not protected by FEC.
+		switch frames[0].(type) {
+		case *wire.FECRepairFrame, *wire.PaddingFrame:
+		default:
+			p.fecEncoder.AddPacket(header.PacketNumber, data[payloadOffset:])
+		}
+	}
+	_ = sealer.Seal(data[payloadOffset:payloadOffset], data[payloadOffset:], header.PacketNumber, data[packetOffset:payloadOffset])
+	data = data[packetOffset : buffer.Len()+sealer.Overhead()]
 
-	pnOffset := payloadOffset - int(header.PacketNumberLen)
+	hdrLen := payloadOffset - packetOffset
+	pnOffset := hdrLen - int(header.PacketNumberLen)
 	sealer.EncryptHeader(
-		raw[pnOffset+4:pnOffset+4+16],
-		&raw[0],
-		raw[pnOffset:payloadOffset],
+		data[pnOffset+4:pnOffset+4+16],
+		&data[0],
+		data[pnOffset:hdrLen],
 	)
 
 	num := p.pnManager.PopPacketNumber(encLevel)
@@ -466,12 +718,17 @@ func (p *packetPacker) writeAndSealPacket(
 	}
 	return &packedPacket{
 		header: header,
-		raw:    raw,
+		raw:    data,
 		frames: frames,
-		buffer: packetBuffer,
 	}, nil
 }
 
+// EnableFEC enables the experimental forward error correction extension.
+// A FEC_REPAIR frame is sent for every groupSize 1-RTT packets.
+func (p *packetPacker) EnableFEC(groupSize int) {
+	p.fecEncoder = newFECEncoder(groupSize)
+}
+
 func (p *packetPacker) ChangeDestConnectionID(connID protocol.ConnectionID) {
 	p.destConnID = connID
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	mockackhandler "github.com/lucas-clemente/quic-go/internal/mocks/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -37,6 +38,17 @@ var _ = Describe("Packet packer", func() {
 		ExpectWithOffset(0, extHdr.Length).To(BeEquivalentTo(r.Len() + int(extHdr.PacketNumberLen)))
 	}
 
+	// packPacket packs a datagram, and expects it to contain at most a single packet
+	packPacket := func() (*packedPacket, error) {
+		p, err := packer.PackCoalescedPacket()
+		if err != nil || p == nil {
+			return nil, err
+		}
+		ExpectWithOffset(1, p.packets).To(HaveLen(1))
+		ExpectWithOffset(1, p.raw).To(Equal(p.packets[0].raw))
+		return p.packets[0], nil
+	}
+
 	expectAppendStreamFrames := func(frames ...wire.Frame) {
 		framer.EXPECT().AppendStreamFrames(gomock.Any(), gomock.Any()).DoAndReturn(func(fs []wire.Frame, _ protocol.ByteCount) []wire.Frame {
 			return append(fs, frames...)
@@ -71,7 +83,8 @@ var _ = Describe("Packet packer", func() {
 			initialStream,
 			handshakeStream,
 			pnManager,
-			&net.TCPAddr{},
+			protocol.MinInitialPacketSize,
+			0,
 			sealingManager,
 			framer,
 			ackFramer,
@@ -177,7 +190,7 @@ var _ = Describe("Packet packer", func() {
 			expectAppendControlFrames()
 			f := &wire.StreamFrame{Data: []byte{0xde, 0xca, 0xfb, 0xad}}
 			expectAppendStreamFrames(f)
-			p, err := packer.PackPacket()
+			p, err := packPacket()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p).ToNot(BeNil())
 			Expect(p.frames).To(Equal([]wire.Frame{f}))
@@ -217,7 +230,7 @@ var _ = Describe("Packet packer", func() {
 				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
 				framer.EXPECT().AppendControlFrames(nil, gomock.Any())
 				framer.EXPECT().AppendStreamFrames(nil, gomock.Any())
-				p, err := packer.PackPacket()
+				p, err := packPacket()
 				Expect(p).To(BeNil())
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -233,7 +246,7 @@ var _ = Describe("Packet packer", func() {
 					Data:     []byte{0xde, 0xca, 0xfb, 0xad},
 				}
 				expectAppendStreamFrames(f)
-				p, err := packer.PackPacket()
+				p, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p).ToNot(BeNil())
 				b := &bytes.Buffer{}
@@ -252,7 +265,7 @@ var _ = Describe("Packet packer", func() {
 					StreamID: 5,
 					Data:     []byte("foobar"),
 				})
-				p, err := packer.PackPacket()
+				p, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 			})
@@ -265,7 +278,7 @@ var _ = Describe("Packet packer", func() {
 				sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
 				expectAppendControlFrames()
 				expectAppendStreamFrames()
-				p, err := packer.PackPacket()
+				p, err := packPacket()
 				Expect(err).NotTo(HaveOccurred())
 				Expect(p).ToNot(BeNil())
 				Expect(p.frames[0]).To(Equal(ack))
@@ -294,7 +307,7 @@ var _ = Describe("Packet packer", func() {
 				frames := []wire.Frame{&wire.ResetStreamFrame{}, &wire.MaxDataFrame{}}
 				expectAppendControlFrames(frames...)
 				expectAppendStreamFrames()
-				p, err := packer.PackPacket()
+				p, err := packPacket()
 				Expect(p).ToNot(BeNil())
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.frames).To(Equal(frames))
@@ -316,10 +329,158 @@ var _ = Describe("Packet packer", func() {
 						return nil
 					}),
 				)
-				_, err := packer.PackPacket()
+				_, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 			})
 
+			Context("forward error correction", func() {
+				BeforeEach(func() {
+					packer.EnableFEC(2)
+				})
+
+				It("leaves space for the FEC_REPAIR frame", func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+					var maxSize protocol.ByteCount
+					framer.EXPECT().AppendControlFrames(gomock.Any(), gomock.Any()).DoAndReturn(func(fs []wire.Frame, maxLen protocol.ByteCount) ([]wire.Frame, protocol.ByteCount) {
+						maxSize = maxLen
+						return fs, 0
+					})
+					framer.EXPECT().AppendStreamFrames(gomock.Any(), gomock.Any())
+					p, err := packPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p).To(BeNil())
+					headerLen := 1 /* type byte */ + 8 /* connection ID */ + 2 /* packet number */
+					Expect(maxSize).To(Equal(maxPacketSize - 7 - protocol.ByteCount(headerLen) - protocol.FECRepairFrameMaxOverhead - 2))
+				})
+
+				It("sends a FEC_REPAIR frame after a group of packets", func() {
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer).Times(3)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Times(2)
+					var payloadLen int
+					for _, pn := range []protocol.PacketNumber{0x42, 0x43} {
+						pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(pn, protocol.PacketNumberLen2)
+						pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(pn)
+						expectAppendControlFrames()
+						f := &wire.StreamFrame{StreamID: 5, Data: bytes.Repeat([]byte{byte(pn)}, int(pn))}
+						expectAppendStreamFrames(f)
+						p, err := packPacket()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(p).ToNot(BeNil())
+						payloadLen = utils.Max(payloadLen, int(f.Length(packer.version)))
+					}
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x44), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x44))
+					p, err := packPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.frames).To(HaveLen(1))
+					Expect(p.frames[0]).To(BeAssignableToTypeOf(&wire.FECRepairFrame{}))
+					f := p.frames[0].(*wire.FECRepairFrame)
+					Expect(f.FirstPacketNumber).To(Equal(protocol.PacketNumber(0x42)))
+					Expect(f.NumPackets).To(BeEquivalentTo(2))
+					Expect(f.Data).To(HaveLen(payloadLen))
+					Expect(ackhandler.HasRetransmittableFrames(p.frames)).To(BeFalse())
+				})
+			})
+
+			Context("padding", func() {
+				BeforeEach(func() {
+					packer.packetPadding = 100
+				})
+
+				It("pads packets to a multiple of the packet padding", func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+					expectAppendControlFrames()
+					expectAppendStreamFrames(&wire.StreamFrame{StreamID: 5, Data: bytes.Repeat([]byte{'f'}, 150)})
+					p, err := packPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.raw).To(HaveLen(200))
+				})
+
+				It("doesn't pad packets beyond the maximum packet size", func() {
+					packer.packetPadding = 1000
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+					expectAppendControlFrames()
+					expectAppendStreamFrames(&wire.StreamFrame{StreamID: 5, Data: bytes.Repeat([]byte{'f'}, 1100)})
+					p, err := packPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.raw).To(HaveLen(int(maxPacketSize)))
+				})
+
+				It("pads packets if there's no space to add the data length to the STREAM frame", func() {
+					packer.packetPadding = maxPacketSize
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+					expectAppendControlFrames()
+					headerLen := 1 /* type byte */ + 8 /* connection ID */ + 2 /* packet number */
+					// one byte smaller than the maximum packet size
+					data := bytes.Repeat([]byte{'f'}, int(maxPacketSize)-headerLen-7-2-1)
+					sf := &wire.StreamFrame{StreamID: 5, Data: data}
+					expectAppendStreamFrames(sf)
+					p, err := packPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.raw).To(HaveLen(int(maxPacketSize)))
+					Expect(sf.DataLenPresent).To(BeFalse())
+					// the padding is inserted before the STREAM frame
+					payload := p.raw[headerLen : len(p.raw)-7]
+					Expect(payload[0]).To(BeZero())
+					frame, err := wire.NewFrameParser(packer.version).ParseNext(bytes.NewReader(payload), protocol.Encryption1RTT)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(frame.(*wire.StreamFrame).Data).To(Equal(data))
+				})
+
+				It("pads ACK packets", func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(ack)
+					p, err := packer.MaybePackAckPacket()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(p.raw).To(HaveLen(100))
+					Expect(p.frames).To(Equal([]wire.Frame{ack}))
+				})
+			})
+
+			Context("packing cover packets", func() {
+				It("packs cover packets", func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					p, err := packer.PackCoverPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.raw).To(HaveLen(int(maxPacketSize)))
+					Expect(p.frames).To(Equal([]wire.Frame{&wire.PaddingFrame{}}))
+					Expect(ackhandler.HasRetransmittableFrames(p.frames)).To(BeFalse())
+				})
+
+				It("doesn't pack cover packets before the handshake completes", func() {
+					sealingManager.EXPECT().GetSealer().Return(protocol.EncryptionHandshake, sealer)
+					p, err := packer.PackCoverPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p).To(BeNil())
+				})
+
+				It("doesn't add cover packets to FEC groups", func() {
+					packer.EnableFEC(2)
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					_, err := packer.PackCoverPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(packer.fecEncoder.numPackets).To(BeZero())
+				})
+			})
+
 			Context("packing ACK packets", func() {
 				It("doesn't pack a packet if there's no ACK to send", func() {
 					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
@@ -349,7 +510,7 @@ var _ = Describe("Packet packer", func() {
 						ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}})
 						expectAppendControlFrames()
 						expectAppendStreamFrames()
-						p, err := packer.PackPacket()
+						p, err := packPacket()
 						Expect(p).ToNot(BeNil())
 						Expect(err).ToNot(HaveOccurred())
 						Expect(p.frames).To(HaveLen(1))
@@ -364,7 +525,7 @@ var _ = Describe("Packet packer", func() {
 					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}})
 					expectAppendControlFrames()
 					expectAppendStreamFrames()
-					p, err := packer.PackPacket()
+					p, err := packPacket()
 					Expect(p).ToNot(BeNil())
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p.frames).To(ContainElement(&wire.PingFrame{}))
@@ -375,7 +536,7 @@ var _ = Describe("Packet packer", func() {
 					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}})
 					expectAppendControlFrames()
 					expectAppendStreamFrames()
-					p, err = packer.PackPacket()
+					p, err = packPacket()
 					Expect(p).ToNot(BeNil())
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p.frames).To(HaveLen(1))
@@ -389,7 +550,7 @@ var _ = Describe("Packet packer", func() {
 					expectAppendControlFrames()
 					expectAppendStreamFrames()
 					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
-					p, err := packer.PackPacket()
+					p, err := packPacket()
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p).To(BeNil())
 					// now add some frame to send
@@ -399,7 +560,7 @@ var _ = Describe("Packet packer", func() {
 					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
 					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
 					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}})
-					p, err = packer.PackPacket()
+					p, err = packPacket()
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p.frames).To(HaveLen(2))
 					Expect(p.frames).To(ContainElement(&wire.PingFrame{}))
@@ -413,7 +574,7 @@ var _ = Describe("Packet packer", func() {
 					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
 					expectAppendStreamFrames()
 					expectAppendControlFrames(&wire.MaxDataFrame{})
-					p, err := packer.PackPacket()
+					p, err := packPacket()
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p).ToNot(BeNil())
 					Expect(p.frames).ToNot(ContainElement(&wire.PingFrame{}))
@@ -436,7 +597,7 @@ var _ = Describe("Packet packer", func() {
 						sf.Data = bytes.Repeat([]byte{'f'}, int(maxSize-sf.Length(packer.version)))
 						return []wire.Frame{sf}, sf.Length(packer.version)
 					})
-					p, err := packer.PackPacket()
+					p, err := packPacket()
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p.frames).To(HaveLen(1))
 					Expect(p.raw).To(HaveLen(int(maxPacketSize)))
@@ -466,7 +627,7 @@ var _ = Describe("Packet packer", func() {
 					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
 					expectAppendControlFrames()
 					expectAppendStreamFrames(f1, f2, f3)
-					p, err := packer.PackPacket()
+					p, err := packPacket()
 					Expect(p).ToNot(BeNil())
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p.frames).To(HaveLen(3))
@@ -659,7 +820,7 @@ var _ = Describe("Packet packer", func() {
 						return nil, 0
 					})
 					expectAppendStreamFrames()
-					_, err := packer.PackPacket()
+					_, err := packPacket()
 					Expect(err).ToNot(HaveOccurred())
 					// now reduce the maxPacketSize
 					packer.HandleTransportParameters(&handshake.TransportParameters{
@@ -670,7 +831,7 @@ var _ = Describe("Packet packer", func() {
 						return nil, 0
 					})
 					expectAppendStreamFrames()
-					_, err = packer.PackPacket()
+					_, err = packPacket()
 					Expect(err).ToNot(HaveOccurred())
 				})
 
@@ -684,7 +845,7 @@ var _ = Describe("Packet packer", func() {
 						return nil, 0
 					})
 					expectAppendStreamFrames()
-					_, err := packer.PackPacket()
+					_, err := packPacket()
 					Expect(err).ToNot(HaveOccurred())
 					// now try to increase the maxPacketSize
 					packer.HandleTransportParameters(&handshake.TransportParameters{
@@ -695,13 +856,24 @@ var _ = Describe("Packet packer", func() {
 						return nil, 0
 					})
 					expectAppendStreamFrames()
-					_, err = packer.PackPacket()
+					_, err = packPacket()
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
 		})
 
 		Context("packing crypto packets", func() {
+			// expectNoCoalescedPackets sets the expectations for the encryption levels
+			// that are checked after packing a crypto packet at encLevel.
+			// The sealing manager doesn't provide 1-RTT keys yet.
+			expectNoCoalescedPackets := func(encLevel protocol.EncryptionLevel) {
+				if encLevel == protocol.EncryptionInitial {
+					handshakeStream.EXPECT().HasData()
+					ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake)
+				}
+				sealingManager.EXPECT().GetSealer().Return(encLevel, sealer)
+			}
+
 			It("sets the length", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42))
@@ -713,7 +885,8 @@ var _ = Describe("Packet packer", func() {
 				initialStream.EXPECT().HasData().Return(true)
 				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(f)
 				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionInitial).Return(sealer, nil)
-				p, err := packer.PackPacket()
+				expectNoCoalescedPackets(protocol.EncryptionInitial)
+				p, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				checkLength(p.raw)
 			})
@@ -733,7 +906,7 @@ var _ = Describe("Packet packer", func() {
 					Expect(f.Length(packer.version)).To(Equal(size))
 					return f
 				})
-				p, err := packer.PackPacket()
+				p, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.frames).To(HaveLen(1))
 				expectedPacketLen := packer.maxPacketSize
@@ -749,7 +922,8 @@ var _ = Describe("Packet packer", func() {
 				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionInitial).Return(sealer, nil)
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42))
-				p, err := packer.PackPacket()
+				expectNoCoalescedPackets(protocol.EncryptionInitial)
+				p, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.frames).To(Equal([]wire.Frame{ack}))
 			})
@@ -763,7 +937,8 @@ var _ = Describe("Packet packer", func() {
 				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionHandshake).Return(sealer, nil)
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42))
-				p, err := packer.PackPacket()
+				expectNoCoalescedPackets(protocol.EncryptionHandshake)
+				p, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.frames).To(Equal([]wire.Frame{ack}))
 			})
@@ -779,7 +954,8 @@ var _ = Describe("Packet packer", func() {
 				initialStream.EXPECT().HasData().Return(true)
 				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(f)
 				packer.perspective = protocol.PerspectiveClient
-				packet, err := packer.PackPacket()
+				expectNoCoalescedPackets(protocol.EncryptionInitial)
+				packet, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(packet.header.Token).To(Equal(token))
 				Expect(packet.raw).To(HaveLen(protocol.MinInitialPacketSize))
@@ -804,7 +980,7 @@ var _ = Describe("Packet packer", func() {
 				handshakeStream.EXPECT().HasData()
 				framer.EXPECT().AppendControlFrames(gomock.Any(), gomock.Any())
 				framer.EXPECT().AppendStreamFrames(gomock.Any(), gomock.Any()).Return([]wire.Frame{f})
-				packet, err := packer.PackPacket()
+				packet, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				// cut off the tag that the mock sealer added
 				packet.raw = packet.raw[:len(packet.raw)-sealer.Overhead()]
@@ -837,7 +1013,8 @@ var _ = Describe("Packet packer", func() {
 					Data: []byte("foobar"),
 				})
 				packer.perspective = protocol.PerspectiveClient
-				packet, err := packer.PackPacket()
+				expectNoCoalescedPackets(protocol.EncryptionInitial)
+				packet, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				checkLength(packet.raw)
 			})
@@ -853,7 +1030,8 @@ var _ = Describe("Packet packer", func() {
 				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(f)
 				packer.version = protocol.VersionTLS
 				packer.perspective = protocol.PerspectiveClient
-				packet, err := packer.PackPacket()
+				expectNoCoalescedPackets(protocol.EncryptionInitial)
+				packet, err := packPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(packet.raw).To(HaveLen(protocol.MinInitialPacketSize))
 				Expect(packet.frames).To(HaveLen(2))
@@ -905,5 +1083,171 @@ var _ = Describe("Packet packer", func() {
 				})
 			})
 		})
+
+		Context("coalescing packets", func() {
+			// parseDatagram parses the headers of all packets contained in a datagram
+			parseDatagram := func(data []byte) []*wire.ExtendedHeader {
+				var hdrs []*wire.ExtendedHeader
+				for len(data) > 0 {
+					hdr, err := wire.ParseHeader(bytes.NewReader(data), len(packer.destConnID))
+					Expect(err).ToNot(HaveOccurred())
+					r := bytes.NewReader(data)
+					extHdr, err := hdr.ParseExtended(r, packer.version)
+					Expect(err).ToNot(HaveOccurred())
+					hdrs = append(hdrs, extHdr)
+					if !extHdr.IsLongHeader {
+						break
+					}
+					hdrLen := len(data) - r.Len()
+					Expect(len(data)).To(BeNumerically(">=", hdrLen+int(extHdr.Length)-int(extHdr.PacketNumberLen)))
+					data = data[hdrLen+int(extHdr.Length)-int(extHdr.PacketNumberLen):]
+				}
+				return hdrs
+			}
+
+			It("coalesces Initial, Handshake and 1-RTT packets", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42))
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337))
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionInitial).Return(sealer, nil)
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionHandshake).Return(sealer, nil)
+				sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+				initialFrame := &wire.CryptoFrame{Data: []byte("server hello")}
+				initialStream.EXPECT().HasData().Return(true)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(initialFrame)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial)
+				handshakeFrame := &wire.CryptoFrame{Data: []byte("certificate")}
+				handshakeStream.EXPECT().HasData().Return(true)
+				handshakeStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(handshakeFrame)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+				expectAppendControlFrames()
+				sf := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+				expectAppendStreamFrames(sf)
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(3))
+				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+				Expect(p.packets[0].frames).To(Equal([]wire.Frame{initialFrame}))
+				Expect(p.packets[1].EncryptionLevel()).To(Equal(protocol.EncryptionHandshake))
+				Expect(p.packets[1].frames).To(Equal([]wire.Frame{handshakeFrame}))
+				Expect(p.packets[2].EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(p.packets[2].frames).To(Equal([]wire.Frame{sf}))
+				// the server doesn't pad
+				Expect(p.raw).To(HaveLen(len(p.packets[0].raw) + len(p.packets[1].raw) + len(p.packets[2].raw)))
+				Expect(len(p.raw)).To(BeNumerically("<", protocol.MinInitialPacketSize))
+				hdrs := parseDatagram(p.raw)
+				Expect(hdrs).To(HaveLen(3))
+				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(hdrs[0].PacketNumber).To(Equal(protocol.PacketNumber(0x24)))
+				Expect(hdrs[1].Type).To(Equal(protocol.PacketTypeHandshake))
+				Expect(hdrs[1].PacketNumber).To(Equal(protocol.PacketNumber(0x42)))
+				Expect(hdrs[2].IsLongHeader).To(BeFalse())
+				Expect(hdrs[2].PacketNumber).To(Equal(protocol.PacketNumber(0x1337)))
+			})
+
+			It("pads datagrams containing an Initial sent by the client", func() {
+				packer.perspective = protocol.PerspectiveClient
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionInitial).Return(sealer, nil)
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionHandshake).Return(sealer, nil)
+				sealingManager.EXPECT().GetSealer().Return(protocol.EncryptionHandshake, sealer)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}
+				initialStream.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial).Return(ack)
+				f := &wire.CryptoFrame{Data: []byte("finished")}
+				handshakeStream.EXPECT().HasData().Return(true)
+				handshakeStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(f)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake)
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(2))
+				Expect(p.packets[0].frames).To(Equal([]wire.Frame{ack}))
+				Expect(p.packets[1].frames).To(Equal([]wire.Frame{f}))
+				Expect(p.raw).To(HaveLen(protocol.MinInitialPacketSize))
+				Expect(p.raw).To(HaveLen(len(p.packets[0].raw) + len(p.packets[1].raw)))
+				hdrs := parseDatagram(p.raw)
+				Expect(hdrs).To(HaveLen(2))
+				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(hdrs[1].Type).To(Equal(protocol.PacketTypeHandshake))
+			})
+
+			It("pads coalesced packets to a multiple of the packet padding", func() {
+				packer.perspective = protocol.PerspectiveClient
+				packer.packetPadding = 650
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionInitial).Return(sealer, nil)
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionHandshake).Return(sealer, nil)
+				sealingManager.EXPECT().GetSealer().Return(protocol.EncryptionHandshake, sealer)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}
+				initialStream.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial).Return(ack)
+				handshakeStream.EXPECT().HasData().Return(true)
+				handshakeStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(&wire.CryptoFrame{Data: []byte("finished")})
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake)
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(2))
+				// the minimum size for Initial packets is rounded up to the next multiple
+				Expect(p.raw).To(HaveLen(1300))
+				Expect(parseDatagram(p.raw)).To(HaveLen(2))
+			})
+
+			It("pads a 1-RTT packet coalesced with an Initial sent by the client", func() {
+				packer.perspective = protocol.PerspectiveClient
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337))
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionInitial).Return(sealer, nil)
+				sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}
+				initialStream.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial).Return(ack)
+				handshakeStream.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+				expectAppendControlFrames()
+				sf := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+				expectAppendStreamFrames(sf)
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(2))
+				Expect(p.raw).To(HaveLen(protocol.MinInitialPacketSize))
+				Expect(p.packets[1].EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				// the padding is appended after the STREAM frame
+				Expect(sf.DataLenPresent).To(BeTrue())
+				hdrs := parseDatagram(p.raw)
+				Expect(hdrs).To(HaveLen(2))
+				Expect(hdrs[1].IsLongHeader).To(BeFalse())
+			})
+
+			It("doesn't coalesce packets if there's not enough space left in the datagram", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				sealingManager.EXPECT().GetSealerWithEncryptionLevel(protocol.EncryptionInitial).Return(sealer, nil)
+				initialStream.EXPECT().HasData().Return(true)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					f := &wire.CryptoFrame{}
+					f.Data = bytes.Repeat([]byte{'f'}, int(size-protocol.MinCoalescedPacketSize))
+					return f
+				})
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial)
+				// don't expect any calls for the Handshake and 1-RTT encryption level
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+			})
+		})
 	})
 })
@@ -3,10 +3,10 @@ package quic
 import (
 	"bytes"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
-	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -19,9 +19,9 @@ type unpackedPacket struct {
 
 // The packetUnpacker unpacks QUIC packets.
 type packetUnpacker struct {
-	cs handshake.CryptoSetup
+	largestRcvdPacketNumber int64 // to be used as an atomic, needs to be the first field for 64 bit alignment
 
-	largestRcvdPacketNumber protocol.PacketNumber
+	cs handshake.CryptoSetup
 
 	version protocol.VersionNumber
 }
@@ -36,8 +36,6 @@ func newPacketUnpacker(cs handshake.CryptoSetup, version protocol.VersionNumber)
 }
 
 func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket, error) {
-	r := bytes.NewReader(data)
-
 	var encLevel protocol.EncryptionLevel
 	switch hdr.Type {
 	case protocol.PacketTypeInitial:
@@ -54,6 +52,22 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket,
 	if err != nil {
 		return nil, err
 	}
+	return u.unpack(hdr, data, encLevel, opener)
+}
+
+// UnpackShortHeader unpacks a short header packet using the given opener.
+// It can be called concurrently with other calls to Unpack and UnpackShortHeader, as long as every caller uses its own opener.
+// Since other packets might be unpacked at the same time, the packet number is decoded based on
+// the largest packet number that was known when decrypting the packet.
+func (u *packetUnpacker) UnpackShortHeader(hdr *wire.Header, data []byte, opener handshake.Opener) (*unpackedPacket, error) {
+	if hdr.IsLongHeader {
+		return nil, fmt.Errorf("expected a short header packet, got a %s packet", hdr.Type)
+	}
+	return u.unpack(hdr, data, protocol.Encryption1RTT, opener)
+}
+
+func (u *packetUnpacker) unpack(hdr *wire.Header, data []byte, encLevel protocol.EncryptionLevel, opener handshake.Opener) (*unpackedPacket, error) {
+	r := bytes.NewReader(data)
 	hdrLen := int(hdr.ParsedLen())
 	if len(data) < hdrLen+4+16 {
 		return nil, fmt.Errorf("Packet too small. Expected at least 20 bytes after the header, got %d", len(data)-hdrLen)
@@ -81,7 +95,7 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket,
 
 	pn := protocol.DecodePacketNumber(
 		extHdr.PacketNumberLen,
-		u.largestRcvdPacketNumber,
+		protocol.PacketNumber(atomic.LoadInt64(&u.largestRcvdPacketNumber)),
 		extHdr.PacketNumber,
 	)
 
@@ -91,7 +105,7 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket,
 	}
 
 	// Only do this after decrypting, so we are sure the packet is not attacker-controlled
-	u.largestRcvdPacketNumber = utils.MaxPacketNumber(u.largestRcvdPacketNumber, pn)
+	u.updateLargestRcvdPacketNumber(pn)
 
 	return &unpackedPacket{
 		hdr:             extHdr,
@@ -100,3 +114,12 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket,
 		data:            decrypted,
 	}, nil
 }
+
+func (u *packetUnpacker) updateLargestRcvdPacketNumber(pn protocol.PacketNumber) {
+	for {
+		largest := atomic.LoadInt64(&u.largestRcvdPacketNumber)
+		if int64(pn) <= largest || atomic.CompareAndSwapInt64(&u.largestRcvdPacketNumber, largest, int64(pn)) {
+			return
+		}
+	}
+}
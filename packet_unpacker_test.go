@@ -177,4 +177,62 @@ var _ = Describe("Packet Unpacker", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(packet.packetNumber).To(Equal(protocol.PacketNumber(0x1338)))
 	})
+
+	Context("unpacking short header packets with a given opener", func() {
+		It("unpacks the packet, without getting an opener from the crypto setup", func() {
+			extHdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: connID},
+				PacketNumber:    0x42,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			hdr, hdrRaw := getHeader(extHdr)
+			opener := mocks.NewMockOpener(mockCtrl)
+			opener.EXPECT().DecryptHeader(gomock.Any(), gomock.Any(), gomock.Any())
+			opener.EXPECT().Open(gomock.Any(), payload, protocol.PacketNumber(0x42), hdrRaw).Return([]byte("decrypted"), nil)
+			packet, err := unpacker.UnpackShortHeader(hdr, append(hdrRaw, payload...), opener)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packet.encryptionLevel).To(Equal(protocol.Encryption1RTT))
+			Expect(packet.data).To(Equal([]byte("decrypted")))
+		})
+
+		It("refuses to unpack long header packets", func() {
+			extHdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					Length:           3 + 6, // packet number len + payload
+					DestConnectionID: connID,
+					Version:          version,
+				},
+				PacketNumber:    2,
+				PacketNumberLen: 3,
+			}
+			hdr, hdrRaw := getHeader(extHdr)
+			_, err := unpacker.UnpackShortHeader(hdr, append(hdrRaw, payload...), mocks.NewMockOpener(mockCtrl))
+			Expect(err).To(MatchError("expected a short header packet, got a Handshake packet"))
+		})
+
+		It("uses the largest packet number of packets unpacked by Unpack", func() {
+			opener := mocks.NewMockOpener(mockCtrl)
+			cs.EXPECT().GetOpener(protocol.Encryption1RTT).Return(opener, nil)
+			opener.EXPECT().DecryptHeader(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			opener.EXPECT().Open(gomock.Any(), gomock.Any(), protocol.PacketNumber(0x1337), gomock.Any()).Return([]byte{0}, nil)
+			hdr, hdrRaw := getHeader(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: connID},
+				PacketNumber:    0x1337,
+				PacketNumberLen: 2,
+			})
+			_, err := unpacker.Unpack(hdr, append(hdrRaw, payload...))
+			Expect(err).ToNot(HaveOccurred())
+			opener.EXPECT().Open(gomock.Any(), gomock.Any(), protocol.PacketNumber(0x1338), gomock.Any()).Return([]byte{0}, nil)
+			hdr, hdrRaw = getHeader(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: connID},
+				PacketNumber:    0x38,
+				PacketNumberLen: 1,
+			})
+			packet, err := unpacker.UnpackShortHeader(hdr, append(hdrRaw, payload...), opener)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packet.packetNumber).To(Equal(protocol.PacketNumber(0x1338)))
+		})
+	})
 })
@@ -0,0 +1,24 @@
+package quic
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// A panicError is a panic that was recovered while processing a session.
+// The session is closed with an InternalError, so a bug triggered by a single peer
+// doesn't crash the process and with it all other sessions.
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+// newPanicError must be called from the deferred function that recovered the panic,
+// so that the stack trace contains the location of the panic.
+func newPanicError(value interface{}) *panicError {
+	return &panicError{value: value, stack: debug.Stack()}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
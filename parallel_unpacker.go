@@ -0,0 +1,132 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+type decryptionJob struct {
+	p    *receivedPacket
+	done chan struct{} // is closed when the worker is done with the packet
+}
+
+// The parallelUnpacker decrypts short header packets on a pool of worker go routines.
+// Decrypted packets are passed on in the order they were received.
+// Every worker uses its own 1-RTT opener, since openers can't be used concurrently.
+type parallelUnpacker struct {
+	unpacker unpacker
+	cs       handshake.CryptoSetup
+	deliver  func(*receivedPacket)
+
+	mutex  sync.Mutex
+	closed bool
+	// jobs are picked up by the workers
+	jobs chan *decryptionJob
+	// ordered contains the same jobs in the order the packets were received
+	ordered chan *decryptionJob
+	// workersDone is used to wait for the workers to return, see Close
+	workersDone sync.WaitGroup
+
+	logger utils.Logger
+}
+
+func newParallelUnpacker(
+	u unpacker,
+	cs handshake.CryptoSetup,
+	numWorkers int,
+	deliver func(*receivedPacket),
+	logger utils.Logger,
+) *parallelUnpacker {
+	pu := &parallelUnpacker{
+		unpacker: u,
+		cs:       cs,
+		deliver:  deliver,
+		jobs:     make(chan *decryptionJob, protocol.MaxSessionUnprocessedPackets),
+		ordered:  make(chan *decryptionJob, protocol.MaxSessionUnprocessedPackets),
+		logger:   logger,
+	}
+	pu.workersDone.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go pu.runWorker()
+	}
+	go pu.runSequencer()
+	return pu
+}
+
+// HandlePacket queues a short header packet for decryption.
+// Packets are dropped when too many packets are queued, or when the parallelUnpacker was closed.
+func (u *parallelUnpacker) HandlePacket(p *receivedPacket) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.closed {
+		return
+	}
+	job := &decryptionJob{p: p, done: make(chan struct{})}
+	select {
+	case u.ordered <- job:
+	default:
+		u.logger.Debugf("Dropping packet, too many packets queued for decryption.")
+		return
+	}
+	// This never blocks for long:
+	// The workers don't wait for anything before picking up the next job.
+	u.jobs <- job
+}
+
+func (u *parallelUnpacker) runWorker() {
+	defer u.workersDone.Done()
+	var opener handshake.Opener
+	for job := range u.jobs {
+		if opener == nil {
+			var err error
+			opener, err = u.cs.NewOneRTTOpener()
+			if err != nil {
+				// Pass on the packet without unpacking it.
+				// The session will try to unpack it itself (and queue it if it's not yet possible).
+				close(job.done)
+				continue
+			}
+		}
+		u.unpack(job.p, opener)
+		close(job.done)
+	}
+}
+
+// unpack unpacks a packet.
+// A panic is passed on to the session as the unpackErr, which then closes the session.
+func (u *parallelUnpacker) unpack(p *receivedPacket, opener handshake.Opener) {
+	defer func() {
+		if e := recover(); e != nil {
+			p.unpacked = nil
+			p.unpackErr = newPanicError(e)
+		}
+	}()
+	p.unpacked, p.unpackErr = u.unpacker.UnpackShortHeader(p.hdr, p.data, opener)
+}
+
+func (u *parallelUnpacker) runSequencer() {
+	for job := range u.ordered {
+		<-job.done
+		u.deliver(job.p)
+	}
+}
+
+// Close stops all workers.
+// Packets that were already queued are still passed on.
+// It returns when all workers have returned, such that the crypto setup can erase the keys afterwards.
+func (u *parallelUnpacker) Close() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.closed {
+		return
+	}
+	u.closed = true
+	close(u.jobs)
+	close(u.ordered)
+	u.workersDone.Wait()
+}
@@ -0,0 +1,149 @@
+package quic
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parallel Unpacker", func() {
+	const numWorkers = 4
+
+	var (
+		pu        *parallelUnpacker
+		u         *MockUnpacker
+		cs        *mocks.MockCryptoSetup
+		delivered chan *receivedPacket
+	)
+
+	getPacket := func(pn protocol.PacketNumber) *receivedPacket {
+		return &receivedPacket{
+			hdr:  &wire.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+			data: []byte{byte(pn)},
+		}
+	}
+
+	BeforeEach(func() {
+		u = NewMockUnpacker(mockCtrl)
+		cs = mocks.NewMockCryptoSetup(mockCtrl)
+		delivered = make(chan *receivedPacket, 1000)
+		pu = newParallelUnpacker(u, cs, numWorkers, func(p *receivedPacket) { delivered <- p }, utils.DefaultLogger)
+	})
+
+	AfterEach(func() {
+		pu.Close()
+	})
+
+	It("delivers packets in the order they were received", func() {
+		const num = 100
+		cs.EXPECT().NewOneRTTOpener().Return(mocks.NewMockOpener(mockCtrl), nil).MinTimes(1).MaxTimes(numWorkers)
+		u.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ *wire.Header, data []byte, _ handshake.Opener) (*unpackedPacket, error) {
+			time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+			return &unpackedPacket{packetNumber: protocol.PacketNumber(data[0])}, nil
+		}).Times(num)
+		for i := 0; i < num; i++ {
+			pu.HandlePacket(getPacket(protocol.PacketNumber(i)))
+		}
+		for i := 0; i < num; i++ {
+			var p *receivedPacket
+			Eventually(delivered).Should(Receive(&p))
+			Expect(p.unpacked).ToNot(BeNil())
+			Expect(p.unpacked.packetNumber).To(Equal(protocol.PacketNumber(i)))
+		}
+	})
+
+	It("passes on errors", func() {
+		testErr := errors.New("decryption failed")
+		cs.EXPECT().NewOneRTTOpener().Return(mocks.NewMockOpener(mockCtrl), nil)
+		u.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, testErr)
+		pu.HandlePacket(getPacket(1))
+		var p *receivedPacket
+		Eventually(delivered).Should(Receive(&p))
+		Expect(p.unpacked).To(BeNil())
+		Expect(p.unpackErr).To(MatchError(testErr))
+	})
+
+	It("passes on panics as errors", func() {
+		cs.EXPECT().NewOneRTTOpener().Return(mocks.NewMockOpener(mockCtrl), nil)
+		u.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(*wire.Header, []byte, handshake.Opener) { panic("foobar") })
+		pu.HandlePacket(getPacket(1))
+		var p *receivedPacket
+		Eventually(delivered).Should(Receive(&p))
+		Expect(p.unpacked).To(BeNil())
+		Expect(p.unpackErr).To(BeAssignableToTypeOf(&panicError{}))
+		Expect(p.unpackErr).To(MatchError("panic: foobar"))
+	})
+
+	It("passes on packets without unpacking them if the 1-RTT keys are not yet available", func() {
+		cs.EXPECT().NewOneRTTOpener().Return(nil, handshake.ErrOpenerNotYetAvailable)
+		pu.HandlePacket(getPacket(1))
+		var p *receivedPacket
+		Eventually(delivered).Should(Receive(&p))
+		Expect(p.unpacked).To(BeNil())
+		Expect(p.unpackErr).ToNot(HaveOccurred())
+	})
+
+	It("drops packets when too many packets are queued", func() {
+		cs.EXPECT().NewOneRTTOpener().Return(mocks.NewMockOpener(mockCtrl), nil).AnyTimes()
+		unblock := make(chan struct{})
+		u.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(*wire.Header, []byte, handshake.Opener) (*unpackedPacket, error) {
+			<-unblock
+			return &unpackedPacket{}, nil
+		}).AnyTimes()
+		for i := 0; i < protocol.MaxSessionUnprocessedPackets+numWorkers+10; i++ {
+			pu.HandlePacket(getPacket(protocol.PacketNumber(i)))
+		}
+		close(unblock)
+		var count int
+		Eventually(func() int {
+			for {
+				select {
+				case <-delivered:
+					count++
+				default:
+					return count
+				}
+			}
+		}).Should(BeNumerically(">=", protocol.MaxSessionUnprocessedPackets))
+		Consistently(delivered).ShouldNot(Receive())
+		Expect(count).To(BeNumerically("<=", protocol.MaxSessionUnprocessedPackets+1))
+	})
+
+	It("waits for the workers to return when closing", func() {
+		cs.EXPECT().NewOneRTTOpener().Return(mocks.NewMockOpener(mockCtrl), nil)
+		unpacking := make(chan struct{})
+		unblock := make(chan struct{})
+		u.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(*wire.Header, []byte, handshake.Opener) (*unpackedPacket, error) {
+			close(unpacking)
+			<-unblock
+			return &unpackedPacket{}, nil
+		})
+		pu.HandlePacket(getPacket(1))
+		Eventually(unpacking).Should(BeClosed())
+		closed := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			pu.Close()
+			close(closed)
+		}()
+		Consistently(closed).ShouldNot(BeClosed())
+		close(unblock)
+		Eventually(closed).Should(BeClosed())
+	})
+
+	It("drops packets after it was closed", func() {
+		pu.Close()
+		pu.HandlePacket(getPacket(1))
+		Consistently(delivered).ShouldNot(Receive())
+	})
+})
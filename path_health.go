@@ -0,0 +1,171 @@
+package quic
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// PathHealth is the health of the network path used by a session.
+type PathHealth uint8
+
+const (
+	// PathHealthy means that no problems were detected.
+	PathHealthy PathHealth = iota
+	// PathDegraded means that writing to the PacketConn failed, or that packets were not acknowledged for a while.
+	PathDegraded
+	// PathBroken means that the path is very likely unusable.
+	// If this is caused by persistent write errors, the session is closed.
+	PathBroken
+)
+
+func (h PathHealth) String() string {
+	switch h {
+	case PathHealthy:
+		return "healthy"
+	case PathDegraded:
+		return "degraded"
+	case PathBroken:
+		return "broken"
+	default:
+		return fmt.Sprintf("unknown path health: %d", h)
+	}
+}
+
+// isPathError says if an error returned when writing to the PacketConn is caused by the network path,
+// e.g. an ICMP unreachable message was received, or there's no route to the peer.
+// Writing might succeed again later.
+func isPathError(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	if opErr.Temporary() {
+		return true
+	}
+	syscallErr, ok := opErr.Err.(*os.SyscallError)
+	if !ok {
+		return false
+	}
+	switch syscallErr.Err {
+	case syscall.ECONNREFUSED, syscall.EHOSTUNREACH, syscall.ENETUNREACH, syscall.EHOSTDOWN, syscall.ENETDOWN, syscall.ENOBUFS, syscall.EPERM:
+		return true
+	default:
+		return false
+	}
+}
+
+// isLocalAddrError says if an error returned when writing to the PacketConn is caused by the local address
+// having become unavailable, e.g. because the network interface went down.
+func isLocalAddrError(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	syscallErr, ok := opErr.Err.(*os.SyscallError)
+	if !ok {
+		return false
+	}
+	switch syscallErr.Err {
+	case syscall.EADDRNOTAVAIL, syscall.ENETDOWN, syscall.ENETUNREACH:
+		return true
+	default:
+		return false
+	}
+}
+
+// The pathHealthMonitor derives the PathHealth from write errors and consecutive PTOs.
+type pathHealthMonitor struct {
+	mutex sync.Mutex
+
+	consecutiveWriteErrors int
+	lastWriteError         error
+	ptoCount               uint32
+	health                 PathHealth
+
+	onChange func(PathHealth, error)
+}
+
+func newPathHealthMonitor(onChange func(PathHealth, error)) *pathHealthMonitor {
+	return &pathHealthMonitor{onChange: onChange}
+}
+
+// OnWriteError is called when writing to the PacketConn failed with a path error.
+// It returns the new PathHealth.
+func (m *pathHealthMonitor) OnWriteError(err error) PathHealth {
+	m.mutex.Lock()
+	m.consecutiveWriteErrors++
+	m.lastWriteError = err
+	m.mutex.Unlock()
+	return m.update()
+}
+
+// OnWriteSuccess is called when a packet was written successfully.
+func (m *pathHealthMonitor) OnWriteSuccess() {
+	m.mutex.Lock()
+	hadErrors := m.consecutiveWriteErrors > 0
+	m.consecutiveWriteErrors = 0
+	m.lastWriteError = nil
+	m.mutex.Unlock()
+	if hadErrors {
+		m.update()
+	}
+}
+
+// SetPTOCount is called when a PTO fired
+func (m *pathHealthMonitor) SetPTOCount(n uint32) {
+	m.mutex.Lock()
+	m.ptoCount = n
+	m.mutex.Unlock()
+	m.update()
+}
+
+// OnPacketReceived is called when a packet from the peer was processed.
+// This shows that the peer is still reachable.
+func (m *pathHealthMonitor) OnPacketReceived() {
+	m.mutex.Lock()
+	hadPTOs := m.ptoCount > 0
+	m.ptoCount = 0
+	m.mutex.Unlock()
+	if hadPTOs {
+		m.update()
+	}
+}
+
+func (m *pathHealthMonitor) Health() PathHealth {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.health
+}
+
+func (m *pathHealthMonitor) update() PathHealth {
+	m.mutex.Lock()
+	var health PathHealth
+	var reason error
+	switch {
+	case m.consecutiveWriteErrors >= protocol.MaxConsecutiveWriteErrors:
+		health = PathBroken
+		reason = m.lastWriteError
+	case m.ptoCount >= protocol.PathBrokenPTOCount:
+		health = PathBroken
+		reason = fmt.Errorf("%d consecutive PTOs", m.ptoCount)
+	case m.consecutiveWriteErrors > 0:
+		health = PathDegraded
+		reason = m.lastWriteError
+	case m.ptoCount >= protocol.PathDegradedPTOCount:
+		health = PathDegraded
+		reason = fmt.Errorf("%d consecutive PTOs", m.ptoCount)
+	}
+	changed := health != m.health
+	m.health = health
+	m.mutex.Unlock()
+
+	if changed && m.onChange != nil {
+		m.onChange(health, reason)
+	}
+	return health
+}
@@ -0,0 +1,84 @@
+package quic
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path Health", func() {
+	It("has a string representation", func() {
+		Expect(PathHealthy.String()).To(Equal("healthy"))
+		Expect(PathDegraded.String()).To(Equal("degraded"))
+		Expect(PathBroken.String()).To(Equal("broken"))
+		Expect(PathHealth(42).String()).To(Equal("unknown path health: 42"))
+	})
+
+	It("detects path errors", func() {
+		Expect(isPathError(&net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.ECONNREFUSED)})).To(BeTrue())
+		Expect(isPathError(&net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EHOSTUNREACH)})).To(BeTrue())
+		Expect(isPathError(&net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EBADF)})).To(BeFalse())
+		Expect(isPathError(errors.New("foobar"))).To(BeFalse())
+	})
+
+	It("detects errors caused by an unavailable local address", func() {
+		Expect(isLocalAddrError(&net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EADDRNOTAVAIL)})).To(BeTrue())
+		Expect(isLocalAddrError(&net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.ENETDOWN)})).To(BeTrue())
+		Expect(isLocalAddrError(&net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.ECONNREFUSED)})).To(BeFalse())
+		Expect(isLocalAddrError(errors.New("foobar"))).To(BeFalse())
+	})
+
+	Context("monitor", func() {
+		var (
+			monitor *pathHealthMonitor
+			changes []PathHealth
+		)
+
+		BeforeEach(func() {
+			changes = nil
+			monitor = newPathHealthMonitor(func(h PathHealth, _ error) { changes = append(changes, h) })
+		})
+
+		It("is healthy initially", func() {
+			Expect(monitor.Health()).To(Equal(PathHealthy))
+		})
+
+		It("considers the path degraded and then broken when writes fail", func() {
+			testErr := errors.New("write error")
+			for i := 1; i < protocol.MaxConsecutiveWriteErrors; i++ {
+				Expect(monitor.OnWriteError(testErr)).To(Equal(PathDegraded))
+			}
+			Expect(monitor.OnWriteError(testErr)).To(Equal(PathBroken))
+			Expect(changes).To(Equal([]PathHealth{PathDegraded, PathBroken}))
+		})
+
+		It("considers the path degraded and then broken after consecutive PTOs", func() {
+			for i := uint32(1); i <= protocol.PathBrokenPTOCount; i++ {
+				monitor.SetPTOCount(i)
+			}
+			Expect(monitor.Health()).To(Equal(PathBroken))
+			Expect(changes).To(Equal([]PathHealth{PathDegraded, PathBroken}))
+		})
+
+		It("recovers when a packet is received", func() {
+			monitor.SetPTOCount(protocol.PathBrokenPTOCount)
+			Expect(monitor.Health()).To(Equal(PathBroken))
+			monitor.OnPacketReceived()
+			Expect(monitor.Health()).To(Equal(PathHealthy))
+			Expect(changes).To(Equal([]PathHealth{PathBroken, PathHealthy}))
+		})
+
+		It("doesn't recover from write errors when a packet is received", func() {
+			monitor.OnWriteError(errors.New("write error"))
+			monitor.OnPacketReceived()
+			Expect(monitor.Health()).To(Equal(PathDegraded))
+			monitor.OnWriteSuccess()
+			Expect(monitor.Health()).To(Equal(PathHealthy))
+		})
+	})
+})
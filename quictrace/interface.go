@@ -0,0 +1,133 @@
+package quictrace
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// A Tracer traces a QUIC connection
+type Tracer interface {
+	Trace(protocol.ConnectionID, Event)
+	// GetAllTraces returns the quic-trace protobuf of every connection, keyed by connection ID.
+	// Traces are removed from the tracer once they have been returned.
+	GetAllTraces() map[string][]byte
+}
+
+// EventType is the type of an event
+type EventType uint8
+
+const (
+	// PacketSent means that a packet was sent
+	PacketSent EventType = 1 + iota
+	// PacketReceived means that a packet was received
+	PacketReceived
+	// PacketLost means that a packet was declared lost
+	PacketLost
+	// CongestionStateUpdated means that the state of the congestion controller changed
+	CongestionStateUpdated
+	// VersionNegotiationReceived means that a Version Negotiation packet was received
+	VersionNegotiationReceived
+	// PanicRecovered means that a panic occurred while processing the connection.
+	// The connection is closed with an InternalError.
+	PanicRecovered
+	// FlowControlUpdated means that a window update or a blocked frame was sent or received
+	FlowControlUpdated
+)
+
+// CongestionStateChange is a state change of the congestion controller
+type CongestionStateChange uint8
+
+const (
+	// SlowStartExited means that the congestion controller left slow start
+	SlowStartExited CongestionStateChange = 1 + iota
+	// RecoveryEntered means that the congestion controller entered recovery
+	RecoveryEntered
+	// RecoveryExited means that the congestion controller left recovery
+	RecoveryExited
+	// PersistentCongestion means that an ACK was received after more than 2 consecutive PTOs.
+	// The congestion window is not reduced.
+	PersistentCongestion
+)
+
+// FlowControlTransition is a transition of the flow control state of a stream or of the connection
+type FlowControlTransition uint8
+
+const (
+	// WindowUpdateSent means that a MAX_DATA or MAX_STREAM_DATA frame was sent
+	WindowUpdateSent FlowControlTransition = 1 + iota
+	// WindowUpdateReceived means that a MAX_DATA or MAX_STREAM_DATA frame was received
+	WindowUpdateReceived
+	// Blocked means that sending was blocked by flow control, and a DATA_BLOCKED or STREAM_DATA_BLOCKED frame was sent
+	Blocked
+	// PeerBlocked means that a DATA_BLOCKED or STREAM_DATA_BLOCKED frame was received
+	PeerBlocked
+)
+
+// FlowControlState is the flow control state of a stream, or of the connection
+type FlowControlState struct {
+	// Connection is set for the connection-level flow control state.
+	// Otherwise, StreamID is the ID of the stream.
+	Connection bool
+	StreamID   protocol.StreamID
+
+	// SendWindow is the highest offset the peer allowed us to send.
+	// It is derived from the window updates received, and from the offsets at which sending was blocked,
+	// and is 0 if neither occurred yet.
+	SendWindow protocol.ByteCount
+	// BlockedSince is the time when sending was blocked at SendWindow.
+	// It is zero when sending is not blocked.
+	BlockedSince             time.Time
+	LastWindowUpdateReceived time.Time
+
+	BytesRead       protocol.ByteCount
+	HighestReceived protocol.ByteCount
+	// ReceiveWindow is the highest offset the peer is allowed to send
+	ReceiveWindow protocol.ByteCount
+	// WindowUpdatePending is set if enough data was read to send a window update, but it wasn't sent yet.
+	WindowUpdatePending bool
+	// PeerBlockedSince is the time when the peer reported that it was blocked at ReceiveWindow.
+	// It is zero when the peer is not blocked, or when a window update was sent since.
+	PeerBlockedSince     time.Time
+	LastWindowUpdateSent time.Time
+}
+
+// Event is a quic-traceable event
+type Event struct {
+	Time      time.Time
+	EventType EventType
+
+	TransportState *TransportState
+
+	EncryptionLevel protocol.EncryptionLevel
+	PacketNumber    protocol.PacketNumber
+	PacketSize      protocol.ByteCount
+	Frames          []wire.Frame
+
+	// only set for CongestionStateUpdated events
+	CongestionStateChange CongestionStateChange
+
+	// only set for VersionNegotiationReceived events
+	SupportedVersions []protocol.VersionNumber
+
+	// only set for PanicRecovered events
+	Panic string
+	Stack []byte
+
+	// only set for FlowControlUpdated events
+	FlowControlTransition FlowControlTransition
+	FlowControlState      *FlowControlState
+}
+
+// TransportState contains some transport and congestion statistics
+type TransportState struct {
+	MinRTT      time.Duration
+	SmoothedRTT time.Duration
+	LatestRTT   time.Duration
+
+	BytesInFlight    protocol.ByteCount
+	CongestionWindow protocol.ByteCount
+	InSlowStart      bool
+	InRecovery       bool
+}
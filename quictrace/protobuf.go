@@ -0,0 +1,55 @@
+package quictrace
+
+// protoBuffer is a minimal protobuf encoder.
+// It only supports the wire types needed to encode the quic-trace messages:
+// varints (wire type 0) and length-delimited fields (wire type 2).
+type protoBuffer struct {
+	data []byte
+}
+
+const (
+	protoWireVarint uint64 = 0
+	protoWireBytes  uint64 = 2
+)
+
+func (b *protoBuffer) writeRawVarint(v uint64) {
+	for v >= 0x80 {
+		b.data = append(b.data, byte(v)|0x80)
+		v >>= 7
+	}
+	b.data = append(b.data, byte(v))
+}
+
+func (b *protoBuffer) writeTag(field int, wireType uint64) {
+	b.writeRawVarint(uint64(field)<<3 | wireType)
+}
+
+func (b *protoBuffer) writeUint64(field int, v uint64) {
+	b.writeTag(field, protoWireVarint)
+	b.writeRawVarint(v)
+}
+
+func (b *protoBuffer) writeBool(field int, v bool) {
+	var val uint64
+	if v {
+		val = 1
+	}
+	b.writeUint64(field, val)
+}
+
+func (b *protoBuffer) writeBytes(field int, v []byte) {
+	b.writeTag(field, protoWireBytes)
+	b.writeRawVarint(uint64(len(v)))
+	b.data = append(b.data, v...)
+}
+
+func (b *protoBuffer) writeString(field int, v string) {
+	b.writeBytes(field, []byte(v))
+}
+
+// writeMessage writes an embedded message
+func (b *protoBuffer) writeMessage(field int, encode func(*protoBuffer)) {
+	msg := &protoBuffer{}
+	encode(msg)
+	b.writeBytes(field, msg.data)
+}
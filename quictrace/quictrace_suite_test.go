@@ -0,0 +1,13 @@
+package quictrace
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestQuicTrace(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "quic-trace Suite")
+}
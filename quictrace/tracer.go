@@ -0,0 +1,246 @@
+package quictrace
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// field numbers and enum values as defined in the quic-trace protobuf definition,
+// see https://github.com/google/quic-trace/blob/master/lib/quic_trace.proto
+const (
+	traceFieldDestConnID = 3
+	traceFieldEvents     = 4
+
+	eventFieldTimeUs          = 1
+	eventFieldEventType       = 2
+	eventFieldPacketNumber    = 3
+	eventFieldFrames          = 4
+	eventFieldPacketSize      = 5
+	eventFieldEncryptionLevel = 6
+	eventFieldTransportState  = 7
+
+	transportStateFieldMinRTTUs               = 1
+	transportStateFieldSmoothedRTTUs          = 2
+	transportStateFieldLastRTTUs              = 3
+	transportStateFieldInFlightBytes          = 4
+	transportStateFieldCwndBytes              = 5
+	transportStateFieldCongestionControlState = 7
+
+	frameFieldFrameType       = 1
+	frameFieldStreamFrameInfo = 2
+	frameFieldAckInfo         = 3
+	frameFieldResetStreamInfo = 4
+	frameFieldCloseInfo       = 5
+	frameFieldFlowControlInfo = 6
+	frameFieldCryptoFrameInfo = 7
+)
+
+const (
+	pbFrameTypeUnknown         = 0
+	pbFrameTypeStream          = 1
+	pbFrameTypeAck             = 2
+	pbFrameTypeResetStream     = 3
+	pbFrameTypeConnectionClose = 4
+	pbFrameTypeMaxData         = 5
+	pbFrameTypeMaxStreamData   = 6
+	pbFrameTypePing            = 7
+	pbFrameTypeBlocked         = 8
+	pbFrameTypeStreamBlocked   = 9
+	pbFrameTypeCrypto          = 11
+)
+
+const (
+	pbEncryptionUnknown   = 0
+	pbEncryptionInitial   = 1
+	pbEncryption1RTT      = 3
+	pbEncryptionHandshake = 4
+)
+
+type tracer struct {
+	mutex  sync.Mutex
+	events map[string][]Event
+}
+
+var _ Tracer = &tracer{}
+
+// NewTracer creates a new Tracer
+func NewTracer() Tracer {
+	return &tracer{events: make(map[string][]Event)}
+}
+
+func (t *tracer) Trace(connID protocol.ConnectionID, ev Event) {
+	t.mutex.Lock()
+	t.events[string(connID)] = append(t.events[string(connID)], ev)
+	t.mutex.Unlock()
+}
+
+func (t *tracer) GetAllTraces() map[string][]byte {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	traces := make(map[string][]byte, len(t.events))
+	for connID, events := range t.events {
+		traces[connID] = encodeTrace(protocol.ConnectionID(connID), events)
+		delete(t.events, connID)
+	}
+	return traces
+}
+
+func encodeTrace(connID protocol.ConnectionID, events []Event) []byte {
+	b := &protoBuffer{}
+	b.writeBytes(traceFieldDestConnID, connID)
+	if len(events) == 0 {
+		return b.data
+	}
+	startTime := events[0].Time
+	for _, ev := range events {
+		// The quic-trace format doesn't have an event type for congestion state changes.
+		// The congestion state is contained in the transport state of the packet events.
+		if ev.EventType == CongestionStateUpdated {
+			continue
+		}
+		// The quic-trace format doesn't have an event type for Version Negotiation packets.
+		if ev.EventType == VersionNegotiationReceived {
+			continue
+		}
+		// The quic-trace format doesn't have an event type for panics.
+		if ev.EventType == PanicRecovered {
+			continue
+		}
+		// The quic-trace format doesn't have an event type for flow control state changes.
+		// The frames are contained in the packet events.
+		if ev.EventType == FlowControlUpdated {
+			continue
+		}
+		b.writeMessage(traceFieldEvents, func(b *protoBuffer) {
+			encodeEvent(b, ev, startTime)
+		})
+	}
+	return b.data
+}
+
+func encodeEvent(b *protoBuffer, ev Event, startTime time.Time) {
+	b.writeUint64(eventFieldTimeUs, durationToMicroseconds(ev.Time.Sub(startTime)))
+	b.writeUint64(eventFieldEventType, uint64(ev.EventType))
+	b.writeUint64(eventFieldPacketNumber, uint64(ev.PacketNumber))
+	for _, f := range ev.Frames {
+		b.writeMessage(eventFieldFrames, func(b *protoBuffer) { encodeFrame(b, f, ev.EncryptionLevel) })
+	}
+	b.writeUint64(eventFieldPacketSize, uint64(ev.PacketSize))
+	b.writeUint64(eventFieldEncryptionLevel, encodeEncryptionLevel(ev.EncryptionLevel))
+	if ev.TransportState != nil {
+		b.writeMessage(eventFieldTransportState, func(b *protoBuffer) { encodeTransportState(b, ev.TransportState) })
+	}
+}
+
+func encodeEncryptionLevel(encLevel protocol.EncryptionLevel) uint64 {
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		return pbEncryptionInitial
+	case protocol.EncryptionHandshake:
+		return pbEncryptionHandshake
+	case protocol.Encryption1RTT:
+		return pbEncryption1RTT
+	default:
+		return pbEncryptionUnknown
+	}
+}
+
+func encodeTransportState(b *protoBuffer, state *TransportState) {
+	b.writeUint64(transportStateFieldMinRTTUs, durationToMicroseconds(state.MinRTT))
+	b.writeUint64(transportStateFieldSmoothedRTTUs, durationToMicroseconds(state.SmoothedRTT))
+	b.writeUint64(transportStateFieldLastRTTUs, durationToMicroseconds(state.LatestRTT))
+	b.writeUint64(transportStateFieldInFlightBytes, uint64(state.BytesInFlight))
+	b.writeUint64(transportStateFieldCwndBytes, uint64(state.CongestionWindow))
+	b.writeString(transportStateFieldCongestionControlState, congestionControlState(state))
+}
+
+func congestionControlState(state *TransportState) string {
+	if state.InRecovery {
+		return "recovery"
+	}
+	if state.InSlowStart {
+		return "slow start"
+	}
+	return "congestion avoidance"
+}
+
+func encodeFrame(b *protoBuffer, f wire.Frame, encLevel protocol.EncryptionLevel) {
+	switch frame := f.(type) {
+	case *wire.StreamFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeStream)
+		b.writeMessage(frameFieldStreamFrameInfo, func(b *protoBuffer) {
+			b.writeUint64(1, uint64(frame.StreamID))
+			b.writeBool(2, frame.FinBit)
+			b.writeUint64(3, uint64(frame.DataLen()))
+			b.writeUint64(4, uint64(frame.Offset))
+		})
+	case *wire.CryptoFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeCrypto)
+		b.writeMessage(frameFieldCryptoFrameInfo, func(b *protoBuffer) {
+			b.writeUint64(1, encodeEncryptionLevel(encLevel))
+			b.writeUint64(2, uint64(frame.Offset))
+			b.writeUint64(3, uint64(len(frame.Data)))
+		})
+	case *wire.AckFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeAck)
+		b.writeMessage(frameFieldAckInfo, func(b *protoBuffer) {
+			for _, r := range frame.AckRanges {
+				b.writeMessage(1, func(b *protoBuffer) {
+					b.writeUint64(1, uint64(r.Smallest))
+					b.writeUint64(2, uint64(r.Largest))
+				})
+			}
+			b.writeUint64(2, durationToMicroseconds(frame.DelayTime))
+		})
+	case *wire.ResetStreamFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeResetStream)
+		b.writeMessage(frameFieldResetStreamInfo, func(b *protoBuffer) {
+			b.writeUint64(1, uint64(frame.StreamID))
+			b.writeUint64(2, uint64(frame.ErrorCode))
+			b.writeUint64(3, uint64(frame.ByteOffset))
+		})
+	case *wire.ConnectionCloseFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeConnectionClose)
+		b.writeMessage(frameFieldCloseInfo, func(b *protoBuffer) {
+			b.writeUint64(1, uint64(frame.ErrorCode))
+			b.writeString(2, frame.ReasonPhrase)
+		})
+	case *wire.MaxDataFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeMaxData)
+		b.writeMessage(frameFieldFlowControlInfo, func(b *protoBuffer) {
+			b.writeUint64(1, uint64(frame.ByteOffset))
+		})
+	case *wire.MaxStreamDataFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeMaxStreamData)
+		b.writeMessage(frameFieldFlowControlInfo, func(b *protoBuffer) {
+			b.writeUint64(2, uint64(frame.ByteOffset))
+			b.writeUint64(3, uint64(frame.StreamID))
+		})
+	case *wire.PingFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypePing)
+	case *wire.DataBlockedFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeBlocked)
+		b.writeMessage(frameFieldFlowControlInfo, func(b *protoBuffer) {
+			b.writeUint64(1, uint64(frame.DataLimit))
+		})
+	case *wire.StreamDataBlockedFrame:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeStreamBlocked)
+		b.writeMessage(frameFieldFlowControlInfo, func(b *protoBuffer) {
+			b.writeUint64(2, uint64(frame.DataLimit))
+			b.writeUint64(3, uint64(frame.StreamID))
+		})
+	default:
+		b.writeUint64(frameFieldFrameType, pbFrameTypeUnknown)
+	}
+}
+
+func durationToMicroseconds(d time.Duration) uint64 {
+	if d < 0 {
+		return 0
+	}
+	return uint64(d / time.Microsecond)
+}
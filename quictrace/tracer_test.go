@@ -0,0 +1,242 @@
+package quictrace
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// protoField is a field of a decoded protobuf message
+type protoField struct {
+	varint uint64
+	bytes  []byte
+}
+
+// decodeProto decodes a protobuf message containing varint and length-delimited fields
+func decodeProto(data []byte) map[int][]protoField {
+	readVarint := func() uint64 {
+		var v uint64
+		for shift := uint(0); ; shift += 7 {
+			ExpectWithOffset(2, data).ToNot(BeEmpty())
+			b := data[0]
+			data = data[1:]
+			v |= uint64(b&0x7f) << shift
+			if b < 0x80 {
+				return v
+			}
+		}
+	}
+	fields := make(map[int][]protoField)
+	for len(data) > 0 {
+		tag := readVarint()
+		field := int(tag >> 3)
+		switch tag & 0x7 {
+		case protoWireVarint:
+			fields[field] = append(fields[field], protoField{varint: readVarint()})
+		case protoWireBytes:
+			l := readVarint()
+			ExpectWithOffset(1, len(data)).To(BeNumerically(">=", l))
+			fields[field] = append(fields[field], protoField{bytes: data[:l]})
+			data = data[l:]
+		default:
+			Fail("unexpected wire type")
+		}
+	}
+	return fields
+}
+
+var _ = Describe("Tracer", func() {
+	var tr Tracer
+
+	BeforeEach(func() {
+		tr = NewTracer()
+	})
+
+	It("returns no traces if nothing was traced", func() {
+		Expect(tr.GetAllTraces()).To(BeEmpty())
+	})
+
+	It("encodes protobuf varints", func() {
+		b := &protoBuffer{}
+		b.writeUint64(1, 300)
+		Expect(b.data).To(Equal([]byte{0x8, 0xac, 0x2}))
+	})
+
+	It("traces events per connection", func() {
+		now := time.Now()
+		tr.Trace(protocol.ConnectionID{1, 2, 3, 4}, Event{Time: now, EventType: PacketSent})
+		tr.Trace(protocol.ConnectionID{5, 6, 7, 8}, Event{Time: now, EventType: PacketReceived})
+		tr.Trace(protocol.ConnectionID{1, 2, 3, 4}, Event{Time: now, EventType: PacketLost})
+		traces := tr.GetAllTraces()
+		Expect(traces).To(HaveLen(2))
+		trace := decodeProto(traces[string([]byte{1, 2, 3, 4})])
+		Expect(trace[traceFieldDestConnID][0].bytes).To(Equal([]byte{1, 2, 3, 4}))
+		Expect(trace[traceFieldEvents]).To(HaveLen(2))
+		Expect(decodeProto(trace[traceFieldEvents][1].bytes)[eventFieldEventType][0].varint).To(BeEquivalentTo(PacketLost))
+		trace = decodeProto(traces[string([]byte{5, 6, 7, 8})])
+		Expect(trace[traceFieldEvents]).To(HaveLen(1))
+	})
+
+	It("deletes traces after returning them", func() {
+		tr.Trace(protocol.ConnectionID{1, 2, 3, 4}, Event{Time: time.Now(), EventType: PacketSent})
+		Expect(tr.GetAllTraces()).To(HaveLen(1))
+		Expect(tr.GetAllTraces()).To(BeEmpty())
+	})
+
+	It("doesn't encode congestion state updates", func() {
+		now := time.Now()
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: PacketSent})
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: CongestionStateUpdated, CongestionStateChange: RecoveryEntered})
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: PacketLost})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		Expect(trace[traceFieldEvents]).To(HaveLen(2))
+		Expect(decodeProto(trace[traceFieldEvents][1].bytes)[eventFieldEventType][0].varint).To(BeEquivalentTo(PacketLost))
+	})
+
+	It("doesn't encode Version Negotiation packets", func() {
+		now := time.Now()
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: VersionNegotiationReceived, SupportedVersions: []protocol.VersionNumber{1, 2}})
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: PacketSent})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		Expect(trace[traceFieldEvents]).To(HaveLen(1))
+		Expect(decodeProto(trace[traceFieldEvents][0].bytes)[eventFieldEventType][0].varint).To(BeEquivalentTo(PacketSent))
+	})
+
+	It("doesn't encode panics", func() {
+		now := time.Now()
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: PacketSent})
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: PanicRecovered, Panic: "panic: foobar"})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		Expect(trace[traceFieldEvents]).To(HaveLen(1))
+	})
+
+	It("doesn't encode flow control state changes", func() {
+		now := time.Now()
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: now, EventType: PacketSent})
+		tr.Trace(protocol.ConnectionID{1}, Event{
+			Time:                  now,
+			EventType:             FlowControlUpdated,
+			FlowControlTransition: Blocked,
+			FlowControlState:      &FlowControlState{Connection: true, SendWindow: 1000, BlockedSince: now},
+		})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		Expect(trace[traceFieldEvents]).To(HaveLen(1))
+	})
+
+	It("encodes events", func() {
+		start := time.Now()
+		tr.Trace(protocol.ConnectionID{1}, Event{Time: start, EventType: PacketSent})
+		tr.Trace(protocol.ConnectionID{1}, Event{
+			Time:            start.Add(1337 * time.Microsecond),
+			EventType:       PacketReceived,
+			EncryptionLevel: protocol.EncryptionHandshake,
+			PacketNumber:    42,
+			PacketSize:      1234,
+			TransportState: &TransportState{
+				MinRTT:           10 * time.Millisecond,
+				SmoothedRTT:      15 * time.Millisecond,
+				LatestRTT:        20 * time.Millisecond,
+				BytesInFlight:    1000,
+				CongestionWindow: 2000,
+				InSlowStart:      true,
+			},
+		})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		Expect(trace[traceFieldEvents]).To(HaveLen(2))
+		ev := decodeProto(trace[traceFieldEvents][1].bytes)
+		Expect(ev[eventFieldTimeUs][0].varint).To(BeEquivalentTo(1337))
+		Expect(ev[eventFieldEventType][0].varint).To(BeEquivalentTo(PacketReceived))
+		Expect(ev[eventFieldPacketNumber][0].varint).To(BeEquivalentTo(42))
+		Expect(ev[eventFieldPacketSize][0].varint).To(BeEquivalentTo(1234))
+		Expect(ev[eventFieldEncryptionLevel][0].varint).To(BeEquivalentTo(pbEncryptionHandshake))
+		state := decodeProto(ev[eventFieldTransportState][0].bytes)
+		Expect(state[transportStateFieldMinRTTUs][0].varint).To(BeEquivalentTo(10000))
+		Expect(state[transportStateFieldSmoothedRTTUs][0].varint).To(BeEquivalentTo(15000))
+		Expect(state[transportStateFieldLastRTTUs][0].varint).To(BeEquivalentTo(20000))
+		Expect(state[transportStateFieldInFlightBytes][0].varint).To(BeEquivalentTo(1000))
+		Expect(state[transportStateFieldCwndBytes][0].varint).To(BeEquivalentTo(2000))
+		Expect(string(state[transportStateFieldCongestionControlState][0].bytes)).To(Equal("slow start"))
+	})
+
+	It("encodes frames", func() {
+		tr.Trace(protocol.ConnectionID{1}, Event{
+			Time:      time.Now(),
+			EventType: PacketSent,
+			Frames: []wire.Frame{
+				&wire.StreamFrame{StreamID: 5, Offset: 100, Data: []byte("foobar"), FinBit: true},
+				&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 7, Largest: 9}, {Smallest: 1, Largest: 3}}},
+				&wire.PingFrame{},
+			},
+		})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		frames := decodeProto(trace[traceFieldEvents][0].bytes)[eventFieldFrames]
+		Expect(frames).To(HaveLen(3))
+		streamFrame := decodeProto(frames[0].bytes)
+		Expect(streamFrame[frameFieldFrameType][0].varint).To(BeEquivalentTo(pbFrameTypeStream))
+		info := decodeProto(streamFrame[frameFieldStreamFrameInfo][0].bytes)
+		Expect(info[1][0].varint).To(BeEquivalentTo(5))
+		Expect(info[2][0].varint).To(BeEquivalentTo(1))
+		Expect(info[3][0].varint).To(BeEquivalentTo(6))
+		Expect(info[4][0].varint).To(BeEquivalentTo(100))
+		ackFrame := decodeProto(frames[1].bytes)
+		Expect(ackFrame[frameFieldFrameType][0].varint).To(BeEquivalentTo(pbFrameTypeAck))
+		blocks := decodeProto(ackFrame[frameFieldAckInfo][0].bytes)[1]
+		Expect(blocks).To(HaveLen(2))
+		block := decodeProto(blocks[1].bytes)
+		Expect(block[1][0].varint).To(BeEquivalentTo(1))
+		Expect(block[2][0].varint).To(BeEquivalentTo(3))
+		Expect(decodeProto(frames[2].bytes)[frameFieldFrameType][0].varint).To(BeEquivalentTo(pbFrameTypePing))
+	})
+
+	It("encodes CRYPTO frames", func() {
+		tr.Trace(protocol.ConnectionID{1}, Event{
+			Time:            time.Now(),
+			EventType:       PacketSent,
+			EncryptionLevel: protocol.EncryptionHandshake,
+			Frames:          []wire.Frame{&wire.CryptoFrame{Offset: 100, Data: []byte("foobar")}},
+		})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		frames := decodeProto(trace[traceFieldEvents][0].bytes)[eventFieldFrames]
+		Expect(frames).To(HaveLen(1))
+		cryptoFrame := decodeProto(frames[0].bytes)
+		Expect(cryptoFrame[frameFieldFrameType][0].varint).To(BeEquivalentTo(pbFrameTypeCrypto))
+		info := decodeProto(cryptoFrame[frameFieldCryptoFrameInfo][0].bytes)
+		Expect(info).To(HaveLen(3))
+		Expect(info[1][0].varint).To(BeEquivalentTo(pbEncryptionHandshake))
+		Expect(info[2][0].varint).To(BeEquivalentTo(100))
+		Expect(info[3][0].varint).To(BeEquivalentTo(6))
+	})
+
+	It("encodes flow control frames", func() {
+		tr.Trace(protocol.ConnectionID{1}, Event{
+			Time:      time.Now(),
+			EventType: PacketSent,
+			Frames: []wire.Frame{
+				&wire.MaxStreamDataFrame{StreamID: 7, ByteOffset: 1000},
+				&wire.DataBlockedFrame{DataLimit: 2000},
+				&wire.StreamDataBlockedFrame{StreamID: 9, DataLimit: 3000},
+			},
+		})
+		trace := decodeProto(tr.GetAllTraces()[string([]byte{1})])
+		frames := decodeProto(trace[traceFieldEvents][0].bytes)[eventFieldFrames]
+		Expect(frames).To(HaveLen(3))
+		maxStreamData := decodeProto(frames[0].bytes)
+		Expect(maxStreamData[frameFieldFrameType][0].varint).To(BeEquivalentTo(pbFrameTypeMaxStreamData))
+		info := decodeProto(maxStreamData[frameFieldFlowControlInfo][0].bytes)
+		Expect(info[2][0].varint).To(BeEquivalentTo(1000))
+		Expect(info[3][0].varint).To(BeEquivalentTo(7))
+		blocked := decodeProto(frames[1].bytes)
+		Expect(blocked[frameFieldFrameType][0].varint).To(BeEquivalentTo(pbFrameTypeBlocked))
+		info = decodeProto(blocked[frameFieldFlowControlInfo][0].bytes)
+		Expect(info[1][0].varint).To(BeEquivalentTo(2000))
+		streamBlocked := decodeProto(frames[2].bytes)
+		Expect(streamBlocked[frameFieldFrameType][0].varint).To(BeEquivalentTo(pbFrameTypeStreamBlocked))
+		info = decodeProto(streamBlocked[frameFieldFlowControlInfo][0].bytes)
+		Expect(info[2][0].varint).To(BeEquivalentTo(3000))
+		Expect(info[3][0].varint).To(BeEquivalentTo(9))
+	})
+})
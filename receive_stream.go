@@ -45,8 +45,9 @@ type receiveStream struct {
 	canceledRead      bool // set when CancelRead() is called
 	resetRemotely     bool // set when HandleResetStreamFrame() is called
 
-	readChan chan struct{}
-	deadline time.Time
+	readChan         chan struct{}
+	deadline         time.Time
+	sessionDeadlines *sessionDeadlines
 
 	flowController flowcontrol.StreamFlowController
 	version        protocol.VersionNumber
@@ -59,16 +60,18 @@ func newReceiveStream(
 	streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	sessionDeadlines *sessionDeadlines,
 	version protocol.VersionNumber,
 ) *receiveStream {
 	return &receiveStream{
-		streamID:       streamID,
-		sender:         sender,
-		flowController: flowController,
-		frameQueue:     newFrameSorter(),
-		readChan:       make(chan struct{}, 1),
-		finalOffset:    protocol.MaxByteCount,
-		version:        version,
+		streamID:         streamID,
+		sender:           sender,
+		flowController:   flowController,
+		frameQueue:       newFrameSorter(),
+		readChan:         make(chan struct{}, 1),
+		finalOffset:      protocol.MaxByteCount,
+		sessionDeadlines: sessionDeadlines,
+		version:          version,
 	}
 }
 
@@ -89,17 +92,8 @@ func (s *receiveStream) Read(p []byte) (int, error) {
 }
 
 func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
-	if s.finRead {
-		return false, 0, io.EOF
-	}
-	if s.canceledRead {
-		return false, 0, s.cancelReadErr
-	}
-	if s.resetRemotely {
-		return false, 0, s.resetRemotelyErr
-	}
-	if s.closedForShutdown {
-		return false, 0, s.closeForShutdownErr
+	if err := s.readError(); err != nil {
+		return false, 0, err
 	}
 
 	bytesRead := 0
@@ -111,48 +105,8 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			return false, bytesRead, s.closeForShutdownErr
 		}
 
-		var deadlineTimer *utils.Timer
-		for {
-			// Stop waiting on errors
-			if s.closedForShutdown {
-				return false, bytesRead, s.closeForShutdownErr
-			}
-			if s.canceledRead {
-				return false, bytesRead, s.cancelReadErr
-			}
-			if s.resetRemotely {
-				return false, bytesRead, s.resetRemotelyErr
-			}
-
-			deadline := s.deadline
-			if !deadline.IsZero() {
-				if !time.Now().Before(deadline) {
-					return false, bytesRead, errDeadline
-				}
-				if deadlineTimer == nil {
-					deadlineTimer = utils.NewTimer()
-				}
-				deadlineTimer.Reset(deadline)
-			}
-
-			if s.currentFrame != nil || s.currentFrameIsLast {
-				break
-			}
-
-			s.mutex.Unlock()
-			if deadline.IsZero() {
-				<-s.readChan
-			} else {
-				select {
-				case <-s.readChan:
-				case <-deadlineTimer.Chan():
-					deadlineTimer.SetRead()
-				}
-			}
-			s.mutex.Lock()
-			if s.currentFrame == nil {
-				s.dequeueNextFrame()
-			}
+		if err := s.waitForData(); err != nil {
+			return false, bytesRead, err
 		}
 
 		if bytesRead > len(p) {
@@ -183,6 +137,123 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 	return false, bytesRead, nil
 }
 
+// ReadChunk returns the next chunk of contiguous data received on the stream, without copying it.
+func (s *receiveStream) ReadChunk() ([]byte, error) {
+	s.mutex.Lock()
+	completed, data, err := s.readChunkImpl()
+	s.mutex.Unlock()
+
+	if completed {
+		s.streamCompleted()
+	}
+	return data, err
+}
+
+func (s *receiveStream) readChunkImpl() (bool /*stream completed */, []byte, error) {
+	if err := s.readError(); err != nil {
+		return false, nil, err
+	}
+
+	for {
+		if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+			s.dequeueNextFrame()
+		}
+		if err := s.waitForData(); err != nil {
+			return false, nil, err
+		}
+		if s.readPosInFrame > len(s.currentFrame) {
+			return false, nil, fmt.Errorf("BUG: readPosInFrame (%d) > frame.DataLen (%d) in stream.ReadChunk", s.readPosInFrame, len(s.currentFrame))
+		}
+
+		data := s.currentFrame[s.readPosInFrame:]
+		s.readPosInFrame = len(s.currentFrame)
+		s.readOffset += protocol.ByteCount(len(data))
+		// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
+		if !s.resetRemotely {
+			s.flowController.AddBytesRead(protocol.ByteCount(len(data)))
+		}
+
+		if s.currentFrameIsLast {
+			s.finRead = true
+			return true, data, io.EOF
+		}
+		if len(data) > 0 {
+			return false, data, nil
+		}
+	}
+}
+
+// readError returns the error that Read returns before reading any data.
+// It must be called with the mutex held.
+func (s *receiveStream) readError() error {
+	if s.finRead {
+		return io.EOF
+	}
+	if s.canceledRead {
+		return s.cancelReadErr
+	}
+	if s.resetRemotely {
+		return s.resetRemotelyErr
+	}
+	if s.closedForShutdown {
+		return s.closeForShutdownErr
+	}
+	return nil
+}
+
+// waitForData blocks until there's data to read in the current frame, or until the current frame is the last frame.
+// It must be called with the mutex held, and releases the mutex while blocking.
+func (s *receiveStream) waitForData() error {
+	var deadlineTimer *utils.Timer
+	for {
+		// Stop waiting on errors
+		if s.closedForShutdown {
+			return s.closeForShutdownErr
+		}
+		if s.canceledRead {
+			return s.cancelReadErr
+		}
+		if s.resetRemotely {
+			return s.resetRemotelyErr
+		}
+
+		sessionDeadline, deadlineChanged := s.sessionDeadlines.readDeadline()
+		deadline := earliestDeadline(s.deadline, sessionDeadline)
+		if !deadline.IsZero() {
+			if !s.sessionDeadlines.getClock().Now().Before(deadline) {
+				return errDeadline
+			}
+			if deadlineTimer == nil {
+				deadlineTimer = utils.NewTimerWithClock(s.sessionDeadlines.getClock())
+			}
+			deadlineTimer.Reset(deadline)
+		}
+
+		if s.currentFrame != nil || s.currentFrameIsLast {
+			return nil
+		}
+
+		s.mutex.Unlock()
+		if deadline.IsZero() {
+			select {
+			case <-s.readChan:
+			case <-deadlineChanged:
+			}
+		} else {
+			select {
+			case <-s.readChan:
+			case <-deadlineTimer.Chan():
+				deadlineTimer.SetRead()
+			case <-deadlineChanged:
+			}
+		}
+		s.mutex.Lock()
+		if s.currentFrame == nil {
+			s.dequeueNextFrame()
+		}
+	}
+}
+
 func (s *receiveStream) dequeueNextFrame() {
 	var offset protocol.ByteCount
 	offset, s.currentFrame = s.frameQueue.Pop()
@@ -289,6 +360,10 @@ func (s *receiveStream) SetReadDeadline(t time.Time) error {
 	return nil
 }
 
+func (s *receiveStream) SetReceiveWindow(size uint64) {
+	s.flowController.SetReceiveWindowSize(protocol.ByteCount(size))
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Read unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RESET.
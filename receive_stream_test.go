@@ -9,6 +9,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -24,12 +25,14 @@ var _ = Describe("Receive Stream", func() {
 		strWithTimeout io.Reader // str wrapped with gbytes.TimeoutReader
 		mockFC         *mocks.MockStreamFlowController
 		mockSender     *MockStreamSender
+		sessDeadlines  *sessionDeadlines
 	)
 
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newReceiveStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		sessDeadlines = newSessionDeadlines(utils.DefaultClock)
+		str = newReceiveStream(streamID, mockSender, mockFC, sessDeadlines, protocol.VersionWhatever)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutReader(str, timeout)
@@ -249,6 +252,36 @@ var _ = Describe("Receive Stream", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("returns an error when Read is called after the session's deadline", func() {
+				sessDeadlines.SetReadDeadline(time.Now().Add(-time.Second))
+				n, err := strWithTimeout.Read(make([]byte, 6))
+				Expect(err).To(MatchError(errDeadline))
+				Expect(n).To(BeZero())
+			})
+
+			It("unblocks after the session's deadline, if it is earlier than the stream's deadline", func() {
+				str.SetReadDeadline(time.Now().Add(time.Hour))
+				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+				sessDeadlines.SetReadDeadline(deadline)
+				n, err := strWithTimeout.Read(make([]byte, 6))
+				Expect(err).To(MatchError(errDeadline))
+				Expect(n).To(BeZero())
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(10*time.Millisecond)))
+			})
+
+			It("unblocks when the session's deadline is set while blocked", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Read(make([]byte, 6))
+					Expect(err).To(MatchError(errDeadline))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				sessDeadlines.SetReadDeadline(time.Now().Add(-time.Hour))
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("unblocks after the deadline", func() {
 				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
 				str.SetReadDeadline(deadline)
@@ -444,6 +477,109 @@ var _ = Describe("Receive Stream", func() {
 				Expect(err).To(MatchError(testErr))
 			})
 		})
+
+		Context("reading chunks", func() {
+			It("returns the data of a STREAM frame without copying it", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				frame := wire.StreamFrame{Data: []byte{0xde, 0xad, 0xbe, 0xef}}
+				Expect(str.handleStreamFrame(&frame)).To(Succeed())
+				data, err := str.ReadChunk()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+				Expect(&data[0]).To(Equal(&frame.Data[0]))
+			})
+
+			It("returns one chunk per STREAM frame", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xbe, 0xef}})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				data, err := str.ReadChunk()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xde, 0xad}))
+				data, err = str.ReadChunk()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xbe, 0xef}))
+			})
+
+			It("returns the rest of a STREAM frame that was partially read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad, 0xbe, 0xef}})).To(Succeed())
+				b := make([]byte, 1)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(1))
+				data, err := str.ReadChunk()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xad, 0xbe, 0xef}))
+			})
+
+			It("waits until data is available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				go func() {
+					defer GinkgoRecover()
+					time.Sleep(10 * time.Millisecond)
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				}()
+				data, err := str.ReadChunk()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xde, 0xad}))
+			})
+
+			It("returns io.EOF together with the last chunk", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Data:   []byte{0xde, 0xad, 0xbe, 0xef},
+					FinBit: true,
+				})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data, err := str.ReadChunk()
+				Expect(err).To(MatchError(io.EOF))
+				Expect(data).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+				data, err = str.ReadChunk()
+				Expect(err).To(MatchError(io.EOF))
+				Expect(data).To(BeEmpty())
+			})
+
+			It("handles immediate FINs", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(0))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{FinBit: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data, err := str.ReadChunk()
+				Expect(err).To(MatchError(io.EOF))
+				Expect(data).To(BeEmpty())
+			})
+
+			It("returns an error when the deadline expires", func() {
+				deadline := time.Now().Add(scaleDuration(20 * time.Millisecond))
+				str.SetReadDeadline(deadline)
+				data, err := str.ReadChunk()
+				Expect(err).To(MatchError(errDeadline))
+				Expect(data).To(BeEmpty())
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+			})
+
+			It("unblocks when the stream is closed for shutdown", func() {
+				testErr := errors.New("test error")
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.ReadChunk()
+					Expect(err).To(MatchError(testErr))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				str.closeForShutdown(testErr)
+				Eventually(done).Should(BeClosed())
+			})
+		})
 	})
 
 	Context("stream cancelations", func() {
@@ -621,5 +757,10 @@ var _ = Describe("Receive Stream", func() {
 			mockFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0x100))
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
+
+		It("sets the receive window", func() {
+			mockFC.EXPECT().SetReceiveWindowSize(protocol.ByteCount(1 << 24))
+			str.SetReceiveWindow(1 << 24)
+		})
 	})
 })
@@ -17,8 +17,11 @@ type sendStreamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	hasData() bool
 	popStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool)
+	getGroup() string
 	closeForShutdown(error)
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	handleAckedStreamFrame(*wire.StreamFrame)
+	allDataAcked() bool
 }
 
 type sendStream struct {
@@ -42,8 +45,16 @@ type sendStream struct {
 
 	dataForWriting []byte
 
-	writeChan chan struct{}
-	deadline  time.Time
+	ackedOffset        protocol.ByteCount     // all data below this offset was acknowledged
+	ackedRanges        utils.ByteIntervalList // acknowledged ranges above the ackedOffset, sorted and not overlapping
+	finAcked           bool
+	writeAckedCallback func(uint64)
+
+	writeChan        chan struct{}
+	deadline         time.Time
+	sessionDeadlines *sessionDeadlines
+
+	group string // the group used for scheduling, see SetGroup
 
 	flowController flowcontrol.StreamFlowController
 
@@ -57,14 +68,16 @@ func newSendStream(
 	streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	sessionDeadlines *sessionDeadlines,
 	version protocol.VersionNumber,
 ) *sendStream {
 	s := &sendStream{
-		streamID:       streamID,
-		sender:         sender,
-		flowController: flowController,
-		writeChan:      make(chan struct{}, 1),
-		version:        version,
+		streamID:         streamID,
+		sender:           sender,
+		flowController:   flowController,
+		writeChan:        make(chan struct{}, 1),
+		sessionDeadlines: sessionDeadlines,
+		version:          version,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	return s
@@ -87,7 +100,7 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	if s.closeForShutdownErr != nil {
 		return 0, s.closeForShutdownErr
 	}
-	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+	if deadline, _ := s.getDeadline(); !deadline.IsZero() && !s.sessionDeadlines.getClock().Now().Before(deadline) {
 		return 0, errDeadline
 	}
 	if len(p) == 0 {
@@ -103,14 +116,14 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	)
 	for {
 		bytesWritten = len(p) - len(s.dataForWriting)
-		deadline := s.deadline
+		deadline, deadlineChanged := s.getDeadline()
 		if !deadline.IsZero() {
-			if !time.Now().Before(deadline) {
+			if !s.sessionDeadlines.getClock().Now().Before(deadline) {
 				s.dataForWriting = nil
 				return bytesWritten, errDeadline
 			}
 			if deadlineTimer == nil {
-				deadlineTimer = utils.NewTimer()
+				deadlineTimer = utils.NewTimerWithClock(s.sessionDeadlines.getClock())
 			}
 			deadlineTimer.Reset(deadline)
 		}
@@ -124,12 +137,16 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			notifiedSender = true
 		}
 		if deadline.IsZero() {
-			<-s.writeChan
+			select {
+			case <-s.writeChan:
+			case <-deadlineChanged:
+			}
 		} else {
 			select {
 			case <-s.writeChan:
 			case <-deadlineTimer.Chan():
 				deadlineTimer.SetRead()
+			case <-deadlineChanged:
 			}
 		}
 		s.mutex.Lock()
@@ -299,10 +316,88 @@ func (s *sendStream) handleStopSendingFrameImpl(frame *wire.StopSendingFrame) bo
 	return s.cancelWriteImpl(errorCodeStopping, writeErr)
 }
 
+// handleAckedStreamFrame is called when a packet containing a STREAM frame for this stream was acknowledged.
+// The same data may be acknowledged multiple times, in any order.
+func (s *sendStream) handleAckedStreamFrame(frame *wire.StreamFrame) {
+	s.mutex.Lock()
+	if frame.FinBit {
+		s.finAcked = true
+	}
+	offset := s.ackedOffset
+	s.addAckedRange(frame.Offset, frame.Offset+frame.DataLen())
+	increased := s.ackedOffset > offset
+	ackedOffset := s.ackedOffset
+	callback := s.writeAckedCallback
+	s.mutex.Unlock()
+
+	if increased && callback != nil {
+		callback(uint64(ackedOffset))
+	}
+}
+
+// must be called after locking the mutex
+func (s *sendStream) addAckedRange(start, end protocol.ByteCount) {
+	if end <= s.ackedOffset {
+		return
+	}
+	start = utils.MaxByteCount(start, s.ackedOffset)
+	// find the first range that ends at or after the start of the new range
+	el := s.ackedRanges.Front()
+	for el != nil && el.Value.End < start {
+		el = el.Next()
+	}
+	// merge all ranges that overlap with (or are adjacent to) the new range
+	for el != nil && el.Value.Start <= end {
+		start = utils.MinByteCount(start, el.Value.Start)
+		end = utils.MaxByteCount(end, el.Value.End)
+		next := el.Next()
+		s.ackedRanges.Remove(el)
+		el = next
+	}
+	if start == s.ackedOffset {
+		s.ackedOffset = end
+		return
+	}
+	interval := utils.ByteInterval{Start: start, End: end}
+	if el == nil {
+		s.ackedRanges.PushBack(interval)
+	} else {
+		s.ackedRanges.InsertBefore(interval, el)
+	}
+}
+
+// allDataAcked says if acknowledgements for this stream are not needed any more.
+// This is the case when all data and the FIN were acknowledged, or when the stream was canceled.
+func (s *sendStream) allDataAcked() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.canceledWrite || (s.finAcked && s.ackedOffset == s.writeOffset)
+}
+
+func (s *sendStream) WriteAcked() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return uint64(s.ackedOffset)
+}
+
+func (s *sendStream) OnWriteAcked(callback func(uint64)) {
+	s.mutex.Lock()
+	s.writeAckedCallback = callback
+	s.mutex.Unlock()
+}
+
 func (s *sendStream) Context() context.Context {
 	return s.ctx
 }
 
+// getDeadline gets the deadline for writing, taking into account the deadline set on the session.
+// It also returns a channel that is closed when the session's deadline changes.
+// It must be called with the mutex held.
+func (s *sendStream) getDeadline() (time.Time, <-chan struct{}) {
+	sessionDeadline, changed := s.sessionDeadlines.writeDeadline()
+	return earliestDeadline(s.deadline, sessionDeadline), changed
+}
+
 func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
@@ -311,6 +406,18 @@ func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+func (s *sendStream) SetGroup(group string) {
+	s.mutex.Lock()
+	s.group = group
+	s.mutex.Unlock()
+}
+
+func (s *sendStream) getGroup() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.group
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Write unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
@@ -2,14 +2,19 @@ package quic
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"io"
+	"io/ioutil"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -25,12 +30,14 @@ var _ = Describe("Send Stream", func() {
 		strWithTimeout io.Writer // str wrapped with gbytes.TimeoutWriter
 		mockFC         *mocks.MockStreamFlowController
 		mockSender     *MockStreamSender
+		sessDeadlines  *sessionDeadlines
 	)
 
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newSendStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		sessDeadlines = newSessionDeadlines(utils.DefaultClock)
+		str = newSendStream(streamID, mockSender, mockFC, sessDeadlines, protocol.VersionWhatever)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutWriter(str, timeout)
@@ -49,6 +56,12 @@ var _ = Describe("Send Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("sets the group", func() {
+		Expect(str.getGroup()).To(BeEmpty())
+		str.SetGroup("foobar")
+		Expect(str.getGroup()).To(Equal("foobar"))
+	})
+
 	Context("writing", func() {
 		It("writes and gets all data at once", func() {
 			mockSender.EXPECT().onHasStreamData(streamID)
@@ -246,6 +259,38 @@ var _ = Describe("Send Stream", func() {
 				Expect(n).To(BeZero())
 			})
 
+			It("returns an error when Write is called after the session's deadline", func() {
+				sessDeadlines.SetWriteDeadline(time.Now().Add(-time.Second))
+				n, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).To(MatchError(errDeadline))
+				Expect(n).To(BeZero())
+			})
+
+			It("unblocks after the session's deadline, if it is earlier than the stream's deadline", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				str.SetWriteDeadline(time.Now().Add(time.Hour))
+				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+				sessDeadlines.SetWriteDeadline(deadline)
+				n, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).To(MatchError(errDeadline))
+				Expect(n).To(BeZero())
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+			})
+
+			It("unblocks when the session's deadline is set while blocked", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Write([]byte("foobar"))
+					Expect(err).To(MatchError(errDeadline))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				sessDeadlines.SetWriteDeadline(time.Now().Add(-time.Hour))
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("unblocks after the deadline", func() {
 				mockSender.EXPECT().onHasStreamData(streamID)
 				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
@@ -517,6 +562,125 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("handling acknowledgements", func() {
+		ack := func(offset protocol.ByteCount, data string, fin bool) {
+			str.handleAckedStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Offset:   offset,
+				Data:     []byte(data),
+				FinBit:   fin,
+			})
+		}
+
+		It("counts the acknowledged bytes", func() {
+			Expect(str.WriteAcked()).To(BeZero())
+			ack(0, "foo", false)
+			Expect(str.WriteAcked()).To(BeEquivalentTo(3))
+			ack(3, "bar", false)
+			Expect(str.WriteAcked()).To(BeEquivalentTo(6))
+		})
+
+		It("only counts data after all data before it was acknowledged", func() {
+			ack(6, "foo", false)
+			ack(12, "bar", false)
+			Expect(str.WriteAcked()).To(BeZero())
+			Expect(str.ackedRanges.Len()).To(Equal(2))
+			ack(0, "foobar", false)
+			Expect(str.WriteAcked()).To(BeEquivalentTo(9))
+			ack(9, "foo", false)
+			Expect(str.WriteAcked()).To(BeEquivalentTo(15))
+			Expect(str.ackedRanges.Len()).To(BeZero())
+		})
+
+		It("merges overlapping ranges", func() {
+			ack(10, "foo", false)
+			ack(20, "foo", false)
+			ack(5, strings.Repeat("a", 20), false)
+			Expect(str.ackedRanges.Len()).To(Equal(1))
+			Expect(str.ackedRanges.Front().Value).To(Equal(utils.ByteInterval{Start: 5, End: 25}))
+			ack(30, "foo", false)
+			ack(1, "foo", false)
+			Expect(str.ackedRanges.Len()).To(Equal(3))
+			ack(0, "f", false)
+			Expect(str.WriteAcked()).To(BeEquivalentTo(4))
+			ack(4, "f", false)
+			Expect(str.WriteAcked()).To(BeEquivalentTo(25))
+			Expect(str.ackedRanges.Len()).To(Equal(1))
+		})
+
+		It("handles duplicate acknowledgements", func() {
+			ack(0, "foobar", false)
+			ack(0, "foo", false)
+			ack(3, "bar", false)
+			Expect(str.WriteAcked()).To(BeEquivalentTo(6))
+			Expect(str.ackedRanges.Len()).To(BeZero())
+		})
+
+		It("calls the callback when the acknowledged offset increases", func() {
+			var acked []uint64
+			str.OnWriteAcked(func(offset uint64) { acked = append(acked, offset) })
+			ack(3, "bar", false)
+			ack(0, "foo", false)
+			ack(0, "foo", false)
+			ack(6, "foo", false)
+			Expect(acked).To(Equal([]uint64{6, 9}))
+		})
+
+		It("says when all data was acknowledged", func() {
+			str.writeOffset = 6
+			str.finishedWriting = true
+			Expect(str.allDataAcked()).To(BeFalse())
+			ack(3, "bar", true)
+			Expect(str.allDataAcked()).To(BeFalse())
+			ack(0, "foo", false)
+			Expect(str.allDataAcked()).To(BeTrue())
+		})
+
+		It("doesn't wait for acknowledgements after the stream was canceled", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.writeOffset = 6
+			str.CancelWrite(1234)
+			Expect(str.allDataAcked()).To(BeTrue())
+		})
+
+		It("reports acknowledged data on a connection", func() {
+			ln, err := ListenAddr("localhost:0", testdata.GetTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			go func() {
+				defer GinkgoRecover()
+				sess, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				str, err := sess.AcceptStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = ioutil.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			sess, err := DialAddr(ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.Close()
+			str, err := sess.OpenStreamSync()
+			Expect(err).ToNot(HaveOccurred())
+			ackedChan := make(chan uint64, 10000)
+			str.OnWriteAcked(func(acked uint64) { ackedChan <- acked })
+			_, err = str.Write(make([]byte, 1<<20))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			Eventually(str.WriteAcked).Should(BeEquivalentTo(1 << 20))
+			Expect(len(ackedChan)).To(BeNumerically(">", 1))
+			// the stream is garbage collected once all data was acknowledged
+			s := sess.(*session)
+			Eventually(func() int {
+				s.sendStreamsAwaitingAckMutex.Lock()
+				defer s.sendStreamsAwaitingAckMutex.Unlock()
+				return len(s.sendStreamsAwaitingAck)
+			}).Should(BeZero())
+		})
+	})
+
 	Context("stream cancelations", func() {
 		Context("canceling writing", func() {
 			It("queues a RESET_STREAM frame", func() {
@@ -9,8 +9,8 @@ import (
 	"net"
 	"sync"
 	"sync/atomic"
-	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -44,6 +44,7 @@ type quicSession interface {
 	Session
 	handlePacket(*receivedPacket)
 	GetVersion() protocol.VersionNumber
+	getBufferedBytes() protocol.ByteCount
 	run() error
 	destroy(error)
 	closeForRecreating() protocol.PacketNumber
@@ -54,17 +55,22 @@ type sessionRunner interface {
 	onHandshakeComplete(Session)
 	retireConnectionID(protocol.ConnectionID)
 	removeConnectionID(protocol.ConnectionID)
+	// rebind replaces the socket used by the session.
+	// It is only called if the Config enables AutoRebind.
+	rebind() error
 }
 
 type runner struct {
 	onHandshakeCompleteImpl func(Session)
 	retireConnectionIDImpl  func(protocol.ConnectionID)
 	removeConnectionIDImpl  func(protocol.ConnectionID)
+	rebindImpl              func() error
 }
 
 func (r *runner) onHandshakeComplete(s Session)              { r.onHandshakeCompleteImpl(s) }
 func (r *runner) retireConnectionID(c protocol.ConnectionID) { r.retireConnectionIDImpl(c) }
 func (r *runner) removeConnectionID(c protocol.ConnectionID) { r.removeConnectionIDImpl(c) }
+func (r *runner) rebind() error                              { return r.rebindImpl() }
 
 var _ sessionRunner = &runner{}
 
@@ -72,7 +78,7 @@ var _ sessionRunner = &runner{}
 type server struct {
 	mutex sync.Mutex
 
-	tlsConf *tls.Config
+	tlsConf *handshake.ServerConfigCache
 	config  *Config
 
 	conn net.PacketConn
@@ -80,12 +86,15 @@ type server struct {
 	// If it is started with Listen, we take a packet conn as a parameter.
 	createdPacketConn bool
 
-	cookieGenerator *handshake.CookieGenerator
+	cookieGenerator  *handshake.CookieGenerator
+	memoryBudget     *flowcontrol.MemoryBudget // nil if the memory used is not limited
+	handshakeLimiter *handshake.Limiter        // nil if the number of concurrent handshakes is not limited
+	timerWheel       *timerWheel               // nil if idle sessions are not parked
 
 	sessionHandler packetHandlerManager
 
 	// set as a member, so they can be set in the tests
-	newSession func(connection, sessionRunner, protocol.ConnectionID /* original connection ID */, protocol.ConnectionID /* destination connection ID */, protocol.ConnectionID /* source connection ID */, *Config, *tls.Config, *handshake.TransportParameters, utils.Logger, protocol.VersionNumber) (quicSession, error)
+	newSession func(connection, sessionRunner, protocol.ConnectionID /* original connection ID */, protocol.ConnectionID /* destination connection ID */, protocol.ConnectionID /* source connection ID */, *Config, *handshake.ServerConfigCache, *handshake.TransportParameters, *flowcontrol.MemoryBudget, *handshake.Limiter, *timerWheel, utils.Logger, protocol.VersionNumber) (quicSession, error)
 
 	serverError error
 	errorChan   chan struct{}
@@ -94,6 +103,14 @@ type server struct {
 	sessionQueue    chan Session
 	sessionQueueLen int32 // to be used as an atomic
 
+	// used for Stats
+	statsMutex            sync.Mutex
+	sessions              map[string]quicSession // keyed by the connection ID chosen by the server
+	handshakeComplete     map[Session]struct{}
+	retriesSent           uint64
+	rejectedServerBusy    uint64
+	rejectedByApplication uint64
+
 	sessionRunner sessionRunner
 
 	logger utils.Logger
@@ -104,6 +121,7 @@ var _ unknownPacketHandler = &server{}
 
 // ListenAddr creates a QUIC server listening on a given address.
 // The tls.Config must not be nil and must contain a certificate configuration.
+// It must not be modified after calling ListenAddr, since it is only read once, and shared by all connections.
 // The quic.Config may be nil, in that case the default values will be used.
 func ListenAddr(addr string, tlsConf *tls.Config, config *Config) (Listener, error) {
 	udpAddr, err := net.ResolveUDPAddr("udp", addr)
@@ -114,6 +132,8 @@ func ListenAddr(addr string, tlsConf *tls.Config, config *Config) (Listener, err
 	if err != nil {
 		return nil, err
 	}
+	conf := populateServerConfig(config)
+	setSocketBuffers(conn, conf.ReceiveBufferSize, conf.SendBufferSize, utils.DefaultLogger.WithPrefix("server"))
 	serv, err := listen(conn, tlsConf, config)
 	if err != nil {
 		return nil, err
@@ -127,6 +147,7 @@ func ListenAddr(addr string, tlsConf *tls.Config, config *Config) (Listener, err
 // The PacketConn can be used for simultaneous calls to Dial.
 // QUIC connection IDs are used for demultiplexing the different connections.
 // The tls.Config must not be nil and must contain a certificate configuration.
+// It must not be modified after calling Listen, since it is only read once, and shared by all connections.
 // The quic.Config may be nil, in that case the default values will be used.
 func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
 	return listen(conn, tlsConf, config)
@@ -144,19 +165,21 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (*server,
 		}
 	}
 
-	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength)
+	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.Clock)
 	if err != nil {
 		return nil, err
 	}
 	s := &server{
-		conn:           conn,
-		tlsConf:        tlsConf,
-		config:         config,
-		sessionHandler: sessionHandler,
-		sessionQueue:   make(chan Session),
-		errorChan:      make(chan struct{}),
-		newSession:     newSession,
-		logger:         utils.DefaultLogger.WithPrefix("server"),
+		conn:              conn,
+		tlsConf:           handshake.NewServerConfigCache(tlsConf),
+		config:            config,
+		sessionHandler:    sessionHandler,
+		sessionQueue:      make(chan Session),
+		errorChan:         make(chan struct{}),
+		newSession:        newSession,
+		sessions:          make(map[string]quicSession),
+		handshakeComplete: make(map[Session]struct{}),
+		logger:            utils.DefaultLogger.WithPrefix("server"),
 	}
 	if err := s.setup(); err != nil {
 		return nil, err
@@ -169,9 +192,22 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (*server,
 func (s *server) setup() error {
 	s.sessionRunner = &runner{
 		onHandshakeCompleteImpl: func(sess Session) {
+			s.statsMutex.Lock()
+			s.handshakeComplete[sess] = struct{}{}
+			s.statsMutex.Unlock()
 			go func() {
 				atomic.AddInt32(&s.sessionQueueLen, 1)
 				defer atomic.AddInt32(&s.sessionQueueLen, -1)
+				if s.config.AcceptSession != nil {
+					if code, err := s.config.AcceptSession(sess); err != nil {
+						s.logger.Debugf("Rejecting session %s: %s", sess.RemoteAddr(), err)
+						s.statsMutex.Lock()
+						s.rejectedByApplication++
+						s.statsMutex.Unlock()
+						sess.CloseWithError(code, err)
+						return
+					}
+				}
 				select {
 				case s.sessionQueue <- sess:
 					// blocks until the session is accepted
@@ -180,14 +216,29 @@ func (s *server) setup() error {
 				}
 			}()
 		},
-		retireConnectionIDImpl: s.sessionHandler.Retire,
-		removeConnectionIDImpl: s.sessionHandler.Remove,
+		retireConnectionIDImpl: func(connID protocol.ConnectionID) {
+			s.removeSession(connID)
+			s.sessionHandler.Retire(connID)
+		},
+		removeConnectionIDImpl: func(connID protocol.ConnectionID) {
+			s.removeSession(connID)
+			s.sessionHandler.Remove(connID)
+		},
 	}
-	cookieGenerator, err := handshake.NewCookieGenerator()
+	cookieGenerator, err := handshake.NewCookieGenerator(s.config.CookieKeys, s.config.Clock)
 	if err != nil {
 		return err
 	}
 	s.cookieGenerator = cookieGenerator
+	if s.config.MaxReceiveBufferMemory > 0 {
+		s.memoryBudget = flowcontrol.NewMemoryBudget(protocol.ByteCount(s.config.MaxReceiveBufferMemory))
+	}
+	if s.config.MaxConcurrentHandshakes > 0 {
+		s.handshakeLimiter = handshake.NewLimiter(s.config.MaxConcurrentHandshakes)
+	}
+	if s.config.ParkIdleSessions {
+		s.timerWheel = newTimerWheel(s.config.Clock)
+	}
 	return nil
 }
 
@@ -195,9 +246,6 @@ var defaultAcceptCookie = func(clientAddr net.Addr, cookie *Cookie) bool {
 	if cookie == nil {
 		return false
 	}
-	if time.Now().After(cookie.SentTime.Add(protocol.CookieExpiryTime)) {
-		return false
-	}
 	var sourceAddr string
 	if udpAddr, ok := clientAddr.(*net.UDPAddr); ok {
 		sourceAddr = udpAddr.IP.String()
@@ -222,6 +270,10 @@ func populateServerConfig(config *Config) *Config {
 	if config.AcceptCookie != nil {
 		vsa = config.AcceptCookie
 	}
+	cookieLifetime := protocol.CookieExpiryTime
+	if config.CookieLifetime != 0 {
+		cookieLifetime = config.CookieLifetime
+	}
 
 	handshakeTimeout := protocol.DefaultHandshakeTimeout
 	if config.HandshakeTimeout != 0 {
@@ -252,22 +304,67 @@ func populateServerConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges <= 0 {
+		maxAckRanges = protocol.DefaultMaxTrackedReceivedAckRanges
+	}
 	connIDLen := config.ConnectionIDLength
 	if connIDLen == 0 {
 		connIDLen = protocol.DefaultConnectionIDLength
 	}
+	receiveBufferSize := config.ReceiveBufferSize
+	if receiveBufferSize == 0 {
+		receiveBufferSize = protocol.DesiredReceiveBufferSize
+	}
+	sendBufferSize := config.SendBufferSize
+	if sendBufferSize == 0 {
+		sendBufferSize = protocol.DesiredSendBufferSize
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = utils.DefaultClock
+	}
+	fecGroupSize := config.FECGroupSize
+	if fecGroupSize > protocol.MaxFECGroupSize {
+		fecGroupSize = protocol.MaxFECGroupSize
+	}
 
 	return &Config{
 		Versions:                              versions,
 		HandshakeTimeout:                      handshakeTimeout,
 		IdleTimeout:                           idleTimeout,
 		AcceptCookie:                          vsa,
+		CookieLifetime:                        cookieLifetime,
+		CookieKeys:                            config.CookieKeys,
+		OffloadInitials:                       config.OffloadInitials,
+		AcceptClientHello:                     config.AcceptClientHello,
+		AcceptVersion:                         config.AcceptVersion,
+		AcceptSession:                         config.AcceptSession,
+		PathHealthChanged:                     config.PathHealthChanged,
 		KeepAlive:                             config.KeepAlive,
+		HyStartPlusPlus:                       config.HyStartPlusPlus,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
+		MaxReceiveBufferMemory:                config.MaxReceiveBufferMemory,
+		MaxReadAhead:                          config.MaxReadAhead,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
+		MaxConcurrentStreamHandlers:           config.MaxConcurrentStreamHandlers,
+		MaxAckRanges:                          maxAckRanges,
+		MaxConcurrentHandshakes:               config.MaxConcurrentHandshakes,
 		ConnectionIDLength:                    connIDLen,
+		QuicTracer:                            config.QuicTracer,
+		ReceiveBufferSize:                     receiveBufferSize,
+		SendBufferSize:                        sendBufferSize,
+		Clock:                                 clock,
+		DecryptionWorkers:                     config.DecryptionWorkers,
+		FECGroupSize:                          fecGroupSize,
+		KernelPacing:                          config.KernelPacing,
+		StreamCompression:                     config.StreamCompression,
+		StreamCompressionDictionary:           config.StreamCompressionDictionary,
+		PacketPadding:                         config.PacketPadding,
+		CoverTrafficInterval:                  config.CoverTrafficInterval,
+		ParkIdleSessions:                      config.ParkIdleSessions,
 	}
 }
 
@@ -323,15 +420,44 @@ func (s *server) Addr() net.Addr {
 	return s.conn.LocalAddr()
 }
 
+// Stats returns statistics about the sessions handled by the server
+func (s *server) Stats() ListenerStats {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+
+	stats := ListenerStats{
+		ActiveSessions:        len(s.sessions),
+		AcceptQueueLength:     int(atomic.LoadInt32(&s.sessionQueueLen)),
+		RetriesSent:           s.retriesSent,
+		RejectedServerBusy:    s.rejectedServerBusy,
+		RejectedByApplication: s.rejectedByApplication,
+	}
+	for _, sess := range s.sessions {
+		if _, ok := s.handshakeComplete[sess]; !ok {
+			stats.HandshakingSessions++
+		}
+		stats.BufferedBytes += uint64(sess.getBufferedBytes())
+	}
+	if s.timerWheel != nil {
+		stats.ParkedSessions = s.timerWheel.Len()
+	}
+	return stats
+}
+
 func (s *server) handlePacket(p *receivedPacket) {
 	hdr := p.hdr
 
 	// send a Version Negotiation Packet if the client is speaking a different protocol version
-	if !protocol.IsSupportedVersion(s.config.Versions, hdr.Version) {
+	if !protocol.IsSupportedVersion(acceptedVersions(s.config, p.remoteAddr), hdr.Version) {
 		go s.sendVersionNegotiationPacket(p)
 		return
 	}
 	if hdr.Type == protocol.PacketTypeInitial {
+		if s.config.OffloadInitials {
+			s.logger.Debugf("Dropping Initial packet. Initial packets are handled by an external component.")
+			p.buffer.Release()
+			return
+		}
 		go s.handleInitial(p)
 		return
 	}
@@ -341,6 +467,7 @@ func (s *server) handlePacket(p *receivedPacket) {
 }
 
 func (s *server) handleInitial(p *receivedPacket) {
+	defer s.recoverPanic()
 	s.logger.Debugf("<- Received Initial packet.")
 	sess, connID, err := s.handleInitialImpl(p)
 	if err != nil {
@@ -358,6 +485,15 @@ func (s *server) handleInitial(p *receivedPacket) {
 	s.sessionHandler.Add(connID, serverSession)
 }
 
+// recoverPanic recovers from a panic that occurred while handling a packet that doesn't belong to a session yet.
+// It must be called using defer.
+func (s *server) recoverPanic() {
+	if r := recover(); r != nil {
+		e := newPanicError(r)
+		s.logger.Errorf("Recovered from a %s while handling a packet: %s", e, e.stack)
+	}
+}
+
 func (s *server) handleInitialImpl(p *receivedPacket) (quicSession, protocol.ConnectionID, error) {
 	hdr := p.hdr
 	if len(hdr.Token) == 0 && hdr.DestConnectionID.Len() < protocol.MinConnectionIDLenInitial {
@@ -371,7 +507,8 @@ func (s *server) handleInitialImpl(p *receivedPacket) (quicSession, protocol.Con
 	var origDestConnectionID protocol.ConnectionID
 	if len(hdr.Token) > 0 {
 		c, err := s.cookieGenerator.DecodeToken(hdr.Token)
-		if err == nil {
+		// Expired Cookies are treated as if the client didn't send a Cookie.
+		if err == nil && !s.config.Clock.Now().After(c.SentTime.Add(s.config.CookieLifetime)) {
 			cookie = &Cookie{
 				RemoteAddr: c.RemoteAddr,
 				SentTime:   c.SentTime,
@@ -385,9 +522,24 @@ func (s *server) handleInitialImpl(p *receivedPacket) (quicSession, protocol.Con
 		(&wire.ExtendedHeader{Header: *p.hdr}).Log(s.logger)
 		return nil, nil, s.sendRetry(p.remoteAddr, hdr)
 	}
+	return s.createSessionForInitial(p, origDestConnectionID)
+}
 
+// createSessionForInitial creates a new session for an Initial packet from a validated client address.
+func (s *server) createSessionForInitial(p *receivedPacket, origDestConnectionID protocol.ConnectionID) (quicSession, protocol.ConnectionID, error) {
+	hdr := p.hdr
 	if queueLen := atomic.LoadInt32(&s.sessionQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
 		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, protocol.MaxAcceptQueueSize)
+		s.statsMutex.Lock()
+		s.rejectedServerBusy++
+		s.statsMutex.Unlock()
+		return nil, nil, s.sendServerBusy(p.remoteAddr, hdr)
+	}
+	if s.memoryBudget.UnderPressure() {
+		s.logger.Debugf("Rejecting new connection. Memory budget nearly exhausted: %d bytes used (max %d)", s.memoryBudget.Used(), s.config.MaxReceiveBufferMemory)
+		s.statsMutex.Lock()
+		s.rejectedServerBusy++
+		s.statsMutex.Unlock()
 		return nil, nil, s.sendServerBusy(p.remoteAddr, hdr)
 	}
 
@@ -411,6 +563,75 @@ func (s *server) handleInitialImpl(p *receivedPacket) (quicSession, protocol.Con
 	return sess, connID, nil
 }
 
+// HandOver passes an Initial packet validated by an external component to the server.
+func (s *server) HandOver(p *HandedOverInitial) error {
+	s.mutex.Lock()
+	closed := s.closed
+	s.mutex.Unlock()
+	if closed {
+		return s.serverError
+	}
+
+	hdr, err := wire.ParseHeader(bytes.NewReader(p.Data), s.config.ConnectionIDLength)
+	if err != nil {
+		return fmt.Errorf("error parsing header: %s", err)
+	}
+	if !hdr.IsLongHeader || hdr.Type != protocol.PacketTypeInitial {
+		return errors.New("quic: can only hand over Initial packets")
+	}
+	if !protocol.IsSupportedVersion(acceptedVersions(s.config, p.RemoteAddr), hdr.Version) {
+		return fmt.Errorf("quic: unsupported version %s", hdr.Version)
+	}
+	if len(p.OriginalDestConnectionID) == 0 && hdr.DestConnectionID.Len() < protocol.MinConnectionIDLenInitial {
+		return errors.New("quic: too short connection ID")
+	}
+	// Only the Initial packet is handled. Coalesced packets are dropped.
+	packetLen := hdr.ParsedLen() + hdr.Length
+	if protocol.ByteCount(len(p.Data)) < packetLen {
+		return fmt.Errorf("packet length (%d bytes) is smaller than the expected length (%d bytes)", len(p.Data)-int(hdr.ParsedLen()), hdr.Length)
+	}
+	if packetLen < protocol.MinInitialPacketSize {
+		return errors.New("quic: too small Initial packet")
+	}
+	if packetLen > protocol.MaxReceivePacketSize {
+		return errors.New("quic: packet too large")
+	}
+
+	// Copy the packet, since the packet buffer is released when the session is done with the packet.
+	// The header needs to be parsed again, since it references the data.
+	buffer := getPacketBuffer()
+	data := buffer.Slice[:packetLen]
+	copy(data, p.Data)
+	hdr, err = wire.ParseHeader(bytes.NewReader(data), s.config.ConnectionIDLength)
+	if err != nil {
+		buffer.Release()
+		return err
+	}
+	var origDestConnectionID protocol.ConnectionID
+	if len(p.OriginalDestConnectionID) > 0 {
+		origDestConnectionID = protocol.ConnectionID(append([]byte{}, p.OriginalDestConnectionID...))
+	}
+	s.logger.Debugf("<- Handed over Initial packet.")
+	rp := &receivedPacket{
+		remoteAddr: p.RemoteAddr,
+		hdr:        hdr,
+		rcvTime:    s.config.Clock.Now(),
+		data:       data,
+		buffer:     buffer,
+	}
+	sess, connID, err := s.createSessionForInitial(rp, origDestConnectionID)
+	if err != nil {
+		buffer.Release()
+		return err
+	}
+	if sess == nil { // the connection attempt was rejected
+		buffer.Release()
+		return nil
+	}
+	s.sessionHandler.Add(connID, newServerSession(sess, s.config, s.logger))
+	return nil
+}
+
 func (s *server) createNewSession(
 	remoteAddr net.Addr,
 	origDestConnID protocol.ConnectionID,
@@ -430,8 +651,11 @@ func (s *server) createNewSession(
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableMigration:               true,
 		// TODO(#855): generate a real token
-		StatelessResetToken:  bytes.Repeat([]byte{42}, 16),
-		OriginalConnectionID: origDestConnID,
+		StatelessResetToken:           bytes.Repeat([]byte{42}, 16),
+		OriginalConnectionID:          origDestConnID,
+		EnableFEC:                     s.config.FECGroupSize > 0,
+		EnableStreamCompression:       s.config.StreamCompression,
+		StreamCompressionDictionaryID: streamCompressionDictionaryID(s.config.StreamCompressionDictionary),
 	}
 	sess, err := s.newSession(
 		&conn{pconn: s.conn, currentAddr: remoteAddr},
@@ -442,16 +666,31 @@ func (s *server) createNewSession(
 		s.config,
 		s.tlsConf,
 		params,
+		s.memoryBudget,
+		s.handshakeLimiter,
+		s.timerWheel,
 		s.logger,
 		version,
 	)
 	if err != nil {
 		return nil, err
 	}
+	s.statsMutex.Lock()
+	s.sessions[string(srcConnID)] = sess
+	s.statsMutex.Unlock()
 	go sess.run()
 	return sess, nil
 }
 
+func (s *server) removeSession(connID protocol.ConnectionID) {
+	s.statsMutex.Lock()
+	if sess, ok := s.sessions[string(connID)]; ok {
+		delete(s.sessions, string(connID))
+		delete(s.handshakeComplete, sess)
+	}
+	s.statsMutex.Unlock()
+}
+
 func (s *server) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	token, err := s.cookieGenerator.NewToken(remoteAddr, hdr.DestConnectionID)
 	if err != nil {
@@ -477,7 +716,11 @@ func (s *server) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	}
 	if _, err := s.conn.WriteTo(buf.Bytes(), remoteAddr); err != nil {
 		s.logger.Debugf("Error sending Retry: %s", err)
+		return nil
 	}
+	s.statsMutex.Lock()
+	s.retriesSent++
+	s.statsMutex.Unlock()
 	return nil
 }
 
@@ -530,10 +773,11 @@ func (s *server) sendServerBusy(remoteAddr net.Addr, hdr *wire.Header) error {
 }
 
 func (s *server) sendVersionNegotiationPacket(p *receivedPacket) {
+	defer s.recoverPanic()
 	defer p.buffer.Release()
 	hdr := p.hdr
 	s.logger.Debugf("Client offered version %s, sending Version Negotiation", hdr.Version)
-	data, err := wire.ComposeVersionNegotiation(hdr.SrcConnectionID, hdr.DestConnectionID, s.config.Versions)
+	data, err := wire.ComposeVersionNegotiation(hdr.SrcConnectionID, hdr.DestConnectionID, acceptedVersions(s.config, p.remoteAddr))
 	if err != nil {
 		s.logger.Debugf("Error composing Version Negotiation: %s", err)
 		return
@@ -542,3 +786,17 @@ func (s *server) sendVersionNegotiationPacket(p *receivedPacket) {
 		s.logger.Debugf("Error sending Version Negotiation: %s", err)
 	}
 }
+
+// acceptedVersions returns the versions that a client is allowed to use.
+func acceptedVersions(config *Config, clientAddr net.Addr) []protocol.VersionNumber {
+	if config.AcceptVersion == nil {
+		return config.Versions
+	}
+	versions := make([]protocol.VersionNumber, 0, len(config.Versions))
+	for _, v := range config.Versions {
+		if config.AcceptVersion(clientAddr, v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
@@ -10,6 +10,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/testdata"
@@ -58,7 +60,18 @@ var _ = Describe("Server", func() {
 		Expect(server.config.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 		Expect(server.config.IdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
 		Expect(reflect.ValueOf(server.config.AcceptCookie)).To(Equal(reflect.ValueOf(defaultAcceptCookie)))
+		Expect(server.config.CookieLifetime).To(Equal(protocol.CookieExpiryTime))
+		Expect(server.config.CookieKeys).To(BeNil())
+		Expect(server.config.AcceptSession).To(BeNil())
 		Expect(server.config.KeepAlive).To(BeFalse())
+		Expect(server.config.HyStartPlusPlus).To(BeFalse())
+		Expect(server.config.MaxAckRanges).To(Equal(protocol.DefaultMaxTrackedReceivedAckRanges))
+		Expect(server.config.MaxConcurrentHandshakes).To(BeZero())
+		Expect(server.handshakeLimiter).To(BeNil())
+		Expect(server.timerWheel).To(BeNil())
+		Expect(server.config.ReceiveBufferSize).To(Equal(protocol.DesiredReceiveBufferSize))
+		Expect(server.config.SendBufferSize).To(Equal(protocol.DesiredSendBufferSize))
+		Expect(server.config.Clock).To(Equal(utils.DefaultClock))
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
 	})
@@ -66,12 +79,38 @@ var _ = Describe("Server", func() {
 	It("setups with the right values", func() {
 		supportedVersions := []protocol.VersionNumber{protocol.VersionTLS}
 		acceptCookie := func(_ net.Addr, _ *Cookie) bool { return true }
+		acceptClientHello := func(_ net.Addr, _ *ClientHelloInfo) bool { return true }
+		acceptVersion := func(_ net.Addr, _ VersionNumber) bool { return true }
+		acceptSession := func(Session) (ErrorCode, error) { return 0, nil }
+		pathHealthChanged := func(Session, PathHealth, error) {}
+		cookieKeys := func() [][]byte { return [][]byte{make([]byte, 32)} }
 		config := Config{
-			Versions:         supportedVersions,
-			AcceptCookie:     acceptCookie,
-			HandshakeTimeout: 1337 * time.Hour,
-			IdleTimeout:      42 * time.Minute,
-			KeepAlive:        true,
+			Versions:                    supportedVersions,
+			AcceptCookie:                acceptCookie,
+			CookieLifetime:              time.Hour,
+			CookieKeys:                  cookieKeys,
+			AcceptClientHello:           acceptClientHello,
+			AcceptVersion:               acceptVersion,
+			AcceptSession:               acceptSession,
+			PathHealthChanged:           pathHealthChanged,
+			HandshakeTimeout:            1337 * time.Hour,
+			IdleTimeout:                 42 * time.Minute,
+			KeepAlive:                   true,
+			HyStartPlusPlus:             true,
+			MaxReceiveBufferMemory:      1 << 30,
+			MaxAckRanges:                42,
+			MaxConcurrentHandshakes:     7,
+			Clock:                       offsetClock{utils.DefaultClock},
+			DecryptionWorkers:           4,
+			FECGroupSize:                10,
+			KernelPacing:                true,
+			MaxConcurrentStreamHandlers: 7,
+			MaxReadAhead:                1 << 20,
+			StreamCompression:           true,
+			StreamCompressionDictionary: []byte("foobar"),
+			PacketPadding:               256,
+			CoverTrafficInterval:        time.Second,
+			ParkIdleSessions:            true,
 		}
 		ln, err := Listen(conn, tlsConf, &config)
 		Expect(err).ToNot(HaveOccurred())
@@ -81,11 +120,44 @@ var _ = Describe("Server", func() {
 		Expect(server.config.HandshakeTimeout).To(Equal(1337 * time.Hour))
 		Expect(server.config.IdleTimeout).To(Equal(42 * time.Minute))
 		Expect(reflect.ValueOf(server.config.AcceptCookie)).To(Equal(reflect.ValueOf(acceptCookie)))
+		Expect(server.config.CookieLifetime).To(Equal(time.Hour))
+		Expect(reflect.ValueOf(server.config.CookieKeys)).To(Equal(reflect.ValueOf(cookieKeys)))
+		Expect(reflect.ValueOf(server.config.AcceptClientHello)).To(Equal(reflect.ValueOf(acceptClientHello)))
+		Expect(reflect.ValueOf(server.config.AcceptVersion)).To(Equal(reflect.ValueOf(acceptVersion)))
+		Expect(reflect.ValueOf(server.config.AcceptSession)).To(Equal(reflect.ValueOf(acceptSession)))
+		Expect(reflect.ValueOf(server.config.PathHealthChanged)).To(Equal(reflect.ValueOf(pathHealthChanged)))
 		Expect(server.config.KeepAlive).To(BeTrue())
+		Expect(server.config.HyStartPlusPlus).To(BeTrue())
+		Expect(server.config.MaxReceiveBufferMemory).To(BeEquivalentTo(1 << 30))
+		Expect(server.memoryBudget).ToNot(BeNil())
+		Expect(server.config.MaxAckRanges).To(Equal(42))
+		Expect(server.config.MaxConcurrentHandshakes).To(Equal(7))
+		Expect(server.config.Clock).To(Equal(offsetClock{utils.DefaultClock}))
+		Expect(server.config.DecryptionWorkers).To(Equal(4))
+		Expect(server.config.FECGroupSize).To(Equal(10))
+		Expect(server.config.KernelPacing).To(BeTrue())
+		Expect(server.config.MaxConcurrentStreamHandlers).To(Equal(7))
+		Expect(server.config.MaxReadAhead).To(BeEquivalentTo(1 << 20))
+		Expect(server.config.StreamCompression).To(BeTrue())
+		Expect(server.config.StreamCompressionDictionary).To(Equal([]byte("foobar")))
+		Expect(server.config.PacketPadding).To(Equal(256))
+		Expect(server.config.CoverTrafficInterval).To(Equal(time.Second))
+		Expect(server.config.ParkIdleSessions).To(BeTrue())
+		Expect(server.timerWheel).ToNot(BeNil())
+		Expect(server.handshakeLimiter).ToNot(BeNil())
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
 	})
 
+	It("doesn't limit the number of concurrent handshakes, if requested", func() {
+		ln, err := Listen(conn, tlsConf, &Config{MaxConcurrentHandshakes: -1})
+		Expect(err).ToNot(HaveOccurred())
+		server := ln.(*server)
+		Expect(server.config.MaxConcurrentHandshakes).To(Equal(-1))
+		Expect(server.handshakeLimiter).To(BeNil())
+		Expect(ln.Close()).To(Succeed())
+	})
+
 	It("listens on a given address", func() {
 		addr := "127.0.0.1:13579"
 		ln, err := ListenAddr(addr, tlsConf, &Config{})
@@ -199,6 +271,61 @@ var _ = Describe("Server", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("passes an empty cookie to the callback, if the cookie expired", func() {
+			raddr := &net.UDPAddr{
+				IP:   net.IPv4(192, 168, 13, 37),
+				Port: 1337,
+			}
+			serv.config.CookieLifetime = time.Nanosecond
+			done := make(chan struct{})
+			serv.config.AcceptCookie = func(addr net.Addr, cookie *Cookie) bool {
+				Expect(addr).To(Equal(raddr))
+				Expect(cookie).To(BeNil())
+				close(done)
+				return false
+			}
+			token, err := serv.cookieGenerator.NewToken(raddr, nil)
+			Expect(err).ToNot(HaveOccurred())
+			serv.handlePacket(insertPacketBuffer(&receivedPacket{
+				remoteAddr: raddr,
+				hdr: &wire.Header{
+					Type:    protocol.PacketTypeInitial,
+					Token:   token,
+					Version: serv.config.Versions[0],
+				},
+				data: bytes.Repeat([]byte{0}, protocol.MinInitialPacketSize),
+			}))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("passes an empty cookie to the callback, if the cookie expired according to the clock", func() {
+			raddr := &net.UDPAddr{
+				IP:   net.IPv4(192, 168, 13, 37),
+				Port: 1337,
+			}
+			serv.config.CookieLifetime = 30 * time.Minute
+			serv.config.Clock = offsetClock{utils.DefaultClock}
+			done := make(chan struct{})
+			serv.config.AcceptCookie = func(addr net.Addr, cookie *Cookie) bool {
+				Expect(addr).To(Equal(raddr))
+				Expect(cookie).To(BeNil())
+				close(done)
+				return false
+			}
+			token, err := serv.cookieGenerator.NewToken(raddr, nil)
+			Expect(err).ToNot(HaveOccurred())
+			serv.handlePacket(insertPacketBuffer(&receivedPacket{
+				remoteAddr: raddr,
+				hdr: &wire.Header{
+					Type:    protocol.PacketTypeInitial,
+					Token:   token,
+					Version: serv.config.Versions[0],
+				},
+				data: bytes.Repeat([]byte{0}, protocol.MinInitialPacketSize),
+			}))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("passes an empty cookie to the callback, if decoding fails", func() {
 			raddr := &net.UDPAddr{
 				IP:   net.IPv4(192, 168, 13, 37),
@@ -247,6 +374,31 @@ var _ = Describe("Server", func() {
 			Expect(hdr.SupportedVersions).ToNot(ContainElement(protocol.VersionNumber(0x42)))
 		})
 
+		It("sends a Version Negotiation Packet for versions rejected by the AcceptVersion callback", func() {
+			serv.config.Versions = []protocol.VersionNumber{protocol.VersionTLS, 0x42}
+			serv.config.AcceptVersion = func(addr net.Addr, v protocol.VersionNumber) bool {
+				Expect(addr.String()).To(Equal("127.0.0.1:1337"))
+				return v != protocol.VersionTLS
+			}
+			serv.handlePacket(insertPacketBuffer(&receivedPacket{
+				remoteAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337},
+				hdr: &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{1, 2, 3, 4, 5},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6},
+					Version:          protocol.VersionTLS,
+				},
+			}))
+			var write mockPacketConnWrite
+			Eventually(conn.dataWritten).Should(Receive(&write))
+			hdr, err := wire.ParseHeader(bytes.NewReader(write.data), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.IsVersionNegotiation()).To(BeTrue())
+			Expect(hdr.SupportedVersions).To(ContainElement(protocol.VersionNumber(0x42)))
+			Expect(hdr.SupportedVersions).ToNot(ContainElement(protocol.VersionTLS))
+		})
+
 		It("replies with a Retry packet, if a Cookie is required", func() {
 			serv.config.AcceptCookie = func(_ net.Addr, _ *Cookie) bool { return false }
 			hdr := &wire.Header{
@@ -269,6 +421,7 @@ var _ = Describe("Server", func() {
 			Expect(replyHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
 			Expect(replyHdr.OrigDestConnectionID).To(Equal(hdr.DestConnectionID))
 			Expect(replyHdr.Token).ToNot(BeEmpty())
+			Expect(serv.Stats().RetriesSent).To(BeEquivalentTo(1))
 		})
 
 		It("creates a session, if no Cookie is required", func() {
@@ -291,8 +444,11 @@ var _ = Describe("Server", func() {
 				destConnID protocol.ConnectionID,
 				srcConnID protocol.ConnectionID,
 				_ *Config,
-				_ *tls.Config,
+				_ *handshake.ServerConfigCache,
 				_ *handshake.TransportParameters,
+				_ *flowcontrol.MemoryBudget,
+				_ *handshake.Limiter,
+				_ *timerWheel,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) (quicSession, error) {
@@ -320,6 +476,166 @@ var _ = Describe("Server", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("drops Initial packets, if the handling of Initial packets is offloaded", func() {
+			serv.config.OffloadInitials = true
+			serv.config.AcceptCookie = func(_ net.Addr, _ *Cookie) bool { return false }
+			serv.handlePacket(insertPacketBuffer(&receivedPacket{
+				remoteAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337},
+				hdr: &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+					Version:          serv.config.Versions[0],
+				},
+				data: bytes.Repeat([]byte{0}, protocol.MinInitialPacketSize),
+			}))
+			Consistently(conn.dataWritten).ShouldNot(Receive())
+			Expect(serv.Stats().RetriesSent).To(BeZero())
+		})
+
+		Context("handing over Initial packets", func() {
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4321}
+
+			getInitial := func(t protocol.PacketType, destConnID protocol.ConnectionID, length protocol.ByteCount) []byte {
+				buf := &bytes.Buffer{}
+				Expect((&wire.ExtendedHeader{
+					Header: wire.Header{
+						IsLongHeader:     true,
+						Type:             t,
+						SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+						DestConnectionID: destConnID,
+						Length:           length,
+						Version:          protocol.VersionTLS,
+					},
+					PacketNumberLen: protocol.PacketNumberLen2,
+				}).Write(buf, protocol.VersionTLS)).To(Succeed())
+				return append(buf.Bytes(), make([]byte, length-2)...)
+			}
+
+			BeforeEach(func() {
+				serv.config.OffloadInitials = true
+				serv.config.AcceptCookie = func(_ net.Addr, _ *Cookie) bool {
+					Fail("the Cookie of handed over packets shouldn't be checked")
+					return false
+				}
+			})
+
+			It("creates a session", func() {
+				origConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+				destConnID := protocol.ConnectionID{1, 2, 3, 4, 5}
+				data := getInitial(protocol.PacketTypeInitial, destConnID, protocol.MinInitialPacketSize)
+				run := make(chan struct{})
+				serv.newSession = func(
+					c connection,
+					_ sessionRunner,
+					clientDestConnID protocol.ConnectionID,
+					destConnID protocol.ConnectionID,
+					srcConnID protocol.ConnectionID,
+					_ *Config,
+					_ *handshake.ServerConfigCache,
+					params *handshake.TransportParameters,
+					_ *flowcontrol.MemoryBudget,
+					_ *handshake.Limiter,
+					_ *timerWheel,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) (quicSession, error) {
+					Expect(c.RemoteAddr()).To(Equal(remoteAddr))
+					Expect(clientDestConnID).To(Equal(protocol.ConnectionID{1, 2, 3, 4, 5}))
+					Expect(destConnID).To(Equal(protocol.ConnectionID{5, 4, 3, 2, 1}))
+					Expect(params.OriginalConnectionID).To(Equal(origConnID))
+					sess := NewMockQuicSession(mockCtrl)
+					sess.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) {
+						Expect(p.remoteAddr).To(Equal(remoteAddr))
+						Expect(p.hdr.Type).To(Equal(protocol.PacketTypeInitial))
+						Expect(p.data).To(Equal(data))
+					})
+					sess.EXPECT().run().Do(func() { close(run) })
+					return sess, nil
+				}
+				Expect(serv.HandOver(&HandedOverInitial{
+					Data:                     data,
+					RemoteAddr:               remoteAddr,
+					OriginalDestConnectionID: origConnID,
+				})).To(Succeed())
+				Eventually(run).Should(BeClosed())
+				Expect(serv.sessions).To(HaveLen(1))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+
+			It("drops coalesced packets", func() {
+				destConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+				data := getInitial(protocol.PacketTypeInitial, destConnID, protocol.MinInitialPacketSize)
+				run := make(chan struct{})
+				serv.newSession = func(connection, sessionRunner, protocol.ConnectionID, protocol.ConnectionID, protocol.ConnectionID, *Config, *handshake.ServerConfigCache, *handshake.TransportParameters, *flowcontrol.MemoryBudget, *handshake.Limiter, *timerWheel, utils.Logger, protocol.VersionNumber) (quicSession, error) {
+					sess := NewMockQuicSession(mockCtrl)
+					sess.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) {
+						Expect(p.data).To(Equal(data))
+					})
+					sess.EXPECT().run().Do(func() { close(run) })
+					return sess, nil
+				}
+				Expect(serv.HandOver(&HandedOverInitial{
+					Data:       append(data, getInitial(protocol.PacketTypeHandshake, destConnID, 100)...),
+					RemoteAddr: remoteAddr,
+				})).To(Succeed())
+				Eventually(run).Should(BeClosed())
+			})
+
+			It("rejects packets that are not Initial packets", func() {
+				err := serv.HandOver(&HandedOverInitial{
+					Data:       getInitial(protocol.PacketTypeHandshake, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.MinInitialPacketSize),
+					RemoteAddr: remoteAddr,
+				})
+				Expect(err).To(MatchError("quic: can only hand over Initial packets"))
+			})
+
+			It("rejects versions that are not accepted by the AcceptVersion callback", func() {
+				serv.config.AcceptVersion = func(net.Addr, protocol.VersionNumber) bool { return false }
+				err := serv.HandOver(&HandedOverInitial{
+					Data:       getInitial(protocol.PacketTypeInitial, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.MinInitialPacketSize),
+					RemoteAddr: remoteAddr,
+				})
+				Expect(err).To(MatchError("quic: unsupported version " + protocol.VersionTLS.String()))
+			})
+
+			It("rejects too small packets", func() {
+				err := serv.HandOver(&HandedOverInitial{
+					Data:       getInitial(protocol.PacketTypeInitial, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.MinInitialPacketSize-100),
+					RemoteAddr: remoteAddr,
+				})
+				Expect(err).To(MatchError("quic: too small Initial packet"))
+			})
+
+			It("rejects packets with a too short connection ID, if no Retry was performed", func() {
+				err := serv.HandOver(&HandedOverInitial{
+					Data:       getInitial(protocol.PacketTypeInitial, protocol.ConnectionID{1, 2, 3, 4}, protocol.MinInitialPacketSize),
+					RemoteAddr: remoteAddr,
+				})
+				Expect(err).To(MatchError("quic: too short connection ID"))
+			})
+
+			It("rejects truncated packets", func() {
+				data := getInitial(protocol.PacketTypeInitial, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.MinInitialPacketSize)
+				err := serv.HandOver(&HandedOverInitial{
+					Data:       data[:len(data)-1],
+					RemoteAddr: remoteAddr,
+				})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("is smaller than the expected length"))
+			})
+
+			It("errors after the server was closed", func() {
+				Expect(serv.Close()).To(Succeed())
+				err := serv.HandOver(&HandedOverInitial{
+					Data:       getInitial(protocol.PacketTypeInitial, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.MinInitialPacketSize),
+					RemoteAddr: remoteAddr,
+				})
+				Expect(err).To(MatchError("server closed"))
+			})
+		})
+
 		It("rejects new connection attempts if the accept queue is full", func() {
 			serv.config.AcceptCookie = func(_ net.Addr, _ *Cookie) bool { return true }
 			senderAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
@@ -342,8 +658,11 @@ var _ = Describe("Server", func() {
 				_ protocol.ConnectionID,
 				_ protocol.ConnectionID,
 				_ *Config,
-				_ *tls.Config,
+				_ *handshake.ServerConfigCache,
 				_ *handshake.TransportParameters,
+				_ *flowcontrol.MemoryBudget,
+				_ *handshake.Limiter,
+				_ *timerWheel,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) (quicSession, error) {
@@ -351,6 +670,7 @@ var _ = Describe("Server", func() {
 				sess.EXPECT().handlePacket(p)
 				sess.EXPECT().run()
 				sess.EXPECT().Context().Return(context.Background())
+				sess.EXPECT().getBufferedBytes().AnyTimes()
 				runner.onHandshakeComplete(sess)
 				return sess, nil
 			}
@@ -376,6 +696,36 @@ var _ = Describe("Server", func() {
 			Expect(rejectHdr.Version).To(Equal(hdr.Version))
 			Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
 			Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+			Expect(serv.Stats().RejectedServerBusy).To(BeEquivalentTo(1))
+		})
+
+		It("rejects new connection attempts if the memory budget is nearly exhausted", func() {
+			serv.config.AcceptCookie = func(_ net.Addr, _ *Cookie) bool { return true }
+			serv.config.MaxReceiveBufferMemory = 1000
+			serv.memoryBudget = flowcontrol.NewMemoryBudget(1000)
+			// use up the memory budget by receiving data on a stream
+			connFC := flowcontrol.NewConnectionFlowController(1000, 1000, func() {}, serv.memoryBudget, 0, nil, utils.DefaultClock, utils.DefaultLogger)
+			streamFC := flowcontrol.NewStreamFlowController(1, connFC, 1000, 1000, 0, func(protocol.StreamID) {}, nil, utils.DefaultClock, utils.DefaultLogger)
+			Expect(streamFC.UpdateHighestReceived(800, false)).To(Succeed())
+
+			hdr := &wire.Header{
+				Type:             protocol.PacketTypeInitial,
+				SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+				DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+				Version:          protocol.VersionTLS,
+			}
+			serv.handlePacket(insertPacketBuffer(&receivedPacket{
+				remoteAddr: &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42},
+				hdr:        hdr,
+				data:       bytes.Repeat([]byte{0}, protocol.MinInitialPacketSize),
+			}))
+			var reject mockPacketConnWrite
+			Eventually(conn.dataWritten).Should(Receive(&reject))
+			rejectHdr, err := wire.ParseHeader(bytes.NewReader(reject.data), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+			Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+			Expect(serv.Stats().RejectedServerBusy).To(BeEquivalentTo(1))
 		})
 
 		It("doesn't accept new sessions if they were closed in the mean time", func() {
@@ -403,8 +753,11 @@ var _ = Describe("Server", func() {
 				_ protocol.ConnectionID,
 				_ protocol.ConnectionID,
 				_ *Config,
-				_ *tls.Config,
+				_ *handshake.ServerConfigCache,
 				_ *handshake.TransportParameters,
+				_ *flowcontrol.MemoryBudget,
+				_ *handshake.Limiter,
+				_ *timerWheel,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) (quicSession, error) {
@@ -490,8 +843,11 @@ var _ = Describe("Server", func() {
 				_ protocol.ConnectionID,
 				_ protocol.ConnectionID,
 				_ *Config,
-				_ *tls.Config,
+				_ *handshake.ServerConfigCache,
 				_ *handshake.TransportParameters,
+				_ *flowcontrol.MemoryBudget,
+				_ *handshake.Limiter,
+				_ *timerWheel,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) (quicSession, error) {
@@ -510,6 +866,91 @@ var _ = Describe("Server", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("rejects sessions using the AcceptSession callback", func() {
+			testErr := errors.New("maintenance")
+			sess := NewMockQuicSession(mockCtrl)
+			serv.config.AcceptSession = func(s Session) (ErrorCode, error) {
+				Expect(s).To(Equal(sess))
+				return 0x1337, testErr
+			}
+			closed := make(chan struct{})
+			sess.EXPECT().RemoteAddr().AnyTimes()
+			sess.EXPECT().CloseWithError(ErrorCode(0x1337), testErr).Do(func(ErrorCode, error) { close(closed) })
+			accepted := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				serv.Accept()
+				close(accepted)
+			}()
+			serv.sessionRunner.onHandshakeComplete(sess)
+			Eventually(closed).Should(BeClosed())
+			Expect(serv.Stats().RejectedByApplication).To(BeEquivalentTo(1))
+			Consistently(accepted).ShouldNot(BeClosed())
+			// make the go routine return
+			Expect(serv.Close()).To(Succeed())
+			Eventually(accepted).Should(BeClosed())
+		})
+
+		It("accepts sessions if the AcceptSession callback returns no error", func() {
+			sess := NewMockQuicSession(mockCtrl)
+			serv.config.AcceptSession = func(Session) (ErrorCode, error) { return 0, nil }
+			sess.EXPECT().Context().Return(context.Background())
+			serv.sessionRunner.onHandshakeComplete(sess)
+			s, err := serv.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal(sess))
+		})
+
+		It("reports statistics about the sessions", func() {
+			var sessions []*MockQuicSession
+			serv.newSession = func(
+				_ connection,
+				_ sessionRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ *Config,
+				_ *handshake.ServerConfigCache,
+				_ *handshake.TransportParameters,
+				_ *flowcontrol.MemoryBudget,
+				_ *handshake.Limiter,
+				_ *timerWheel,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) (quicSession, error) {
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().run().AnyTimes()
+				sess.EXPECT().getBufferedBytes().Return(protocol.ByteCount(100 * (len(sessions) + 1))).AnyTimes()
+				sessions = append(sessions, sess)
+				return sess, nil
+			}
+			for i := 0; i < 3; i++ {
+				_, err := serv.createNewSession(&net.UDPAddr{}, nil, nil, nil, protocol.ConnectionID{byte(i)}, protocol.VersionWhatever)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(serv.Stats()).To(Equal(ListenerStats{
+				ActiveSessions:      3,
+				HandshakingSessions: 3,
+				BufferedBytes:       100 + 200 + 300,
+			}))
+			// complete the handshake of the first session
+			sessions[0].EXPECT().Context().Return(context.Background())
+			serv.sessionRunner.onHandshakeComplete(sessions[0])
+			Eventually(func() int { return serv.Stats().AcceptQueueLength }).Should(Equal(1))
+			stats := serv.Stats()
+			Expect(stats.ActiveSessions).To(Equal(3))
+			Expect(stats.HandshakingSessions).To(Equal(2))
+			// close two sessions
+			serv.sessionRunner.retireConnectionID(protocol.ConnectionID{0})
+			serv.sessionRunner.removeConnectionID(protocol.ConnectionID{1})
+			stats = serv.Stats()
+			Expect(stats.ActiveSessions).To(Equal(1))
+			Expect(stats.HandshakingSessions).To(Equal(1))
+			Expect(stats.BufferedBytes).To(BeEquivalentTo(300))
+			// make the go routine blocking on the accept queue return
+			Expect(serv.Close()).To(Succeed())
+		})
+
 		It("never blocks when calling the onHandshakeComplete callback", func() {
 			const num = 50
 
@@ -521,8 +962,11 @@ var _ = Describe("Server", func() {
 				_ protocol.ConnectionID,
 				_ protocol.ConnectionID,
 				_ *Config,
-				_ *tls.Config,
+				_ *handshake.ServerConfigCache,
 				_ *handshake.TransportParameters,
+				_ *flowcontrol.MemoryBudget,
+				_ *handshake.Limiter,
+				_ *timerWheel,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) (quicSession, error) {
@@ -550,7 +994,7 @@ var _ = Describe("default source address verification", func() {
 		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)}
 		cookie := &Cookie{
 			RemoteAddr: "192.168.0.1",
-			SentTime:   time.Now().Add(-protocol.CookieExpiryTime).Add(time.Second), // will expire in 1 second
+			SentTime:   time.Now(),
 		}
 		Expect(defaultAcceptCookie(remoteAddr, cookie)).To(BeTrue())
 	})
@@ -586,13 +1030,4 @@ var _ = Describe("default source address verification", func() {
 		}
 		Expect(defaultAcceptCookie(remoteAddr, cookie)).To(BeFalse())
 	})
-
-	It("rejects an expired token", func() {
-		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)}
-		cookie := &Cookie{
-			RemoteAddr: "192.168.0.1",
-			SentTime:   time.Now().Add(-protocol.CookieExpiryTime).Add(-time.Second), // expired 1 second ago
-		}
-		Expect(defaultAcceptCookie(remoteAddr, cookie)).To(BeFalse())
-	})
 })
@@ -20,10 +20,12 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 type unpacker interface {
 	Unpack(hdr *wire.Header, data []byte) (*unpackedPacket, error)
+	UnpackShortHeader(hdr *wire.Header, data []byte, opener handshake.Opener) (*unpackedPacket, error)
 }
 
 type streamGetter interface {
@@ -51,6 +53,7 @@ type cryptoStreamHandler interface {
 	ChangeConnectionID(protocol.ConnectionID) error
 	io.Closer
 	ConnectionState() handshake.ConnectionState
+	HandshakeState() handshake.HandshakeState
 }
 
 type receivedPacket struct {
@@ -60,16 +63,25 @@ type receivedPacket struct {
 	data       []byte
 
 	buffer *packetBuffer
+
+	// set if the packet was already unpacked by the parallelUnpacker
+	unpacked  *unpackedPacket
+	unpackErr error
 }
 
 type closeError struct {
 	err       error
 	remote    bool
 	sendClose bool
+	// If set, the session stays in the closing state after sending the CONNECTION_CLOSE,
+	// until the closing period ends or the context is canceled.
+	closingCtx context.Context
 }
 
 var errCloseForRecreating = errors.New("closing session in order to recreate it")
 
+var errStreamHandlerRegistered = errors.New("quic: a stream handler was already registered")
+
 // A Session is a QUIC session
 type session struct {
 	sessionRunner sessionRunner
@@ -80,12 +92,17 @@ type session struct {
 
 	perspective    protocol.Perspective
 	initialVersion protocol.VersionNumber // if version negotiation is performed, this is the version we initially tried
-	version        protocol.VersionNumber
-	config         *Config
+	// the versions supported by the server, as sent in the transport parameters (only set for the client)
+	serverSupportedVersions []protocol.VersionNumber
+	version                 protocol.VersionNumber
+	config                  *Config
 
 	conn connection
 
 	streamsMap streamManager
+	// completed streams that still wait for the peer to acknowledge the data sent on them
+	sendStreamsAwaitingAckMutex sync.Mutex
+	sendStreamsAwaitingAck      map[protocol.StreamID]sendStreamI
 
 	rttStats *congestion.RTTStats
 
@@ -94,11 +111,21 @@ type session struct {
 	receivedPacketHandler ackhandler.ReceivedPacketHandler
 	framer                framer
 	windowUpdateQueue     *windowUpdateQueue
+	pathHealth            *pathHealthMonitor
+	flowControlMonitor    *flowControlMonitor
+	deadlines             *sessionDeadlines
 	connFlowController    flowcontrol.ConnectionFlowController
-
-	unpacker    unpacker
-	frameParser wire.FrameParser
-	packer      packer
+	memoryBudget          *flowcontrol.MemoryBudget // only set for the server, shared between all sessions
+
+	unpacker unpacker
+	// parallelUnpacker is only set if Config.DecryptionWorkers is larger than 1
+	parallelUnpacker *parallelUnpacker
+	frameParser      wire.FrameParser
+	packer           packer
+	// fecDecoder is only set if the experimental FEC extension was negotiated
+	fecDecoder *fecDecoder
+	// packets recovered from FEC_REPAIR frames, processed after the packet containing the frame
+	fecRecoveredPackets []*unpackedPacket
 
 	cryptoStreamHandler cryptoStreamHandler
 
@@ -114,6 +141,8 @@ type session struct {
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
+	// runDone is closed when the run loop returns (after the closing period, if any)
+	runDone chan struct{}
 
 	undecryptablePackets []*receivedPacket
 
@@ -125,18 +154,51 @@ type session struct {
 	receivedFirstPacket              bool
 	receivedFirstForwardSecurePacket bool
 
+	// lastRebindTime is the time when the socket was last replaced (see Config.AutoRebind)
+	lastRebindTime time.Time
+
 	sessionCreationTime     time.Time
 	lastNetworkActivityTime time.Time
+	// lastPacketSentTime is used to decide when to send cover packets (see Config.CoverTrafficInterval)
+	lastPacketSentTime time.Time
 	// pacingDeadline is the time when the next packet should be sent
 	pacingDeadline time.Time
+	// kernelPacing is set if packets are paced by the kernel (see Config.KernelPacing)
+	kernelPacing bool
+	// txTime is the time at which the kernel should transmit the packet that is currently being sent.
+	// It is only used if kernelPacing is set.
+	txTime time.Time
 
 	peerParams *handshake.TransportParameters
+	// idleTimeout is the idle timeout used for the connection.
+	// It is the minimum of the local idle timeout and the idle timeout advertised by the peer.
+	idleTimeout time.Duration
+	// peerTransportParameters is a copy of peerParams that can be read safely by the application
+	peerTransportParametersMutex sync.Mutex
+	peerTransportParameters      *TransportParameters
+	// streamCompressor is only set if the experimental stream compression extension was negotiated.
+	// It is protected by the peerTransportParametersMutex.
+	streamCompressor *streamCompressor
+	// compressedStreams contains the wrappers of the streams handed out to the application,
+	// such that a stream is always returned with the same wrapper.
+	// Entries are removed when the stream is completed. It is protected by the peerTransportParametersMutex.
+	compressedStreams map[protocol.StreamID]interface{}
+
+	// streamHandlerRegistered is set when a handler for incoming streams was registered (see OnStream)
+	streamHandlerMutex      sync.Mutex
+	streamHandlerRegistered bool
 
 	timer *utils.Timer
+	// timerWheel is used to park the session while it is idle (see Config.ParkIdleSessions).
+	// It is only set for the server, shared between all sessions.
+	timerWheel *timerWheel
+	parked     int32 // accessed atomically, 1 while the session is parked
 	// keepAlivePingSent stores whether a Ping frame was sent to the peer or not
 	// it is reset as soon as we receive a packet from the peer
 	keepAlivePingSent bool
 
+	traceCallback func(quictrace.Event)
+
 	logger utils.Logger
 }
 
@@ -150,8 +212,11 @@ var newSession = func(
 	destConnID protocol.ConnectionID,
 	srcConnID protocol.ConnectionID,
 	conf *Config,
-	tlsConf *tls.Config,
+	tlsConf *handshake.ServerConfigCache,
 	params *handshake.TransportParameters,
+	memoryBudget *flowcontrol.MemoryBudget,
+	handshakeLimiter *handshake.Limiter,
+	timerWheel *timerWheel,
 	logger utils.Logger,
 	v protocol.VersionNumber,
 ) (quicSession, error) {
@@ -162,15 +227,24 @@ var newSession = func(
 		srcConnID:             srcConnID,
 		destConnID:            destConnID,
 		perspective:           protocol.PerspectiveServer,
+		memoryBudget:          memoryBudget,
+		timerWheel:            timerWheel,
 		handshakeCompleteChan: make(chan struct{}),
+		idleTimeout:           conf.IdleTimeout,
 		logger:                logger,
 		version:               v,
 	}
+	if s.config.QuicTracer != nil {
+		s.traceCallback = func(ev quictrace.Event) {
+			s.config.QuicTracer.Trace(clientDestConnID, ev)
+		}
+	}
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.config.HyStartPlusPlus, s.config.Clock, s.traceCallback, s.onFrameAcked, s.logger)
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
+		s.deadlines,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
 		s.perspective,
@@ -182,9 +256,16 @@ var newSession = func(
 	oneRTTStream := newPostHandshakeCryptoStream(s.framer)
 	eetp := &handshake.EncryptedExtensionsTransportParameters{
 		NegotiatedVersion: s.version,
-		SupportedVersions: protocol.GetGreasedVersions(conf.Versions),
+		SupportedVersions: protocol.GetGreasedVersions(acceptedVersions(conf, conn.RemoteAddr())),
 		Parameters:        *params,
 	}
+	var acceptClientHello func(*handshake.ClientHelloInfo) bool
+	if conf.AcceptClientHello != nil {
+		acceptClientHello = func(chi *handshake.ClientHelloInfo) bool {
+			chi.Version = s.version
+			return conf.AcceptClientHello(conn.RemoteAddr(), chi)
+		}
+	}
 	cs, err := handshake.NewCryptoSetupServer(
 		initialStream,
 		handshakeStream,
@@ -193,6 +274,8 @@ var newSession = func(
 		eetp,
 		s.processTransportParameters,
 		tlsConf,
+		acceptClientHello,
+		handshakeLimiter,
 		logger,
 	)
 	if err != nil {
@@ -205,7 +288,8 @@ var newSession = func(
 		initialStream,
 		handshakeStream,
 		s.sentPacketHandler,
-		s.RemoteAddr(),
+		s.conn.MaxPacketSize(),
+		protocol.ByteCount(s.config.PacketPadding),
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -218,6 +302,9 @@ var newSession = func(
 		return nil, err
 	}
 	s.unpacker = newPacketUnpacker(cs, s.version)
+	if s.config.DecryptionWorkers > 1 {
+		s.parallelUnpacker = newParallelUnpacker(s.unpacker, cs, s.config.DecryptionWorkers, s.queuePacket, s.logger)
+	}
 	return s, nil
 }
 
@@ -243,12 +330,18 @@ var newClientSession = func(
 		destConnID:            destConnID,
 		perspective:           protocol.PerspectiveClient,
 		handshakeCompleteChan: make(chan struct{}),
+		idleTimeout:           conf.IdleTimeout,
 		logger:                logger,
 		initialVersion:        initialVersion,
 		version:               v,
 	}
+	if s.config.QuicTracer != nil {
+		s.traceCallback = func(ev quictrace.Event) {
+			s.config.QuicTracer.Trace(destConnID, ev)
+		}
+	}
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.config.HyStartPlusPlus, s.config.Clock, s.traceCallback, s.onFrameAcked, s.logger)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
 	oneRTTStream := newPostHandshakeCryptoStream(s.framer)
@@ -273,9 +366,13 @@ var newClientSession = func(
 	s.cryptoStreamHandler = cs
 	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream)
 	s.unpacker = newPacketUnpacker(cs, s.version)
+	if s.config.DecryptionWorkers > 1 {
+		s.parallelUnpacker = newParallelUnpacker(s.unpacker, cs, s.config.DecryptionWorkers, s.queuePacket, s.logger)
+	}
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
+		s.deadlines,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
 		s.perspective,
@@ -288,7 +385,8 @@ var newClientSession = func(
 		initialStream,
 		handshakeStream,
 		s.sentPacketHandler,
-		s.RemoteAddr(),
+		s.conn.MaxPacketSize(),
+		protocol.ByteCount(s.config.PacketPadding),
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -301,14 +399,20 @@ var newClientSession = func(
 func (s *session) preSetup() {
 	s.frameParser = wire.NewFrameParser(s.version)
 	s.rttStats = &congestion.RTTStats{}
-	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.rttStats, s.logger, s.version)
+	s.deadlines = newSessionDeadlines(s.config.Clock)
+	s.sendStreamsAwaitingAck = make(map[protocol.StreamID]sendStreamI)
+	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.rttStats, s.config.MaxAckRanges, s.config.Clock, s.logger, s.version)
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.InitialMaxData,
 		protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow),
 		s.onHasConnectionWindowUpdate,
+		s.memoryBudget,
+		protocol.ByteCount(s.config.MaxReadAhead),
 		s.rttStats,
+		s.config.Clock,
 		s.logger,
 	)
+	s.flowControlMonitor = newFlowControlMonitor(s.config.Clock, s.connFlowController)
 }
 
 func (s *session) postSetup() error {
@@ -317,21 +421,31 @@ func (s *session) postSetup() error {
 	s.sendingScheduled = make(chan struct{}, 1)
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	s.runDone = make(chan struct{})
 
-	s.timer = utils.NewTimer()
-	now := time.Now()
+	s.timer = utils.NewTimerWithClock(s.config.Clock)
+	now := s.config.Clock.Now()
 	s.lastNetworkActivityTime = now
 	s.sessionCreationTime = now
 
-	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
+	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.queueWindowUpdate)
+	s.pathHealth = newPathHealthMonitor(s.onPathHealthChanged)
+	if s.config.KernelPacing {
+		s.kernelPacing = s.enableKernelPacing()
+	}
 	return nil
 }
 
 // run the session main loop
+// If the session is parked (see Config.ParkIdleSessions), run returns errSessionParked,
+// and the run loop is resumed on a new go routine when needed.
 func (s *session) run() error {
-	defer s.ctxCancel()
-
 	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				s.handlePanic(newPanicError(e))
+			}
+		}()
 		if err := s.cryptoStreamHandler.RunHandshake(); err != nil {
 			s.closeLocal(err)
 			return
@@ -348,24 +462,82 @@ func (s *session) run() error {
 		}
 	}
 
-	var closeErr closeError
+	closeErr, parked := s.runLoopUntilClosed()
+	if parked {
+		return errSessionParked
+	}
+	return s.shutdown(closeErr)
+}
+
+// runLoopUntilClosed runs the run loop until the session is closed or parked.
+func (s *session) runLoopUntilClosed() (closeError, bool /* parked */) {
+	for {
+		// If the run loop panicked, the session was closed with an InternalError.
+		// Restart the run loop, which then receives the close error right away.
+		closeErr, panicked, parked := s.runLoop()
+		if parked {
+			return closeError{}, true
+		}
+		if !panicked {
+			return closeErr, false
+		}
+	}
+}
+
+// shutdown tears down the session after the run loop returned.
+func (s *session) shutdown(closeErr closeError) error {
+	defer close(s.runDone)
+	defer s.ctxCancel()
+
+	if err := s.handleCloseErrorRecovering(closeErr); err != nil {
+		s.logger.Infof("Handling close error failed: %s", err)
+	}
+	s.closed.Set(true)
+	s.logger.Infof("Connection %s closed.", s.srcConnID)
+	// Stop the decryption workers before the keys are erased.
+	if s.parallelUnpacker != nil {
+		s.parallelUnpacker.Close()
+	}
+	s.cryptoStreamHandler.Close()
+	s.connFlowController.Abandon()
+	if closeErr.closingCtx != nil && s.connectionClosePacket != nil {
+		s.ctxCancel()
+		s.waitForClosingPeriod(closeErr.closingCtx)
+	}
+	if s.perspective == protocol.PerspectiveClient && !s.handshakeComplete && closeErr.err != nil && closeErr.err != errCloseForRecreating {
+		return newHandshakeError(closeErr.err, s.cryptoStreamHandler.HandshakeState())
+	}
+	return closeErr.err
+}
+
+// runLoop processes packets, timers and sending until the session is closed or parked.
+// A panic is recovered, and closes the session with an InternalError.
+func (s *session) runLoop() (closeErr closeError, panicked bool, parked bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			s.handlePanic(newPanicError(e))
+			panicked = true
+		}
+	}()
 
-runLoop:
 	for {
 		// Close immediately if requested
 		select {
 		case closeErr = <-s.closeChan:
-			break runLoop
+			return closeErr, false, false
 		case <-s.handshakeCompleteChan:
 			s.handleHandshakeComplete()
 		default:
 		}
 
 		s.maybeResetTimer()
+		if s.park() {
+			return closeError{}, false, true
+		}
 
 		select {
 		case closeErr = <-s.closeChan:
-			break runLoop
+			return closeErr, false, false
 		case <-s.timer.Chan():
 			s.timer.SetRead()
 			// We do all the interesting stuff after the switch statement, so
@@ -380,24 +552,31 @@ runLoop:
 			if wasProcessed := s.handlePacketImpl(p); !wasProcessed {
 				continue
 			}
+			s.pathHealth.OnPacketReceived()
 		case <-s.handshakeCompleteChan:
 			s.handleHandshakeComplete()
 		}
 
-		now := time.Now()
+		now := s.config.Clock.Now()
 		if timeout := s.sentPacketHandler.GetAlarmTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
 			if err := s.sentPacketHandler.OnAlarm(); err != nil {
 				s.closeLocal(err)
 			}
+			ptoCount := s.sentPacketHandler.GetPTOCount()
+			s.pathHealth.SetPTOCount(ptoCount)
+			if ptoCount >= protocol.PathBrokenPTOCount {
+				// The path might be broken because the local address changed, e.g. when a VPN was turned on.
+				s.maybeRebind(fmt.Errorf("%d consecutive PTOs", ptoCount))
+			}
 		}
 
 		var pacingDeadline time.Time
 		if s.pacingDeadline.IsZero() { // the timer didn't have a pacing deadline set
-			pacingDeadline = s.sentPacketHandler.TimeUntilSend()
+			pacingDeadline = s.nextPacingDeadline()
 		}
-		if s.config.KeepAlive && !s.keepAlivePingSent && s.handshakeComplete && time.Since(s.lastNetworkActivityTime) >= s.peerParams.IdleTimeout/2 {
+		if s.config.KeepAlive && !s.keepAlivePingSent && s.handshakeComplete && s.config.Clock.Now().Sub(s.lastNetworkActivityTime) >= s.idleTimeout/2 {
 			// send a PING frame since there is no activity in the session
 			s.logger.Debugf("Sending a keep-alive ping to keep the connection alive.")
 			s.framer.QueueControlFrame(&wire.PingFrame{})
@@ -414,23 +593,36 @@ runLoop:
 			s.closeLocal(qerr.Error(qerr.HandshakeTimeout, "Crypto handshake did not complete in time."))
 			continue
 		}
-		if s.handshakeComplete && now.Sub(s.lastNetworkActivityTime) >= s.config.IdleTimeout {
+		if s.handshakeComplete && now.Sub(s.lastNetworkActivityTime) >= s.idleTimeout {
 			s.closeLocal(qerr.Error(qerr.NetworkIdleTimeout, "No recent network activity."))
 			continue
 		}
 
 		if err := s.sendPackets(); err != nil {
 			s.closeLocal(err)
+			continue
+		}
+		if s.config.CoverTrafficInterval > 0 && s.handshakeComplete && now.Sub(s.lastPacketSentTime) >= s.config.CoverTrafficInterval {
+			if err := s.sendCoverPacket(); err != nil {
+				s.closeLocal(err)
+			}
 		}
 	}
 
-	if err := s.handleCloseError(closeErr); err != nil {
-		s.logger.Infof("Handling close error failed: %s", err)
+}
+
+// waitForClosingPeriod blocks until the closing period ends, or the context is canceled.
+// During the closing period, the CONNECTION_CLOSE is retransmitted in response to incoming packets (see handlePacketAfterClosed).
+func (s *session) waitForClosingPeriod(ctx context.Context) {
+	// The closing period lasts for 3 PTOs.
+	closingPeriod := 3 * (s.rttStats.SmoothedOrInitialRTT() + 4*s.rttStats.MeanDeviation())
+	s.logger.Debugf("Entering the closing period for %s.", closingPeriod)
+	timer := s.config.Clock.NewTimer(closingPeriod)
+	defer timer.Stop()
+	select {
+	case <-timer.Chan():
+	case <-ctx.Done():
 	}
-	s.closed.Set(true)
-	s.logger.Infof("Connection %s closed.", s.srcConnID)
-	s.cryptoStreamHandler.Close()
-	return closeErr.err
 }
 
 func (s *session) Context() context.Context {
@@ -438,13 +630,27 @@ func (s *session) Context() context.Context {
 }
 
 func (s *session) ConnectionState() ConnectionState {
-	return s.cryptoStreamHandler.ConnectionState()
+	state := s.cryptoStreamHandler.ConnectionState()
+	state.Version = s.version
+	if s.perspective == protocol.PerspectiveClient {
+		state.UsedVersionNegotiation = s.initialVersion != 0 && s.initialVersion != s.version
+		state.ServerSupportedVersions = s.serverSupportedVersions
+	}
+	s.peerTransportParametersMutex.Lock()
+	if s.peerTransportParameters != nil {
+		state.IdleTimeout = negotiateIdleTimeout(s.config.IdleTimeout, s.peerTransportParameters.IdleTimeout)
+	}
+	state.StreamCompression = s.streamCompressor != nil
+	s.peerTransportParametersMutex.Unlock()
+	return state
 }
 
 func (s *session) maybeResetTimer() {
 	var deadline time.Time
 	if s.config.KeepAlive && s.handshakeComplete && !s.keepAlivePingSent {
-		deadline = s.lastNetworkActivityTime.Add(s.peerParams.IdleTimeout / 2)
+		deadline = s.lastNetworkActivityTime.Add(s.idleTimeout / 2)
+	} else if s.handshakeComplete {
+		deadline = s.lastNetworkActivityTime.Add(s.idleTimeout)
 	} else {
 		deadline = s.lastNetworkActivityTime.Add(s.config.IdleTimeout)
 	}
@@ -462,6 +668,9 @@ func (s *session) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if s.config.CoverTrafficInterval > 0 && s.handshakeComplete {
+		deadline = utils.MinTime(deadline, s.lastPacketSentTime.Add(s.config.CoverTrafficInterval))
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -508,7 +717,14 @@ func (s *session) handlePacketImpl(p *receivedPacket) bool /* was the packet suc
 		return false
 	}
 
-	packet, err := s.unpacker.Unpack(p.hdr, p.data)
+	var packet *unpackedPacket
+	var err error
+	if p.unpacked != nil || p.unpackErr != nil {
+		// the packet was already unpacked by the parallelUnpacker
+		packet, err = p.unpacked, p.unpackErr
+	} else {
+		packet, err = s.unpacker.Unpack(p.hdr, p.data)
+	}
 	if err != nil {
 		if err == handshake.ErrOpenerNotYetAvailable {
 			// Sealer for this encryption level not yet available.
@@ -517,6 +733,10 @@ func (s *session) handlePacketImpl(p *receivedPacket) bool /* was the packet suc
 			s.tryQueueingUndecryptablePacket(p)
 			return false
 		}
+		if perr, ok := err.(*panicError); ok {
+			s.handlePanic(perr)
+			return false
+		}
 		// This might be a packet injected by an attacker.
 		// Drop it.
 		s.logger.Debugf("Dropping packet that could not be unpacked. Unpack error: %s", err)
@@ -528,7 +748,11 @@ func (s *session) handlePacketImpl(p *receivedPacket) bool /* was the packet suc
 		packet.hdr.Log(s.logger)
 	}
 
-	if err := s.handleUnpackedPacket(packet, p.rcvTime); err != nil {
+	if err := s.handleUnpackedPacket(packet, p.rcvTime, len(p.data)); err != nil {
+		s.closeLocal(err)
+		return false
+	}
+	if err := s.handleFECRecoveredPackets(packet.hdr, p.rcvTime); err != nil {
 		s.closeLocal(err)
 		return false
 	}
@@ -576,7 +800,7 @@ func (s *session) handleRetryPacket(p *receivedPacket) bool /* was this a valid
 	return true
 }
 
-func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time) error {
+func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time, packetSize int) error {
 	if len(packet.data) == 0 {
 		return qerr.MissingPayload
 	}
@@ -601,8 +825,13 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 		}
 	}
 
+	if s.fecDecoder != nil && packet.encryptionLevel == protocol.Encryption1RTT {
+		s.fecDecoder.ReceivedPacket(packet.packetNumber, packet.data)
+	}
+
 	r := bytes.NewReader(packet.data)
 	var isRetransmittable bool
+	var frames []wire.Frame
 	for {
 		frame, err := s.frameParser.ParseNext(r, packet.encryptionLevel)
 		if err != nil {
@@ -614,11 +843,26 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 		if ackhandler.IsFrameRetransmittable(frame) {
 			isRetransmittable = true
 		}
+		if s.traceCallback != nil {
+			frames = append(frames, frame)
+		}
 		if err := s.handleFrame(frame, packet.packetNumber, packet.encryptionLevel); err != nil {
 			return err
 		}
 	}
 
+	if s.traceCallback != nil {
+		s.traceCallback(quictrace.Event{
+			Time:            rcvTime,
+			EventType:       quictrace.PacketReceived,
+			TransportState:  s.sentPacketHandler.GetStats(),
+			EncryptionLevel: packet.encryptionLevel,
+			PacketNumber:    packet.packetNumber,
+			PacketSize:      protocol.ByteCount(packetSize),
+			Frames:          frames,
+		})
+	}
+
 	if err := s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, packet.encryptionLevel, rcvTime, isRetransmittable); err != nil {
 		return err
 	}
@@ -636,7 +880,7 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 	case *wire.AckFrame:
 		err = s.handleAckFrame(frame, pn, encLevel)
 	case *wire.ConnectionCloseFrame:
-		s.closeRemote(qerr.Error(frame.ErrorCode, frame.ReasonPhrase))
+		s.handleConnectionCloseFrame(frame)
 	case *wire.ResetStreamFrame:
 		err = s.handleResetStreamFrame(frame)
 	case *wire.MaxDataFrame:
@@ -645,8 +889,8 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 		err = s.handleMaxStreamDataFrame(frame)
 	case *wire.MaxStreamsFrame:
 		err = s.handleMaxStreamsFrame(frame)
-	case *wire.DataBlockedFrame:
-	case *wire.StreamDataBlockedFrame:
+	case *wire.DataBlockedFrame, *wire.StreamDataBlockedFrame:
+		s.onFlowControlFrameReceived(frame)
 	case *wire.StreamsBlockedFrame:
 	case *wire.StopSendingFrame:
 		err = s.handleStopSendingFrame(frame)
@@ -657,6 +901,8 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
 		err = errors.New("unexpected PATH_RESPONSE frame")
 	case *wire.NewTokenFrame:
+	case *wire.FECRepairFrame:
+		err = s.handleFECRepairFrame(frame, encLevel)
 	case *wire.NewConnectionIDFrame:
 	case *wire.RetireConnectionIDFrame:
 		// since we don't send new connection IDs, we don't expect retirements
@@ -667,15 +913,61 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 	return err
 }
 
+func (s *session) handleFECRepairFrame(frame *wire.FECRepairFrame, encLevel protocol.EncryptionLevel) error {
+	if s.fecDecoder == nil {
+		return qerr.Error(qerr.InvalidFecData, "received a FEC_REPAIR frame, but FEC was not negotiated")
+	}
+	if encLevel != protocol.Encryption1RTT {
+		return qerr.Error(qerr.UnencryptedFecData, fmt.Sprintf("received a FEC_REPAIR frame with encryption level %s", encLevel))
+	}
+	pn, payload, err := s.fecDecoder.HandleRepairFrame(frame)
+	if err != nil || payload == nil {
+		return err
+	}
+	s.logger.Debugf("Recovered packet %#x using FEC.", pn)
+	s.fecRecoveredPackets = append(s.fecRecoveredPackets, &unpackedPacket{
+		packetNumber:    pn,
+		encryptionLevel: protocol.Encryption1RTT,
+		data:            payload,
+	})
+	return nil
+}
+
+// handleFECRecoveredPackets processes the packets that were recovered using FEC.
+// They are treated as if they had been received together with the packet containing the FEC_REPAIR frame.
+func (s *session) handleFECRecoveredPackets(hdr *wire.ExtendedHeader, rcvTime time.Time) error {
+	for len(s.fecRecoveredPackets) > 0 {
+		packet := s.fecRecoveredPackets[0]
+		s.fecRecoveredPackets = s.fecRecoveredPackets[1:]
+		recoveredHdr := *hdr
+		recoveredHdr.PacketNumber = packet.packetNumber
+		packet.hdr = &recoveredHdr
+		if err := s.handleUnpackedPacket(packet, rcvTime, len(packet.data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // handlePacket is called by the server with a new packet
 func (s *session) handlePacket(p *receivedPacket) {
 	if s.closed.Get() {
 		s.handlePacketAfterClosed(p)
 	}
+	if s.parallelUnpacker != nil && !p.hdr.IsLongHeader {
+		s.parallelUnpacker.HandlePacket(p)
+		return
+	}
+	s.queuePacket(p)
+}
+
+// queuePacket passes a packet to the run loop.
+func (s *session) queuePacket(p *receivedPacket) {
 	// Discard packets once the amount of queued packets is larger than
 	// the channel size, protocol.MaxSessionUnprocessedPackets
 	select {
 	case s.receivedPackets <- p:
+		s.wakeUp()
 	default:
 	}
 }
@@ -727,6 +1019,7 @@ func (s *session) handleStreamFrame(frame *wire.StreamFrame, encLevel protocol.E
 
 func (s *session) handleMaxDataFrame(frame *wire.MaxDataFrame) {
 	s.connFlowController.UpdateSendWindow(frame.ByteOffset)
+	s.onFlowControlFrameReceived(frame)
 }
 
 func (s *session) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) error {
@@ -739,6 +1032,7 @@ func (s *session) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) error
 		return nil
 	}
 	str.handleMaxStreamDataFrame(frame)
+	s.onFlowControlFrameReceived(frame)
 	return nil
 }
 
@@ -746,6 +1040,14 @@ func (s *session) handleMaxStreamsFrame(frame *wire.MaxStreamsFrame) error {
 	return s.streamsMap.HandleMaxStreamsFrame(frame)
 }
 
+func (s *session) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
+	if frame.IsApplicationError {
+		s.closeRemote(qerr.ApplicationError(frame.ErrorCode, frame.ReasonPhrase))
+		return
+	}
+	s.closeRemote(qerr.Error(frame.ErrorCode, frame.ReasonPhrase))
+}
+
 func (s *session) handleResetStreamFrame(frame *wire.ResetStreamFrame) error {
 	str, err := s.streamsMap.GetOrOpenReceiveStream(frame.StreamID)
 	if err != nil {
@@ -787,9 +1089,14 @@ func (s *session) handleAckFrame(frame *wire.AckFrame, pn protocol.PacketNumber,
 
 // closeLocal closes the session and send a CONNECTION_CLOSE containing the error
 func (s *session) closeLocal(e error) {
+	s.closeLocalImpl(closeError{err: e, sendClose: true, remote: false})
+}
+
+func (s *session) closeLocalImpl(closeErr closeError) {
 	s.closeOnce.Do(func() {
 		s.sessionRunner.retireConnectionID(s.srcConnID)
-		s.closeChan <- closeError{err: e, sendClose: true, remote: false}
+		s.closeChan <- closeErr
+		s.wakeUp()
 	})
 }
 
@@ -798,6 +1105,7 @@ func (s *session) destroy(e error) {
 	s.closeOnce.Do(func() {
 		s.sessionRunner.removeConnectionID(s.srcConnID)
 		s.closeChan <- closeError{err: e, sendClose: false, remote: false}
+		s.wakeUp()
 	})
 }
 
@@ -810,9 +1118,13 @@ func (s *session) closeForRecreating() protocol.PacketNumber {
 }
 
 func (s *session) closeRemote(e error) {
+	if quicErr, ok := e.(*qerr.QuicError); ok {
+		e = qerr.RemoteError(quicErr)
+	}
 	s.closeOnce.Do(func() {
 		s.sessionRunner.removeConnectionID(s.srcConnID)
 		s.closeChan <- closeError{err: e, remote: true}
+		s.wakeUp()
 	})
 }
 
@@ -825,11 +1137,66 @@ func (s *session) Close() error {
 }
 
 func (s *session) CloseWithError(code protocol.ApplicationErrorCode, e error) error {
-	s.closeLocal(qerr.Error(qerr.ErrorCode(code), e.Error()))
+	s.closeLocal(qerr.ApplicationError(qerr.ErrorCode(code), e.Error()))
+	<-s.ctx.Done()
+	return nil
+}
+
+// RetireKeys closes the connection, and retires the connection ID right away.
+// It waits until the keys have been erased before returning.
+func (s *session) RetireKeys() error {
+	s.closeOnce.Do(func() {
+		s.closeChan <- closeError{sendClose: true, remote: false}
+		s.wakeUp()
+	})
+	// If the session was already closed, the connection ID might only have been retired.
+	// Removing it a second time doesn't do any harm.
+	s.sessionRunner.removeConnectionID(s.srcConnID)
 	<-s.ctx.Done()
 	return nil
 }
 
+func (s *session) SetStreamGroupWeight(group string, weight int) {
+	s.framer.SetGroupWeight(group, weight)
+}
+
+// CloseSync closes the connection, and waits until the closing period has ended.
+func (s *session) CloseSync(ctx context.Context) error {
+	s.closeLocalImpl(closeError{sendClose: true, remote: false, closingCtx: ctx})
+	select {
+	case <-s.runDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handleCloseErrorRecovering calls handleCloseError, recovering from a panic.
+// The session might be in an inconsistent state after the run loop panicked,
+// and sending the CONNECTION_CLOSE might panic as well.
+func (s *session) handleCloseErrorRecovering(closeErr closeError) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = newPanicError(e)
+		}
+	}()
+	return s.handleCloseError(closeErr)
+}
+
+// handlePanic logs a panic that was recovered, and closes the session with an InternalError.
+func (s *session) handlePanic(e *panicError) {
+	s.logger.Errorf("Recovered from a %s in session %s: %s", e, s.srcConnID, e.stack)
+	if s.traceCallback != nil {
+		s.traceCallback(quictrace.Event{
+			Time:      s.config.Clock.Now(),
+			EventType: quictrace.PanicRecovered,
+			Panic:     e.Error(),
+			Stack:     e.stack,
+		})
+	}
+	s.closeLocal(qerr.Error(qerr.InternalError, e.Error()))
+}
+
 func (s *session) handleCloseError(closeErr closeError) error {
 	if closeErr.err == nil {
 		closeErr.err = qerr.PeerGoingAway
@@ -848,6 +1215,7 @@ func (s *session) handleCloseError(closeErr closeError) error {
 	}
 
 	s.streamsMap.CloseWithError(quicErr)
+	s.flowControlMonitor.RemoveStreams()
 
 	if !closeErr.sendClose {
 		return nil
@@ -876,12 +1244,53 @@ func (s *session) processTransportParameters(data []byte) {
 	}
 	s.logger.Debugf("Received Transport Parameters: %s", params)
 	s.peerParams = params
+	s.idleTimeout = negotiateIdleTimeout(s.config.IdleTimeout, params.IdleTimeout)
+	if s.idleTimeout < s.config.IdleTimeout {
+		s.logger.Debugf("Using the peer's idle timeout of %s.", s.idleTimeout)
+	}
+	s.peerTransportParametersMutex.Lock()
+	s.peerTransportParameters = &TransportParameters{
+		IdleTimeout:                    params.IdleTimeout,
+		MaxPacketSize:                  uint64(params.MaxPacketSize),
+		InitialMaxData:                 uint64(params.InitialMaxData),
+		InitialMaxStreamDataBidiLocal:  uint64(params.InitialMaxStreamDataBidiLocal),
+		InitialMaxStreamDataBidiRemote: uint64(params.InitialMaxStreamDataBidiRemote),
+		InitialMaxStreamDataUni:        uint64(params.InitialMaxStreamDataUni),
+		MaxBidiStreams:                 params.MaxBidiStreams,
+		MaxUniStreams:                  params.MaxUniStreams,
+		AckDelayExponent:               params.AckDelayExponent,
+		DisableMigration:               params.DisableMigration,
+	}
+	if s.config.StreamCompression && params.EnableStreamCompression {
+		if params.StreamCompressionDictionaryID == streamCompressionDictionaryID(s.config.StreamCompressionDictionary) {
+			s.logger.Debugf("Enabling stream compression.")
+			s.streamCompressor = newStreamCompressor(s.config.StreamCompressionDictionary)
+			s.compressedStreams = make(map[protocol.StreamID]interface{})
+		} else {
+			s.logger.Debugf("Not enabling stream compression, since the peer uses a different dictionary (%#x).", params.StreamCompressionDictionaryID)
+		}
+	}
+	s.peerTransportParametersMutex.Unlock()
 	s.streamsMap.UpdateLimits(params)
 	s.packer.HandleTransportParameters(params)
+	if s.config.FECGroupSize > 0 && params.EnableFEC {
+		s.logger.Debugf("Enabling FEC, sending a FEC_REPAIR frame every %d packets.", s.config.FECGroupSize)
+		s.packer.EnableFEC(s.config.FECGroupSize)
+		s.fecDecoder = newFECDecoder()
+	}
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 }
 
+// negotiateIdleTimeout returns the idle timeout used for a connection.
+// A peer that doesn't advertise an idle timeout (or advertises 0) doesn't impose any limit.
+func negotiateIdleTimeout(local, peer time.Duration) time.Duration {
+	if peer == 0 {
+		return local
+	}
+	return utils.MinDuration(local, peer)
+}
+
 func (s *session) processTransportParametersForClient(data []byte) (*handshake.TransportParameters, error) {
 	eetp := handshake.EncryptedExtensionsTransportParameters{}
 	if err := eetp.Unmarshal(data); err != nil {
@@ -902,6 +1311,7 @@ func (s *session) processTransportParametersForClient(data []byte) (*handshake.T
 			return nil, qerr.Error(qerr.VersionNegotiationMismatch, "would have picked a different version")
 		}
 	}
+	s.serverSupportedVersions = eetp.SupportedVersions
 
 	params := &eetp.Parameters
 	// check that the server sent a stateless reset token
@@ -924,7 +1334,7 @@ func (s *session) processTransportParametersForServer(data []byte) (*handshake.T
 	// perform the stateless version negotiation validation:
 	// make sure that we would have sent a Version Negotiation Packet if the client offered the initial version
 	// this is the case if and only if the initial version is not contained in the supported versions
-	if chtp.InitialVersion != s.version && protocol.IsSupportedVersion(s.config.Versions, chtp.InitialVersion) {
+	if chtp.InitialVersion != s.version && protocol.IsSupportedVersion(acceptedVersions(s.config, s.conn.RemoteAddr()), chtp.InitialVersion) {
 		return nil, qerr.Error(qerr.VersionNegotiationMismatch, "Client should have used the initial version")
 	}
 	return &chtp.Parameters, nil
@@ -940,8 +1350,21 @@ func (s *session) sendPackets() error {
 
 	numPackets := s.sentPacketHandler.ShouldSendNumPackets()
 	var numPacketsSent int
+	now := s.config.Clock.Now()
+	if s.kernelPacing {
+		// Packets sent outside of sendPackets (e.g. a CONNECTION_CLOSE) are not delayed.
+		defer func() { s.txTime = time.Time{} }()
+	}
 sendLoop:
 	for {
+		if s.kernelPacing {
+			// The kernel holds the packet until the pacing deadline.
+			// Probe packets are not paced.
+			s.txTime = time.Time{}
+			if sendMode != ackhandler.SendPTO {
+				s.txTime = s.sentPacketHandler.TimeUntilSend()
+			}
+		}
 		switch sendMode {
 		case ackhandler.SendNone:
 			break sendLoop
@@ -983,19 +1406,42 @@ sendLoop:
 		default:
 			return fmt.Errorf("BUG: invalid send mode %d", sendMode)
 		}
-		if numPacketsSent >= numPackets {
+		// With kernel pacing, keep sending packets as long as they are due within the pacing horizon.
+		if numPacketsSent >= numPackets && (!s.kernelPacing || s.nextPacingDeadline().After(now)) {
 			break
 		}
 		sendMode = s.sentPacketHandler.SendMode()
 	}
 	// Only start the pacing timer if we sent as many packets as we were allowed.
 	// There will probably be more to send when calling sendPacket again.
-	if numPacketsSent == numPackets {
-		s.pacingDeadline = s.sentPacketHandler.TimeUntilSend()
+	if numPacketsSent >= numPackets {
+		s.pacingDeadline = s.nextPacingDeadline()
 	}
 	return nil
 }
 
+// nextPacingDeadline returns the time when the next packet should be sent.
+// With kernel pacing, packets are passed to the kernel ahead of time,
+// so this is KernelPacingHorizon before the time when the packet is due.
+func (s *session) nextPacingDeadline() time.Time {
+	deadline := s.sentPacketHandler.TimeUntilSend()
+	if s.kernelPacing && !deadline.IsZero() {
+		return deadline.Add(-protocol.KernelPacingHorizon)
+	}
+	return deadline
+}
+
+// enableKernelPacing enables pacing by the kernel (see Config.KernelPacing).
+// It returns false if the connection doesn't support it.
+func (s *session) enableKernelPacing() bool {
+	if c, ok := s.conn.(txTimeConnection); ok && c.EnableTxTime() {
+		s.logger.Debugf("Using kernel pacing.")
+		return true
+	}
+	s.logger.Infof("Kernel pacing is not supported on this connection. Pacing packets in user space.")
+	return false
+}
+
 func (s *session) maybeSendAckOnlyPacket() error {
 	packet, err := s.packer.MaybePackAckPacket()
 	if err != nil {
@@ -1004,7 +1450,7 @@ func (s *session) maybeSendAckOnlyPacket() error {
 	if packet == nil {
 		return nil
 	}
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket())
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.config.Clock.Now()))
 	return s.sendPackedPacket(packet)
 }
 
@@ -1035,7 +1481,7 @@ func (s *session) maybeSendRetransmission() (bool, error) {
 	}
 	ackhandlerPackets := make([]*ackhandler.Packet, len(packets))
 	for i, packet := range packets {
-		ackhandlerPackets[i] = packet.ToAckHandlerPacket()
+		ackhandlerPackets[i] = packet.ToAckHandlerPacket(s.config.Clock.Now())
 	}
 	s.sentPacketHandler.SentPacketsAsRetransmission(ackhandlerPackets, retransmitPacket.PacketNumber)
 	for _, packet := range packets {
@@ -1059,7 +1505,7 @@ func (s *session) sendProbePacket() error {
 	}
 	ackhandlerPackets := make([]*ackhandler.Packet, len(packets))
 	for i, packet := range packets {
-		ackhandlerPackets[i] = packet.ToAckHandlerPacket()
+		ackhandlerPackets[i] = packet.ToAckHandlerPacket(s.config.Clock.Now())
 	}
 	s.sentPacketHandler.SentPacketsAsRetransmission(ackhandlerPackets, p.PacketNumber)
 	for _, packet := range packets {
@@ -1072,40 +1518,183 @@ func (s *session) sendProbePacket() error {
 
 func (s *session) sendPacket() (bool, error) {
 	if isBlocked, offset := s.connFlowController.IsNewlyBlocked(); isBlocked {
-		s.framer.QueueControlFrame(&wire.DataBlockedFrame{DataLimit: offset})
+		frame := &wire.DataBlockedFrame{DataLimit: offset}
+		s.framer.QueueControlFrame(frame)
+		s.onFlowControlFrameSent(frame)
 	}
 	s.windowUpdateQueue.QueueAll()
 
-	packet, err := s.packer.PackPacket()
+	packet, err := s.packer.PackCoalescedPacket()
 	if err != nil || packet == nil {
 		return false, err
 	}
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket())
-	if err := s.sendPackedPacket(packet); err != nil {
+	for _, p := range packet.packets {
+		s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(s.config.Clock.Now()))
+	}
+	if err := s.sendCoalescedPacket(packet); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
+// sendCoverPacket sends a packet that only contains PADDING (see Config.CoverTrafficInterval).
+func (s *session) sendCoverPacket() error {
+	packet, err := s.packer.PackCoverPacket()
+	if err != nil || packet == nil {
+		return err
+	}
+	s.logger.Debugf("Sending a cover packet.")
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.config.Clock.Now()))
+	return s.sendPackedPacket(packet)
+}
+
+func (s *session) sendCoalescedPacket(packet *coalescedPacket) error {
+	defer packet.buffer.Release()
+	if len(packet.packets) > 1 && s.logger.Debug() {
+		s.logger.Debugf("-> Sending coalesced packet (%d parts, %d bytes) for connection %s", len(packet.packets), len(packet.raw), s.srcConnID)
+	}
+	for _, p := range packet.packets {
+		s.logPacket(p)
+		s.tracePacket(p)
+	}
+	return s.writePacket(packet.raw)
+}
+
 func (s *session) sendPackedPacket(packet *packedPacket) error {
 	defer packet.buffer.Release()
 	s.logPacket(packet)
-	return s.conn.Write(packet.raw)
+	s.tracePacket(packet)
+	return s.writePacket(packet.raw)
+}
+
+// writePacket writes a packet to the connection.
+// Errors caused by the network path are only returned once the path is considered broken.
+// Until then, the packet is treated as lost.
+func (s *session) writePacket(raw []byte) error {
+	s.lastPacketSentTime = s.config.Clock.Now()
+	var err error
+	if s.kernelPacing {
+		err = s.conn.(txTimeConnection).WriteWithDelay(raw, s.txTime.Sub(s.config.Clock.Now()))
+	} else {
+		err = s.conn.Write(raw)
+	}
+	if err == nil {
+		s.pathHealth.OnWriteSuccess()
+		return nil
+	}
+	if isLocalAddrError(err) && s.maybeRebind(err) {
+		// The packet is declared lost and retransmitted on the new socket.
+		return nil
+	}
+	if !isPathError(err) {
+		return err
+	}
+	s.logger.Debugf("Error writing packet: %s", err)
+	if s.pathHealth.OnWriteError(err) == PathBroken {
+		return err
+	}
+	return nil
+}
+
+// maybeRebind replaces the socket, if the Config enables AutoRebind.
+// Rebinding is only possible after the handshake completed, and if the peer allows migration.
+// It returns true if a new socket is used.
+func (s *session) maybeRebind(reason error) bool {
+	if !s.config.AutoRebind || !s.handshakeComplete || s.peerParams.DisableMigration {
+		return false
+	}
+	if !s.lastRebindTime.IsZero() && s.config.Clock.Now().Sub(s.lastRebindTime) < protocol.MinRebindInterval {
+		return false
+	}
+	s.lastRebindTime = s.config.Clock.Now()
+	if err := s.sessionRunner.rebind(); err != nil {
+		s.logger.Debugf("Rebinding failed: %s", err)
+		return false
+	}
+	s.logger.Infof("Rebound to %s (reason: %s)", s.conn.LocalAddr(), reason)
+	if s.kernelPacing {
+		s.kernelPacing = s.enableKernelPacing()
+	}
+	// make sure the peer learns about the new address as soon as possible
+	s.queueControlFrame(&wire.PingFrame{})
+	return true
+}
+
+func (s *session) onPathHealthChanged(health PathHealth, reason error) {
+	s.logger.Debugf("Path health changed to %s (reason: %v)", health, reason)
+	if s.config.PathHealthChanged != nil {
+		s.config.PathHealthChanged(s, health, reason)
+	}
+}
+
+func (s *session) SetDeadline(t time.Time) error {
+	s.deadlines.SetReadDeadline(t)
+	s.deadlines.SetWriteDeadline(t)
+	return nil
+}
+
+func (s *session) SetReadDeadline(t time.Time) error {
+	s.deadlines.SetReadDeadline(t)
+	return nil
+}
+
+func (s *session) SetWriteDeadline(t time.Time) error {
+	s.deadlines.SetWriteDeadline(t)
+	return nil
+}
+
+func (s *session) PeerTransportParameters() *TransportParameters {
+	s.peerTransportParametersMutex.Lock()
+	defer s.peerTransportParametersMutex.Unlock()
+	if s.peerTransportParameters == nil {
+		return nil
+	}
+	params := *s.peerTransportParameters
+	return &params
+}
+
+func (s *session) PathHealth() PathHealth {
+	return s.pathHealth.Health()
+}
+
+func (s *session) FlowControlState() []FlowControlState {
+	return s.flowControlMonitor.State()
+}
+
+func (s *session) FlowControlDeadlocks(threshold time.Duration) []FlowControlDeadlock {
+	return s.flowControlMonitor.Deadlocks(threshold)
 }
 
 func (s *session) sendConnectionClose(quicErr *qerr.QuicError) error {
 	packet, err := s.packer.PackConnectionClose(&wire.ConnectionCloseFrame{
-		ErrorCode:    quicErr.ErrorCode,
-		ReasonPhrase: quicErr.ErrorMessage,
+		IsApplicationError: quicErr.IsApplicationError(),
+		ErrorCode:          quicErr.ErrorCode,
+		ReasonPhrase:       quicErr.ErrorMessage,
 	})
 	if err != nil {
 		return err
 	}
 	s.connectionClosePacket = packet
 	s.logPacket(packet)
+	s.tracePacket(packet)
 	return s.conn.Write(packet.raw)
 }
 
+func (s *session) tracePacket(packet *packedPacket) {
+	if s.traceCallback == nil {
+		return
+	}
+	s.traceCallback(quictrace.Event{
+		Time:            s.config.Clock.Now(),
+		EventType:       quictrace.PacketSent,
+		TransportState:  s.sentPacketHandler.GetStats(),
+		EncryptionLevel: packet.EncryptionLevel(),
+		PacketNumber:    packet.header.PacketNumber,
+		PacketSize:      protocol.ByteCount(len(packet.raw)),
+		Frames:          packet.frames,
+	})
+}
+
 func (s *session) logPacket(packet *packedPacket) {
 	if !s.logger.Debug() {
 		// We don't need to allocate the slices for calling the format functions
@@ -1125,7 +1714,7 @@ func (s *session) GetOrOpenStream(id protocol.StreamID) (Stream, error) {
 	str, err := s.streamsMap.GetOrOpenSendStream(id)
 	if str != nil {
 		if bstr, ok := str.(Stream); ok {
-			return bstr, err
+			return s.wrapStream(bstr), err
 		}
 		return nil, fmt.Errorf("Stream %d is not a bidirectional stream", id)
 	}
@@ -1135,28 +1724,114 @@ func (s *session) GetOrOpenStream(id protocol.StreamID) (Stream, error) {
 
 // AcceptStream returns the next stream openend by the peer
 func (s *session) AcceptStream() (Stream, error) {
-	return s.streamsMap.AcceptStream()
+	str, err := s.streamsMap.AcceptStream()
+	if err != nil {
+		return nil, err
+	}
+	return s.wrapStream(str), nil
 }
 
 func (s *session) AcceptUniStream() (ReceiveStream, error) {
-	return s.streamsMap.AcceptUniStream()
+	str, err := s.streamsMap.AcceptUniStream()
+	if err != nil {
+		return nil, err
+	}
+	return s.wrapReceiveStream(str), nil
+}
+
+func (s *session) OnStream(handler func(Stream)) error {
+	s.streamHandlerMutex.Lock()
+	defer s.streamHandlerMutex.Unlock()
+	if s.streamHandlerRegistered {
+		return errStreamHandlerRegistered
+	}
+	s.streamHandlerRegistered = true
+	maxConcurrent := s.config.MaxConcurrentStreamHandlers
+	if maxConcurrent == 0 {
+		maxConcurrent = s.config.MaxIncomingStreams
+	}
+	go newIncomingStreamHandler(s.AcceptStream, handler, s.handlePanic, maxConcurrent, s.ctx.Done()).run()
+	return nil
 }
 
 // OpenStream opens a stream
 func (s *session) OpenStream() (Stream, error) {
-	return s.streamsMap.OpenStream()
+	str, err := s.streamsMap.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	return s.wrapStream(str), nil
 }
 
 func (s *session) OpenStreamSync() (Stream, error) {
-	return s.streamsMap.OpenStreamSync()
+	str, err := s.streamsMap.OpenStreamSync()
+	if err != nil {
+		return nil, err
+	}
+	return s.wrapStream(str), nil
 }
 
 func (s *session) OpenUniStream() (SendStream, error) {
-	return s.streamsMap.OpenUniStream()
+	str, err := s.streamsMap.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	return s.wrapSendStream(str), nil
 }
 
 func (s *session) OpenUniStreamSync() (SendStream, error) {
-	return s.streamsMap.OpenUniStreamSync()
+	str, err := s.streamsMap.OpenUniStreamSync()
+	if err != nil {
+		return nil, err
+	}
+	return s.wrapSendStream(str), nil
+}
+
+// getStreamCompressor returns nil if stream compression wasn't negotiated
+func (s *session) getStreamCompressor() *streamCompressor {
+	s.peerTransportParametersMutex.Lock()
+	defer s.peerTransportParametersMutex.Unlock()
+	return s.streamCompressor
+}
+
+// getCompressedStream returns the wrapper of a stream, creating it if the stream wasn't handed out before.
+// Every stream is only wrapped once, so that all calls of (e.g.) GetOrOpenStream and AcceptStream return the same wrapper.
+// If stream compression wasn't negotiated, it returns nil.
+func (s *session) getCompressedStream(str interface{ StreamID() StreamID }, wrap func(*streamCompressor) interface{}) interface{} {
+	s.peerTransportParametersMutex.Lock()
+	defer s.peerTransportParametersMutex.Unlock()
+
+	if s.streamCompressor == nil {
+		return nil
+	}
+	id := str.StreamID()
+	if str, ok := s.compressedStreams[id]; ok {
+		return str
+	}
+	cstr := wrap(s.streamCompressor)
+	s.compressedStreams[id] = cstr
+	return cstr
+}
+
+func (s *session) wrapStream(str Stream) Stream {
+	if cstr := s.getCompressedStream(str, func(c *streamCompressor) interface{} { return c.WrapStream(str) }); cstr != nil {
+		return cstr.(Stream)
+	}
+	return str
+}
+
+func (s *session) wrapSendStream(str SendStream) SendStream {
+	if cstr := s.getCompressedStream(str, func(c *streamCompressor) interface{} { return c.WrapSendStream(str) }); cstr != nil {
+		return cstr.(SendStream)
+	}
+	return str
+}
+
+func (s *session) wrapReceiveStream(str ReceiveStream) ReceiveStream {
+	if cstr := s.getCompressedStream(str, func(c *streamCompressor) interface{} { return c.WrapReceiveStream(str) }); cstr != nil {
+		return cstr.(ReceiveStream)
+	}
+	return str
 }
 
 func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlowController {
@@ -1172,7 +1847,7 @@ func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlow
 			}
 		}
 	}
-	return flowcontrol.NewStreamFlowController(
+	fc := flowcontrol.NewStreamFlowController(
 		id,
 		s.connFlowController,
 		protocol.InitialMaxStreamData,
@@ -1180,8 +1855,11 @@ func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlow
 		initialSendWindow,
 		s.onHasStreamWindowUpdate,
 		s.rttStats,
+		s.config.Clock,
 		s.logger,
 	)
+	s.flowControlMonitor.AddStream(id, fc)
+	return fc
 }
 
 // scheduleSending signals that we have data for sending
@@ -1190,6 +1868,7 @@ func (s *session) scheduleSending() {
 	case s.sendingScheduled <- struct{}{}:
 	default:
 	}
+	s.wakeUp()
 }
 
 func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket) {
@@ -1214,9 +1893,42 @@ func (s *session) tryDecryptingQueuedPackets() {
 
 func (s *session) queueControlFrame(f wire.Frame) {
 	s.framer.QueueControlFrame(f)
+	// STREAM_DATA_BLOCKED frames are queued by the send streams
+	s.onFlowControlFrameSent(f)
 	s.scheduleSending()
 }
 
+// queueWindowUpdate is called by the windowUpdateQueue
+func (s *session) queueWindowUpdate(f wire.Frame) {
+	s.framer.QueueControlFrame(f)
+	s.onFlowControlFrameSent(f)
+}
+
+func (s *session) onFlowControlFrameSent(f wire.Frame) {
+	if transition, state := s.flowControlMonitor.OnFrameSent(f); state != nil {
+		s.traceFlowControl(transition, state, f)
+	}
+}
+
+func (s *session) onFlowControlFrameReceived(f wire.Frame) {
+	if transition, state := s.flowControlMonitor.OnFrameReceived(f); state != nil {
+		s.traceFlowControl(transition, state, f)
+	}
+}
+
+func (s *session) traceFlowControl(transition quictrace.FlowControlTransition, state *FlowControlState, f wire.Frame) {
+	if s.traceCallback == nil {
+		return
+	}
+	s.traceCallback(quictrace.Event{
+		Time:                  s.config.Clock.Now(),
+		EventType:             quictrace.FlowControlUpdated,
+		Frames:                []wire.Frame{f},
+		FlowControlTransition: transition,
+		FlowControlState:      state,
+	})
+}
+
 func (s *session) onHasStreamWindowUpdate(id protocol.StreamID) {
 	s.windowUpdateQueue.AddStream(id)
 	s.scheduleSending()
@@ -1233,11 +1945,48 @@ func (s *session) onHasStreamData(id protocol.StreamID) {
 }
 
 func (s *session) onStreamCompleted(id protocol.StreamID) {
+	// If the peer didn't acknowledge all data yet, keep the send stream around,
+	// so that the application can learn when the data is acknowledged (see Stream.WriteAcked).
+	if id.Type() == protocol.StreamTypeBidi || id.InitiatedBy() == s.perspective {
+		if str, _ := s.streamsMap.GetOrOpenSendStream(id); str != nil && !str.allDataAcked() {
+			s.sendStreamsAwaitingAckMutex.Lock()
+			s.sendStreamsAwaitingAck[id] = str
+			s.sendStreamsAwaitingAckMutex.Unlock()
+		}
+	}
+	s.flowControlMonitor.RemoveStream(id)
+	s.peerTransportParametersMutex.Lock()
+	delete(s.compressedStreams, id)
+	s.peerTransportParametersMutex.Unlock()
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
 	}
 }
 
+// onFrameAcked is called by the sentPacketHandler for every frame of an acknowledged packet.
+func (s *session) onFrameAcked(f wire.Frame) {
+	frame, ok := f.(*wire.StreamFrame)
+	if !ok {
+		return
+	}
+	s.sendStreamsAwaitingAckMutex.Lock()
+	str, awaitingAck := s.sendStreamsAwaitingAck[frame.StreamID]
+	s.sendStreamsAwaitingAckMutex.Unlock()
+	if !awaitingAck {
+		var err error
+		str, err = s.streamsMap.GetOrOpenSendStream(frame.StreamID)
+		if err != nil || str == nil {
+			return
+		}
+	}
+	str.handleAckedStreamFrame(frame)
+	if awaitingAck && str.allDataAcked() {
+		s.sendStreamsAwaitingAckMutex.Lock()
+		delete(s.sendStreamsAwaitingAck, frame.StreamID)
+		s.sendStreamsAwaitingAckMutex.Unlock()
+	}
+}
+
 func (s *session) LocalAddr() net.Addr {
 	return s.conn.LocalAddr()
 }
@@ -1249,3 +1998,8 @@ func (s *session) RemoteAddr() net.Addr {
 func (s *session) GetVersion() protocol.VersionNumber {
 	return s.version
 }
+
+// getBufferedBytes returns the number of bytes that were received, but not yet read by the application
+func (s *session) getBufferedBytes() protocol.ByteCount {
+	return s.connFlowController.BufferedBytes()
+}
@@ -0,0 +1,90 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// sessionDeadlines are the read and write deadlines set on a session.
+// They apply to all streams of the session, in addition to the deadlines set on the streams.
+// All methods can be called on a nil sessionDeadlines, which doesn't impose any deadline.
+type sessionDeadlines struct {
+	mutex sync.Mutex
+
+	read  time.Time
+	write time.Time
+	// changed is closed (and replaced) when a deadline is changed,
+	// such that blocked Read and Write calls can pick up the new deadline.
+	changed chan struct{}
+
+	// clock is the clock that all deadlines of the session are measured against
+	clock utils.Clock
+}
+
+func newSessionDeadlines(clock utils.Clock) *sessionDeadlines {
+	return &sessionDeadlines{
+		changed: make(chan struct{}),
+		clock:   clock,
+	}
+}
+
+// getClock returns the clock that deadlines are measured against.
+func (d *sessionDeadlines) getClock() utils.Clock {
+	if d == nil {
+		return utils.DefaultClock
+	}
+	return d.clock
+}
+
+func (d *sessionDeadlines) SetReadDeadline(t time.Time) {
+	d.mutex.Lock()
+	d.read = t
+	d.signalChange()
+	d.mutex.Unlock()
+}
+
+func (d *sessionDeadlines) SetWriteDeadline(t time.Time) {
+	d.mutex.Lock()
+	d.write = t
+	d.signalChange()
+	d.mutex.Unlock()
+}
+
+// must be called with the mutex held
+func (d *sessionDeadlines) signalChange() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// readDeadline returns the read deadline,
+// and a channel that is closed when the deadline changes.
+func (d *sessionDeadlines) readDeadline() (time.Time, <-chan struct{}) {
+	if d == nil {
+		return time.Time{}, nil
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.read, d.changed
+}
+
+// writeDeadline returns the write deadline,
+// and a channel that is closed when the deadline changes.
+func (d *sessionDeadlines) writeDeadline() (time.Time, <-chan struct{}) {
+	if d == nil {
+		return time.Time{}, nil
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.write, d.changed
+}
+
+// earliestDeadline returns the earlier of two deadlines.
+// A zero time means that no deadline is set.
+func earliestDeadline(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,53 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session Deadlines", func() {
+	It("sets the read and write deadline", func() {
+		d := newSessionDeadlines(utils.DefaultClock)
+		readDeadline := time.Now().Add(time.Hour)
+		writeDeadline := time.Now().Add(time.Minute)
+		d.SetReadDeadline(readDeadline)
+		d.SetWriteDeadline(writeDeadline)
+		t, _ := d.readDeadline()
+		Expect(t).To(Equal(readDeadline))
+		t, _ = d.writeDeadline()
+		Expect(t).To(Equal(writeDeadline))
+	})
+
+	It("signals when a deadline changes", func() {
+		d := newSessionDeadlines(utils.DefaultClock)
+		_, changed := d.readDeadline()
+		Expect(changed).ToNot(BeClosed())
+		d.SetWriteDeadline(time.Now())
+		Expect(changed).To(BeClosed())
+		_, changed = d.writeDeadline()
+		Expect(changed).ToNot(BeClosed())
+	})
+
+	It("doesn't set any deadline if nil", func() {
+		var d *sessionDeadlines
+		t, changed := d.readDeadline()
+		Expect(t).To(BeZero())
+		Expect(changed).To(BeNil())
+		t, changed = d.writeDeadline()
+		Expect(t).To(BeZero())
+		Expect(changed).To(BeNil())
+	})
+
+	It("determines the earliest deadline", func() {
+		now := time.Now()
+		Expect(earliestDeadline(time.Time{}, time.Time{})).To(BeZero())
+		Expect(earliestDeadline(now, time.Time{})).To(Equal(now))
+		Expect(earliestDeadline(time.Time{}, now)).To(Equal(now))
+		Expect(earliestDeadline(now, now.Add(time.Second))).To(Equal(now))
+		Expect(earliestDeadline(now.Add(time.Second), now)).To(Equal(now))
+	})
+})
@@ -0,0 +1,56 @@
+package quic
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// errSessionParked is returned by run when the session was parked.
+var errSessionParked = errors.New("session parked")
+
+var _ wakeable = &session{}
+
+// park parks the session, if it is idle and parking is enabled (see Config.ParkIdleSessions).
+// The caller must return from the run loop if park returns true.
+// A parked session doesn't use a go routine, and its timer is replaced by the timerWheel.
+// It is resumed by wakeUp, which is called when a packet is received, when sending is scheduled,
+// when the session is closed, and when its timer expires.
+func (s *session) park() bool {
+	if s.timerWheel == nil || !s.handshakeComplete {
+		return false
+	}
+	deadline := s.timer.Deadline()
+	if deadline.Sub(s.config.Clock.Now()) < s.timerWheel.minDelay {
+		return false
+	}
+	s.timer.Reset(time.Time{})
+	s.timerWheel.Add(s, deadline)
+	atomic.StoreInt32(&s.parked, 1)
+	// An event might have been queued before the session was marked as parked.
+	// Its wakeUp call didn't resume the session, so we have to check for it here.
+	if len(s.receivedPackets) == 0 && len(s.sendingScheduled) == 0 && len(s.closeChan) == 0 {
+		return true
+	}
+	if !atomic.CompareAndSwapInt32(&s.parked, 1, 0) {
+		// The session was already resumed on a new go routine.
+		return true
+	}
+	s.timerWheel.Remove(s)
+	return false
+}
+
+// wakeUp resumes the run loop of a parked session on a new go routine.
+// It is a no-op if the session is not parked.
+func (s *session) wakeUp() {
+	if atomic.CompareAndSwapInt32(&s.parked, 1, 0) {
+		go s.resume()
+	}
+}
+
+func (s *session) resume() {
+	s.timerWheel.Remove(s)
+	if closeErr, parked := s.runLoopUntilClosed(); !parked {
+		s.shutdown(closeErr)
+	}
+}
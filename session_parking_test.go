@@ -0,0 +1,114 @@
+package quic
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session Parking", func() {
+	var (
+		ln         Listener
+		serverSess chan Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		ln, err = ListenAddr("localhost:0", testdata.GetTLSConfig(), &Config{ParkIdleSessions: true, IdleTimeout: 500 * time.Millisecond})
+		Expect(err).ToNot(HaveOccurred())
+		// park sessions as soon as possible, in order to speed up the tests
+		ln.(*server).timerWheel.minDelay = 50 * time.Millisecond
+		serverSess = make(chan Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			serverSess <- sess
+			for {
+				str, err := sess.AcceptStream()
+				if err != nil {
+					return
+				}
+				go io.Copy(str, str)
+			}
+		}()
+	})
+
+	AfterEach(func() {
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	dial := func() Session {
+		sess, err := DialAddr(ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}, &Config{IdleTimeout: 500 * time.Millisecond})
+		Expect(err).ToNot(HaveOccurred())
+		return sess
+	}
+
+	echo := func(sess Session) {
+		str, err := sess.OpenStreamSync()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 6)
+		_, err = io.ReadFull(str, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("foobar")))
+	}
+
+	isParked := func(sess Session) func() bool {
+		return func() bool { return atomic.LoadInt32(&sess.(*session).parked) == 1 }
+	}
+
+	It("parks idle sessions, and resumes them when a packet is received", func() {
+		sess := dial()
+		defer sess.Close()
+		echo(sess)
+		var s Session
+		Eventually(serverSess).Should(Receive(&s))
+		Eventually(isParked(s)).Should(BeTrue())
+		Expect(ln.Stats().ParkedSessions).To(Equal(1))
+		echo(sess)
+		Eventually(isParked(s)).Should(BeTrue())
+		Expect(ln.Stats().ParkedSessions).To(Equal(1))
+	})
+
+	It("resumes parked sessions when they are closed", func() {
+		sess := dial()
+		echo(sess)
+		var s Session
+		Eventually(serverSess).Should(Receive(&s))
+		Eventually(isParked(s)).Should(BeTrue())
+		Expect(s.Close()).To(Succeed())
+		Expect(ln.Stats().ParkedSessions).To(BeZero())
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("closes parked sessions when the idle timeout expires", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		sess, err := Dial(conn, ln.Addr(), ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		echo(sess)
+		var s Session
+		Eventually(serverSess).Should(Receive(&s))
+		Eventually(isParked(s)).Should(BeTrue())
+		// make sure that the client can't close the session
+		Expect(conn.Close()).To(Succeed())
+		Eventually(s.Context().Done(), time.Second).Should(BeClosed())
+		Expect(ln.Stats().ParkedSessions).To(BeZero())
+		_, err = s.AcceptStream()
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.NetworkIdleTimeout))
+		sess.Close()
+	})
+})
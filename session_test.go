@@ -3,10 +3,14 @@ package quic
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
 	"errors"
 	"net"
+	"os"
 	"runtime/pprof"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -21,12 +25,14 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 type mockConnection struct {
 	remoteAddr net.Addr
 	localAddr  net.Addr
 	written    chan []byte
+	writeErr   error
 }
 
 func newMockConnection() *mockConnection {
@@ -37,6 +43,9 @@ func newMockConnection() *mockConnection {
 }
 
 func (m *mockConnection) Write(p []byte) error {
+	if m.writeErr != nil {
+		return m.writeErr
+	}
 	b := make([]byte, len(p))
 	copy(b, p)
 	select {
@@ -53,7 +62,35 @@ func (m *mockConnection) SetCurrentRemoteAddr(addr net.Addr) {
 }
 func (m *mockConnection) LocalAddr() net.Addr  { return m.localAddr }
 func (m *mockConnection) RemoteAddr() net.Addr { return m.remoteAddr }
-func (*mockConnection) Close() error           { panic("not implemented") }
+func (m *mockConnection) MaxPacketSize() protocol.ByteCount {
+	return getMaxPacketSize(m.remoteAddr)
+}
+func (*mockConnection) Close() error { panic("not implemented") }
+
+type mockTxTimeConnection struct {
+	*mockConnection
+	supported bool
+
+	mutex  sync.Mutex
+	delays []time.Duration
+}
+
+var _ txTimeConnection = &mockTxTimeConnection{}
+
+func (m *mockTxTimeConnection) EnableTxTime() bool { return m.supported }
+
+func (m *mockTxTimeConnection) WriteWithDelay(p []byte, delay time.Duration) error {
+	m.mutex.Lock()
+	m.delays = append(m.delays, delay)
+	m.mutex.Unlock()
+	return m.Write(p)
+}
+
+func (m *mockTxTimeConnection) getDelays() []time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.delays
+}
 
 func areSessionsRunning() bool {
 	var b bytes.Buffer
@@ -66,6 +103,11 @@ func insertPacketBuffer(p *receivedPacket) *receivedPacket {
 	return p
 }
 
+// A clock that runs an hour ahead of the system clock
+type offsetClock struct{ utils.Clock }
+
+func (c offsetClock) Now() time.Time { return c.Clock.Now().Add(time.Hour) }
+
 var _ = Describe("Session", func() {
 	var (
 		sess          *session
@@ -92,6 +134,9 @@ var _ = Describe("Session", func() {
 			populateServerConfig(&Config{}),
 			nil, // tls.Config
 			&handshake.TransportParameters{},
+			nil, // memory budget
+			nil, // handshake limiter
+			nil, // timer wheel
 			utils.DefaultLogger,
 			protocol.VersionTLS,
 		)
@@ -175,6 +220,46 @@ var _ = Describe("Session", func() {
 				sess.receivedPacketHandler = rph
 				Expect(sess.handleAckFrame(ack, 0, protocol.Encryption1RTT)).To(Succeed())
 			})
+
+			It("passes acknowledged STREAM frames to the stream", func() {
+				f := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+				str := NewMockSendStreamI(mockCtrl)
+				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(5)).Return(str, nil)
+				str.EXPECT().handleAckedStreamFrame(f)
+				sess.onFrameAcked(f)
+			})
+
+			It("ignores acknowledged STREAM frames for closed streams", func() {
+				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(5)).Return(nil, nil)
+				sess.onFrameAcked(&wire.StreamFrame{StreamID: 5, Data: []byte("foobar")})
+			})
+
+			It("ignores other acknowledged frames", func() {
+				sess.onFrameAcked(&wire.PingFrame{})
+			})
+
+			It("keeps completed streams until all data was acknowledged", func() {
+				str := NewMockSendStreamI(mockCtrl)
+				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(4)).Return(str, nil)
+				str.EXPECT().allDataAcked()
+				streamManager.EXPECT().DeleteStream(protocol.StreamID(4))
+				sess.onStreamCompleted(4)
+				Expect(sess.sendStreamsAwaitingAck).To(HaveKey(protocol.StreamID(4)))
+				f := &wire.StreamFrame{StreamID: 4, Data: []byte("foobar"), FinBit: true}
+				str.EXPECT().handleAckedStreamFrame(f)
+				str.EXPECT().allDataAcked().Return(true)
+				sess.onFrameAcked(f)
+				Expect(sess.sendStreamsAwaitingAck).To(BeEmpty())
+			})
+
+			It("doesn't keep completed streams if all data was acknowledged", func() {
+				str := NewMockSendStreamI(mockCtrl)
+				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(4)).Return(str, nil)
+				str.EXPECT().allDataAcked().Return(true)
+				streamManager.EXPECT().DeleteStream(protocol.StreamID(4))
+				sess.onStreamCompleted(4)
+				Expect(sess.sendStreamsAwaitingAck).To(BeEmpty())
+			})
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -313,6 +398,22 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("records and traces BLOCKED frames", func() {
+			var events []quictrace.Event
+			sess.traceCallback = func(ev quictrace.Event) { events = append(events, ev) }
+			frame := &wire.DataBlockedFrame{DataLimit: protocol.InitialMaxData}
+			Expect(sess.handleFrame(frame, 0, protocol.EncryptionUnspecified)).To(Succeed())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].EventType).To(Equal(quictrace.FlowControlUpdated))
+			Expect(events[0].FlowControlTransition).To(Equal(quictrace.PeerBlocked))
+			Expect(events[0].Frames).To(Equal([]wire.Frame{frame}))
+			Expect(events[0].FlowControlState.Connection).To(BeTrue())
+			Expect(events[0].FlowControlState.PeerBlockedSince).ToNot(BeZero())
+			Expect(sess.FlowControlState()[0].PeerBlockedSince).ToNot(BeZero())
+			Expect(sess.FlowControlDeadlocks(time.Hour)).To(BeEmpty())
+			Expect(sess.FlowControlDeadlocks(0)).To(HaveLen(1))
+		})
+
 		It("handles STREAM_BLOCKED frames", func() {
 			err := sess.handleFrame(&wire.StreamDataBlockedFrame{}, 0, protocol.EncryptionUnspecified)
 			Expect(err).NotTo(HaveOccurred())
@@ -324,7 +425,7 @@ var _ = Describe("Session", func() {
 		})
 
 		It("handles CONNECTION_CLOSE frames", func() {
-			testErr := qerr.Error(qerr.ProofInvalid, "foobar")
+			testErr := qerr.RemoteError(qerr.Error(qerr.ProofInvalid, "foobar"))
 			streamManager.EXPECT().CloseWithError(testErr)
 			sessionRunner.EXPECT().removeConnectionID(gomock.Any())
 			cryptoSetup.EXPECT().Close()
@@ -339,6 +440,54 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
+
+		It("handles CONNECTION_CLOSE frames carrying an application error", func() {
+			testErr := qerr.RemoteError(qerr.ApplicationError(0x42, "foobar"))
+			streamManager.EXPECT().CloseWithError(testErr)
+			sessionRunner.EXPECT().removeConnectionID(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+				err := sess.run()
+				Expect(err).To(MatchError(testErr))
+				code, ok := AsApplicationError(err)
+				Expect(ok).To(BeTrue())
+				Expect(code).To(BeEquivalentTo(0x42))
+				Expect(IsRemoteError(err)).To(BeTrue())
+			}()
+			err := sess.handleFrame(&wire.ConnectionCloseFrame{
+				IsApplicationError: true,
+				ErrorCode:          0x42,
+				ReasonPhrase:       "foobar",
+			}, 0, protocol.EncryptionUnspecified)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+	})
+
+	It("uses the configured clock", func() {
+		clock := offsetClock{utils.DefaultClock}
+		pSess, err := newSession(
+			mconn,
+			sessionRunner,
+			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1},
+			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+			populateServerConfig(&Config{Clock: clock}),
+			nil, // tls.Config
+			&handshake.TransportParameters{},
+			nil, // memory budget
+			nil, // handshake limiter
+			nil, // timer wheel
+			utils.DefaultLogger,
+			protocol.VersionTLS,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		s := pSess.(*session)
+		Expect(s.sessionCreationTime).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+		Expect(s.deadlines.getClock()).To(Equal(clock))
 	})
 
 	It("tells its versions", func() {
@@ -346,6 +495,126 @@ var _ = Describe("Session", func() {
 		Expect(sess.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
 	})
 
+	Context("path health", func() {
+		pathErr := &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.ENETUNREACH)}
+
+		It("treats path errors as packet loss, and reports a degraded path", func() {
+			var health PathHealth
+			var reason error
+			sess.config.PathHealthChanged = func(s Session, h PathHealth, r error) {
+				Expect(s).To(Equal(sess))
+				health = h
+				reason = r
+			}
+			mconn.writeErr = pathErr
+			Expect(sess.writePacket([]byte("foobar"))).To(Succeed())
+			Expect(sess.PathHealth()).To(Equal(PathDegraded))
+			Expect(health).To(Equal(PathDegraded))
+			Expect(reason).To(MatchError(pathErr))
+			// writing succeeds again
+			mconn.writeErr = nil
+			Expect(sess.writePacket([]byte("foobar"))).To(Succeed())
+			Expect(mconn.written).To(Receive(Equal([]byte("foobar"))))
+			Expect(sess.PathHealth()).To(Equal(PathHealthy))
+			Expect(health).To(Equal(PathHealthy))
+			Expect(reason).To(BeNil())
+		})
+
+		It("returns the error when the path is broken", func() {
+			var health PathHealth
+			sess.config.PathHealthChanged = func(_ Session, h PathHealth, _ error) { health = h }
+			mconn.writeErr = pathErr
+			for i := 1; i < protocol.MaxConsecutiveWriteErrors; i++ {
+				Expect(sess.writePacket([]byte("foobar"))).To(Succeed())
+			}
+			Expect(sess.writePacket([]byte("foobar"))).To(MatchError(pathErr))
+			Expect(sess.PathHealth()).To(Equal(PathBroken))
+			Expect(health).To(Equal(PathBroken))
+		})
+
+		It("returns other errors right away", func() {
+			testErr := errors.New("test error")
+			mconn.writeErr = testErr
+			Expect(sess.writePacket([]byte("foobar"))).To(MatchError(testErr))
+			Expect(sess.PathHealth()).To(Equal(PathHealthy))
+		})
+
+		Context("rebinding", func() {
+			localAddrErr := &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EADDRNOTAVAIL)}
+
+			BeforeEach(func() {
+				sess.config.AutoRebind = true
+				sess.handshakeComplete = true
+				sess.peerParams = &handshake.TransportParameters{}
+			})
+
+			It("rebinds when the local address becomes unavailable", func() {
+				sessionRunner.EXPECT().rebind()
+				mconn.writeErr = localAddrErr
+				Expect(sess.writePacket([]byte("foobar"))).To(Succeed())
+				Expect(sess.PathHealth()).To(Equal(PathHealthy))
+				frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(ContainElement(&wire.PingFrame{}))
+			})
+
+			It("doesn't rebind more than once per MinRebindInterval", func() {
+				sessionRunner.EXPECT().rebind()
+				mconn.writeErr = localAddrErr
+				Expect(sess.writePacket([]byte("foobar"))).To(Succeed())
+				Expect(sess.writePacket([]byte("foobar"))).To(MatchError(localAddrErr))
+				sess.lastRebindTime = time.Now().Add(-protocol.MinRebindInterval)
+				sessionRunner.EXPECT().rebind()
+				Expect(sess.writePacket([]byte("foobar"))).To(Succeed())
+			})
+
+			It("returns the error if rebinding fails", func() {
+				sessionRunner.EXPECT().rebind().Return(errors.New("no network"))
+				mconn.writeErr = localAddrErr
+				Expect(sess.writePacket([]byte("foobar"))).To(MatchError(localAddrErr))
+			})
+
+			It("doesn't rebind if not enabled", func() {
+				sess.config.AutoRebind = false
+				mconn.writeErr = localAddrErr
+				Expect(sess.writePacket([]byte("foobar"))).To(MatchError(localAddrErr))
+			})
+
+			It("doesn't rebind before the handshake completes", func() {
+				sess.handshakeComplete = false
+				mconn.writeErr = localAddrErr
+				Expect(sess.writePacket([]byte("foobar"))).To(MatchError(localAddrErr))
+			})
+
+			It("doesn't rebind if the peer disabled migration", func() {
+				sess.peerParams.DisableMigration = true
+				mconn.writeErr = localAddrErr
+				Expect(sess.writePacket([]byte("foobar"))).To(MatchError(localAddrErr))
+			})
+		})
+	})
+
+	It("sets the deadlines for all streams", func() {
+		deadline := time.Now().Add(time.Hour)
+		Expect(sess.SetDeadline(deadline)).To(Succeed())
+		t, _ := sess.deadlines.readDeadline()
+		Expect(t).To(Equal(deadline))
+		t, _ = sess.deadlines.writeDeadline()
+		Expect(t).To(Equal(deadline))
+		Expect(sess.SetReadDeadline(time.Time{})).To(Succeed())
+		t, _ = sess.deadlines.readDeadline()
+		Expect(t).To(BeZero())
+		Expect(sess.SetWriteDeadline(time.Time{})).To(Succeed())
+		t, _ = sess.deadlines.writeDeadline()
+		Expect(t).To(BeZero())
+	})
+
+	It("tells how many bytes are buffered", func() {
+		connFC := mocks.NewMockConnectionFlowController(mockCtrl)
+		connFC.EXPECT().BufferedBytes().Return(protocol.ByteCount(1337))
+		sess.connFlowController = connFC
+		Expect(sess.getBufferedBytes()).To(Equal(protocol.ByteCount(1337)))
+	})
+
 	It("accepts new streams", func() {
 		mstr := NewMockStreamI(mockCtrl)
 		streamManager.EXPECT().AcceptStream().Return(mstr, nil)
@@ -403,6 +672,18 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("removes the streams from the flow control state", func() {
+			sess.newFlowController(5)
+			Expect(sess.FlowControlState()).To(HaveLen(2))
+			streamManager.EXPECT().CloseWithError(qerr.Error(qerr.PeerGoingAway, ""))
+			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			Expect(sess.Close()).To(Succeed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.FlowControlState()).To(HaveLen(1))
+		})
+
 		It("only closes once", func() {
 			streamManager.EXPECT().CloseWithError(qerr.Error(qerr.PeerGoingAway, ""))
 			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
@@ -415,12 +696,39 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("retires the keys", func() {
+			streamManager.EXPECT().CloseWithError(qerr.Error(qerr.PeerGoingAway, ""))
+			sessionRunner.EXPECT().removeConnectionID(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{raw: []byte("connection close")}, nil)
+			Expect(sess.RetireKeys()).To(Succeed())
+			Expect(sess.Context().Done()).To(BeClosed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(mconn.written).To(Receive(ContainSubstring("connection close")))
+		})
+
+		It("removes the connection ID when retiring the keys of a closed session", func() {
+			streamManager.EXPECT().CloseWithError(qerr.Error(qerr.PeerGoingAway, ""))
+			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			Expect(sess.Close()).To(Succeed())
+			sessionRunner.EXPECT().removeConnectionID(gomock.Any())
+			Expect(sess.RetireKeys()).To(Succeed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(mconn.written).To(HaveLen(1))
+		})
+
 		It("closes streams with proper error", func() {
 			testErr := errors.New("test error")
-			streamManager.EXPECT().CloseWithError(qerr.Error(0x1337, testErr.Error()))
+			streamManager.EXPECT().CloseWithError(qerr.ApplicationError(0x1337, testErr.Error()))
 			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
 			cryptoSetup.EXPECT().Close()
-			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			packer.EXPECT().PackConnectionClose(&wire.ConnectionCloseFrame{
+				IsApplicationError: true,
+				ErrorCode:          0x1337,
+				ReasonPhrase:       testErr.Error(),
+			}).Return(&packedPacket{}, nil)
 			sess.CloseWithError(0x1337, testErr)
 			Eventually(areSessionsRunning).Should(BeFalse())
 			Expect(sess.Context().Done()).To(BeClosed())
@@ -482,6 +790,61 @@ var _ = Describe("Session", func() {
 				}
 			}
 		})
+
+		Context("closing synchronously", func() {
+			BeforeEach(func() {
+				sess.rttStats.UpdateRTT(scaleDuration(10*time.Millisecond), 0, time.Now())
+				// the closing period is 3 PTOs, which is 3 * (10ms + 4 * 5ms) = 90ms
+				Expect(3 * (sess.rttStats.SmoothedRTT() + 4*sess.rttStats.MeanDeviation())).To(Equal(scaleDuration(90 * time.Millisecond)))
+			})
+
+			It("waits for the closing period, retransmitting the CONNECTION_CLOSE", func() {
+				streamManager.EXPECT().CloseWithError(qerr.Error(qerr.PeerGoingAway, ""))
+				sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{raw: []byte("connection close")}, nil)
+				done := make(chan struct{})
+				start := time.Now()
+				go func() {
+					defer GinkgoRecover()
+					Expect(sess.CloseSync(context.Background())).To(Succeed())
+					close(done)
+				}()
+				Eventually(mconn.written).Should(Receive(Equal([]byte("connection close"))))
+				Eventually(sess.Context().Done()).Should(BeClosed())
+				Expect(done).ToNot(BeClosed())
+				sess.handlePacket(&receivedPacket{})
+				Expect(mconn.written).To(Receive(Equal([]byte("connection close"))))
+				Eventually(done).Should(BeClosed())
+				Expect(time.Since(start)).To(BeNumerically(">=", scaleDuration(90*time.Millisecond)))
+				Eventually(areSessionsRunning).Should(BeFalse())
+			})
+
+			It("returns when the context is canceled", func() {
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{raw: []byte("connection close")}, nil)
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+				defer cancel()
+				start := time.Now()
+				Expect(sess.CloseSync(ctx)).To(MatchError(context.DeadlineExceeded))
+				Expect(time.Since(start)).To(BeNumerically("<", scaleDuration(90*time.Millisecond)))
+				Eventually(areSessionsRunning).Should(BeFalse())
+			})
+
+			It("doesn't wait if the session was already closed", func() {
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+				Expect(sess.Close()).To(Succeed())
+				Eventually(areSessionsRunning).Should(BeFalse())
+				start := time.Now()
+				Expect(sess.CloseSync(context.Background())).To(Succeed())
+				Expect(time.Since(start)).To(BeNumerically("<", scaleDuration(90*time.Millisecond)))
+			})
+		})
 	})
 
 	Context("receiving packets", func() {
@@ -548,6 +911,149 @@ var _ = Describe("Session", func() {
 			}))).To(BeTrue())
 		})
 
+		It("traces received packets", func() {
+			var events []quictrace.Event
+			sess.traceCallback = func(ev quictrace.Event) { events = append(events, ev) }
+			hdr := &wire.ExtendedHeader{
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			rcvTime := time.Now().Add(-10 * time.Second)
+			buf := &bytes.Buffer{}
+			Expect((&wire.PingFrame{}).Write(buf, sess.version)).To(Succeed())
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.EncryptionHandshake,
+				hdr:             hdr,
+				data:            buf.Bytes(),
+			}, nil)
+			data := getData(hdr)
+			Expect(sess.handlePacketImpl(insertPacketBuffer(&receivedPacket{
+				rcvTime: rcvTime,
+				hdr:     &hdr.Header,
+				data:    data,
+			}))).To(BeTrue())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].EventType).To(Equal(quictrace.PacketReceived))
+			Expect(events[0].Time).To(Equal(rcvTime))
+			Expect(events[0].PacketNumber).To(Equal(protocol.PacketNumber(0x1337)))
+			Expect(events[0].PacketSize).To(Equal(protocol.ByteCount(len(data))))
+			Expect(events[0].EncryptionLevel).To(Equal(protocol.EncryptionHandshake))
+			Expect(events[0].Frames).To(Equal([]wire.Frame{&wire.PingFrame{}}))
+			Expect(events[0].TransportState).ToNot(BeNil())
+		})
+
+		It("uses packets that were unpacked by the parallel unpacker", func() {
+			hdr := &wire.ExtendedHeader{
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			rcvTime := time.Now().Add(-10 * time.Second)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.Encryption1RTT, rcvTime, false)
+			sess.receivedPacketHandler = rph
+			Expect(sess.handlePacketImpl(insertPacketBuffer(&receivedPacket{
+				rcvTime: rcvTime,
+				hdr:     &hdr.Header,
+				data:    getData(hdr),
+				unpacked: &unpackedPacket{
+					packetNumber:    0x1337,
+					encryptionLevel: protocol.Encryption1RTT,
+					hdr:             hdr,
+					data:            []byte{0}, // one PADDING frame
+				},
+			}))).To(BeTrue())
+		})
+
+		It("drops packets that the parallel unpacker failed to unpack", func() {
+			hdr := &wire.ExtendedHeader{
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			Expect(sess.handlePacketImpl(insertPacketBuffer(&receivedPacket{
+				hdr:       &hdr.Header,
+				data:      getData(hdr),
+				unpackErr: errors.New("unpack error"),
+			}))).To(BeFalse())
+		})
+
+		It("passes short header packets to the parallel unpacker", func() {
+			cs := mocks.NewMockCryptoSetup(mockCtrl)
+			sess.parallelUnpacker = newParallelUnpacker(unpacker, cs, 2, sess.queuePacket, utils.DefaultLogger)
+			defer sess.parallelUnpacker.Close()
+			hdr := &wire.ExtendedHeader{
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			cs.EXPECT().NewOneRTTOpener().Return(mocks.NewMockOpener(mockCtrl), nil)
+			unpacked := &unpackedPacket{
+				packetNumber:    0x37,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}
+			unpacker.EXPECT().UnpackShortHeader(&hdr.Header, gomock.Any(), gomock.Any()).Return(unpacked, nil)
+			sess.handlePacket(&receivedPacket{hdr: &hdr.Header, data: getData(hdr)})
+			var p *receivedPacket
+			Eventually(sess.receivedPackets).Should(Receive(&p))
+			Expect(p.unpacked).To(Equal(unpacked))
+		})
+
+		Context("forward error correction", func() {
+			getPacket := func(pn protocol.PacketNumber, frames ...wire.Frame) *receivedPacket {
+				hdr := &wire.ExtendedHeader{
+					PacketNumber:    pn,
+					PacketNumberLen: protocol.PacketNumberLen2,
+				}
+				buf := &bytes.Buffer{}
+				for _, f := range frames {
+					Expect(f.Write(buf, sess.version)).To(Succeed())
+				}
+				return insertPacketBuffer(&receivedPacket{
+					rcvTime: time.Now(),
+					hdr:     &hdr.Header,
+					data:    getData(hdr),
+					unpacked: &unpackedPacket{
+						packetNumber:    pn,
+						encryptionLevel: protocol.Encryption1RTT,
+						hdr:             hdr,
+						data:            buf.Bytes(),
+					},
+				})
+			}
+
+			It("recovers a lost packet", func() {
+				sess.fecDecoder = newFECDecoder()
+				encoder := newFECEncoder(2)
+				p10 := getPacket(10, &wire.PingFrame{})
+				p11 := getPacket(11, &wire.MaxDataFrame{ByteOffset: 0x1337})
+				encoder.AddPacket(10, p10.unpacked.data)
+				encoder.AddPacket(11, p11.unpacked.data)
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				sess.receivedPacketHandler = rph
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(10), protocol.Encryption1RTT, gomock.Any(), true)
+				Expect(sess.handlePacketImpl(p10)).To(BeTrue())
+				// packet 11 is lost
+				gomock.InOrder(
+					rph.EXPECT().ReceivedPacket(protocol.PacketNumber(12), protocol.Encryption1RTT, gomock.Any(), false),
+					rph.EXPECT().ReceivedPacket(protocol.PacketNumber(11), protocol.Encryption1RTT, gomock.Any(), true),
+				)
+				Expect(sess.handlePacketImpl(getPacket(12, encoder.PopRepairFrame()))).To(BeTrue())
+				Expect(sess.connFlowController.SendWindowSize()).To(BeEquivalentTo(0x1337))
+			})
+
+			It("errors when receiving a FEC_REPAIR frame if FEC wasn't negotiated", func() {
+				err := sess.handleFrame(&wire.FECRepairFrame{NumPackets: 1}, 42, protocol.Encryption1RTT)
+				Expect(err).To(MatchError(qerr.Error(qerr.InvalidFecData, "received a FEC_REPAIR frame, but FEC was not negotiated")))
+			})
+
+			It("errors when receiving a FEC_REPAIR frame in a Handshake packet", func() {
+				sess.fecDecoder = newFECDecoder()
+				err := sess.handleFrame(&wire.FECRepairFrame{NumPackets: 1}, 42, protocol.EncryptionHandshake)
+				Expect(err).To(MatchError(qerr.Error(qerr.UnencryptedFecData, "received a FEC_REPAIR frame with encryption level Handshake")))
+			})
+		})
+
 		It("drops a packet when unpacking fails", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Return(nil, errors.New("unpack error"))
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -569,6 +1075,97 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
+		Context("recovering from panics", func() {
+			It("closes the session when handling a packet panics", func() {
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Do(func(*wire.Header, []byte) { panic("foobar") })
+				var events []quictrace.Event
+				sess.traceCallback = func(ev quictrace.Event) { events = append(events, ev) }
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+					Expect(f.ErrorCode).To(Equal(qerr.InternalError))
+					Expect(f.ReasonPhrase).To(Equal("panic: foobar"))
+					return &packedPacket{}, nil
+				})
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+					err := sess.run()
+					Expect(err).To(MatchError(qerr.Error(qerr.InternalError, "panic: foobar")))
+					close(done)
+				}()
+				sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+				sess.handlePacket(insertPacketBuffer(&receivedPacket{
+					hdr:  &wire.Header{},
+					data: getData(&wire.ExtendedHeader{PacketNumberLen: protocol.PacketNumberLen1}),
+				}))
+				Eventually(done).Should(BeClosed())
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].EventType).To(Equal(quictrace.PanicRecovered))
+				Expect(events[0].Panic).To(Equal("panic: foobar"))
+				Expect(string(events[0].Stack)).To(ContainSubstring("session_test.go"))
+			})
+
+			It("closes the session when the packet was unpacked by the parallel unpacker", func() {
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+					err := sess.run()
+					Expect(err).To(MatchError(qerr.Error(qerr.InternalError, "panic: foobar")))
+					close(done)
+				}()
+				sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+				sess.handlePacket(insertPacketBuffer(&receivedPacket{
+					hdr:       &wire.Header{},
+					data:      getData(&wire.ExtendedHeader{PacketNumberLen: protocol.PacketNumberLen1}),
+					unpackErr: newPanicError("foobar"),
+				}))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("closes the session when the handshake panics", func() {
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+				sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					cryptoSetup.EXPECT().RunHandshake().Do(func() { panic("foobar") })
+					err := sess.run()
+					Expect(err).To(MatchError(qerr.Error(qerr.InternalError, "panic: foobar")))
+					close(done)
+				}()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("closes the session when sending the CONNECTION_CLOSE panics", func() {
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Do(func(*wire.Header, []byte) { panic("foobar") })
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Do(func(*wire.ConnectionCloseFrame) { panic("raboof") })
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+					err := sess.run()
+					Expect(err).To(MatchError(qerr.Error(qerr.InternalError, "panic: foobar")))
+					close(done)
+				}()
+				sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+				sess.handlePacket(insertPacketBuffer(&receivedPacket{
+					hdr:  &wire.Header{},
+					data: getData(&wire.ExtendedHeader{PacketNumberLen: protocol.PacketNumberLen1}),
+				}))
+				Eventually(done).Should(BeClosed())
+			})
+		})
+
 		It("rejects packets with empty payload", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Return(&unpackedPacket{
 				hdr:  &wire.ExtendedHeader{},
@@ -679,16 +1276,40 @@ var _ = Describe("Session", func() {
 			}
 		}
 
+		coalesce := func(p *packedPacket) *coalescedPacket {
+			return &coalescedPacket{
+				raw:     p.raw,
+				packets: []*packedPacket{p},
+				buffer:  p.buffer,
+			}
+		}
+
 		It("sends packets", func() {
-			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+			packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(1)), nil)
 			Expect(sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.Encryption1RTT, time.Now(), true)).To(Succeed())
 			sent, err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(sent).To(BeTrue())
 		})
 
+		It("traces sent packets", func() {
+			var events []quictrace.Event
+			sess.traceCallback = func(ev quictrace.Event) { events = append(events, ev) }
+			p := getPacket(1)
+			p.frames = []wire.Frame{&wire.PingFrame{}}
+			packer.EXPECT().PackCoalescedPacket().Return(coalesce(p), nil)
+			sent, err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sent).To(BeTrue())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].EventType).To(Equal(quictrace.PacketSent))
+			Expect(events[0].PacketNumber).To(Equal(protocol.PacketNumber(1)))
+			Expect(events[0].PacketSize).To(Equal(protocol.ByteCount(6)))
+			Expect(events[0].Frames).To(Equal([]wire.Frame{&wire.PingFrame{}}))
+		})
+
 		It("doesn't send packets if there's nothing to send", func() {
-			packer.EXPECT().PackPacket().Return(getPacket(2), nil)
+			packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(2)), nil)
 			Expect(sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.Encryption1RTT, time.Now(), true)).To(Succeed())
 			sent, err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
@@ -708,7 +1329,7 @@ var _ = Describe("Session", func() {
 		It("adds a BLOCKED frame when it is connection-level flow control blocked", func() {
 			fc := mocks.NewMockConnectionFlowController(mockCtrl)
 			fc.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(1337))
-			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+			packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(1)), nil)
 			sess.connFlowController = fc
 			sent, err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
@@ -717,6 +1338,22 @@ var _ = Describe("Session", func() {
 			Expect(frames).To(Equal([]wire.Frame{&wire.DataBlockedFrame{DataLimit: 1337}}))
 		})
 
+		It("traces when it is connection-level flow control blocked", func() {
+			var events []quictrace.Event
+			sess.traceCallback = func(ev quictrace.Event) { events = append(events, ev) }
+			fc := mocks.NewMockConnectionFlowController(mockCtrl)
+			fc.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(1337))
+			packer.EXPECT().PackCoalescedPacket()
+			sess.connFlowController = fc
+			_, err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].EventType).To(Equal(quictrace.FlowControlUpdated))
+			Expect(events[0].FlowControlTransition).To(Equal(quictrace.Blocked))
+			Expect(events[0].FlowControlState.SendWindow).To(Equal(protocol.ByteCount(1337)))
+			Expect(events[0].FlowControlState.BlockedSince).ToNot(BeZero())
+		})
+
 		It("sends a retransmission and a regular packet in the same run", func() {
 			packetToRetransmit := &ackhandler.Packet{
 				PacketNumber: 10,
@@ -737,7 +1374,7 @@ var _ = Describe("Session", func() {
 					Expect(packets).To(HaveLen(1))
 					Expect(packets[0].PacketNumber).To(Equal(protocol.PacketNumber(123)))
 				}),
-				packer.EXPECT().PackPacket().Return(newPacket, nil),
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(newPacket), nil),
 				sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
 					Expect(p.PacketNumber).To(Equal(protocol.PacketNumber(234)))
 				}),
@@ -799,6 +1436,18 @@ var _ = Describe("Session", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("enables kernel pacing, if the connection supports it", func() {
+			sess.conn = &mockTxTimeConnection{mockConnection: mconn, supported: true}
+			Expect(sess.enableKernelPacing()).To(BeTrue())
+		})
+
+		It("falls back to pacing in user space, if the connection doesn't support it", func() {
+			sess.conn = &mockTxTimeConnection{mockConnection: mconn}
+			Expect(sess.enableKernelPacing()).To(BeFalse())
+			sess.conn = mconn
+			Expect(sess.enableKernelPacing()).To(BeFalse())
+		})
+
 		Context("packet pacing", func() {
 			var sph *mockackhandler.MockSentPacketHandler
 
@@ -816,8 +1465,8 @@ var _ = Describe("Session", func() {
 				sph.EXPECT().TimeUntilSend().Return(time.Now()).Times(2)
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
 				sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(2) // allow 2 packets...
-				packer.EXPECT().PackPacket().Return(getPacket(10), nil)
-				packer.EXPECT().PackPacket().Return(getPacket(11), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(10)), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(11)), nil)
 				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
@@ -844,7 +1493,7 @@ var _ = Describe("Session", func() {
 				sph.EXPECT().TimeUntilSend().Return(time.Now())
 				sph.EXPECT().SendMode().Return(ackhandler.SendAny)
 				sph.EXPECT().SendMode().Return(ackhandler.SendAck)
-				packer.EXPECT().PackPacket().Return(getPacket(100), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(100)), nil)
 				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
@@ -871,8 +1520,8 @@ var _ = Describe("Session", func() {
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
 				sph.EXPECT().ShouldSendNumPackets().Times(2).Return(1)
 				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
-				packer.EXPECT().PackPacket().Return(getPacket(100), nil)
-				packer.EXPECT().PackPacket().Return(getPacket(101), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(100)), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(101)), nil)
 				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
@@ -898,9 +1547,9 @@ var _ = Describe("Session", func() {
 				sph.EXPECT().TimeUntilSend().Return(time.Now())
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
 				sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(3)
-				packer.EXPECT().PackPacket().Return(getPacket(1000), nil)
-				packer.EXPECT().PackPacket().Return(getPacket(1001), nil)
-				packer.EXPECT().PackPacket().Return(getPacket(1002), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(1000)), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(1001)), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(1002)), nil)
 				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
@@ -918,11 +1567,65 @@ var _ = Describe("Session", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			Context("kernel pacing", func() {
+				BeforeEach(func() {
+					sess.kernelPacing = true
+				})
+
+				It("passes packets that are due within the pacing horizon to the kernel", func() {
+					conn := &mockTxTimeConnection{mockConnection: mconn, supported: true}
+					sess.conn = conn
+					var mutex sync.Mutex
+					var nextSendTime time.Time
+					sph.EXPECT().SentPacket(gomock.Any()).Do(func(*ackhandler.Packet) {
+						mutex.Lock()
+						defer mutex.Unlock()
+						if nextSendTime.IsZero() {
+							nextSendTime = time.Now()
+						}
+						nextSendTime = nextSendTime.Add(protocol.KernelPacingHorizon * 2 / 5)
+					}).Times(3)
+					sph.EXPECT().TimeUntilSend().DoAndReturn(func() time.Time {
+						mutex.Lock()
+						defer mutex.Unlock()
+						return nextSendTime
+					}).AnyTimes()
+					sph.EXPECT().ShouldSendNumPackets().Return(1).AnyTimes()
+					sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+					packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(10)), nil)
+					packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(11)), nil)
+					packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(12)), nil)
+					packer.EXPECT().PackCoalescedPacket().AnyTimes()
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+						sess.run()
+						close(done)
+					}()
+					sess.scheduleSending()
+					Eventually(mconn.written).Should(HaveLen(3))
+					Consistently(mconn.written).Should(HaveLen(3))
+					delays := conn.getDelays()
+					Expect(delays).To(HaveLen(3))
+					Expect(delays[0]).To(BeNumerically("<=", 0))
+					Expect(delays[1]).To(BeNumerically(">", 0))
+					Expect(delays[2]).To(BeNumerically(">", delays[1]))
+					Expect(delays[2]).To(BeNumerically("<", protocol.KernelPacingHorizon))
+					// make the go routine return
+					packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+					sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+					cryptoSetup.EXPECT().Close()
+					sess.Close()
+					Eventually(done).Should(BeClosed())
+				})
+			})
+
 			It("doesn't set a pacing timer when there is no data to send", func() {
 				sph.EXPECT().TimeUntilSend().Return(time.Now())
 				sph.EXPECT().ShouldSendNumPackets().Return(1)
 				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
-				packer.EXPECT().PackPacket()
+				packer.EXPECT().PackCoalescedPacket()
 				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
@@ -950,7 +1653,7 @@ var _ = Describe("Session", func() {
 				sph.EXPECT().ShouldSendNumPackets().AnyTimes().Return(1)
 				sph.EXPECT().SentPacket(gomock.Any())
 				sess.sentPacketHandler = sph
-				packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(1)), nil)
 
 				go func() {
 					defer GinkgoRecover()
@@ -970,7 +1673,7 @@ var _ = Describe("Session", func() {
 			})
 
 			It("sets the timer to the ack timer", func() {
-				packer.EXPECT().PackPacket().Return(getPacket(1234), nil)
+				packer.EXPECT().PackCoalescedPacket().Return(coalesce(getPacket(1234)), nil)
 				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 				sph.EXPECT().TimeUntilSend().Return(time.Now())
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
@@ -1020,7 +1723,7 @@ var _ = Describe("Session", func() {
 	})
 
 	It("calls the onHandshakeComplete callback when the handshake completes", func() {
-		packer.EXPECT().PackPacket().AnyTimes()
+		packer.EXPECT().PackCoalescedPacket().AnyTimes()
 		go func() {
 			defer GinkgoRecover()
 			sessionRunner.EXPECT().onHandshakeComplete(gomock.Any())
@@ -1041,14 +1744,14 @@ var _ = Describe("Session", func() {
 		done := make(chan struct{})
 		gomock.InOrder(
 			sessionRunner.EXPECT().onHandshakeComplete(gomock.Any()),
-			packer.EXPECT().PackPacket().DoAndReturn(func() (*packedPacket, error) {
+			packer.EXPECT().PackCoalescedPacket().DoAndReturn(func() (*coalescedPacket, error) {
 				defer close(done)
-				return &packedPacket{
-					header: &wire.ExtendedHeader{},
-					buffer: getPacketBuffer(),
+				return &coalescedPacket{
+					packets: []*packedPacket{{header: &wire.ExtendedHeader{}}},
+					buffer:  getPacketBuffer(),
 				}, nil
 			}),
-			packer.EXPECT().PackPacket().AnyTimes(),
+			packer.EXPECT().PackCoalescedPacket().AnyTimes(),
 		)
 		go func() {
 			defer GinkgoRecover()
@@ -1088,7 +1791,7 @@ var _ = Describe("Session", func() {
 			defer GinkgoRecover()
 			cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
 			err := sess.run()
-			Expect(err).To(MatchError(qerr.Error(0x1337, testErr.Error())))
+			Expect(err).To(MatchError(qerr.ApplicationError(0x1337, testErr.Error())))
 			close(done)
 		}()
 		streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1135,7 +1838,14 @@ var _ = Describe("Session", func() {
 			}
 			streamManager.EXPECT().UpdateLimits(params)
 			packer.EXPECT().HandleTransportParameters(params)
+			Expect(sess.PeerTransportParameters()).To(BeNil())
 			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.PeerTransportParameters()).To(Equal(&TransportParameters{
+				IdleTimeout:                   90 * time.Second,
+				InitialMaxStreamDataBidiLocal: 0x5000,
+				InitialMaxData:                0x5000,
+				MaxPacketSize:                 uint64(protocol.MaxReceivePacketSize),
+			}))
 			// make the go routine return
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
@@ -1145,6 +1855,138 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
+		It("enables FEC if the client supports it", func() {
+			sess.config.FECGroupSize = 4
+			chtp := &handshake.ClientHelloTransportParameters{
+				InitialVersion: sess.version,
+				Parameters:     handshake.TransportParameters{EnableFEC: true},
+			}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			packer.EXPECT().EnableFEC(4)
+			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.fecDecoder).ToNot(BeNil())
+		})
+
+		It("doesn't enable FEC if the client doesn't support it", func() {
+			sess.config.FECGroupSize = 4
+			chtp := &handshake.ClientHelloTransportParameters{InitialVersion: sess.version}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.fecDecoder).To(BeNil())
+		})
+
+		It("enables stream compression if the client uses the same dictionary", func() {
+			sess.config.StreamCompression = true
+			sess.config.StreamCompressionDictionary = []byte("foobar")
+			chtp := &handshake.ClientHelloTransportParameters{
+				InitialVersion: sess.version,
+				Parameters: handshake.TransportParameters{
+					EnableStreamCompression:       true,
+					StreamCompressionDictionaryID: streamCompressionDictionaryID([]byte("foobar")),
+				},
+			}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.getStreamCompressor()).ToNot(BeNil())
+			str := NewMockStreamI(mockCtrl)
+			str.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+			streamManager.EXPECT().OpenStream().Return(str, nil)
+			s, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(BeAssignableToTypeOf(&compressedStream{}))
+		})
+
+		It("wraps every stream only once", func() {
+			sess.config.StreamCompression = true
+			chtp := &handshake.ClientHelloTransportParameters{
+				InitialVersion: sess.version,
+				Parameters:     handshake.TransportParameters{EnableStreamCompression: true},
+			}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(chtp.Marshal())
+			str := NewMockStreamI(mockCtrl)
+			str.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
+			streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(5)).Return(str, nil)
+			s1, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s1).To(BeAssignableToTypeOf(&compressedStream{}))
+			streamManager.EXPECT().AcceptStream().Return(str, nil)
+			s2, err := sess.AcceptStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s2).To(BeIdenticalTo(s1))
+			// the wrapper is removed when the stream is completed
+			streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(5)).Return(str, nil)
+			str.EXPECT().allDataAcked().Return(true)
+			streamManager.EXPECT().DeleteStream(protocol.StreamID(5))
+			sess.onStreamCompleted(5)
+			Expect(sess.compressedStreams).To(BeEmpty())
+		})
+
+		It("doesn't enable stream compression if the client uses a different dictionary", func() {
+			sess.config.StreamCompression = true
+			sess.config.StreamCompressionDictionary = []byte("foobar")
+			chtp := &handshake.ClientHelloTransportParameters{
+				InitialVersion: sess.version,
+				Parameters: handshake.TransportParameters{
+					EnableStreamCompression:       true,
+					StreamCompressionDictionaryID: streamCompressionDictionaryID([]byte("raboof")),
+				},
+			}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.getStreamCompressor()).To(BeNil())
+		})
+
+		It("doesn't enable stream compression if the client doesn't support it", func() {
+			sess.config.StreamCompression = true
+			chtp := &handshake.ClientHelloTransportParameters{InitialVersion: sess.version}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.getStreamCompressor()).To(BeNil())
+			str := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStream().Return(str, nil)
+			Expect(sess.OpenStream()).To(Equal(str))
+		})
+
+		It("uses the peer's idle timeout, if it is shorter", func() {
+			sess.config.IdleTimeout = time.Minute
+			chtp := &handshake.ClientHelloTransportParameters{
+				InitialVersion: sess.version,
+				Parameters:     handshake.TransportParameters{IdleTimeout: 10 * time.Second},
+			}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			cryptoSetup.EXPECT().ConnectionState().Times(2)
+			Expect(sess.ConnectionState().IdleTimeout).To(BeZero())
+			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.idleTimeout).To(Equal(10 * time.Second))
+			Expect(sess.ConnectionState().IdleTimeout).To(Equal(10 * time.Second))
+		})
+
+		It("uses the local idle timeout, if the peer's idle timeout is longer", func() {
+			sess.config.IdleTimeout = time.Minute
+			chtp := &handshake.ClientHelloTransportParameters{
+				InitialVersion: sess.version,
+				Parameters:     handshake.TransportParameters{IdleTimeout: time.Hour},
+			}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(chtp.Marshal())
+			Expect(sess.idleTimeout).To(Equal(time.Minute))
+			cryptoSetup.EXPECT().ConnectionState()
+			Expect(sess.ConnectionState().IdleTimeout).To(Equal(time.Minute))
+		})
+
+		It("uses the local idle timeout, if the peer didn't send an idle timeout", func() {
+			Expect(negotiateIdleTimeout(time.Minute, 0)).To(Equal(time.Minute))
+		})
+
 		It("accepts a valid version negotiation", func() {
 			sess.version = 42
 			sess.config.Versions = []protocol.VersionNumber{13, 37, 42}
@@ -1164,6 +2006,20 @@ var _ = Describe("Session", func() {
 			_, err := sess.processTransportParametersForServer(chtp.Marshal())
 			Expect(err).To(MatchError("VersionNegotiationMismatch: Client should have used the initial version"))
 		})
+
+		It("accepts a version negotiation, if the initial version was rejected by the AcceptVersion callback", func() {
+			sess.version = 42
+			sess.config.Versions = []protocol.VersionNumber{13, 37, 42}
+			sess.config.AcceptVersion = func(addr net.Addr, v protocol.VersionNumber) bool {
+				Expect(addr).To(Equal(mconn.RemoteAddr()))
+				return v != 13
+			}
+			chtp := &handshake.ClientHelloTransportParameters{
+				InitialVersion: 13, // this version is rejected for this client
+			}
+			_, err := sess.processTransportParametersForServer(chtp.Marshal())
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -1173,6 +2029,7 @@ var _ = Describe("Session", func() {
 
 		BeforeEach(func() {
 			sess.peerParams = &handshake.TransportParameters{IdleTimeout: remoteIdleTimeout}
+			sess.idleTimeout = remoteIdleTimeout
 		})
 
 		It("sends a PING", func() {
@@ -1180,7 +2037,7 @@ var _ = Describe("Session", func() {
 			sess.config.KeepAlive = true
 			sess.lastNetworkActivityTime = time.Now().Add(-remoteIdleTimeout / 2)
 			sent := make(chan struct{})
-			packer.EXPECT().PackPacket().Do(func() (*packedPacket, error) {
+			packer.EXPECT().PackCoalescedPacket().Do(func() (*coalescedPacket, error) {
 				close(sent)
 				return nil, nil
 			})
@@ -1244,6 +2101,59 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("cover traffic", func() {
+		BeforeEach(func() {
+			sess.config.CoverTrafficInterval = 10 * time.Millisecond
+			packer.EXPECT().PackCoalescedPacket().AnyTimes()
+		})
+
+		runSession := func() <-chan struct{} {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+				sess.run()
+				close(done)
+			}()
+			return done
+		}
+
+		closeSession := func(done <-chan struct{}) {
+			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			cryptoSetup.EXPECT().Close()
+			sess.Close()
+			Eventually(done).Should(BeClosed())
+		}
+
+		It("sends cover packets when no other packets are sent", func() {
+			sess.handshakeComplete = true
+			var pn protocol.PacketNumber
+			packer.EXPECT().PackCoverPacket().DoAndReturn(func() (*packedPacket, error) {
+				pn++
+				buffer := getPacketBuffer()
+				return &packedPacket{
+					raw:    append(buffer.Slice[:0], []byte("cover")...),
+					buffer: buffer,
+					header: &wire.ExtendedHeader{PacketNumber: pn},
+					frames: []wire.Frame{&wire.PaddingFrame{}},
+				}, nil
+			}).MinTimes(2)
+			done := runSession()
+			Eventually(mconn.written).Should(Receive(Equal([]byte("cover"))))
+			Eventually(mconn.written).Should(Receive(Equal([]byte("cover"))))
+			closeSession(done)
+		})
+
+		It("doesn't send cover packets before the handshake completes", func() {
+			sess.handshakeComplete = false
+			done := runSession()
+			Consistently(mconn.written).ShouldNot(Receive())
+			closeSession(done)
+		})
+	})
+
 	Context("timeouts", func() {
 		BeforeEach(func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1269,6 +2179,28 @@ var _ = Describe("Session", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("times out due to the peer's idle timeout", func() {
+			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+			sess.handshakeComplete = true
+			sess.config.IdleTimeout = time.Hour
+			sess.idleTimeout = time.Minute
+			sess.lastNetworkActivityTime = time.Now().Add(-2 * time.Minute)
+			done := make(chan struct{})
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+				Expect(f.ErrorCode).To(Equal(qerr.NetworkIdleTimeout))
+				return &packedPacket{}, nil
+			})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+				err := sess.run()
+				Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.NetworkIdleTimeout))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("times out due to non-completed handshake", func() {
 			sess.sessionCreationTime = time.Now().Add(-protocol.DefaultHandshakeTimeout).Add(-time.Second)
 			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
@@ -1311,7 +2243,7 @@ var _ = Describe("Session", func() {
 		})
 
 		It("closes the session due to the idle timeout after handshake", func() {
-			packer.EXPECT().PackPacket().AnyTimes()
+			packer.EXPECT().PackCoalescedPacket().AnyTimes()
 			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
 			cryptoSetup.EXPECT().Close()
 			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
@@ -1319,6 +2251,7 @@ var _ = Describe("Session", func() {
 				return &packedPacket{}, nil
 			})
 			sess.config.IdleTimeout = 0
+			sess.idleTimeout = 0
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
@@ -1412,6 +2345,21 @@ var _ = Describe("Session", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
+
+		It("passes accepted streams to the stream handler", func() {
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().AcceptStream().Return(mstr, nil)
+			streamManager.EXPECT().AcceptStream().Return(nil, errors.New("session closed")).AnyTimes()
+			handled := make(chan Stream, 1)
+			Expect(sess.OnStream(func(str Stream) { handled <- str })).To(Succeed())
+			Eventually(handled).Should(Receive(Equal(mstr)))
+		})
+
+		It("only allows registering a single stream handler", func() {
+			streamManager.EXPECT().AcceptStream().Return(nil, errors.New("session closed")).AnyTimes()
+			Expect(sess.OnStream(func(Stream) {})).To(Succeed())
+			Expect(sess.OnStream(func(Stream) {})).To(MatchError(errStreamHandlerRegistered))
+		})
 	})
 
 	It("returns the local address", func() {
@@ -1551,6 +2499,40 @@ var _ = Describe("Client Session", func() {
 		})
 	})
 
+	Context("handshake errors", func() {
+		It("returns a HandshakeError, if the handshake fails", func() {
+			certErr := x509.UnknownAuthorityError{}
+			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			cryptoSetup.EXPECT().RunHandshake().Return(certErr)
+			cryptoSetup.EXPECT().Close()
+			cryptoSetup.EXPECT().HandshakeState().Return(handshake.HandshakeStateReceivedCertificate)
+			err := sess.run()
+			Expect(err).To(BeAssignableToTypeOf(&HandshakeError{}))
+			hErr := err.(*HandshakeError)
+			Expect(hErr.Err).To(Equal(certErr))
+			Expect(hErr.State).To(Equal(HandshakeStateReceivedCertificate))
+			Expect(hErr.CertificateError).To(Equal(certErr))
+		})
+
+		It("returns a HandshakeError, if the handshake times out", func() {
+			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			cryptoSetup.EXPECT().RunHandshake().Return(qerr.Error(qerr.HandshakeTimeout, "Crypto handshake did not complete in time."))
+			cryptoSetup.EXPECT().Close()
+			cryptoSetup.EXPECT().HandshakeState().Return(handshake.HandshakeStateStarted)
+			err := sess.run()
+			Expect(err).To(BeAssignableToTypeOf(&HandshakeError{}))
+			hErr := err.(*HandshakeError)
+			Expect(hErr.Timeout()).To(BeTrue())
+			Expect(hErr.State).To(Equal(HandshakeStateStarted))
+			Expect(hErr.CertificateError).ToNot(HaveOccurred())
+			code, ok := AsTransportError(err)
+			Expect(ok).To(BeTrue())
+			Expect(code).To(Equal(qerr.HandshakeTimeout))
+		})
+	})
+
 	Context("transport parameters", func() {
 		It("errors if it can't unmarshal the TransportParameters", func() {
 			go func() {
@@ -1564,6 +2546,7 @@ var _ = Describe("Client Session", func() {
 			sessionRunner.EXPECT().retireConnectionID(gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
 			cryptoSetup.EXPECT().Close()
+			cryptoSetup.EXPECT().HandshakeState().Return(handshake.HandshakeStateReceivedHello)
 			sess.processTransportParameters([]byte("invalid"))
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -1627,6 +2610,43 @@ var _ = Describe("Client Session", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 
+			It("exposes the outcome of the version negotiation", func() {
+				sess.initialVersion = 13
+				sess.version = 37
+				sess.config.Versions = []protocol.VersionNumber{13, 37, 42}
+				eetp := &handshake.EncryptedExtensionsTransportParameters{
+					NegotiatedVersion: 37,
+					SupportedVersions: []protocol.VersionNumber{36, 37, 38},
+					Parameters:        params,
+				}
+				_, err := sess.processTransportParametersForClient(eetp.Marshal())
+				Expect(err).ToNot(HaveOccurred())
+				cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{HandshakeComplete: true})
+				state := sess.ConnectionState()
+				Expect(state.HandshakeComplete).To(BeTrue())
+				Expect(state.Version).To(Equal(protocol.VersionNumber(37)))
+				Expect(state.UsedVersionNegotiation).To(BeTrue())
+				Expect(state.ServerSupportedVersions).To(Equal([]protocol.VersionNumber{36, 37, 38}))
+			})
+
+			It("exposes the server's supported versions, if no version negotiation was performed", func() {
+				sess.initialVersion = 0
+				sess.version = 37
+				sess.config.Versions = []protocol.VersionNumber{37, 42}
+				eetp := &handshake.EncryptedExtensionsTransportParameters{
+					NegotiatedVersion: 37,
+					SupportedVersions: []protocol.VersionNumber{37, 38},
+					Parameters:        params,
+				}
+				_, err := sess.processTransportParametersForClient(eetp.Marshal())
+				Expect(err).ToNot(HaveOccurred())
+				cryptoSetup.EXPECT().ConnectionState()
+				state := sess.ConnectionState()
+				Expect(state.Version).To(Equal(protocol.VersionNumber(37)))
+				Expect(state.UsedVersionNegotiation).To(BeFalse())
+				Expect(state.ServerSupportedVersions).To(Equal([]protocol.VersionNumber{37, 38}))
+			})
+
 			It("errors if the current version doesn't match negotiated_version", func() {
 				sess.initialVersion = 13
 				sess.version = 37
@@ -52,7 +52,10 @@ type streamI interface {
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool)
+	getGroup() string
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	handleAckedStreamFrame(*wire.StreamFrame)
+	allDataAcked() bool
 }
 
 var _ receiveStreamI = (streamI)(nil)
@@ -97,6 +100,7 @@ var _ StreamError = &streamCanceledError{}
 func newStream(streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	sessionDeadlines *sessionDeadlines,
 	version protocol.VersionNumber,
 ) *stream {
 	s := &stream{sender: sender, version: version}
@@ -109,7 +113,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, version)
+	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, sessionDeadlines, version)
 	senderForReceiveStream := &uniStreamSender{
 		streamSender: sender,
 		onStreamCompletedImpl: func() {
@@ -119,7 +123,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController, version)
+	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController, sessionDeadlines, version)
 	return s
 }
 
@@ -0,0 +1,325 @@
+package quic
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	// Since every Write is flushed, the data is compressed in small pieces.
+	// At lower levels, the compress/flate package doesn't find matches outside of the current piece
+	// (including matches in the dictionary), so small writes wouldn't be compressed at all.
+	streamCompressionLevel = flate.BestCompression
+	// the size of the buffer used by ReadChunk on a compressed stream
+	compressedReadChunkSize = 16 * 1024
+)
+
+// Every direction of a stream starts with one of these bytes.
+const (
+	streamCompressionNone    byte = 0
+	streamCompressionDeflate byte = 1
+)
+
+var errStreamCompressionAlreadySelected = errors.New("quic: data was already written to the stream")
+
+// streamCompressionDictionaryID identifies a dictionary in the transport parameters.
+// Like zlib, it uses the Adler-32 checksum of the dictionary.
+func streamCompressionDictionaryID(dict []byte) uint32 {
+	if len(dict) == 0 {
+		return 0
+	}
+	return adler32.Checksum(dict)
+}
+
+// The streamCompressor implements the experimental stream compression extension.
+// If both peers enable it, every stream starts with a byte that says if the data sent on it is compressed using DEFLATE.
+// This is decided separately for each direction of every stream (see CompressibleStream).
+// The STREAM frames themselves are unchanged, so flow control applies to the compressed data.
+type streamCompressor struct {
+	dictionary []byte
+	// allocating a flate.Writer is expensive, so they're reused between streams
+	writerPool sync.Pool
+}
+
+func newStreamCompressor(dict []byte) *streamCompressor {
+	c := &streamCompressor{dictionary: dict}
+	c.writerPool.New = func() interface{} {
+		// NewWriterDict only returns an error for invalid compression levels
+		w, _ := flate.NewWriterDict(ioutil.Discard, streamCompressionLevel, dict)
+		return w
+	}
+	return c
+}
+
+// WrapStream returns a stream that compresses the data written to str, and decompresses the data read from it.
+// If stream compression wasn't negotiated, c is nil, and str is returned unchanged.
+func (c *streamCompressor) WrapStream(str Stream) Stream {
+	if c == nil {
+		return str
+	}
+	return &compressedStream{
+		Stream: str,
+		w:      newCompressingWriter(c, str),
+		r:      &decompressingReader{compressor: c, str: str},
+	}
+}
+
+// WrapSendStream is like WrapStream, for unidirectional streams opened by us.
+func (c *streamCompressor) WrapSendStream(str SendStream) SendStream {
+	if c == nil {
+		return str
+	}
+	return &compressedSendStream{
+		SendStream: str,
+		w:          newCompressingWriter(c, str),
+	}
+}
+
+// WrapReceiveStream is like WrapStream, for unidirectional streams opened by the peer.
+func (c *streamCompressor) WrapReceiveStream(str ReceiveStream) ReceiveStream {
+	if c == nil {
+		return str
+	}
+	return &compressedReceiveStream{
+		ReceiveStream: str,
+		r:             &decompressingReader{compressor: c, str: str},
+	}
+}
+
+func (c *streamCompressor) getWriter(w io.Writer) *flate.Writer {
+	fw := c.writerPool.Get().(*flate.Writer)
+	fw.Reset(w)
+	return fw
+}
+
+func (c *streamCompressor) putWriter(fw *flate.Writer) {
+	fw.Reset(ioutil.Discard)
+	c.writerPool.Put(fw)
+}
+
+// The compressingWriter uses a single flate.Writer for the whole stream.
+// The compressed data of a Write refers to the data of previous Writes (and to the dictionary),
+// so the writer must not be reset between Writes.
+// The writer is returned to the pool when the stream is closed or canceled.
+type compressingWriter struct {
+	compressor *streamCompressor
+	str        io.WriteCloser
+
+	mutex      sync.Mutex
+	compress   bool
+	headerSent bool
+	fw         *flate.Writer // created on the first compressed Write
+	closed     bool
+	buf        bytes.Buffer
+}
+
+func newCompressingWriter(c *streamCompressor, str io.WriteCloser) *compressingWriter {
+	return &compressingWriter{compressor: c, str: str, compress: true}
+}
+
+func (w *compressingWriter) SetCompression(enabled bool) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.headerSent {
+		return errStreamCompressionAlreadySelected
+	}
+	w.compress = enabled
+	return nil
+}
+
+// must be called with the mutex held
+func (w *compressingWriter) writeHeader() {
+	if w.headerSent {
+		return
+	}
+	w.headerSent = true
+	if w.compress {
+		w.buf.WriteByte(streamCompressionDeflate)
+	} else {
+		w.buf.WriteByte(streamCompressionNone)
+	}
+}
+
+// must be called with the mutex held
+func (w *compressingWriter) flush() error {
+	_, err := w.str.Write(w.buf.Bytes())
+	// don't keep large buffers around
+	if w.buf.Cap() > compressedReadChunkSize {
+		w.buf = bytes.Buffer{}
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressingWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.writeHeader()
+	if !w.compress {
+		if w.buf.Len() > 0 {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+		return w.str.Write(p)
+	}
+	if w.closed {
+		// let the stream return the error
+		return w.str.Write(p)
+	}
+	if w.fw == nil {
+		w.fw = w.compressor.getWriter(&w.buf)
+	}
+	if _, err := w.fw.Write(p); err != nil {
+		return 0, err
+	}
+	// Flush, so that the peer can decompress everything that was written so far.
+	if err := w.fw.Flush(); err != nil {
+		return 0, err
+	}
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *compressingWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.writeHeader()
+	if w.compress && !w.closed {
+		// The peer expects a final DEFLATE block, even if nothing was written.
+		if w.fw == nil {
+			w.fw = w.compressor.getWriter(&w.buf)
+		}
+		err := w.fw.Close()
+		w.releaseWriter()
+		if err != nil {
+			return err
+		}
+	}
+	if w.buf.Len() > 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	return w.str.Close()
+}
+
+// cancel returns the flate.Writer to the pool, since no more data will be sent.
+// It must be called after canceling the stream, since it waits for a pending Write to return.
+func (w *compressingWriter) cancel() {
+	w.mutex.Lock()
+	w.releaseWriter()
+	w.mutex.Unlock()
+}
+
+// must be called with the mutex held
+func (w *compressingWriter) releaseWriter() {
+	w.closed = true
+	if w.fw != nil {
+		w.compressor.putWriter(w.fw)
+		w.fw = nil
+	}
+}
+
+type decompressingReader struct {
+	compressor *streamCompressor
+	str        io.Reader
+
+	r     io.Reader // set when the header was read
+	chunk []byte    // used by ReadChunk
+}
+
+func (r *decompressingReader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		var header [1]byte
+		if _, err := io.ReadFull(r.str, header[:]); err != nil {
+			return 0, err
+		}
+		switch header[0] {
+		case streamCompressionNone:
+			r.r = r.str
+		case streamCompressionDeflate:
+			r.r = flate.NewReaderDict(r.str, r.compressor.dictionary)
+		default:
+			return 0, fmt.Errorf("unknown stream compression: %#x", header[0])
+		}
+	}
+	return r.r.Read(p)
+}
+
+// ReadChunk decompresses into a buffer that is reused between calls.
+// Since the application may retain the returned slice, it returns a copy of the data that was read.
+func (r *decompressingReader) ReadChunk() ([]byte, error) {
+	if r.chunk == nil {
+		r.chunk = make([]byte, compressedReadChunkSize)
+	}
+	n, err := r.Read(r.chunk)
+	if n == 0 {
+		return nil, err
+	}
+	return append([]byte(nil), r.chunk[:n]...), err
+}
+
+type compressedStream struct {
+	Stream
+
+	w *compressingWriter
+	r *decompressingReader
+}
+
+var _ Stream = &compressedStream{}
+var _ CompressibleStream = &compressedStream{}
+
+func (s *compressedStream) Read(p []byte) (int, error)        { return s.r.Read(p) }
+func (s *compressedStream) ReadChunk() ([]byte, error)        { return s.r.ReadChunk() }
+func (s *compressedStream) Write(p []byte) (int, error)       { return s.w.Write(p) }
+func (s *compressedStream) Close() error                      { return s.w.Close() }
+func (s *compressedStream) SetCompression(enabled bool) error { return s.w.SetCompression(enabled) }
+
+func (s *compressedStream) CancelWrite(code ErrorCode) {
+	s.Stream.CancelWrite(code)
+	s.w.cancel()
+}
+
+type compressedSendStream struct {
+	SendStream
+
+	w *compressingWriter
+}
+
+var _ SendStream = &compressedSendStream{}
+var _ CompressibleStream = &compressedSendStream{}
+
+func (s *compressedSendStream) Write(p []byte) (int, error)       { return s.w.Write(p) }
+func (s *compressedSendStream) Close() error                      { return s.w.Close() }
+func (s *compressedSendStream) SetCompression(enabled bool) error { return s.w.SetCompression(enabled) }
+
+func (s *compressedSendStream) CancelWrite(code ErrorCode) {
+	s.SendStream.CancelWrite(code)
+	s.w.cancel()
+}
+
+type compressedReceiveStream struct {
+	ReceiveStream
+
+	r *decompressingReader
+}
+
+var _ ReceiveStream = &compressedReceiveStream{}
+
+func (s *compressedReceiveStream) Read(p []byte) (int, error) { return s.r.Read(p) }
+func (s *compressedReceiveStream) ReadChunk() ([]byte, error) { return s.r.ReadChunk() }
@@ -0,0 +1,309 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type closeRecordingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeRecordingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+var _ = Describe("Stream Compression", func() {
+	message := []byte(`{"type":"status","service":"foobar","healthy":true,"load":0.42}` + "\n")
+
+	compress := func(c *streamCompressor, messages ...[]byte) *closeRecordingBuffer {
+		buf := &closeRecordingBuffer{}
+		w := newCompressingWriter(c, buf)
+		for _, m := range messages {
+			n, err := w.Write(m)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(len(m)))
+		}
+		Expect(w.Close()).To(Succeed())
+		Expect(buf.closed).To(BeTrue())
+		return buf
+	}
+
+	decompress := func(c *streamCompressor, data []byte) ([]byte, error) {
+		return ioutil.ReadAll(&decompressingReader{compressor: c, str: bytes.NewReader(data)})
+	}
+
+	It("uses the Adler-32 checksum to identify the dictionary", func() {
+		Expect(streamCompressionDictionaryID(nil)).To(BeZero())
+		Expect(streamCompressionDictionaryID([]byte("foobar"))).To(Equal(uint32(0x8ab027a)))
+	})
+
+	It("compresses and decompresses", func() {
+		c := newStreamCompressor(nil)
+		buf := compress(c, bytes.Repeat(message, 100))
+		Expect(buf.Len()).To(BeNumerically("<", 10*len(message)))
+		data, err := decompress(c, buf.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(bytes.Repeat(message, 100)))
+	})
+
+	It("compresses small writes, using the dictionary", func() {
+		c := newStreamCompressor(message)
+		buf := &closeRecordingBuffer{}
+		w := newCompressingWriter(c, buf)
+		_, err := w.Write(message)
+		Expect(err).ToNot(HaveOccurred())
+		l := buf.Len()
+		for i := 0; i < 10; i++ {
+			_, err := w.Write(message)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(buf.Len() - l).To(BeNumerically("<", 2*len(message)))
+		Expect(w.Close()).To(Succeed())
+		data, err := decompress(c, buf.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(bytes.Repeat(message, 11)))
+	})
+
+	It("decompresses multiple writes, using the dictionary", func() {
+		c := newStreamCompressor(message)
+		var messages [][]byte
+		for i := 0; i < 20; i++ {
+			messages = append(messages, []byte(fmt.Sprintf(`{"type":"status","service":"foobar","healthy":%t,"load":0.%d}`+"\n", i%3 == 0, i)))
+		}
+		buf := compress(c, messages...)
+		data, err := decompress(c, buf.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(bytes.Join(messages, nil)))
+	})
+
+	It("flushes every Write", func() {
+		c := newStreamCompressor(nil)
+		buf := &closeRecordingBuffer{}
+		w := newCompressingWriter(c, buf)
+		_, err := w.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		r := &decompressingReader{compressor: c, str: bytes.NewReader(buf.Bytes())}
+		b := make([]byte, 6)
+		_, err = io.ReadFull(r, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("foobar")))
+	})
+
+	It("uses the dictionary", func() {
+		dict := bytes.Repeat(message, 3)
+		withoutDict := compress(newStreamCompressor(nil), message)
+		c := newStreamCompressor(dict)
+		withDict := compress(c, message)
+		Expect(withDict.Len()).To(BeNumerically("<", withoutDict.Len()/2))
+		data, err := decompress(c, withDict.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(message))
+	})
+
+	It("reuses writers", func() {
+		c := newStreamCompressor([]byte("foobar"))
+		for i := 0; i < 3; i++ {
+			buf := compress(c, []byte("foobar"), []byte("raboof"))
+			data, err := decompress(c, buf.Bytes())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobarraboof")))
+		}
+	})
+
+	It("returns the writer to the pool when canceled", func() {
+		c := newStreamCompressor(nil)
+		w := newCompressingWriter(c, &closeRecordingBuffer{})
+		_, err := w.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.fw).ToNot(BeNil())
+		w.cancel()
+		Expect(w.fw).To(BeNil())
+	})
+
+	It("sends an empty stream", func() {
+		c := newStreamCompressor(nil)
+		buf := compress(c)
+		Expect(buf.Len()).ToNot(BeZero())
+		data, err := decompress(c, buf.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(BeEmpty())
+	})
+
+	It("errors if the stream ends before the final block", func() {
+		c := newStreamCompressor(nil)
+		buf := &closeRecordingBuffer{}
+		w := newCompressingWriter(c, buf)
+		_, err := w.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = decompress(c, buf.Bytes())
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+
+	It("reads chunks", func() {
+		c := newStreamCompressor(nil)
+		buf := compress(c, []byte("foobar"))
+		r := &decompressingReader{compressor: c, str: bytes.NewReader(buf.Bytes())}
+		var data []byte
+		for {
+			chunk, err := r.ReadChunk()
+			data = append(data, chunk...)
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+
+	It("returns chunks that are not overwritten by the next call", func() {
+		var input []byte
+		for i := 0; len(input) < 3*compressedReadChunkSize; i++ {
+			input = append(input, []byte(fmt.Sprintf("message %d\n", i))...)
+		}
+		c := newStreamCompressor(nil)
+		buf := compress(c, input)
+		r := &decompressingReader{compressor: c, str: bytes.NewReader(buf.Bytes())}
+		var chunks [][]byte
+		for {
+			chunk, err := r.ReadChunk()
+			chunks = append(chunks, chunk)
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(len(chunks)).To(BeNumerically(">", 2))
+		Expect(bytes.Join(chunks, nil)).To(Equal(input))
+	})
+
+	It("starts the stream with a header byte", func() {
+		c := newStreamCompressor(nil)
+		Expect(compress(c).Bytes()[0]).To(Equal(streamCompressionDeflate))
+	})
+
+	It("sends uncompressed data, if compression is disabled for a stream", func() {
+		c := newStreamCompressor(nil)
+		buf := &closeRecordingBuffer{}
+		w := newCompressingWriter(c, buf)
+		Expect(w.SetCompression(false)).To(Succeed())
+		_, err := w.Write([]byte("foo"))
+		Expect(err).ToNot(HaveOccurred())
+		n, err := w.Write([]byte("bar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		Expect(w.Close()).To(Succeed())
+		Expect(buf.Bytes()).To(Equal(append([]byte{streamCompressionNone}, []byte("foobar")...)))
+		data, err := decompress(c, buf.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+
+	It("doesn't allow changing the compression after data was written", func() {
+		c := newStreamCompressor(nil)
+		w := newCompressingWriter(c, &closeRecordingBuffer{})
+		_, err := w.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.SetCompression(false)).To(MatchError(errStreamCompressionAlreadySelected))
+	})
+
+	It("errors on unknown header bytes", func() {
+		_, err := decompress(newStreamCompressor(nil), []byte{0x42, 'f', 'o', 'o'})
+		Expect(err).To(MatchError("unknown stream compression: 0x42"))
+	})
+
+	It("returns EOF if the stream ends before the header byte", func() {
+		r := &decompressingReader{compressor: newStreamCompressor(nil), str: &bytes.Buffer{}}
+		_, err := r.Read(make([]byte, 10))
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("doesn't wrap streams if compression wasn't negotiated", func() {
+		var c *streamCompressor
+		str := NewMockStreamI(mockCtrl)
+		Expect(c.WrapStream(str)).To(Equal(str))
+		Expect(c.WrapSendStream(str)).To(Equal(str))
+		Expect(c.WrapReceiveStream(str)).To(Equal(str))
+	})
+
+	Context("using a connection", func() {
+		echo := func(serverConf, clientConf *Config, setup ...func(Stream)) (Session, []byte) {
+			ln, err := ListenAddr("localhost:0", testdata.GetTLSConfig(), serverConf)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			go func() {
+				defer GinkgoRecover()
+				sess, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				str, err := sess.AcceptStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = io.Copy(str, str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}()
+			sess, err := DialAddr(ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}, clientConf)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStreamSync()
+			Expect(err).ToNot(HaveOccurred())
+			for _, f := range setup {
+				f(str)
+			}
+			_, err = str.Write([]byte(strings.Repeat("foobar", 1000)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			return sess, data
+		}
+
+		It("compresses streams if both peers enable it", func() {
+			sess, data := echo(
+				&Config{StreamCompression: true, StreamCompressionDictionary: []byte("foobar")},
+				&Config{StreamCompression: true, StreamCompressionDictionary: []byte("foobar")},
+			)
+			defer sess.Close()
+			Expect(data).To(Equal([]byte(strings.Repeat("foobar", 1000))))
+			Expect(sess.ConnectionState().StreamCompression).To(BeTrue())
+		})
+
+		It("only compresses one direction of a stream, if the other one disables compression", func() {
+			conf := &Config{StreamCompression: true}
+			sess, data := echo(conf, conf, func(str Stream) {
+				Expect(str).To(BeAssignableToTypeOf(&compressedStream{}))
+				Expect(str.(CompressibleStream).SetCompression(false)).To(Succeed())
+			})
+			defer sess.Close()
+			Expect(data).To(Equal([]byte(strings.Repeat("foobar", 1000))))
+		})
+
+		It("doesn't compress streams if only one peer enables it", func() {
+			sess, data := echo(&Config{StreamCompression: true}, nil)
+			defer sess.Close()
+			Expect(data).To(Equal([]byte(strings.Repeat("foobar", 1000))))
+			Expect(sess.ConnectionState().StreamCompression).To(BeFalse())
+		})
+
+		It("doesn't compress streams if the peers use different dictionaries", func() {
+			sess, data := echo(
+				&Config{StreamCompression: true, StreamCompressionDictionary: []byte("foobar")},
+				&Config{StreamCompression: true},
+			)
+			defer sess.Close()
+			Expect(data).To(Equal([]byte(strings.Repeat("foobar", 1000))))
+			Expect(sess.ConnectionState().StreamCompression).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,62 @@
+package quic
+
+// The incomingStreamHandler accepts incoming streams and passes them to a handler function (see Session.OnStream).
+type incomingStreamHandler struct {
+	acceptStream func() (Stream, error)
+	handler      func(Stream)
+	// onPanic is called when the handler panics
+	onPanic func(*panicError)
+	// closed is closed when the session is closed
+	closed <-chan struct{}
+
+	// used as a semaphore, limiting the number of concurrently running handlers
+	running chan struct{}
+}
+
+func newIncomingStreamHandler(
+	acceptStream func() (Stream, error),
+	handler func(Stream),
+	onPanic func(*panicError),
+	maxConcurrent int,
+	closed <-chan struct{},
+) *incomingStreamHandler {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &incomingStreamHandler{
+		acceptStream: acceptStream,
+		handler:      handler,
+		onPanic:      onPanic,
+		closed:       closed,
+		running:      make(chan struct{}, maxConcurrent),
+	}
+}
+
+// run accepts streams until the session is closed.
+// Handlers that are still running when it returns are not interrupted.
+func (h *incomingStreamHandler) run() {
+	for {
+		// Only accept a stream when there's capacity to handle it.
+		// Streams that are not accepted yet still count towards the peer's stream limit,
+		// so the peer is prevented from opening an arbitrary number of streams.
+		select {
+		case h.running <- struct{}{}:
+		case <-h.closed:
+			return
+		}
+		str, err := h.acceptStream()
+		if err != nil {
+			<-h.running
+			return
+		}
+		go func() {
+			defer func() { <-h.running }()
+			defer func() {
+				if e := recover(); e != nil {
+					h.onPanic(newPanicError(e))
+				}
+			}()
+			h.handler(str)
+		}()
+	}
+}
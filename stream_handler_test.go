@@ -0,0 +1,117 @@
+package quic
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Incoming Stream Handler", func() {
+	var (
+		streamChan chan Stream
+		closed     chan struct{}
+	)
+
+	acceptStream := func() (Stream, error) {
+		select {
+		case str := <-streamChan:
+			return str, nil
+		case <-closed:
+			return nil, errors.New("session closed")
+		}
+	}
+
+	BeforeEach(func() {
+		streamChan = make(chan Stream)
+		closed = make(chan struct{})
+	})
+
+	It("calls the handler for every stream", func() {
+		handled := make(chan Stream, 2)
+		h := newIncomingStreamHandler(acceptStream, func(str Stream) { handled <- str }, nil, 10, closed)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			h.run()
+			close(done)
+		}()
+		str1 := NewMockStreamI(mockCtrl)
+		str2 := NewMockStreamI(mockCtrl)
+		streamChan <- str1
+		streamChan <- str2
+		Eventually(handled).Should(Receive(Equal(str1)))
+		Eventually(handled).Should(Receive(Equal(str2)))
+		Consistently(done).ShouldNot(BeClosed())
+		close(closed)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("limits the number of concurrently running handlers", func() {
+		unblock := make(chan struct{})
+		handled := make(chan Stream, 3)
+		h := newIncomingStreamHandler(acceptStream, func(str Stream) {
+			handled <- str
+			<-unblock
+		}, nil, 2, closed)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			h.run()
+			close(done)
+		}()
+		strs := []Stream{NewMockStreamI(mockCtrl), NewMockStreamI(mockCtrl), NewMockStreamI(mockCtrl)}
+		streamChan <- strs[0]
+		streamChan <- strs[1]
+		Eventually(handled).Should(HaveLen(2))
+		// the third stream is not accepted while two handlers are running
+		Consistently(streamChan).ShouldNot(BeSent(strs[2]))
+		unblock <- struct{}{}
+		Eventually(streamChan).Should(BeSent(strs[2]))
+		Eventually(handled).Should(HaveLen(3))
+		close(unblock)
+		close(closed)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("doesn't wait for running handlers when the session is closed", func() {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		handlerStarted := make(chan struct{})
+		h := newIncomingStreamHandler(acceptStream, func(Stream) {
+			close(handlerStarted)
+			<-unblock
+		}, nil, 1, closed)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			h.run()
+			close(done)
+		}()
+		streamChan <- NewMockStreamI(mockCtrl)
+		Eventually(handlerStarted).Should(BeClosed())
+		close(closed)
+		Eventually(done).Should(BeClosed())
+		Consistently(handlerStarted).Should(BeClosed())
+	})
+
+	It("recovers from a panic in the handler", func() {
+		panicked := make(chan *panicError, 1)
+		h := newIncomingStreamHandler(acceptStream, func(Stream) { panic("foobar") }, func(e *panicError) { panicked <- e }, 1, closed)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			h.run()
+			close(done)
+		}()
+		streamChan <- NewMockStreamI(mockCtrl)
+		var e *panicError
+		Eventually(panicked).Should(Receive(&e))
+		Expect(e).To(MatchError("panic: foobar"))
+		Expect(string(e.stack)).To(ContainSubstring("stream_handler_test.go"))
+		// the next stream is accepted after the handler panicked
+		Eventually(streamChan).Should(BeSent(NewMockStreamI(mockCtrl)))
+		close(closed)
+		Eventually(done).Should(BeClosed())
+	})
+})
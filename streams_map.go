@@ -26,6 +26,7 @@ type streamsMap struct {
 
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
+	deadlines         *sessionDeadlines
 
 	outgoingBidiStreams *outgoingBidiStreamsMap
 	outgoingUniStreams  *outgoingUniStreamsMap
@@ -38,6 +39,7 @@ var _ streamManager = &streamsMap{}
 func newStreamsMap(
 	sender streamSender,
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
+	deadlines *sessionDeadlines,
 	maxIncomingStreams uint64,
 	maxIncomingUniStreams uint64,
 	perspective protocol.Perspective,
@@ -46,16 +48,17 @@ func newStreamsMap(
 	m := &streamsMap{
 		perspective:       perspective,
 		newFlowController: newFlowController,
+		deadlines:         deadlines,
 		sender:            sender,
 	}
 	newBidiStream := func(id protocol.StreamID) streamI {
-		return newStream(id, m.sender, m.newFlowController(id), version)
+		return newStream(id, m.sender, m.newFlowController(id), m.deadlines, version)
 	}
 	newUniSendStream := func(id protocol.StreamID) sendStreamI {
-		return newSendStream(id, m.sender, m.newFlowController(id), version)
+		return newSendStream(id, m.sender, m.newFlowController(id), m.deadlines, version)
 	}
 	newUniReceiveStream := func(id protocol.StreamID) receiveStreamI {
-		return newReceiveStream(id, m.sender, m.newFlowController(id), version)
+		return newReceiveStream(id, m.sender, m.newFlowController(id), m.deadlines, version)
 	}
 	m.outgoingBidiStreams = newOutgoingBidiStreamsMap(
 		protocol.FirstStream(protocol.StreamTypeBidi, perspective),
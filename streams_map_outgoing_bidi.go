@@ -51,6 +51,10 @@ func (m *outgoingBidiStreamsMap) OpenStream() (streamI, error) {
 
 	str, err := m.openStreamImpl()
 	if err != nil {
+		if err == m.closeErr {
+			// return the error the session was closed with, such that it can be inspected by the application
+			return nil, err
+		}
 		return nil, streamOpenErr{err}
 	}
 	return str, nil
@@ -65,6 +69,9 @@ func (m *outgoingBidiStreamsMap) OpenStreamSync() (streamI, error) {
 		if err == nil {
 			return str, nil
 		}
+		if err == m.closeErr {
+			return nil, err
+		}
 		if err != nil && err != errTooManyOpenStreams {
 			return nil, streamOpenErr{err}
 		}
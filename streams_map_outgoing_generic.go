@@ -49,6 +49,10 @@ func (m *outgoingItemsMap) OpenStream() (item, error) {
 
 	str, err := m.openStreamImpl()
 	if err != nil {
+		if err == m.closeErr {
+			// return the error the session was closed with, such that it can be inspected by the application
+			return nil, err
+		}
 		return nil, streamOpenErr{err}
 	}
 	return str, nil
@@ -63,6 +67,9 @@ func (m *outgoingItemsMap) OpenStreamSync() (item, error) {
 		if err == nil {
 			return str, nil
 		}
+		if err == m.closeErr {
+			return nil, err
+		}
 		if err != nil && err != errTooManyOpenStreams {
 			return nil, streamOpenErr{err}
 		}
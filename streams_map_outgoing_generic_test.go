@@ -44,11 +44,10 @@ var _ = Describe("Streams Map (outgoing)", func() {
 		})
 
 		It("doesn't open streams after it has been closed", func() {
-			testErr := errors.New("close")
+			testErr := qerr.Error(qerr.PeerGoingAway, "close")
 			m.CloseWithError(testErr)
 			_, err := m.OpenStream()
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal(testErr.Error()))
+			Expect(err).To(BeIdenticalTo(testErr))
 			nerr, ok := err.(net.Error)
 			Expect(ok).To(BeTrue())
 			Expect(nerr.Timeout()).To(BeFalse())
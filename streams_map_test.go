@@ -81,7 +81,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, maxBidiStreams, maxUniStreams, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, nil, maxBidiStreams, maxUniStreams, perspective, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -400,6 +400,21 @@ var _ = Describe("Streams Map", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal(testErr.Error()))
 			})
+
+			It("returns the close error from all calls", func() {
+				testErr := qerr.RemoteError(qerr.ApplicationError(0x42, "foobar"))
+				m.CloseWithError(testErr)
+				_, err := m.OpenStream()
+				Expect(err).To(BeIdenticalTo(testErr))
+				_, err = m.OpenStreamSync()
+				Expect(err).To(BeIdenticalTo(testErr))
+				_, err = m.OpenUniStream()
+				Expect(err).To(BeIdenticalTo(testErr))
+				_, err = m.AcceptStream()
+				Expect(err).To(BeIdenticalTo(testErr))
+				_, err = m.AcceptUniStream()
+				Expect(err).To(BeIdenticalTo(testErr))
+			})
 		})
 	}
 })
@@ -0,0 +1,163 @@
+package quic
+
+import (
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type peerID string
+
+func (p peerID) Network() string { return "overlay" }
+func (p peerID) String() string  { return string(p) }
+
+type overlayDatagram struct {
+	from peerID
+	data []byte
+}
+
+// overlaySubstrate is an in-memory Substrate, that uses peer IDs as addresses
+type overlaySubstrate struct {
+	id      peerID
+	mtu     int
+	network map[peerID]*overlaySubstrate
+
+	mutex       sync.Mutex
+	maxSentSize int
+
+	incoming  chan overlayDatagram
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ Substrate = &overlaySubstrate{}
+
+func newOverlayNetwork(mtu int, ids ...peerID) map[peerID]*overlaySubstrate {
+	network := make(map[peerID]*overlaySubstrate)
+	for _, id := range ids {
+		network[id] = &overlaySubstrate{
+			id:       id,
+			mtu:      mtu,
+			network:  network,
+			incoming: make(chan overlayDatagram, 100),
+			closed:   make(chan struct{}),
+		}
+	}
+	return network
+}
+
+func (s *overlaySubstrate) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case d := <-s.incoming:
+		return copy(b, d.data), d.from, nil
+	case <-s.closed:
+		return 0, nil, errors.New("closed")
+	}
+}
+
+func (s *overlaySubstrate) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if len(b) > s.mtu {
+		return 0, errors.New("datagram too large")
+	}
+	s.mutex.Lock()
+	if len(b) > s.maxSentSize {
+		s.maxSentSize = len(b)
+	}
+	s.mutex.Unlock()
+	peer, ok := s.network[addr.(peerID)]
+	if !ok {
+		return 0, errors.New("unknown peer")
+	}
+	select {
+	case peer.incoming <- overlayDatagram{from: s.id, data: append([]byte{}, b...)}:
+	default: // drop the datagram
+	}
+	return len(b), nil
+}
+
+func (s *overlaySubstrate) MaxDatagramSize(net.Addr) int     { return s.mtu }
+func (s *overlaySubstrate) LocalAddr() net.Addr              { return s.id }
+func (s *overlaySubstrate) SetDeadline(time.Time) error      { return nil }
+func (s *overlaySubstrate) SetReadDeadline(time.Time) error  { return nil }
+func (s *overlaySubstrate) SetWriteDeadline(time.Time) error { return nil }
+
+func (s *overlaySubstrate) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+func (s *overlaySubstrate) getMaxSentSize() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.maxSentSize
+}
+
+var _ = Describe("Substrates", func() {
+	It("uses the maximum datagram size of the substrate", func() {
+		network := newOverlayNetwork(1300, "client")
+		c := &conn{pconn: network["client"], currentAddr: peerID("server")}
+		Expect(c.MaxPacketSize()).To(BeEquivalentTo(1300))
+	})
+
+	It("doesn't use packets larger than the maximum receive packet size", func() {
+		network := newOverlayNetwork(5000, "client")
+		c := &conn{pconn: network["client"], currentAddr: peerID("server")}
+		Expect(c.MaxPacketSize()).To(Equal(protocol.MaxReceivePacketSize))
+	})
+
+	It("refuses to dial if the maximum datagram size is too small", func() {
+		network := newOverlayNetwork(1000, "client")
+		_, err := Dial(network["client"], peerID("server"), "server", nil, nil)
+		Expect(err).To(MatchError("quic: the substrate's maximum datagram size (1000 bytes) is smaller than the minimum QUIC packet size (1200 bytes)"))
+	})
+
+	It("runs QUIC over the substrate, using peer IDs as addresses", func() {
+		network := newOverlayNetwork(1300, "client", "server")
+		defer network["client"].Close()
+		defer network["server"].Close()
+		ln, err := Listen(network["server"], testdata.GetTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		data := make([]byte, 20000)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			Expect(sess.RemoteAddr()).To(Equal(peerID("client")))
+			str, err := sess.OpenStreamSync()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		sess, err := Dial(
+			network["client"],
+			peerID("server"),
+			"server",
+			&tls.Config{InsecureSkipVerify: true},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sess.RemoteAddr()).To(Equal(peerID("server")))
+		str, err := sess.AcceptStream()
+		Expect(err).ToNot(HaveOccurred())
+		rcvd, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rcvd).To(Equal(data))
+		Expect(sess.Close()).To(Succeed())
+		Expect(network["server"].getMaxSentSize()).To(Equal(1300))
+		Expect(network["client"].getMaxSentSize()).To(BeNumerically("<=", 1300))
+	})
+})
@@ -0,0 +1,127 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A wakeable is woken up by the timerWheel when its timer expires.
+type wakeable interface {
+	wakeUp()
+}
+
+// A timerWheel wakes up parked sessions when their timer expires (see Config.ParkIdleSessions).
+// Instead of one timer per session, a single go routine serves all sessions of a server.
+// Timers are grouped into slots of one tick, so they expire up to one tick late.
+// The go routine only runs while the wheel contains timers.
+type timerWheel struct {
+	clock utils.Clock
+	tick  time.Duration
+	// minDelay is the minimum time until a timer expires for it to be added to the wheel.
+	// Shorter timers (e.g. for acknowledgements and pacing) would be delayed too much.
+	minDelay time.Duration
+
+	mutex   sync.Mutex
+	running bool  // is the go routine running
+	next    int64 // the next tick to be processed
+	slots   []map[wakeable]time.Time
+	slotOf  map[wakeable]int
+}
+
+func newTimerWheel(clock utils.Clock) *timerWheel {
+	slots := make([]map[wakeable]time.Time, protocol.TimerWheelSlots)
+	for i := range slots {
+		slots[i] = make(map[wakeable]time.Time)
+	}
+	return &timerWheel{
+		clock:    clock,
+		tick:     protocol.TimerWheelTick,
+		minDelay: protocol.MinSessionParkDuration,
+		slots:    slots,
+		slotOf:   make(map[wakeable]int),
+	}
+}
+
+func (w *timerWheel) tickOf(t time.Time) int64 {
+	return t.UnixNano() / int64(w.tick)
+}
+
+// Add sets the timer for s, replacing a timer that was previously added.
+func (w *timerWheel) Add(s wakeable, deadline time.Time) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.removeLocked(s)
+	if !w.running {
+		w.running = true
+		w.next = w.tickOf(w.clock.Now())
+		go w.run()
+	}
+	tick := w.tickOf(deadline)
+	if tick < w.next {
+		tick = w.next
+	}
+	slot := int(tick % int64(len(w.slots)))
+	w.slots[slot][s] = deadline
+	w.slotOf[s] = slot
+}
+
+// Remove removes the timer for s, if it was added.
+func (w *timerWheel) Remove(s wakeable) {
+	w.mutex.Lock()
+	w.removeLocked(s)
+	w.mutex.Unlock()
+}
+
+func (w *timerWheel) removeLocked(s wakeable) {
+	if slot, ok := w.slotOf[s]; ok {
+		delete(w.slots[slot], s)
+		delete(w.slotOf, s)
+	}
+}
+
+// Len returns the number of timers in the wheel.
+func (w *timerWheel) Len() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.slotOf)
+}
+
+func (w *timerWheel) run() {
+	timer := w.clock.NewTimer(time.Duration(1<<63 - 1))
+	defer timer.Stop()
+	for {
+		w.mutex.Lock()
+		if len(w.slotOf) == 0 {
+			w.running = false
+			w.mutex.Unlock()
+			return
+		}
+		// timers in the next tick have expired once the tick is over
+		if wait := time.Unix(0, (w.next+1)*int64(w.tick)).Sub(w.clock.Now()); wait > 0 {
+			w.mutex.Unlock()
+			timer.Reset(wait)
+			<-timer.Chan()
+			continue
+		}
+		var expired []wakeable
+		slot := w.slots[w.next%int64(len(w.slots))]
+		for s, deadline := range slot {
+			// The slot also contains timers that expire in later rotations of the wheel.
+			if w.tickOf(deadline) <= w.next {
+				expired = append(expired, s)
+				delete(slot, s)
+				delete(w.slotOf, s)
+			}
+		}
+		w.next++
+		w.mutex.Unlock()
+
+		for _, s := range expired {
+			s.wakeUp()
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type wakeableFunc func()
+
+func (f wakeableFunc) wakeUp() { f() }
+
+var _ = Describe("Timer Wheel", func() {
+	const tick = 5 * time.Millisecond
+
+	var w *timerWheel
+
+	newWakeable := func() (*wakeableFunc, chan struct{}) {
+		woken := make(chan struct{}, 10)
+		f := wakeableFunc(func() { woken <- struct{}{} })
+		return &f, woken
+	}
+
+	BeforeEach(func() {
+		w = newTimerWheel(utils.DefaultClock)
+		w.tick = tick
+		w.slots = w.slots[:8] // one rotation takes 40ms
+	})
+
+	AfterEach(func() {
+		// make sure that the go routine returns
+		for _, slot := range w.slots {
+			for s := range slot {
+				w.Remove(s)
+			}
+		}
+	})
+
+	It("wakes up when the timer expires", func() {
+		s, woken := newWakeable()
+		start := time.Now()
+		w.Add(s, start.Add(20*time.Millisecond))
+		Expect(w.Len()).To(Equal(1))
+		Eventually(woken).Should(Receive())
+		Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+		Expect(w.Len()).To(BeZero())
+		Consistently(woken).ShouldNot(Receive())
+	})
+
+	It("wakes up for timers that expire after more than one rotation", func() {
+		s, woken := newWakeable()
+		start := time.Now()
+		w.Add(s, start.Add(100*time.Millisecond))
+		Eventually(woken, time.Second).Should(Receive())
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+	})
+
+	It("wakes up right away for timers that already expired", func() {
+		s, woken := newWakeable()
+		w.Add(s, time.Now().Add(-time.Hour))
+		Eventually(woken).Should(Receive())
+	})
+
+	It("replaces timers", func() {
+		s, woken := newWakeable()
+		start := time.Now()
+		w.Add(s, start.Add(10*time.Millisecond))
+		w.Add(s, start.Add(60*time.Millisecond))
+		Expect(w.Len()).To(Equal(1))
+		Eventually(woken).Should(Receive())
+		Expect(time.Since(start)).To(BeNumerically(">=", 60*time.Millisecond))
+		Consistently(woken).ShouldNot(Receive())
+	})
+
+	It("removes timers", func() {
+		s, woken := newWakeable()
+		w.Add(s, time.Now().Add(10*time.Millisecond))
+		w.Remove(s)
+		Expect(w.Len()).To(BeZero())
+		Consistently(woken).ShouldNot(Receive())
+	})
+
+	It("stops the go routine when no timers are left", func() {
+		s, woken := newWakeable()
+		w.Add(s, time.Now().Add(10*time.Millisecond))
+		Eventually(woken).Should(Receive())
+		Eventually(func() bool {
+			w.mutex.Lock()
+			defer w.mutex.Unlock()
+			return w.running
+		}).Should(BeFalse())
+		// adding a timer restarts the go routine
+		w.Add(s, time.Now().Add(10*time.Millisecond))
+		Eventually(woken).Should(Receive())
+	})
+})
@@ -0,0 +1,203 @@
+package quic
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// link types, see http://www.tcpdump.org/linktypes.html
+const (
+	pcapLinkTypeNull     = 0
+	pcapLinkTypeEthernet = 1
+	pcapLinkTypeRaw      = 101
+	pcapLinkTypeLinuxSLL = 113
+	pcapLinkTypeIPv4     = 228
+	pcapLinkTypeIPv6     = 229
+)
+
+// the maximum snapshot length used by tcpdump
+const pcapMaxRecordSize = 262144
+
+// ReadPcapTrace reads the packets of a connection from a packet capture in the pcap format (as written by tcpdump and Wireshark),
+// such that captures of failed connections can be used as regression tests (see Trace.CheckPackets).
+// Packet captures don't contain the TLS secrets, so they have to be passed separately, in the NSS key log format
+// (as written to the tls.Config.KeyLogWriter).
+// Only UDP packets sent from or to localAddr are read. If localAddr doesn't contain an IP, only the port is compared.
+// The supported link types are Ethernet, Linux cooked capture, BSD loopback and raw IP. Fragmented IP packets are skipped.
+// The pcapng format is not supported.
+func ReadPcapTrace(r io.Reader, keyLog []byte, localAddr *net.UDPAddr) (*Trace, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, errors.New("not a pcap file")
+	}
+	var order binary.ByteOrder
+	var nanoseconds bool
+	switch binary.LittleEndian.Uint32(header) {
+	case 0xa1b2c3d4:
+		order = binary.LittleEndian
+	case 0xa1b23c4d:
+		order = binary.LittleEndian
+		nanoseconds = true
+	case 0xd4c3b2a1:
+		order = binary.BigEndian
+	case 0x4d3cb2a1:
+		order = binary.BigEndian
+		nanoseconds = true
+	default:
+		return nil, errors.New("not a pcap file")
+	}
+	linkType := order.Uint32(header[20:24])
+	switch linkType {
+	case pcapLinkTypeNull, pcapLinkTypeEthernet, pcapLinkTypeRaw, pcapLinkTypeLinuxSLL, pcapLinkTypeIPv4, pcapLinkTypeIPv6:
+	default:
+		return nil, fmt.Errorf("unsupported pcap link type: %d", linkType)
+	}
+
+	t := &Trace{KeyLog: keyLog}
+	var start time.Time
+	recordHeader := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(br, recordHeader); err != nil {
+			if err == io.EOF {
+				return t, nil
+			}
+			return nil, fmt.Errorf("error reading pcap record: %s", err)
+		}
+		length := order.Uint32(recordHeader[8:12])
+		if length > pcapMaxRecordSize {
+			return nil, fmt.Errorf("pcap record too large (%d bytes)", length)
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return nil, fmt.Errorf("error reading pcap record: %s", err)
+		}
+		if length < order.Uint32(recordHeader[12:16]) {
+			return nil, errors.New("pcap contains truncated packets (increase the snapshot length)")
+		}
+		src, dst, data, ok := parsePcapUDPPacket(linkType, order, frame)
+		if !ok {
+			continue
+		}
+		var sent bool
+		var remoteAddr *net.UDPAddr
+		switch {
+		case matchesUDPAddr(localAddr, src):
+			sent = true
+			remoteAddr = dst
+		case matchesUDPAddr(localAddr, dst):
+			remoteAddr = src
+		default:
+			continue
+		}
+		frac := time.Duration(order.Uint32(recordHeader[4:8]))
+		if !nanoseconds {
+			frac *= time.Microsecond
+		}
+		ts := time.Unix(int64(order.Uint32(recordHeader[0:4])), int64(frac))
+		if start.IsZero() {
+			start = ts
+		}
+		t.Packets = append(t.Packets, TracePacket{
+			Time:       ts.Sub(start),
+			Sent:       sent,
+			RemoteAddr: remoteAddr.String(),
+			Data:       data,
+		})
+	}
+}
+
+func matchesUDPAddr(local, addr *net.UDPAddr) bool {
+	if local.Port != addr.Port {
+		return false
+	}
+	return local.IP == nil || local.IP.IsUnspecified() || local.IP.Equal(addr.IP)
+}
+
+// parsePcapUDPPacket returns the addresses and the payload of a UDP packet.
+// It returns false for all other packets.
+func parsePcapUDPPacket(linkType uint32, order binary.ByteOrder, frame []byte) (*net.UDPAddr, *net.UDPAddr, []byte, bool) {
+	var ip []byte
+	switch linkType {
+	case pcapLinkTypeNull:
+		if len(frame) < 4 {
+			return nil, nil, nil, false
+		}
+		// The address family is written in the byte order of the capturing host.
+		// The values for IPv6 differ between the BSDs.
+		switch order.Uint32(frame) {
+		case 2, 24, 28, 30:
+			ip = frame[4:]
+		}
+	case pcapLinkTypeEthernet:
+		if len(frame) < 14 {
+			return nil, nil, nil, false
+		}
+		etherType := binary.BigEndian.Uint16(frame[12:14])
+		ip = frame[14:]
+		if etherType == 0x8100 && len(ip) >= 4 { // 802.1Q VLAN tag
+			etherType = binary.BigEndian.Uint16(ip[2:4])
+			ip = ip[4:]
+		}
+		if etherType != 0x0800 && etherType != 0x86dd {
+			return nil, nil, nil, false
+		}
+	case pcapLinkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return nil, nil, nil, false
+		}
+		ip = frame[16:]
+	default:
+		ip = frame
+	}
+	if len(ip) == 0 {
+		return nil, nil, nil, false
+	}
+
+	var srcIP, dstIP net.IP
+	var udp []byte
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 {
+			return nil, nil, nil, false
+		}
+		headerLen := int(ip[0]&0xf) * 4
+		totalLen := int(binary.BigEndian.Uint16(ip[2:4]))
+		// skip fragments, as well as other protocols than UDP
+		if binary.BigEndian.Uint16(ip[6:8])&0x3fff != 0 || ip[9] != 17 || headerLen < 20 || totalLen < headerLen || totalLen > len(ip) {
+			return nil, nil, nil, false
+		}
+		srcIP = net.IP(ip[12:16])
+		dstIP = net.IP(ip[16:20])
+		udp = ip[headerLen:totalLen]
+	case 6:
+		if len(ip) < 40 {
+			return nil, nil, nil, false
+		}
+		// extension headers are not supported
+		payloadLen := int(binary.BigEndian.Uint16(ip[4:6]))
+		if ip[6] != 17 || 40+payloadLen > len(ip) {
+			return nil, nil, nil, false
+		}
+		srcIP = net.IP(ip[8:24])
+		dstIP = net.IP(ip[24:40])
+		udp = ip[40 : 40+payloadLen]
+	default:
+		return nil, nil, nil, false
+	}
+	if len(udp) < 8 {
+		return nil, nil, nil, false
+	}
+	udpLen := int(binary.BigEndian.Uint16(udp[4:6]))
+	if udpLen < 8 || udpLen > len(udp) {
+		return nil, nil, nil, false
+	}
+	src := &net.UDPAddr{IP: srcIP, Port: int(binary.BigEndian.Uint16(udp[0:2]))}
+	dst := &net.UDPAddr{IP: dstIP, Port: int(binary.BigEndian.Uint16(udp[2:4]))}
+	return src, dst, udp[8:udpLen], true
+}
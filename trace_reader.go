@@ -0,0 +1,323 @@
+package quic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// A TracePacket is a packet recorded by a TraceRecorder.
+type TracePacket struct {
+	// Time is the time since the start of the recording.
+	Time time.Duration
+	// Sent says if the packet was sent by the endpoint that recorded the trace.
+	Sent bool
+	// RemoteAddr is the address of the peer.
+	RemoteAddr string
+	Data       []byte
+}
+
+// A Trace is a recording of a connection, as recorded by a TraceRecorder.
+type Trace struct {
+	Packets []TracePacket
+	// KeyLog contains the TLS secrets, in the NSS key log format.
+	KeyLog []byte
+}
+
+// ReadTrace reads a trace written by a TraceRecorder.
+func ReadTrace(r io.Reader) (*Trace, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(traceMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != traceMagic {
+		return nil, errors.New("not a quic-go trace")
+	}
+	t := &Trace{}
+	for {
+		recordType, err := br.ReadByte()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		header := make([]byte, 9)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, fmt.Errorf("error reading trace record: %s", err)
+		}
+		addr := make([]byte, header[8])
+		if _, err := io.ReadFull(br, addr); err != nil {
+			return nil, fmt.Errorf("error reading trace record: %s", err)
+		}
+		length := make([]byte, 4)
+		if _, err := io.ReadFull(br, length); err != nil {
+			return nil, fmt.Errorf("error reading trace record: %s", err)
+		}
+		// Both packets and key log lines are small, so there's no need to allocate large buffers for corrupted traces.
+		l := binary.BigEndian.Uint32(length)
+		if l > uint32(protocol.MaxReceivePacketSize) {
+			return nil, fmt.Errorf("trace record too large (%d bytes)", l)
+		}
+		data := make([]byte, l)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, fmt.Errorf("error reading trace record: %s", err)
+		}
+		switch recordType {
+		case traceRecordKeyLog:
+			t.KeyLog = append(t.KeyLog, data...)
+		case traceRecordPacketSent, traceRecordPacketReceived:
+			t.Packets = append(t.Packets, TracePacket{
+				Time:       time.Duration(binary.BigEndian.Uint64(header[:8])),
+				Sent:       recordType == traceRecordPacketSent,
+				RemoteAddr: string(addr),
+				Data:       data,
+			})
+		default:
+			return nil, fmt.Errorf("unknown trace record type: %d", recordType)
+		}
+	}
+}
+
+// CheckPackets decrypts all packets of the trace and parses them, using the current packet and frame parsing code.
+// It returns the first error that occurs, such that the trace can be used as a regression test for the parsers.
+// It only checks that the packets can be parsed: the packets are not passed to a session,
+// so it doesn't detect bugs in the way a session handles them.
+// Replaying a trace against a session isn't possible, since a new handshake would derive different keys,
+// and the recorded packets of the peer can't be decrypted with them.
+// Version Negotiation, Retry and 0-RTT packets are skipped.
+func (t *Trace) CheckPackets() error {
+	return t.decryptPackets(func(*decryptedPacket) {})
+}
+
+type decryptedPacket struct {
+	index           int // the index of the TracePacket
+	sent            bool
+	hdr             *wire.ExtendedHeader
+	packetNumber    protocol.PacketNumber
+	encryptionLevel protocol.EncryptionLevel
+	frames          []wire.Frame
+}
+
+func (t *Trace) decryptPackets(onPacket func(*decryptedPacket)) error {
+	r, err := newTraceDecrypter(t.KeyLog)
+	if err != nil {
+		return err
+	}
+	for i, p := range t.Packets {
+		if err := r.decryptPacket(i, p, onPacket); err != nil {
+			return fmt.Errorf("packet %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// The traceDecrypter decrypts recorded packets.
+// The key log doesn't say which connection the secrets belong to, and which cipher suite was used,
+// so all candidate keys are tried for every packet.
+// The keys that decrypted a packet are tried first for the next packet with the same connection ID.
+type traceDecrypter struct {
+	handshakeOpeners []handshake.Opener
+	oneRTTOpeners    []handshake.Opener
+	initialOpeners   []handshake.Opener
+	initialConnIDs   map[string]struct{}
+
+	// an unpacker per opener, so packet numbers are decoded separately for every packet number space
+	unpackers map[handshake.Opener]*packetUnpacker
+	// the opener that last decrypted a packet, per connection ID and encryption level
+	lastOpener map[string]handshake.Opener
+
+	// connection IDs used by the endpoints, needed to parse short header packets
+	connIDs []protocol.ConnectionID
+	version protocol.VersionNumber
+
+	frameParser wire.FrameParser
+}
+
+func newTraceDecrypter(keyLog []byte) (*traceDecrypter, error) {
+	r := &traceDecrypter{
+		initialConnIDs: make(map[string]struct{}),
+		unpackers:      make(map[handshake.Opener]*packetUnpacker),
+		lastOpener:     make(map[string]handshake.Opener),
+	}
+	for _, line := range strings.Split(string(keyLog), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid key log line: %s", line)
+		}
+		secret, err := hex.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key log line: %s", line)
+		}
+		switch fields[0] {
+		case "CLIENT_HANDSHAKE_TRAFFIC_SECRET", "SERVER_HANDSHAKE_TRAFFIC_SECRET":
+			r.handshakeOpeners = append(r.handshakeOpeners, handshake.NewOpenersFromTrafficSecret(secret, false)...)
+		case "CLIENT_TRAFFIC_SECRET_0", "SERVER_TRAFFIC_SECRET_0":
+			r.oneRTTOpeners = append(r.oneRTTOpeners, handshake.NewOpenersFromTrafficSecret(secret, true)...)
+		}
+	}
+	return r, nil
+}
+
+func (r *traceDecrypter) decryptPacket(index int, p TracePacket, onPacket func(*decryptedPacket)) error {
+	data := p.Data
+	for len(data) > 0 {
+		var connIDLen int
+		if data[0]&0x80 == 0 { // short header
+			connID, err := r.findConnectionID(data)
+			if err != nil {
+				return err
+			}
+			connIDLen = connID.Len()
+		}
+		hdr, err := wire.ParseHeader(bytes.NewReader(data), connIDLen)
+		if err != nil {
+			return fmt.Errorf("error parsing header: %s", err)
+		}
+		if hdr.IsVersionNegotiation() {
+			return nil
+		}
+		var rest []byte
+		if hdr.IsLongHeader {
+			if protocol.ByteCount(len(data)) < hdr.ParsedLen()+hdr.Length {
+				return fmt.Errorf("packet length (%d bytes) is smaller than the expected length (%d bytes)", len(data)-int(hdr.ParsedLen()), hdr.Length)
+			}
+			packetLen := int(hdr.ParsedLen() + hdr.Length)
+			rest = data[packetLen:]
+			data = data[:packetLen]
+			r.version = hdr.Version
+			r.addConnectionID(hdr.SrcConnectionID)
+			r.addConnectionID(hdr.DestConnectionID)
+		}
+		switch hdr.Type {
+		case protocol.PacketTypeRetry, protocol.PacketType0RTT:
+		default:
+			packet, err := r.unpack(hdr, data)
+			if err != nil {
+				return err
+			}
+			packet.index = index
+			packet.sent = p.Sent
+			onPacket(packet)
+		}
+		data = rest
+	}
+	return nil
+}
+
+func (r *traceDecrypter) unpack(hdr *wire.Header, data []byte) (*decryptedPacket, error) {
+	var encLevel protocol.EncryptionLevel
+	var openers []handshake.Opener
+	switch hdr.Type {
+	case protocol.PacketTypeInitial:
+		encLevel = protocol.EncryptionInitial
+		if _, ok := r.initialConnIDs[string(hdr.DestConnectionID)]; !ok {
+			// The client derives the Initial keys from the destination connection ID it chose.
+			r.initialConnIDs[string(hdr.DestConnectionID)] = struct{}{}
+			for _, pers := range []protocol.Perspective{protocol.PerspectiveClient, protocol.PerspectiveServer} {
+				_, opener, err := handshake.NewInitialAEAD(hdr.DestConnectionID, pers)
+				if err != nil {
+					return nil, err
+				}
+				r.initialOpeners = append(r.initialOpeners, opener)
+			}
+		}
+		openers = r.initialOpeners
+	case protocol.PacketTypeHandshake:
+		encLevel = protocol.EncryptionHandshake
+		openers = r.handshakeOpeners
+	default:
+		if hdr.IsLongHeader {
+			return nil, fmt.Errorf("unknown packet type: %s", hdr.Type)
+		}
+		encLevel = protocol.Encryption1RTT
+		openers = r.oneRTTOpeners
+	}
+
+	key := string(hdr.DestConnectionID) + encLevel.String()
+	if opener, ok := r.lastOpener[key]; ok {
+		openers = append([]handshake.Opener{opener}, openers...)
+	}
+	for _, opener := range openers {
+		unpacker, ok := r.unpackers[opener]
+		if !ok {
+			unpacker = newPacketUnpacker(nil, r.version).(*packetUnpacker)
+			r.unpackers[opener] = unpacker
+		}
+		// unpacking removes header protection in place, so every attempt needs a fresh copy
+		unpacked, err := unpacker.unpack(hdr, append([]byte{}, data...), encLevel, opener)
+		if err != nil {
+			continue
+		}
+		r.lastOpener[key] = opener
+		frames, err := r.parseFrames(unpacked)
+		if err != nil {
+			return nil, err
+		}
+		return &decryptedPacket{
+			hdr:             unpacked.hdr,
+			packetNumber:    unpacked.packetNumber,
+			encryptionLevel: encLevel,
+			frames:          frames,
+		}, nil
+	}
+	return nil, fmt.Errorf("failed to decrypt %s packet (connection ID %s)", encLevel, hdr.DestConnectionID)
+}
+
+func (r *traceDecrypter) parseFrames(p *unpackedPacket) ([]wire.Frame, error) {
+	if r.frameParser == nil {
+		r.frameParser = wire.NewFrameParser(r.version)
+	}
+	var frames []wire.Frame
+	b := bytes.NewReader(p.data)
+	for {
+		frame, err := r.frameParser.ParseNext(b, p.encryptionLevel)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing frames of %s packet %d: %s", p.encryptionLevel, p.packetNumber, err)
+		}
+		if frame == nil {
+			return frames, nil
+		}
+		if f, ok := frame.(*wire.NewConnectionIDFrame); ok {
+			r.addConnectionID(f.ConnectionID)
+		}
+		frames = append(frames, frame)
+	}
+}
+
+func (r *traceDecrypter) addConnectionID(c protocol.ConnectionID) {
+	for _, connID := range r.connIDs {
+		if connID.Equal(c) {
+			return
+		}
+	}
+	r.connIDs = append(r.connIDs, c)
+}
+
+// findConnectionID finds the destination connection ID of a short header packet
+func (r *traceDecrypter) findConnectionID(data []byte) (protocol.ConnectionID, error) {
+	var found protocol.ConnectionID
+	var ok bool
+	for _, connID := range r.connIDs {
+		// prefer the longest match, since a zero-length connection ID always matches
+		if len(data) > connID.Len() && bytes.Equal(data[1:1+connID.Len()], connID) && (!ok || connID.Len() > found.Len()) {
+			found = connID
+			ok = true
+		}
+	}
+	if !ok {
+		return nil, errors.New("short header packet with an unknown connection ID")
+	}
+	return found, nil
+}
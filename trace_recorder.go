@@ -0,0 +1,127 @@
+package quic
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// traceMagic is written at the beginning of every trace
+const traceMagic = "quic-go trace v1\n"
+
+const (
+	traceRecordKeyLog uint8 = iota
+	traceRecordPacketSent
+	traceRecordPacketReceived
+)
+
+// A TraceRecorder records all packets sent and received on a net.PacketConn,
+// together with the TLS secrets needed to decrypt them.
+// The resulting trace is self-contained, and can be read by ReadTrace.
+// Traces contain all keys used by the connection, so they should only be recorded for debugging.
+type TraceRecorder struct {
+	mutex sync.Mutex
+	w     io.Writer
+	clock Clock
+	start time.Time
+	err   error
+}
+
+// NewTraceRecorder creates a new TraceRecorder that writes the trace to w.
+// The packets are timestamped using the system clock.
+func NewTraceRecorder(w io.Writer) *TraceRecorder {
+	return NewTraceRecorderWithClock(w, utils.DefaultClock)
+}
+
+// NewTraceRecorderWithClock is like NewTraceRecorder, but timestamps the packets using clock.
+// It should be the Config.Clock of the connection that is recorded.
+func NewTraceRecorderWithClock(w io.Writer, clock Clock) *TraceRecorder {
+	r := &TraceRecorder{
+		w:     w,
+		clock: clock,
+		start: clock.Now(),
+	}
+	_, r.err = io.WriteString(w, traceMagic)
+	return r
+}
+
+// PacketConn wraps a net.PacketConn, such that all packets sent and received on it are recorded.
+// The returned net.PacketConn can be passed to Listen or Dial.
+// Since it is not a *net.UDPConn, socket options that require access to the UDP socket (like Config.KernelPacing) are not used.
+func (r *TraceRecorder) PacketConn(c net.PacketConn) net.PacketConn {
+	return &recordingPacketConn{PacketConn: c, recorder: r}
+}
+
+// KeyLogWriter returns an io.Writer that records the TLS secrets.
+// It must be set as the KeyLogWriter in the tls.Config.
+func (r *TraceRecorder) KeyLogWriter() io.Writer {
+	return &recordingKeyLogWriter{recorder: r}
+}
+
+// Err returns the first error that occurred while writing the trace.
+func (r *TraceRecorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+func (r *TraceRecorder) record(recordType uint8, addr net.Addr, data []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return
+	}
+	var addrStr string
+	if addr != nil {
+		addrStr = addr.String()
+	}
+	if len(addrStr) > 0xff {
+		r.err = errors.New("address too long")
+		return
+	}
+	b := make([]byte, 1+8+1+len(addrStr)+4+len(data))
+	b[0] = recordType
+	binary.BigEndian.PutUint64(b[1:9], uint64(r.clock.Now().Sub(r.start)))
+	b[9] = uint8(len(addrStr))
+	copy(b[10:], addrStr)
+	binary.BigEndian.PutUint32(b[10+len(addrStr):], uint32(len(data)))
+	copy(b[14+len(addrStr):], data)
+	_, r.err = r.w.Write(b)
+}
+
+type recordingPacketConn struct {
+	net.PacketConn
+	recorder *TraceRecorder
+}
+
+var _ net.PacketConn = &recordingPacketConn{}
+
+func (c *recordingPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if n > 0 {
+		c.recorder.record(traceRecordPacketReceived, addr, b[:n])
+	}
+	return n, addr, err
+}
+
+func (c *recordingPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	if err == nil {
+		c.recorder.record(traceRecordPacketSent, addr, b[:n])
+	}
+	return n, err
+}
+
+type recordingKeyLogWriter struct {
+	recorder *TraceRecorder
+}
+
+func (w *recordingKeyLogWriter) Write(b []byte) (int, error) {
+	w.recorder.record(traceRecordKeyLog, nil, b)
+	return len(b), nil
+}
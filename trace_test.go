@@ -0,0 +1,285 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+// writePcap writes a pcap file with Ethernet frames containing IPv4 UDP packets
+func writePcap(packets []TracePacket, localAddr *net.UDPAddr) []byte {
+	b := &bytes.Buffer{}
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535)
+	binary.LittleEndian.PutUint32(header[20:], pcapLinkTypeEthernet)
+	b.Write(header)
+	for _, p := range packets {
+		remoteAddr, err := net.ResolveUDPAddr("udp", p.RemoteAddr)
+		Expect(err).ToNot(HaveOccurred())
+		src, dst := remoteAddr, localAddr
+		if p.Sent {
+			src, dst = localAddr, remoteAddr
+		}
+		frame := make([]byte, 14+20+8, 14+20+8+len(p.Data))
+		binary.BigEndian.PutUint16(frame[12:], 0x0800)
+		ip := frame[14:]
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:], uint16(20+8+len(p.Data)))
+		ip[9] = 17
+		copy(ip[12:16], src.IP.To4())
+		copy(ip[16:20], dst.IP.To4())
+		udp := ip[20:]
+		binary.BigEndian.PutUint16(udp[0:], uint16(src.Port))
+		binary.BigEndian.PutUint16(udp[2:], uint16(dst.Port))
+		binary.BigEndian.PutUint16(udp[4:], uint16(8+len(p.Data)))
+		frame = append(frame, p.Data...)
+		recordHeader := make([]byte, 16)
+		binary.LittleEndian.PutUint32(recordHeader[0:], uint32(1e6+p.Time/time.Second))
+		binary.LittleEndian.PutUint32(recordHeader[4:], uint32((p.Time%time.Second)/time.Microsecond))
+		binary.LittleEndian.PutUint32(recordHeader[8:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(recordHeader[12:], uint32(len(frame)))
+		b.Write(recordHeader)
+		b.Write(frame)
+	}
+	return b.Bytes()
+}
+
+var _ = Describe("Traces", func() {
+	It("rejects data that is not a trace", func() {
+		_, err := ReadTrace(bytes.NewReader([]byte("foobar")))
+		Expect(err).To(MatchError("not a quic-go trace"))
+	})
+
+	It("records and reads packets and key log lines", func() {
+		buf := &bytes.Buffer{}
+		rec := NewTraceRecorder(buf)
+		rec.record(traceRecordPacketSent, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1337}, []byte("foo"))
+		rec.record(traceRecordPacketReceived, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1337}, []byte("bar"))
+		_, err := rec.KeyLogWriter().Write([]byte("CLIENT_TRAFFIC_SECRET_0 deadbeef cafe\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rec.Err()).ToNot(HaveOccurred())
+		trace, err := ReadTrace(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(trace.Packets).To(HaveLen(2))
+		Expect(trace.Packets[0].Sent).To(BeTrue())
+		Expect(trace.Packets[0].RemoteAddr).To(Equal("1.2.3.4:1337"))
+		Expect(trace.Packets[0].Data).To(Equal([]byte("foo")))
+		Expect(trace.Packets[1].Sent).To(BeFalse())
+		Expect(trace.Packets[1].Data).To(Equal([]byte("bar")))
+		Expect(trace.Packets[1].Time).To(BeNumerically(">=", trace.Packets[0].Time))
+		Expect(trace.KeyLog).To(Equal([]byte("CLIENT_TRAFFIC_SECRET_0 deadbeef cafe\n")))
+	})
+
+	It("uses the clock to timestamp packets", func() {
+		buf := &bytes.Buffer{}
+		clock := &fixedClock{Clock: utils.DefaultClock, now: time.Now()}
+		rec := NewTraceRecorderWithClock(buf, clock)
+		clock.now = clock.now.Add(time.Hour)
+		rec.record(traceRecordPacketSent, nil, []byte("foo"))
+		trace, err := ReadTrace(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(trace.Packets).To(HaveLen(1))
+		Expect(trace.Packets[0].Time).To(Equal(time.Hour))
+	})
+
+	It("errors on truncated traces", func() {
+		buf := &bytes.Buffer{}
+		NewTraceRecorder(buf).record(traceRecordPacketSent, nil, []byte("foobar"))
+		_, err := ReadTrace(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("error reading trace record"))
+	})
+
+	It("errors on records that are too large", func() {
+		buf := &bytes.Buffer{}
+		NewTraceRecorder(buf).record(traceRecordPacketSent, nil, make([]byte, protocol.MaxReceivePacketSize+1))
+		_, err := ReadTrace(buf)
+		Expect(err).To(MatchError(fmt.Sprintf("trace record too large (%d bytes)", protocol.MaxReceivePacketSize+1)))
+	})
+
+	It("errors on invalid key logs", func() {
+		trace := &Trace{KeyLog: []byte("foobar\n")}
+		Expect(trace.CheckPackets()).To(MatchError("invalid key log line: foobar"))
+	})
+
+	Context("reading packet captures", func() {
+		localAddr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+
+		It("reads UDP packets from and to the local address", func() {
+			data := writePcap([]TracePacket{
+				{Time: 0, Sent: true, RemoteAddr: "10.0.0.2:443", Data: []byte("foo")},
+				{Time: 1500 * time.Millisecond, RemoteAddr: "10.0.0.2:443", Data: []byte("bar")},
+			}, localAddr)
+			trace, err := ReadPcapTrace(bytes.NewReader(data), []byte("keylog"), localAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(trace.KeyLog).To(Equal([]byte("keylog")))
+			Expect(trace.Packets).To(HaveLen(2))
+			Expect(trace.Packets[0].Sent).To(BeTrue())
+			Expect(trace.Packets[0].RemoteAddr).To(Equal("10.0.0.2:443"))
+			Expect(trace.Packets[0].Data).To(Equal([]byte("foo")))
+			Expect(trace.Packets[1].Sent).To(BeFalse())
+			Expect(trace.Packets[1].Time).To(Equal(1500 * time.Millisecond))
+			Expect(trace.Packets[1].Data).To(Equal([]byte("bar")))
+		})
+
+		It("skips packets of other connections", func() {
+			data := writePcap([]TracePacket{
+				{Sent: true, RemoteAddr: "10.0.0.2:443", Data: []byte("foo")},
+			}, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321})
+			trace, err := ReadPcapTrace(bytes.NewReader(data), nil, localAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(trace.Packets).To(BeEmpty())
+		})
+
+		It("only compares the port, if the local address doesn't contain an IP", func() {
+			data := writePcap([]TracePacket{{Sent: true, RemoteAddr: "10.0.0.2:443", Data: []byte("foo")}}, localAddr)
+			trace, err := ReadPcapTrace(bytes.NewReader(data), nil, &net.UDPAddr{Port: 1234})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(trace.Packets).To(HaveLen(1))
+		})
+
+		It("rejects data that is not a pcap file", func() {
+			_, err := ReadPcapTrace(bytes.NewReader(bytes.Repeat([]byte("foobar"), 10)), nil, localAddr)
+			Expect(err).To(MatchError("not a pcap file"))
+		})
+
+		It("rejects captures with truncated packets", func() {
+			data := writePcap([]TracePacket{{Sent: true, RemoteAddr: "10.0.0.2:443", Data: []byte("foo")}}, localAddr)
+			// increase the original length of the packet
+			data[24+12]++
+			_, err := ReadPcapTrace(bytes.NewReader(data), nil, localAddr)
+			Expect(err).To(MatchError("pcap contains truncated packets (increase the snapshot length)"))
+		})
+	})
+
+	Context("checking a recorded connection", func() {
+		var trace *Trace
+		var localAddr *net.UDPAddr
+
+		BeforeEach(func() {
+			ln, err := ListenAddr("localhost:0", testdata.GetTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			go func() {
+				defer GinkgoRecover()
+				sess, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				str, err := sess.AcceptStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = io.Copy(str, str)
+				Expect(err).ToNot(HaveOccurred())
+				str.Close()
+			}()
+
+			buf := &lockedBuffer{}
+			rec := NewTraceRecorder(buf)
+			udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+			Expect(err).ToNot(HaveOccurred())
+			localAddr = udpConn.LocalAddr().(*net.UDPAddr)
+			conn := rec.PacketConn(udpConn)
+			defer conn.Close()
+			sess, err := Dial(
+				conn,
+				ln.Addr(),
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				&tls.Config{
+					RootCAs:            testdata.GetRootCA(),
+					InsecureSkipVerify: true,
+					KeyLogWriter:       rec.KeyLogWriter(),
+				},
+				nil,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(sess.Close()).To(Succeed())
+			Expect(rec.Err()).ToNot(HaveOccurred())
+			trace, err = ReadTrace(bytes.NewReader(buf.Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("checks the packets", func() {
+			Expect(trace.CheckPackets()).To(Succeed())
+		})
+
+		It("decrypts the packets and parses the frames", func() {
+			var sentStreamData, rcvdStreamData []byte
+			encLevels := make(map[protocol.EncryptionLevel]bool)
+			Expect(trace.decryptPackets(func(p *decryptedPacket) {
+				encLevels[p.encryptionLevel] = true
+				for _, f := range p.frames {
+					if sf, ok := f.(*wire.StreamFrame); ok {
+						if p.sent {
+							sentStreamData = append(sentStreamData, sf.Data...)
+						} else {
+							rcvdStreamData = append(rcvdStreamData, sf.Data...)
+						}
+					}
+				}
+			})).To(Succeed())
+			Expect(encLevels).To(HaveKey(protocol.EncryptionInitial))
+			Expect(encLevels).To(HaveKey(protocol.EncryptionHandshake))
+			Expect(encLevels).To(HaveKey(protocol.Encryption1RTT))
+			Expect(sentStreamData).To(Equal([]byte("foobar")))
+			Expect(rcvdStreamData).To(Equal([]byte("foobar")))
+		})
+
+		It("checks the packets of a packet capture", func() {
+			pcapTrace, err := ReadPcapTrace(bytes.NewReader(writePcap(trace.Packets, localAddr)), trace.KeyLog, localAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pcapTrace.Packets).To(HaveLen(len(trace.Packets)))
+			Expect(pcapTrace.CheckPackets()).To(Succeed())
+		})
+
+		It("errors if a packet can't be decrypted", func() {
+			last := trace.Packets[len(trace.Packets)-1].Data
+			last[len(last)-1] ^= 0xff
+			err := trace.CheckPackets()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to decrypt"))
+			Expect(err.Error()).To(ContainSubstring("packet %d: ", len(trace.Packets)-1))
+		})
+	})
+})
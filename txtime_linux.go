@@ -0,0 +1,88 @@
+package quic
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// soTxTime is SO_TXTIME, which is also used as the type of the SCM_TXTIME control message.
+// It is not defined in the syscall package.
+const soTxTime = 0x3d
+
+const clockMonotonic = 1
+
+// sockTxTime is the struct sock_txtime passed to setsockopt(2) for SO_TXTIME.
+type sockTxTime struct {
+	clockID int32
+	flags   uint32
+}
+
+// enableTxTime enables SO_TXTIME on the socket, using CLOCK_MONOTONIC.
+// It returns false if the net.PacketConn is not a *net.UDPConn, or if the kernel doesn't support SO_TXTIME.
+func enableTxTime(c net.PacketConn) bool {
+	udpConn, ok := c.(*net.UDPConn)
+	if !ok {
+		return false
+	}
+	rawConn, err := udpConn.SyscallConn()
+	if err != nil {
+		return false
+	}
+	cfg := sockTxTime{clockID: clockMonotonic}
+	b := (*[unsafe.Sizeof(cfg)]byte)(unsafe.Pointer(&cfg))[:]
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, soTxTime, string(b))
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+// writeWithTxTime writes a packet that the kernel transmits after delay.
+// The socket must have SO_TXTIME enabled.
+func writeWithTxTime(c net.PacketConn, b []byte, addr net.Addr, delay time.Duration) error {
+	udpConn, ok := c.(*net.UDPConn)
+	udpAddr, ok2 := addr.(*net.UDPAddr)
+	if !ok || !ok2 {
+		_, err := c.WriteTo(b, addr)
+		return err
+	}
+	_, _, err := udpConn.WriteMsgUDP(b, appendTxTime(nil, monotonicNow()+delay), udpAddr)
+	return err
+}
+
+// appendTxTime appends a SCM_TXTIME control message.
+func appendTxTime(b []byte, txTime time.Duration) []byte {
+	const dataLen = 8
+	start := len(b)
+	b = append(b, make([]byte, syscall.CmsgSpace(dataLen))...)
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[start]))
+	h.Level = syscall.SOL_SOCKET
+	h.Type = soTxTime
+	h.SetLen(syscall.CmsgLen(dataLen))
+	*(*uint64)(unsafe.Pointer(&b[start+syscall.CmsgLen(0)])) = uint64(txTime)
+	return b
+}
+
+var monotonicBase struct {
+	once sync.Once
+	mono time.Duration // CLOCK_MONOTONIC at the time of the wall clock reading
+	wall time.Time
+}
+
+// monotonicNow returns the current value of CLOCK_MONOTONIC.
+// It only reads the clock once, and then uses the monotonic clock reading of time.Now(),
+// which is based on the same clock.
+func monotonicNow() time.Duration {
+	monotonicBase.once.Do(func() {
+		var ts syscall.Timespec
+		syscall.Syscall(syscall.SYS_CLOCK_GETTIME, clockMonotonic, uintptr(unsafe.Pointer(&ts)), 0)
+		monotonicBase.wall = time.Now()
+		monotonicBase.mono = time.Duration(ts.Nano())
+	})
+	return monotonicBase.mono + time.Since(monotonicBase.wall)
+}
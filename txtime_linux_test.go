@@ -0,0 +1,55 @@
+package quic
+
+import (
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SO_TXTIME", func() {
+	It("encodes the SCM_TXTIME control message", func() {
+		b := appendTxTime(nil, 1337*time.Microsecond)
+		msgs, err := syscall.ParseSocketControlMessage(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msgs).To(HaveLen(1))
+		Expect(msgs[0].Header.Level).To(BeEquivalentTo(syscall.SOL_SOCKET))
+		Expect(msgs[0].Header.Type).To(BeEquivalentTo(soTxTime))
+		Expect(msgs[0].Data).To(HaveLen(8))
+		Expect(*(*uint64)(unsafe.Pointer(&msgs[0].Data[0]))).To(BeEquivalentTo(1337 * time.Microsecond))
+	})
+
+	It("doesn't enable SO_TXTIME for connections that are not UDP connections", func() {
+		Expect(enableTxTime(newMockPacketConn())).To(BeFalse())
+	})
+
+	It("sends packets with a transmission time", func() {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		server, err := net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+		client, err := net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		defer client.Close()
+		if !enableTxTime(client) {
+			Skip("SO_TXTIME not supported by the kernel")
+		}
+		Expect(writeWithTxTime(client, []byte("foobar"), server.LocalAddr(), time.Millisecond)).To(Succeed())
+		b := make([]byte, 100)
+		server.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := server.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("foobar")))
+	})
+
+	It("reads CLOCK_MONOTONIC", func() {
+		t1 := monotonicNow()
+		time.Sleep(time.Millisecond)
+		t2 := monotonicNow()
+		Expect(t2 - t1).To(BeNumerically(">=", time.Millisecond))
+	})
+})
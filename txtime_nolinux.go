@@ -0,0 +1,17 @@
+// +build !linux
+
+package quic
+
+import (
+	"net"
+	"time"
+)
+
+func enableTxTime(net.PacketConn) bool {
+	return false
+}
+
+func writeWithTxTime(c net.PacketConn, b []byte, addr net.Addr, _ time.Duration) error {
+	_, err := c.WriteTo(b, addr)
+	return err
+}
@@ -0,0 +1,88 @@
+package quic
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// UDPSocketStats contains diagnostic information about a UDP socket.
+type UDPSocketStats struct {
+	// ReceiveBufferSize is the size of the receive buffer, as granted by the OS.
+	ReceiveBufferSize int
+	// SendBufferSize is the size of the send buffer, as granted by the OS.
+	SendBufferSize int
+	// ReceiveQueueDrops is the number of packets that the OS dropped since the socket was opened,
+	// because the receive buffer was full.
+	// It is only available on Linux, and -1 on all other platforms.
+	ReceiveQueueDrops int64
+}
+
+var errSocketStatsNotSupported = errors.New("quic: socket statistics are not supported for this net.PacketConn on this platform")
+
+// GetUDPSocketStats returns diagnostic information about a UDP socket.
+// The net.PacketConn must provide access to the underlying socket, i.e. it must implement syscall.Conn, like the *net.UDPConn does.
+func GetUDPSocketStats(conn net.PacketConn) (*UDPSocketStats, error) {
+	c, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil, errSocketStatsNotSupported
+	}
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	rcvBuf, sndBuf, err := getSocketBufferSizes(rawConn)
+	if err != nil {
+		return nil, err
+	}
+	drops, err := getReceiveQueueDrops(rawConn)
+	if err != nil {
+		return nil, err
+	}
+	return &UDPSocketStats{
+		ReceiveBufferSize: rcvBuf,
+		SendBufferSize:    sndBuf,
+		ReceiveQueueDrops: drops,
+	}, nil
+}
+
+type bufferSizeSetter interface {
+	SetReadBuffer(int) error
+	SetWriteBuffer(int) error
+}
+
+// setSocketBuffers tries to increase the buffers of a socket created by quic-go.
+// A non-positive size leaves the respective buffer unchanged.
+// The OS might grant a smaller buffer than requested. This is logged, but not treated as an error.
+func setSocketBuffers(conn net.PacketConn, rcvBufSize, sndBufSize int, logger utils.Logger) {
+	c, ok := conn.(bufferSizeSetter)
+	if !ok {
+		return
+	}
+	if rcvBufSize > 0 {
+		if err := c.SetReadBuffer(rcvBufSize); err != nil {
+			logger.Infof("Failed to set the receive buffer size to %d bytes: %s", rcvBufSize, err)
+		}
+	}
+	if sndBufSize > 0 {
+		if err := c.SetWriteBuffer(sndBufSize); err != nil {
+			logger.Infof("Failed to set the send buffer size to %d bytes: %s", sndBufSize, err)
+		}
+	}
+	stats, err := GetUDPSocketStats(conn)
+	if err != nil {
+		return
+	}
+	if rcvBufSize > 0 && stats.ReceiveBufferSize < rcvBufSize {
+		logger.Infof("Requested a receive buffer of %d bytes, but only got %d bytes. Packets might be dropped under load.", rcvBufSize, stats.ReceiveBufferSize)
+	} else {
+		logger.Debugf("Using a receive buffer of %d bytes.", stats.ReceiveBufferSize)
+	}
+	if sndBufSize > 0 && stats.SendBufferSize < sndBufSize {
+		logger.Infof("Requested a send buffer of %d bytes, but only got %d bytes.", sndBufSize, stats.SendBufferSize)
+	} else {
+		logger.Debugf("Using a send buffer of %d bytes.", stats.SendBufferSize)
+	}
+}
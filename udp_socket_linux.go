@@ -0,0 +1,68 @@
+package quic
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// getReceiveQueueDrops reads the number of dropped packets from /proc/net/udp (or /proc/net/udp6).
+// The socket is identified by its inode.
+func getReceiveQueueDrops(c syscall.RawConn) (int64, error) {
+	var stat syscall.Stat_t
+	var serr error
+	if err := c.Control(func(fd uintptr) {
+		serr = syscall.Fstat(int(fd), &stat)
+	}); err != nil {
+		return 0, err
+	}
+	if serr != nil {
+		return 0, serr
+	}
+	inode := strconv.FormatUint(uint64(stat.Ino), 10)
+	for _, filename := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		drops, found, err := readDropsFromProc(filename, inode)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			return drops, nil
+		}
+	}
+	return -1, nil
+}
+
+func readDropsFromProc(filename, inode string) (int64, bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	defer f.Close()
+	return parseProcNetUDP(f, inode)
+}
+
+// parseProcNetUDP parses the drops column of the line for the socket with the given inode.
+// The format of the file is:
+// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode ref pointer drops
+func parseProcNetUDP(r io.Reader, inode string) (int64, bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // skip the header line
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 || fields[9] != inode {
+			continue
+		}
+		drops, err := strconv.ParseInt(fields[12], 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		return drops, true, nil
+	}
+	return 0, false, scanner.Err()
+}
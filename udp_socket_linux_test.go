@@ -0,0 +1,28 @@
+package quic
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parsing /proc/net/udp", func() {
+	const procNetUDP = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  246: 00000000:14E9 00000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 19180 2 0000000000000000 0
+  890: 0100007F:976D 00000000:0000 07 00000000:00000000 00:00000000 00000000  1000        0 1337 2 0000000000000000 42
+`
+
+	It("finds the drops for a socket", func() {
+		drops, found, err := parseProcNetUDP(bytes.NewReader([]byte(procNetUDP)), "1337")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(drops).To(BeEquivalentTo(42))
+	})
+
+	It("doesn't find unknown sockets", func() {
+		_, found, err := parseProcNetUDP(bytes.NewReader([]byte(procNetUDP)), "42")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+})
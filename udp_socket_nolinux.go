@@ -0,0 +1,9 @@
+// +build !linux
+
+package quic
+
+import "syscall"
+
+func getReceiveQueueDrops(syscall.RawConn) (int64, error) {
+	return -1, nil
+}
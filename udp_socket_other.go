@@ -0,0 +1,9 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package quic
+
+import "syscall"
+
+func getSocketBufferSizes(syscall.RawConn) (int, int, error) {
+	return 0, 0, errSocketStatsNotSupported
+}
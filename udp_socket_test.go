@@ -0,0 +1,67 @@
+package quic
+
+import (
+	"net"
+	"runtime"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UDP socket", func() {
+	var conn *net.UDPConn
+
+	BeforeEach(func() {
+		var err error
+		conn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(conn.Close()).To(Succeed())
+	})
+
+	It("errors for net.PacketConns that don't provide access to the socket", func() {
+		_, err := GetUDPSocketStats(newMockPacketConn())
+		Expect(err).To(MatchError(errSocketStatsNotSupported))
+	})
+
+	It("sets the buffer sizes", func() {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+			Skip("reading the buffer sizes is not supported on " + runtime.GOOS)
+		}
+		// use buffer sizes that are small enough to be granted without special privileges
+		setSocketBuffers(conn, 50000, 60000, utils.DefaultLogger)
+		stats, err := GetUDPSocketStats(conn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.ReceiveBufferSize).To(Equal(50000))
+		Expect(stats.SendBufferSize).To(Equal(60000))
+	})
+
+	It("doesn't change the buffer sizes, if requested", func() {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+			Skip("reading the buffer sizes is not supported on " + runtime.GOOS)
+		}
+		before, err := GetUDPSocketStats(conn)
+		Expect(err).ToNot(HaveOccurred())
+		setSocketBuffers(conn, -1, -1, utils.DefaultLogger)
+		after, err := GetUDPSocketStats(conn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(after.ReceiveBufferSize).To(Equal(before.ReceiveBufferSize))
+		Expect(after.SendBufferSize).To(Equal(before.SendBufferSize))
+	})
+
+	It("reports the receive queue drops", func() {
+		stats, err := GetUDPSocketStats(conn)
+		if runtime.GOOS != "linux" {
+			if err == nil {
+				Expect(stats.ReceiveQueueDrops).To(BeEquivalentTo(-1))
+			}
+			return
+		}
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.ReceiveQueueDrops).To(BeZero())
+	})
+})
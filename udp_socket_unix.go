@@ -0,0 +1,31 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package quic
+
+import (
+	"runtime"
+	"syscall"
+)
+
+func getSocketBufferSizes(c syscall.RawConn) (rcvBuf, sndBuf int, err error) {
+	var serr error
+	if err := c.Control(func(fd uintptr) {
+		rcvBuf, serr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		if serr != nil {
+			return
+		}
+		sndBuf, serr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+	}); err != nil {
+		return 0, 0, err
+	}
+	if serr != nil {
+		return 0, 0, serr
+	}
+	// Linux doubles the value set by setsockopt(2), to allow space for bookkeeping overhead,
+	// and returns this doubled value.
+	if runtime.GOOS == "linux" {
+		rcvBuf /= 2
+		sndBuf /= 2
+	}
+	return rcvBuf, sndBuf, nil
+}